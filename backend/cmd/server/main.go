@@ -10,6 +10,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	lksdk "github.com/livekit/server-sdk-go/v2"
 
 	"github.com/M-McCallum/thicket/internal/auth"
 	"github.com/M-McCallum/thicket/internal/config"
@@ -90,9 +91,14 @@ func main() {
 
 	friendService := service.NewFriendService(queries)
 	stageService := service.NewStageService(queries, permissionService)
+	livekitRooms := lksdk.NewRoomServiceClient(cfg.LiveKit.URL, cfg.LiveKit.APIKey, cfg.LiveKit.APISecret)
+	voiceService := service.NewVoiceService(queries, permissionService, livekitRooms)
 	soundboardService := service.NewSoundboardService(queries, storageClient)
-	botService := service.NewBotService(queries)
+	botService := service.NewBotService(queries, permissionService)
 	webhookService := service.NewWebhookService(queries, permissionService)
+	interactionService := service.NewInteractionService(queries, permissionService, messageService)
+	eventSubscriptionService := service.NewEventSubscriptionService(queries, permissionService)
+	eventSubscriptionService.Start()
 	exportService := service.NewExportService(queries)
 	forumService := service.NewForumService(queries, permissionService)
 	onboardingService := service.NewOnboardingService(queries, permissionService)
@@ -118,6 +124,23 @@ func main() {
 
 	// WebSocket hub
 	hub := ws.NewHub()
+	switch cfg.WS.Bus {
+	case "":
+	case "postgres":
+		hub.SetBus(ws.NewPGBus(pool))
+		log.Printf("WebSocket bus: postgres (node %s)", hub.NodeID())
+	default:
+		log.Fatalf("Unknown WS_BUS %q", cfg.WS.Bus)
+	}
+	hub.SetSlowConsumerLimit(cfg.WS.SlowConsumerLimit)
+	eventLimits, err := ws.ParseEventLimits(cfg.WS.EventRateLimits)
+	if err != nil {
+		log.Fatalf("Invalid WS_EVENT_RATE_LIMITS: %v", err)
+	}
+	hub.SetEventLimits(eventLimits)
+	hub.SetMaxRateViolations(cfg.WS.MaxRateViolations)
+	hub.SetExternalVoiceState(cfg.LiveKit.Webhooks)
+	hub.SetEventObserver(eventSubscriptionService.Observe)
 	hub.SetOnConnect(func(userID uuid.UUID, username string) {
 		ctx := context.Background()
 		// Only set to "online" if the user was offline. Preserve preferred
@@ -169,11 +192,24 @@ func main() {
 	// Wire DM participants function for WS DM call events
 	ws.HandlerDMParticipantsFn = dmService.GetParticipantIDs
 
+	// Bots IDENTIFY with "Bot <token>"
+	ws.HandlerBotValidatorFn = botService.AuthenticateBot
+
+	// Optional initial state in READY
+	readyService := service.NewReadyService(queries, hub)
+	ws.HandlerReadyStateFn = readyService.BuildReadyState
+
+	// Only members who can view a channel may subscribe to its events
+	hub.SetChannelAccessFn(func(ctx context.Context, userID, channelID uuid.UUID) (bool, error) {
+		return permissionService.CanViewChannel(ctx, channelID, userID)
+	})
+
 	go hub.Run()
 
 	// AutoMod service (needs hub for alerts)
 	automodService := service.NewAutoModService(queries, permissionService, hub)
 	messageService.SetAutoModService(automodService)
+	messageService.SetOnlineChecker(hub.IsOnline)
 
 	// Handlers
 	serverHandler := handler.NewServerHandler(serverService, channelService, hub)
@@ -190,10 +226,13 @@ func main() {
 	searchService := service.NewSearchService(queries)
 	searchHandler := handler.NewSearchHandler(searchService)
 	attachmentHandler := handler.NewAttachmentHandler(queries, storageClient)
-	stageHandler := handler.NewStageHandler(stageService, serverService, hub)
+	stageHandler := handler.NewStageHandler(stageService, serverService, voiceService, hub)
 	soundboardHandler := handler.NewSoundboardHandler(soundboardService, serverService)
 	botHandler := handler.NewBotHandler(botService)
-	webhookHandler := handler.NewWebhookHandler(webhookService, hub)
+	botAuthorizationHandler := handler.NewBotAuthorizationHandler(botService, serverService, identityService, hydraClient, hub, cfg.Ory.HydraBrowserURL, cfg.Ory.BotRedirectURL)
+	oryHandler.SetBotAuthorization(botAuthorizationHandler)
+	interactionHandler := handler.NewInteractionHandler(interactionService, hub)
+	eventSubscriptionHandler := handler.NewEventSubscriptionHandler(eventSubscriptionService)
 	keysHandler := handler.NewKeysHandler(keysService)
 	exportHandler := handler.NewExportHandler(exportService)
 	forumHandler := handler.NewForumHandler(forumService, hub)
@@ -212,6 +251,7 @@ func main() {
 	// Attachment + upload handlers
 	attachmentService := service.NewAttachmentService(queries, storageClient)
 	uploadHandler := handler.NewUploadHandler(attachmentService)
+	webhookHandler := handler.NewWebhookHandler(webhookService, attachmentService, hub)
 
 	// Fiber app
 	app := fiber.New(fiber.Config{
@@ -221,7 +261,7 @@ func main() {
 	})
 
 	// LiveKit handler
-	livekitHandler := handler.NewLiveKitHandler(serverService, dmService, cfg.LiveKit.APIKey, cfg.LiveKit.APISecret)
+	livekitHandler := handler.NewLiveKitHandler(serverService, dmService, voiceService, hub, cfg.LiveKit.APIKey, cfg.LiveKit.APISecret)
 
 	// GIF handler (only if GIPHY API key configured)
 	var gifHandler *handler.GifHandler
@@ -235,6 +275,7 @@ func main() {
 		DMHandler:          dmHandler,
 		OryHandler:         oryHandler,
 		LiveKitHandler:     livekitHandler,
+		LiveKitWebhooks:    cfg.LiveKit.Webhooks,
 		UserHandler:        userHandler,
 		EmojiHandler:       emojiHandler,
 		GifHandler:         gifHandler,
@@ -260,11 +301,15 @@ func main() {
 		StageHandler:       stageHandler,
 		SoundboardHandler:  soundboardHandler,
 		BotHandler:         botHandler,
+		BotAuthorizationHandler: botAuthorizationHandler,
 		WebhookHandler:     webhookHandler,
+		EventSubscriptionHandler: eventSubscriptionHandler,
+		InteractionHandler: interactionHandler,
 		ExportHandler:      exportHandler,
 		UploadHandler:      uploadHandler,
 		KeysHandler:        keysHandler,
 		JWKSManager:        jwksManager,
+		BotValidator:       botService.AuthenticateBot,
 		Hub:                hub,
 		CoMemberIDsFn:      serverService.GetUserCoMemberIDs,
 		ServerMemberIDsFn:  serverService.GetServerMemberUserIDs,
@@ -272,6 +317,16 @@ func main() {
 		StorageClient:      storageClient,
 	})
 
+	// Operator endpoints on a listener of their own, off the public port
+	internal := fiber.New(fiber.Config{AppName: "Thicket internal"})
+	router.SetupInternal(internal, hub)
+	go func() {
+		log.Printf("Internal endpoints listening on %s", cfg.API.InternalAddr)
+		if err := internal.Listen(cfg.API.InternalAddr, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
+			log.Printf("Internal listener stopped: %v", err)
+		}
+	}()
+
 	addr := fmt.Sprintf("%s:%s", cfg.API.Host, cfg.API.Port)
 	log.Printf("Thicket API starting on %s", addr)
 	if err := app.Listen(addr); err != nil {
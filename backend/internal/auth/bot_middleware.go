@@ -44,3 +44,34 @@ func BotOrUserMiddleware(jwksManager *JWKSManager, botValidator BotValidator) fi
 		return jwtMiddleware(c)
 	}
 }
+
+// IsBot reports whether the request was authenticated with a bot token.
+func IsBot(c fiber.Ctx) bool {
+	isBot, _ := c.Locals("isBot").(bool)
+	return isBot
+}
+
+// RequireUser rejects requests authenticated with a bot token, for routes
+// only a human account should reach.
+func RequireUser() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if IsBot(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "bots cannot use this endpoint",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireBot rejects requests not authenticated with a bot token.
+func RequireBot() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !IsBot(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "only bots can use this endpoint",
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/testutil"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBotTestApp(jwksManager *auth.JWKSManager, validator auth.BotValidator) *fiber.App {
+	app := fiber.New()
+	app.Use(auth.BotOrUserMiddleware(jwksManager, validator))
+
+	app.Get("/protected", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"user_id": auth.GetUserID(c).String(),
+			"is_bot":  auth.IsBot(c),
+		})
+	})
+	app.Get("/humans", auth.RequireUser(), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	return app
+}
+
+func TestBotOrUserMiddleware(t *testing.T) {
+	jwksServer := testutil.NewTestJWKSServer()
+	defer jwksServer.Close()
+
+	botID := uuid.New()
+	validator := func(_ context.Context, token string) (uuid.UUID, string, error) {
+		if token != "abc.secret" {
+			return uuid.Nil, "", errors.New("invalid")
+		}
+		return botID, "bot", nil
+	}
+	app := setupBotTestApp(auth.NewJWKSManager(jwksServer.JWKSURL()), validator)
+	userToken := jwksServer.CreateToken(uuid.New(), "human")
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{"bot token", "/protected", "Bot abc.secret", http.StatusOK},
+		{"bad bot token", "/protected", "Bot abc.wrong", http.StatusUnauthorized},
+		{"user token", "/protected", "Bearer " + userToken, http.StatusOK},
+		{"bot on user-only route", "/humans", "Bot abc.secret", http.StatusForbidden},
+		{"user on user-only route", "/humans", "Bearer " + userToken, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", tt.header)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -12,9 +13,25 @@ type Config struct {
 	Ory     OryConfig
 	MinIO   MinIOConfig
 	Giphy   GiphyConfig
+	WS      WSConfig
 	Env     string
 }
 
+type WSConfig struct {
+	// Bus selects the cross-node fan-out backend for the WebSocket hub.
+	// Empty runs a single-replica hub; "postgres" uses LISTEN/NOTIFY.
+	Bus string
+	// SlowConsumerLimit is how many frames a socket may drop before it is
+	// disconnected. Zero uses the hub default.
+	SlowConsumerLimit int
+	// EventRateLimits overrides per-connection client event limits, e.g.
+	// "TYPING_START=5/10s,DM_CALL_START=3/1m".
+	EventRateLimits string
+	// MaxRateViolations is how many rate-limited events a socket may send
+	// per minute before it is disconnected. Zero uses the hub default.
+	MaxRateViolations int
+}
+
 type GiphyConfig struct {
 	APIKey string
 }
@@ -33,7 +50,11 @@ type OryConfig struct {
 	KratosBrowserURL string // URL the browser uses to reach Kratos (may differ from internal)
 	KratosAdminURL   string
 	HydraPublicURL   string
+	HydraBrowserURL  string // URL the browser uses to reach Hydra (may differ from internal)
 	HydraAdminURL    string
+	// BotRedirectURL is where Hydra sends the browser back to once a bot
+	// authorization is approved or cancelled.
+	BotRedirectURL string
 }
 
 func (c OryConfig) JWKSURL() string {
@@ -58,12 +79,17 @@ type APIConfig struct {
 	Port       string
 	Host       string
 	CORSOrigin string
+	// InternalAddr is a separate listener for operator endpoints such as
+	// /health/ws. Keep it off the public network.
+	InternalAddr string
 }
 
 type LiveKitConfig struct {
 	APIKey    string
 	APISecret string
 	URL       string
+	// Webhooks makes LiveKit room events the source of voice state
+	Webhooks bool
 }
 
 func Load() (*Config, error) {
@@ -85,21 +111,25 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSL_MODE", sslDefault),
 		},
 		API: APIConfig{
-			Port:       getEnv("API_PORT", "8080"),
-			Host:       getEnv("API_HOST", "0.0.0.0"),
-			CORSOrigin: getEnv("CORS_ORIGIN", "http://localhost:5173"),
+			Port:         getEnv("API_PORT", "8080"),
+			Host:         getEnv("API_HOST", "0.0.0.0"),
+			CORSOrigin:   getEnv("CORS_ORIGIN", "http://localhost:5173"),
+			InternalAddr: getEnv("INTERNAL_ADDR", "127.0.0.1:9090"),
 		},
 		LiveKit: LiveKitConfig{
 			APIKey:    getEnv("LIVEKIT_API_KEY", "devkey"),
 			APISecret: getEnv("LIVEKIT_API_SECRET", "secret"),
 			URL:       getEnv("LIVEKIT_URL", "ws://localhost:7880"),
+			Webhooks:  getEnv("LIVEKIT_WEBHOOKS", "false") == "true",
 		},
 		Ory: OryConfig{
 			KratosPublicURL:  getEnv("KRATOS_PUBLIC_URL", "http://localhost:4433"),
 			KratosBrowserURL: getEnv("KRATOS_BROWSER_URL", getEnv("KRATOS_PUBLIC_URL", "http://localhost:4433")),
 			KratosAdminURL:   getEnv("KRATOS_ADMIN_URL", "http://localhost:4434"),
 			HydraPublicURL:   getEnv("HYDRA_PUBLIC_URL", "http://localhost:4444"),
+			HydraBrowserURL:  getEnv("HYDRA_BROWSER_URL", getEnv("HYDRA_PUBLIC_URL", "http://localhost:4444")),
 			HydraAdminURL:    getEnv("HYDRA_ADMIN_URL", "http://localhost:4445"),
+			BotRedirectURL:   getEnv("BOT_REDIRECT_URL", "http://localhost:8080/auth/bot/authorized"),
 		},
 		MinIO: MinIOConfig{
 			Endpoint:       getEnv("MINIO_ENDPOINT", "localhost:9000"),
@@ -112,6 +142,12 @@ func Load() (*Config, error) {
 		Giphy: GiphyConfig{
 			APIKey: getEnv("GIPHY_API_KEY", ""),
 		},
+		WS: WSConfig{
+			Bus:               getEnv("WS_BUS", ""),
+			SlowConsumerLimit: getEnvInt("WS_SLOW_CONSUMER_LIMIT", 0),
+			EventRateLimits:   getEnv("WS_EVENT_RATE_LIMITS", ""),
+			MaxRateViolations: getEnvInt("WS_MAX_RATE_VIOLATIONS", 0),
+		},
 		Env: env,
 	}
 
@@ -124,3 +160,10 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if val, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return val
+	}
+	return fallback
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ws_bus_payloads;
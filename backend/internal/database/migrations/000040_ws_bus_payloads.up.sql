@@ -0,0 +1,9 @@
+-- Overflow storage for cross-node WebSocket bus messages that exceed the
+-- 8000-byte pg_notify payload limit. Rows are short-lived and pruned by the
+-- listening nodes.
+CREATE TABLE ws_bus_payloads (
+    id         BIGSERIAL PRIMARY KEY,
+    payload    JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_ws_bus_payloads_created ON ws_bus_payloads(created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_bot_users_token_id;
+ALTER TABLE bot_users DROP COLUMN IF EXISTS token_id;
+ALTER TABLE bot_users DROP CONSTRAINT IF EXISTS bot_users_id_fkey;
+
+DELETE FROM users WHERE is_bot;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_kratos_id_required;
+ALTER TABLE users ALTER COLUMN kratos_id SET NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
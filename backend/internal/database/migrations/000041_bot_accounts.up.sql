@@ -0,0 +1,25 @@
+-- Bots get a backing users row (sharing the bot's ID) so they can be server
+-- members, message authors and role holders.
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ALTER COLUMN kratos_id DROP NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_kratos_id_required CHECK (is_bot OR kratos_id IS NOT NULL);
+
+INSERT INTO users (id, username, email, avatar_url, is_bot)
+SELECT b.id,
+       CASE WHEN EXISTS (SELECT 1 FROM users u WHERE u.username = b.username)
+            THEN left(b.username, 23) || '_' || left(b.id::text, 8)
+            ELSE b.username END,
+       b.id::text || '@bots.invalid',
+       NULLIF(b.avatar_url, ''),
+       true
+FROM bot_users b;
+
+ALTER TABLE bot_users ADD CONSTRAINT bot_users_id_fkey
+    FOREIGN KEY (id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- Tokens are "<token_id>.<secret>": token_id is an indexed lookup key and
+-- token_hash is the SHA-256 of the secret. Existing bcrypt tokens cannot be
+-- converted, so those bots must regenerate their token.
+ALTER TABLE bot_users ADD COLUMN token_id TEXT;
+UPDATE bot_users SET token_hash = '';
+CREATE UNIQUE INDEX idx_bot_users_token_id ON bot_users(token_id);
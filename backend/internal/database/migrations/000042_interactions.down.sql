@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS interactions;
+DROP INDEX IF EXISTS idx_slash_commands_server;
+DROP INDEX IF EXISTS idx_slash_commands_scope_name;
+ALTER TABLE bot_users DROP COLUMN IF EXISTS interactions_secret;
+ALTER TABLE bot_users DROP COLUMN IF EXISTS interactions_url;
@@ -0,0 +1,31 @@
+-- Bots that set an interactions URL receive interactions as signed HTTP
+-- callbacks instead of INTERACTION_CREATE gateway events.
+ALTER TABLE bot_users ADD COLUMN interactions_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE bot_users ADD COLUMN interactions_secret TEXT NOT NULL DEFAULT '';
+
+-- Registering a command with an existing name in the same scope replaces it.
+DELETE FROM slash_commands a USING slash_commands b
+WHERE a.bot_id = b.bot_id
+  AND a.server_id IS NOT DISTINCT FROM b.server_id
+  AND a.name = b.name
+  AND a.created_at < b.created_at;
+CREATE UNIQUE INDEX idx_slash_commands_scope_name
+    ON slash_commands (bot_id, COALESCE(server_id, '00000000-0000-0000-0000-000000000000'::uuid), name);
+CREATE INDEX idx_slash_commands_server ON slash_commands(server_id);
+
+CREATE TABLE interactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type TEXT NOT NULL,
+    bot_id UUID NOT NULL REFERENCES bot_users(id) ON DELETE CASCADE,
+    command_id UUID REFERENCES slash_commands(id) ON DELETE SET NULL,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    data JSONB NOT NULL DEFAULT '{}',
+    token_hash TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    response_message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_interactions_created ON interactions(created_at);
@@ -0,0 +1,11 @@
+DELETE FROM interactions WHERE bot_id IS NULL;
+ALTER TABLE interactions DROP CONSTRAINT IF EXISTS interactions_target_required;
+ALTER TABLE interactions DROP COLUMN IF EXISTS message_id;
+ALTER TABLE interactions DROP COLUMN IF EXISTS webhook_id;
+ALTER TABLE interactions ALTER COLUMN bot_id SET NOT NULL;
+
+ALTER TABLE webhooks DROP COLUMN IF EXISTS interactions_secret;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS interactions_url;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS webhook_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS components;
@@ -0,0 +1,16 @@
+-- Component rows (buttons and selects) attached to bot and webhook messages.
+ALTER TABLE messages ADD COLUMN components JSONB;
+
+-- Webhook messages remember their webhook so component clicks can be routed
+-- back to it.
+ALTER TABLE messages ADD COLUMN webhook_id UUID REFERENCES webhooks(id) ON DELETE SET NULL;
+
+ALTER TABLE webhooks ADD COLUMN interactions_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE webhooks ADD COLUMN interactions_secret TEXT NOT NULL DEFAULT '';
+
+-- Component interactions belong to a message and go to its bot or webhook.
+ALTER TABLE interactions ALTER COLUMN bot_id DROP NOT NULL;
+ALTER TABLE interactions ADD COLUMN webhook_id UUID REFERENCES webhooks(id) ON DELETE CASCADE;
+ALTER TABLE interactions ADD COLUMN message_id UUID REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE interactions ADD CONSTRAINT interactions_target_required
+    CHECK (bot_id IS NOT NULL OR webhook_id IS NOT NULL);
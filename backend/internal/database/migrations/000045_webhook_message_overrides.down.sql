@@ -0,0 +1,8 @@
+ALTER TABLE thread_messages DROP COLUMN IF EXISTS webhook_avatar_url;
+ALTER TABLE thread_messages DROP COLUMN IF EXISTS webhook_username;
+ALTER TABLE thread_messages DROP COLUMN IF EXISTS webhook_id;
+ALTER TABLE thread_messages DROP COLUMN IF EXISTS embeds;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS webhook_avatar_url;
+ALTER TABLE messages DROP COLUMN IF EXISTS webhook_username;
+ALTER TABLE messages DROP COLUMN IF EXISTS embeds;
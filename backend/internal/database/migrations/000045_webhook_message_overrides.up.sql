@@ -0,0 +1,13 @@
+-- Rich embeds on webhook messages.
+ALTER TABLE messages ADD COLUMN embeds JSONB;
+
+-- The name and avatar a webhook message was posted under: the webhook's own,
+-- or the overrides sent with that execution.
+ALTER TABLE messages ADD COLUMN webhook_username TEXT;
+ALTER TABLE messages ADD COLUMN webhook_avatar_url TEXT;
+
+-- Webhooks can also post into threads.
+ALTER TABLE thread_messages ADD COLUMN embeds JSONB;
+ALTER TABLE thread_messages ADD COLUMN webhook_id UUID REFERENCES webhooks(id) ON DELETE SET NULL;
+ALTER TABLE thread_messages ADD COLUMN webhook_username TEXT;
+ALTER TABLE thread_messages ADD COLUMN webhook_avatar_url TEXT;
@@ -0,0 +1,3 @@
+-- Shared secret that GitHub and GitLab sign their deliveries with when they
+-- post through a webhook's provider adapter.
+ALTER TABLE webhooks ADD COLUMN adapter_secret TEXT NOT NULL DEFAULT '';
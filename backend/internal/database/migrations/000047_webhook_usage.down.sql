@@ -0,0 +1,2 @@
+ALTER TABLE webhooks DROP COLUMN IF EXISTS message_count;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS last_used_at;
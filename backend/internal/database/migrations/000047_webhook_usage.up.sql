@@ -0,0 +1,3 @@
+-- Usage stats shown to the people managing a webhook.
+ALTER TABLE webhooks ADD COLUMN last_used_at TIMESTAMPTZ;
+ALTER TABLE webhooks ADD COLUMN message_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DELETE FROM roles WHERE managed_bot_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_roles_managed_bot;
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_managed_bot_fkey;
+ALTER TABLE roles DROP COLUMN IF EXISTS managed_bot_id;
@@ -0,0 +1,7 @@
+-- Authorizing a bot into a server gives it a managed role holding the
+-- approved permissions. The role hangs off the bot's membership, so it is
+-- removed whenever the bot leaves, is kicked or banned, or is deleted.
+ALTER TABLE roles ADD COLUMN managed_bot_id UUID;
+ALTER TABLE roles ADD CONSTRAINT roles_managed_bot_fkey
+    FOREIGN KEY (server_id, managed_bot_id) REFERENCES server_members(server_id, user_id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX idx_roles_managed_bot ON roles(server_id, managed_bot_id) WHERE managed_bot_id IS NOT NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_mention_notifications_message;
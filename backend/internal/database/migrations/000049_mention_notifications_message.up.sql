@@ -0,0 +1 @@
+CREATE INDEX idx_mention_notifications_message ON mention_notifications(message_id);
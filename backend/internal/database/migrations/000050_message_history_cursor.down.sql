@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_messages_channel_created;
+CREATE INDEX idx_messages_channel_created ON messages(channel_id, created_at DESC);
+
+DROP INDEX IF EXISTS idx_dm_messages_conv_created;
+CREATE INDEX idx_dm_messages_conv_created ON dm_messages(conversation_id, created_at DESC);
+
+DROP INDEX IF EXISTS idx_thread_messages_thread;
+CREATE INDEX idx_thread_messages_thread ON thread_messages(thread_id, created_at);
+
+DROP INDEX IF EXISTS idx_forum_post_messages_post;
+CREATE INDEX idx_forum_post_messages_post ON forum_post_messages(post_id, created_at ASC);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_forum_post_messages_nonce;
+ALTER TABLE forum_post_messages DROP COLUMN IF EXISTS nonce;
+
+DROP INDEX IF EXISTS idx_thread_messages_nonce;
+ALTER TABLE thread_messages DROP COLUMN IF EXISTS nonce;
+
+DROP INDEX IF EXISTS idx_dm_messages_nonce;
+ALTER TABLE dm_messages DROP COLUMN IF EXISTS nonce;
+
+DROP INDEX IF EXISTS idx_messages_nonce;
+ALTER TABLE messages DROP COLUMN IF EXISTS nonce;
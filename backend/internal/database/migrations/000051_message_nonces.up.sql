@@ -0,0 +1,11 @@
+ALTER TABLE messages ADD COLUMN nonce TEXT;
+CREATE UNIQUE INDEX idx_messages_nonce ON messages(channel_id, author_id, nonce) WHERE nonce IS NOT NULL;
+
+ALTER TABLE dm_messages ADD COLUMN nonce TEXT;
+CREATE UNIQUE INDEX idx_dm_messages_nonce ON dm_messages(conversation_id, author_id, nonce) WHERE nonce IS NOT NULL;
+
+ALTER TABLE thread_messages ADD COLUMN nonce TEXT;
+CREATE UNIQUE INDEX idx_thread_messages_nonce ON thread_messages(thread_id, author_id, nonce) WHERE nonce IS NOT NULL;
+
+ALTER TABLE forum_post_messages ADD COLUMN nonce TEXT;
+CREATE UNIQUE INDEX idx_forum_post_messages_nonce ON forum_post_messages(post_id, author_id, nonce) WHERE nonce IS NOT NULL;
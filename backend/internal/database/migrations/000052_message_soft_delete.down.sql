@@ -0,0 +1,6 @@
+ALTER TABLE servers DROP COLUMN IF EXISTS deleted_message_display;
+
+DELETE FROM messages WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_messages_deleted;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
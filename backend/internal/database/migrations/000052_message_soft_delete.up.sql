@@ -0,0 +1,6 @@
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_messages_deleted ON messages(channel_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE servers ADD COLUMN deleted_message_display TEXT NOT NULL DEFAULT 'tombstone'
+    CHECK (deleted_message_display IN ('tombstone', 'hidden'));
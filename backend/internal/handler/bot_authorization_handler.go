@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/ory"
+	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+// botScope is the only scope bot clients are registered with.
+const botScope = "bot"
+
+// permissionNames labels the permission bits on the bot consent page.
+var permissionNames = []struct {
+	Bit  int64
+	Name string
+}{
+	{models.PermAdministrator, "Administrator"},
+	{models.PermViewChannels, "View Channels"},
+	{models.PermSendMessages, "Send Messages"},
+	{models.PermManageMessages, "Manage Messages"},
+	{models.PermManageChannels, "Manage Channels"},
+	{models.PermManageRoles, "Manage Roles"},
+	{models.PermKickMembers, "Kick Members"},
+	{models.PermBanMembers, "Ban Members"},
+	{models.PermManageServer, "Manage Server"},
+	{models.PermAddReactions, "Add Reactions"},
+	{models.PermAttachFiles, "Attach Files"},
+	{models.PermCreateInvite, "Create Invite"},
+	{models.PermMentionEveryone, "Mention @everyone and @here"},
+	{models.PermPinMessages, "Pin Messages"},
+	{models.PermVoiceConnect, "Connect to Voice"},
+	{models.PermVoiceSpeak, "Speak in Voice"},
+}
+
+// BotAuthorizationHandler runs the OAuth2 flow that adds a bot to a server.
+// Each bot is a Hydra client. Hydra authenticates the admin through the
+// regular login provider, then hands the consent step to RenderConsent,
+// where the admin picks a server and approves the requested permissions.
+type BotAuthorizationHandler struct {
+	botService      *service.BotService
+	serverService   *service.ServerService
+	identityService *service.IdentityService
+	hydraClient     *ory.HydraClient
+	hub             *ws.Hub
+	hydraBrowserURL string
+	redirectURL     string
+	consentStore    *challengeStore
+	consentTmpl     *template.Template
+	resultTmpl      *template.Template
+}
+
+// NewBotAuthorizationHandler creates a BotAuthorizationHandler. Hydra sends
+// the browser to redirectURL once the admin approves or cancels.
+func NewBotAuthorizationHandler(bs *service.BotService, ss *service.ServerService, is *service.IdentityService, hydraClient *ory.HydraClient, hub *ws.Hub, hydraBrowserURL, redirectURL string) *BotAuthorizationHandler {
+	return &BotAuthorizationHandler{
+		botService:      bs,
+		serverService:   ss,
+		identityService: is,
+		hydraClient:     hydraClient,
+		hub:             hub,
+		hydraBrowserURL: hydraBrowserURL,
+		redirectURL:     redirectURL,
+		consentStore:    newChallengeStore(),
+		consentTmpl:     mustParsePages("templates/base.html", "templates/bot_authorize.html"),
+		resultTmpl:      mustParsePages("templates/base.html", "templates/bot_authorized.html"),
+	}
+}
+
+// Authorize handles GET /auth/bot/authorize?client_id=<bot>&permissions=<n>&server_id=<optional>,
+// the link a bot owner shares to get their bot added to servers. It makes
+// sure the bot is registered with Hydra and starts an authorization request
+// there. Without a permissions parameter the bot's default permissions are
+// requested.
+func (h *BotAuthorizationHandler) Authorize(c fiber.Ctx) error {
+	botID, err := uuid.Parse(c.Query("client_id"))
+	if err != nil {
+		return h.renderResult(c, fiber.StatusBadRequest, "Invalid bot link", "The link is missing a valid client_id.")
+	}
+
+	bot, err := h.botService.GetBot(c.Context(), botID)
+	if err != nil {
+		if errors.Is(err, service.ErrBotNotFound) {
+			return h.renderResult(c, fiber.StatusNotFound, "Bot not found", "This bot no longer exists.")
+		}
+		return h.renderResult(c, fiber.StatusInternalServerError, "Something went wrong", "Please try again.")
+	}
+
+	permissions := bot.Permissions
+	if raw := c.Query("permissions"); raw != "" {
+		permissions, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || permissions < 0 || permissions&^models.PermAll != 0 {
+			return h.renderResult(c, fiber.StatusBadRequest, "Invalid bot link", "The link requests unknown permissions.")
+		}
+	}
+
+	if err := h.ensureClient(c.Context(), bot); err != nil {
+		log.Printf("Failed to register bot %s with Hydra: %v", bot.ID, err)
+		return h.renderResult(c, fiber.StatusBadGateway, "Something went wrong", "Please try again.")
+	}
+
+	// Hydra enforces PKCE. The code is never exchanged (the authorization
+	// itself happens at consent), so the verifier is not kept.
+	verifier := rand.Text() + rand.Text()
+	sum := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("client_id", bot.ID.String())
+	query.Set("response_type", "code")
+	query.Set("scope", botScope)
+	query.Set("redirect_uri", h.redirectURL)
+	query.Set("state", rand.Text())
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+	// Hydra keeps unknown parameters in the request URL it hands to consent
+	query.Set("permissions", strconv.FormatInt(permissions, 10))
+	if serverID, err := uuid.Parse(c.Query("server_id")); err == nil {
+		query.Set("server_id", serverID.String())
+	}
+
+	return c.Redirect().To(h.hydraBrowserURL + "/oauth2/auth?" + query.Encode())
+}
+
+// ensureClient registers the bot as a Hydra client the first time it is
+// authorized.
+func (h *BotAuthorizationHandler) ensureClient(ctx context.Context, bot *models.BotUser) error {
+	_, err := h.hydraClient.GetOAuth2Client(ctx, bot.ID.String())
+	if !errors.Is(err, ory.ErrNotFound) {
+		return err
+	}
+	_, err = h.hydraClient.CreateOAuth2Client(ctx, ory.OAuth2Client{
+		ClientID:                bot.ID.String(),
+		ClientName:              bot.Username,
+		Scope:                   botScope,
+		GrantTypes:              []string{"authorization_code"},
+		ResponseTypes:           []string{"code"},
+		RedirectURIs:            []string{h.redirectURL},
+		TokenEndpointAuthMethod: "none",
+		Metadata:                map[string]interface{}{"bot_id": bot.ID.String()},
+	})
+	return err
+}
+
+// RenderConsent shows the add-to-server page for a bot client's consent
+// request. OryHandler.GetConsent hands bot clients over to it.
+func (h *BotAuthorizationHandler) RenderConsent(c fiber.Ctx, challenge string, cr *ory.ConsentRequest) error {
+	return h.renderConsent(c, challenge, cr, "")
+}
+
+func (h *BotAuthorizationHandler) renderConsent(c fiber.Ctx, challenge string, cr *ory.ConsentRequest, errMsg string) error {
+	botID, _ := botClientID(cr.Client)
+	bot, err := h.botService.GetBot(c.Context(), botID)
+	if err != nil {
+		if errors.Is(err, service.ErrBotNotFound) {
+			return h.reject(c, challenge, "This bot no longer exists.")
+		}
+		return h.renderResult(c, fiber.StatusInternalServerError, "Something went wrong", "Please try again.")
+	}
+
+	user, err := h.identityService.FindOrCreateUser(c.Context(), cr.Subject)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sync user"})
+	}
+
+	servers, err := h.botService.AuthorizableServers(c.Context(), user.ID)
+	if err != nil {
+		return h.renderResult(c, fiber.StatusInternalServerError, "Something went wrong", "Please try again.")
+	}
+
+	permissions, serverID := botRequest(cr.RequestURL)
+	var names []string
+	for _, p := range permissionNames {
+		if permissions&p.Bit != 0 {
+			names = append(names, p.Name)
+		}
+	}
+
+	status := fiber.StatusOK
+	if errMsg != "" {
+		status = fiber.StatusBadRequest
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return h.consentTmpl.ExecuteTemplate(c.Status(status), "base", struct {
+		ID          string
+		Bot         *models.BotUser
+		Servers     []models.Server
+		ServerID    uuid.UUID
+		Permissions []string
+		Error       string
+	}{h.consentStore.put(challenge), bot, servers, serverID, names, errMsg})
+}
+
+// SubmitConsent handles POST /auth/bot/consent from the add-to-server page.
+// The short challenge ID in the form is single-use and only reachable by
+// the browser that went through Hydra's login, so it doubles as the CSRF
+// token. The acting user is taken from the consent request, never the form.
+func (h *BotAuthorizationHandler) SubmitConsent(c fiber.Ctx) error {
+	challenge := h.consentStore.take(c.FormValue("id"))
+	if challenge == "" {
+		return h.renderResult(c, fiber.StatusBadRequest, "Request expired", "Open the bot's link again to add it to a server.")
+	}
+
+	cr, err := h.hydraClient.GetConsentRequest(c.Context(), challenge)
+	if err != nil {
+		log.Printf("GetConsentRequest failed: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "failed to fetch consent request"})
+	}
+	botID, ok := botClientID(cr.Client)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "not a bot authorization request"})
+	}
+
+	if c.FormValue("action") != "authorize" {
+		return h.reject(c, challenge, "The authorization was cancelled.")
+	}
+
+	serverID, err := uuid.Parse(c.FormValue("server_id"))
+	if err != nil {
+		return h.renderConsent(c, challenge, cr, "Choose a server.")
+	}
+
+	user, err := h.identityService.FindOrCreateUser(c.Context(), cr.Subject)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sync user"})
+	}
+
+	permissions, _ := botRequest(cr.RequestURL)
+	result, err := h.botService.AuthorizeBot(c.Context(), botID, serverID, user.ID, permissions)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrInsufficientRole):
+		return h.renderConsent(c, challenge, cr, "You need Manage Server and every requested permission in that server.")
+	case errors.Is(err, service.ErrUserBanned):
+		return h.renderConsent(c, challenge, cr, "This bot is banned from that server.")
+	case errors.Is(err, service.ErrServerNotFound):
+		return h.renderConsent(c, challenge, cr, "Choose a server.")
+	case errors.Is(err, service.ErrBotNotFound), errors.Is(err, service.ErrInvalidBotPermissions):
+		return h.reject(c, challenge, err.Error())
+	default:
+		log.Printf("Failed to authorize bot %s in server %s: %v", botID, serverID, err)
+		return h.renderResult(c, fiber.StatusInternalServerError, "Something went wrong", "Please try again.")
+	}
+
+	h.announce(c, serverID, result)
+
+	completed, err := h.hydraClient.AcceptConsent(c.Context(), challenge, ory.AcceptConsentRequest{
+		GrantScope:               cr.RequestedScope,
+		GrantAccessTokenAudience: cr.RequestedAccessTokenAudience,
+		Session: &ory.ConsentSession{
+			AccessToken: map[string]interface{}{
+				"server_id": serverID.String(),
+			},
+		},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "failed to accept consent"})
+	}
+
+	return c.Redirect().To(completed.RedirectTo)
+}
+
+// Authorized handles GET /auth/bot/authorized, where Hydra returns the
+// browser when the flow ends.
+func (h *BotAuthorizationHandler) Authorized(c fiber.Ctx) error {
+	if c.Query("error") != "" {
+		desc := c.Query("error_description")
+		if desc == "" {
+			desc = "The bot was not added."
+		}
+		return h.renderResult(c, fiber.StatusOK, "Authorization cancelled", desc)
+	}
+	return h.renderResult(c, fiber.StatusOK, "Bot added", "The bot has joined the server. You can close this window.")
+}
+
+// RemoveBot removes a bot from a server along with its managed role.
+func (h *BotAuthorizationHandler) RemoveBot(c fiber.Ctx) error {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+	botID, err := uuid.Parse(c.Params("botId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid bot ID"})
+	}
+
+	// Get member IDs before removal (the bot is still a member)
+	memberIDs, _ := h.serverService.GetServerMemberUserIDs(c.Context(), serverID)
+
+	role, err := h.botService.RemoveBot(c.Context(), serverID, botID, auth.GetUserID(c))
+	if err != nil {
+		return handleBotError(c, err)
+	}
+	h.hub.RevalidateUser(botID)
+
+	event, _ := ws.NewEvent(ws.EventMemberLeave, fiber.Map{
+		"server_id": serverID,
+		"user_id":   botID,
+	})
+	if event != nil {
+		ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
+	}
+	broadcastManagedRoleDelete(h.hub, memberIDs, role)
+
+	return c.JSON(fiber.Map{"message": "bot removed"})
+}
+
+// announce tells the server about a newly added bot and its managed role,
+// or about the new permissions of a bot that was already there.
+func (h *BotAuthorizationHandler) announce(c fiber.Ctx, serverID uuid.UUID, result *service.BotAuthorization) {
+	memberIDs, err := h.serverService.GetServerMemberUserIDs(c.Context(), serverID)
+	if err != nil {
+		log.Printf("Failed to get member IDs for bot authorization broadcast: %v", err)
+		return
+	}
+
+	broadcast := func(eventType string, data any) {
+		event, _ := ws.NewEvent(eventType, data)
+		if event != nil {
+			ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
+		}
+	}
+
+	if !result.Joined {
+		broadcast(ws.EventRoleUpdate, result.Role)
+		h.hub.RevalidateUser(result.Bot.ID)
+		return
+	}
+	broadcast(ws.EventMemberJoin, fiber.Map{
+		"server_id": serverID,
+		"user_id":   result.Bot.ID,
+		"username":  result.Bot.Username,
+	})
+	broadcast(ws.EventRoleCreate, result.Role)
+	broadcast(ws.EventMemberRoleUpdate, fiber.Map{
+		"server_id": serverID,
+		"user_id":   result.Bot.ID,
+		"role_id":   result.Role.ID,
+		"action":    "assign",
+	})
+}
+
+// reject ends the Hydra flow with access_denied, sending the browser back
+// to the redirect URL with the reason.
+func (h *BotAuthorizationHandler) reject(c fiber.Ctx, challenge, reason string) error {
+	completed, err := h.hydraClient.RejectConsent(c.Context(), challenge, ory.RejectRequest{
+		Error:            "access_denied",
+		ErrorDescription: reason,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "failed to reject consent"})
+	}
+	return c.Redirect().To(completed.RedirectTo)
+}
+
+func (h *BotAuthorizationHandler) renderResult(c fiber.Ctx, status int, title, message string) error {
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return h.resultTmpl.ExecuteTemplate(c.Status(status), "base", struct {
+		Title   string
+		Message string
+	}{title, message})
+}
+
+// botClientID returns the bot behind a Hydra client registered by
+// ensureClient.
+func botClientID(client ory.OAuth2Client) (uuid.UUID, bool) {
+	if client.Metadata == nil {
+		return uuid.Nil, false
+	}
+	v, ok := client.Metadata["bot_id"].(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(v)
+	return id, err == nil
+}
+
+// botRequest reads the permissions and preselected server that Authorize
+// put in the authorization request URL.
+func botRequest(requestURL string) (int64, uuid.UUID) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return 0, uuid.Nil
+	}
+	query := u.Query()
+	permissions, _ := strconv.ParseInt(query.Get("permissions"), 10, 64)
+	serverID, _ := uuid.Parse(query.Get("server_id"))
+	return permissions, serverID
+}
+
+// broadcastManagedRoleDelete tells the remaining members that a departed
+// bot's managed role is gone with it.
+func broadcastManagedRoleDelete(hub *ws.Hub, memberIDs []uuid.UUID, role *models.Role) {
+	if role == nil {
+		return
+	}
+	event, _ := ws.NewEvent(ws.EventRoleDelete, fiber.Map{
+		"id":        role.ID,
+		"server_id": role.ServerID,
+	})
+	if event != nil {
+		ws.BroadcastToServerMembers(hub, memberIDs, event, nil)
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -80,15 +81,74 @@ func (h *BotHandler) RegenerateToken(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{"token": token})
 }
 
+// UpdateInteractionsEndpoint sets the URL a bot receives interactions at.
+// An empty URL switches the bot back to gateway delivery.
+func (h *BotHandler) UpdateInteractionsEndpoint(c fiber.Ctx) error {
+	botID, err := uuid.Parse(c.Params("botId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid bot ID"})
+	}
+
+	var body struct {
+		InteractionsURL string `json:"interactions_url"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	bot, secret, err := h.botService.SetInteractionsURL(c.Context(), botID, userID, body.InteractionsURL)
+	if err != nil {
+		return handleBotError(c, err)
+	}
+
+	// The signing secret is shown once, when the endpoint is set
+	return c.JSON(fiber.Map{
+		"bot":                 bot,
+		"interactions_secret": secret,
+	})
+}
+
+// UpdatePermissions sets the permissions a bot requests by default when
+// it is authorized into a server.
+func (h *BotHandler) UpdatePermissions(c fiber.Ctx) error {
+	botID, err := uuid.Parse(c.Params("botId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid bot ID"})
+	}
+
+	var body struct {
+		Permissions string `json:"permissions"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	permissions, err := strconv.ParseInt(body.Permissions, 10, 64)
+	if err != nil || permissions < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "permissions must be a decimal bitmask"})
+	}
+
+	userID := auth.GetUserID(c)
+	bot, err := h.botService.SetPermissions(c.Context(), botID, userID, permissions)
+	if err != nil {
+		return handleBotError(c, err)
+	}
+
+	return c.JSON(bot)
+}
+
 func handleBotError(c fiber.Ctx, err error) error {
 	switch {
-	case errors.Is(err, service.ErrBotNotFound):
+	case errors.Is(err, service.ErrBotNotFound), errors.Is(err, service.ErrServerNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrBotNotOwner):
+	case errors.Is(err, service.ErrBotNotOwner), errors.Is(err, service.ErrInsufficientRole):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotMember):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "bot is not a member of this server"})
 	case errors.Is(err, service.ErrBotNameTaken):
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrInvalidBotName):
+	case errors.Is(err, service.ErrInvalidBotName), errors.Is(err, service.ErrInvalidInteractionsURL),
+		errors.Is(err, service.ErrInvalidBotPermissions), errors.Is(err, service.ErrNotABot):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
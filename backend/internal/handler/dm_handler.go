@@ -62,31 +62,22 @@ func (h *DMHandler) GetConversations(c fiber.Ctx) error {
 	return c.JSON(convos)
 }
 
+// GetDMMessages returns a page of conversation history, newest first.
+// before, after and around take a message ID or, for older clients, a
+// timestamp.
 func (h *DMHandler) GetDMMessages(c fiber.Ctx) error {
 	conversationID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid conversation ID"})
 	}
 
-	var before *time.Time
-	if b := c.Query("before"); b != "" {
-		t, err := time.Parse(time.RFC3339, b)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid before timestamp"})
-		}
-		before = &t
-	}
-
-	limitVal := 50
-	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil {
-			limitVal = parsed
-		}
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	limit := int32(limitVal)
 	userID := auth.GetUserID(c)
 
-	messages, err := h.dmService.GetDMMessages(c.Context(), conversationID, userID, before, limit)
+	messages, err := h.dmService.GetDMMessageHistory(c.Context(), conversationID, userID, query)
 	if err != nil {
 		return handleDMError(c, err)
 	}
@@ -124,33 +115,22 @@ func (h *DMHandler) GetDMMessagesAround(c fiber.Ctx) error {
 		}
 	}
 
-	limitVal := 25
+	var limit int32
 	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 50 {
-			limitVal = parsed
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = int32(parsed)
 		}
 	}
-	limit := int32(limitVal)
 	userID := auth.GetUserID(c)
 
-	// Get messages before timestamp
-	before, err := h.dmService.GetDMMessages(c.Context(), conversationID, userID, &ts, limit)
-	if err != nil {
-		return handleDMError(c, err)
-	}
-
-	// Get messages after timestamp
-	after, err := h.dmService.GetDMMessagesAfter(c.Context(), conversationID, userID, ts, limit)
+	merged, err := h.dmService.GetDMMessageHistory(c.Context(), conversationID, userID, service.HistoryQuery{
+		Around: &service.HistoryAnchor{Time: ts},
+		Limit:  limit,
+	})
 	if err != nil {
 		return handleDMError(c, err)
 	}
 
-	// Reverse after (ASC) to DESC, then merge
-	for i, j := 0, len(after)-1; i < j; i, j = i+1, j-1 {
-		after[i], after[j] = after[j], after[i]
-	}
-	merged := append(after, before...)
-
 	_ = h.attachmentService.AttachToDMMessages(c.Context(), merged)
 	resolveDMMessageAvatars(merged)
 
@@ -167,6 +147,7 @@ func (h *DMHandler) SendDM(c fiber.Ctx) error {
 	content := c.FormValue("content")
 	msgType := c.FormValue("type", "text")
 	replyToIDStr := c.FormValue("reply_to_id")
+	nonce := c.FormValue("nonce")
 
 	// Parse file uploads
 	form, _ := c.MultipartForm()
@@ -192,6 +173,7 @@ func (h *DMHandler) SendDM(c fiber.Ctx) error {
 			Content   string  `json:"content"`
 			Type      string  `json:"type"`
 			ReplyToID *string `json:"reply_to_id"`
+			Nonce     string  `json:"nonce"`
 		}
 		if err := c.Bind().JSON(&body); err == nil {
 			content = body.Content
@@ -201,6 +183,7 @@ func (h *DMHandler) SendDM(c fiber.Ctx) error {
 			if body.ReplyToID != nil {
 				replyToIDStr = *body.ReplyToID
 			}
+			nonce = body.Nonce
 		}
 	}
 
@@ -216,17 +199,22 @@ func (h *DMHandler) SendDM(c fiber.Ctx) error {
 		}
 	}
 
-	msg, err := h.dmService.SendDMWithOptions(c.Context(), conversationID, userID, content, service.SendDMOptions{
+	msg, replayed, err := h.dmService.SendDMWithOptions(c.Context(), conversationID, userID, content, service.SendDMOptions{
 		MsgType:   msgType,
 		ReplyToID: replyToID,
+		Nonce:     nonce,
 	})
-	if err != nil {
+	if err != nil || replayed {
 		for _, fi := range fileInputs {
 			if closer, ok := fi.Reader.(interface{ Close() error }); ok {
 				closer.Close()
 			}
 		}
-		return handleDMError(c, err)
+		if err != nil {
+			return handleDMError(c, err)
+		}
+		// A retried send: the first attempt already broadcast the message
+		return c.JSON(msg)
 	}
 
 	var attachments []fiber.Map
@@ -282,6 +270,7 @@ func (h *DMHandler) SendDM(c fiber.Ctx) error {
 			"author_display_name": authorDisplayName,
 			"attachments":         attachments,
 			"encrypted":           conv.Encrypted,
+			"nonce":               msg.Nonce,
 		})
 		if event != nil {
 			for _, pid := range participantIDs {
@@ -719,6 +708,8 @@ func handleDMError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrMessageTooLong):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidNonce):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrMaxParticipants):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrAlreadyParticipant):
@@ -729,6 +720,8 @@ func handleDMError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrDMMessageNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidHistoryQuery):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrNotDMMessageAuthor):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrConversationNotPending):
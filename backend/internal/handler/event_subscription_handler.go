@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/service"
+)
+
+type EventSubscriptionHandler struct {
+	subscriptionService *service.EventSubscriptionService
+}
+
+func NewEventSubscriptionHandler(ss *service.EventSubscriptionService) *EventSubscriptionHandler {
+	return &EventSubscriptionHandler{subscriptionService: ss}
+}
+
+// CreateSubscription subscribes an external endpoint to server events.
+func (h *EventSubscriptionHandler) CreateSubscription(c fiber.Ctx) error {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+
+	var body service.EventSubscriptionInput
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	sub, secret, err := h.subscriptionService.CreateSubscription(c.Context(), serverID, userID, body)
+	if err != nil {
+		return handleEventSubscriptionError(c, err)
+	}
+
+	// The signing secret is shown once, on creation
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"subscription": sub,
+		"secret":       secret,
+	})
+}
+
+// ListSubscriptions lists a server's event subscriptions.
+func (h *EventSubscriptionHandler) ListSubscriptions(c fiber.Ctx) error {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+
+	userID := auth.GetUserID(c)
+	subs, err := h.subscriptionService.ListSubscriptions(c.Context(), serverID, userID)
+	if err != nil {
+		return handleEventSubscriptionError(c, err)
+	}
+	return c.JSON(subs)
+}
+
+// UpdateSubscription changes a subscription's URL, events or enabled state.
+func (h *EventSubscriptionHandler) UpdateSubscription(c fiber.Ctx) error {
+	subscriptionID, err := uuid.Parse(c.Params("subscriptionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription ID"})
+	}
+
+	var body service.EventSubscriptionInput
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	sub, err := h.subscriptionService.UpdateSubscription(c.Context(), subscriptionID, userID, body)
+	if err != nil {
+		return handleEventSubscriptionError(c, err)
+	}
+	return c.JSON(sub)
+}
+
+// DeleteSubscription removes a subscription.
+func (h *EventSubscriptionHandler) DeleteSubscription(c fiber.Ctx) error {
+	subscriptionID, err := uuid.Parse(c.Params("subscriptionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription ID"})
+	}
+
+	userID := auth.GetUserID(c)
+	if err := h.subscriptionService.DeleteSubscription(c.Context(), subscriptionID, userID); err != nil {
+		return handleEventSubscriptionError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "subscription deleted"})
+}
+
+// ListDeliveries returns a subscription's recent deliveries.
+func (h *EventSubscriptionHandler) ListDeliveries(c fiber.Ctx) error {
+	subscriptionID, err := uuid.Parse(c.Params("subscriptionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid subscription ID"})
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	userID := auth.GetUserID(c)
+	deliveries, err := h.subscriptionService.ListDeliveries(c.Context(), subscriptionID, userID, limit)
+	if err != nil {
+		return handleEventSubscriptionError(c, err)
+	}
+	return c.JSON(deliveries)
+}
+
+func handleEventSubscriptionError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrSubscriptionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSubscription),
+		errors.Is(err, service.ErrInvalidSubscriptionEvent),
+		errors.Is(err, service.ErrTooManySubscriptions):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientRole):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
@@ -201,7 +201,7 @@ func (h *ForumHandler) CreatePost(c fiber.Ctx) error {
 	}
 
 	// Broadcast to channel
-	event, _ := ws.NewEvent("FORUM_POST_CREATE", post)
+	event, _ := ws.NewEvent(ws.EventForumPostCreate, post)
 	if event != nil {
 		h.hub.BroadcastToChannel(channelID.String(), event, nil)
 	}
@@ -243,7 +243,7 @@ func (h *ForumHandler) DeletePost(c fiber.Ctx) error {
 	}
 
 	// Broadcast
-	event, _ := ws.NewEvent("FORUM_POST_DELETE", fiber.Map{"post_id": postID, "channel_id": channelID})
+	event, _ := ws.NewEvent(ws.EventForumPostDelete, fiber.Map{"post_id": postID, "channel_id": channelID})
 	if event != nil {
 		h.hub.BroadcastToChannel(channelID.String(), event, nil)
 	}
@@ -292,7 +292,7 @@ func (h *ForumHandler) PinPost(c fiber.Ctx) error {
 
 	// Broadcast
 	channelID, _ := h.forumService.GetPostChannelID(c.Context(), postID)
-	event, _ := ws.NewEvent("FORUM_POST_PIN", fiber.Map{"post_id": postID, "channel_id": channelID, "pinned": true})
+	event, _ := ws.NewEvent(ws.EventForumPostPin, fiber.Map{"post_id": postID, "channel_id": channelID, "pinned": true})
 	if event != nil {
 		h.hub.BroadcastToChannel(channelID.String(), event, nil)
 	}
@@ -312,7 +312,7 @@ func (h *ForumHandler) UnpinPost(c fiber.Ctx) error {
 	}
 
 	channelID, _ := h.forumService.GetPostChannelID(c.Context(), postID)
-	event, _ := ws.NewEvent("FORUM_POST_PIN", fiber.Map{"post_id": postID, "channel_id": channelID, "pinned": false})
+	event, _ := ws.NewEvent(ws.EventForumPostPin, fiber.Map{"post_id": postID, "channel_id": channelID, "pinned": false})
 	if event != nil {
 		h.hub.BroadcastToChannel(channelID.String(), event, nil)
 	}
@@ -342,7 +342,18 @@ func (h *ForumHandler) GetPostMessages(c fiber.Ctx) error {
 		}
 	}
 
-	messages, err := h.forumService.GetPostMessages(c.Context(), postID, userID, limit, offset)
+	// Message ID (or timestamp) cursors take precedence over offset paging
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var messages []models.ForumPostMessageWithAuthor
+	if query.HasAnchor() {
+		messages, err = h.forumService.GetPostMessageHistory(c.Context(), postID, userID, query)
+	} else {
+		messages, err = h.forumService.GetPostMessages(c.Context(), postID, userID, limit, offset)
+	}
 	if err != nil {
 		return handleForumError(c, err)
 	}
@@ -367,12 +378,13 @@ func (h *ForumHandler) CreatePostMessage(c fiber.Ctx) error {
 
 	var body struct {
 		Content string `json:"content"`
+		Nonce   string `json:"nonce"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	msg, err := h.forumService.CreatePostMessage(c.Context(), postID, userID, body.Content)
+	msg, replayed, err := h.forumService.CreatePostMessageWithNonce(c.Context(), postID, userID, body.Content, body.Nonce)
 	if err != nil {
 		return handleForumError(c, err)
 	}
@@ -381,10 +393,14 @@ func (h *ForumHandler) CreatePostMessage(c fiber.Ctx) error {
 		proxyURL := "/api/files/" + *msg.AuthorAvatarURL
 		msg.AuthorAvatarURL = &proxyURL
 	}
+	if replayed {
+		// A retried send: the first attempt already broadcast the message
+		return c.JSON(msg)
+	}
 
 	// Broadcast to channel subscribers
 	channelID, _ := h.forumService.GetPostChannelID(c.Context(), postID)
-	event, _ := ws.NewEvent("FORUM_POST_MESSAGE_CREATE", fiber.Map{
+	event, _ := ws.NewEvent(ws.EventForumPostMessageCreate, fiber.Map{
 		"post_id":    postID,
 		"channel_id": channelID,
 		"message":    msg,
@@ -445,12 +461,16 @@ func handleForumError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrForumPostNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrForumTagNotFound):
+	case errors.Is(err, service.ErrForumTagNotFound), errors.Is(err, service.ErrMessageNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidHistoryQuery):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrEmptyTitle):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrEmptyMessage):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidNonce):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrEmptyTagName):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrInsufficientRole):
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/service"
+)
+
+// parseHistoryQuery reads the before, after, around and limit parameters of
+// a message history request. Cursors are message IDs; RFC 3339 timestamps
+// are still accepted for older clients.
+func parseHistoryQuery(c fiber.Ctx) (service.HistoryQuery, error) {
+	var q service.HistoryQuery
+	for _, p := range []struct {
+		name   string
+		anchor **service.HistoryAnchor
+	}{
+		{"before", &q.Before},
+		{"after", &q.After},
+		{"around", &q.Around},
+	} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		anchor, err := parseHistoryAnchor(v)
+		if err != nil {
+			return q, errors.New("invalid " + p.name + " cursor: expected a message ID or timestamp")
+		}
+		*p.anchor = anchor
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			q.Limit = int32(parsed)
+		}
+	}
+	return q, nil
+}
+
+func parseHistoryAnchor(v string) (*service.HistoryAnchor, error) {
+	if id, err := uuid.Parse(v); err == nil {
+		return &service.HistoryAnchor{MessageID: &id}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &service.HistoryAnchor{Time: t}, nil
+}
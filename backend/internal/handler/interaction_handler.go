@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+var errBotOffline = errors.New("bot is not connected")
+
+type InteractionHandler struct {
+	interactionService *service.InteractionService
+	hub                *ws.Hub
+}
+
+func NewInteractionHandler(is *service.InteractionService, hub *ws.Hub) *InteractionHandler {
+	return &InteractionHandler{interactionService: is, hub: hub}
+}
+
+// commandScope reads the optional :serverId route param; nil means global.
+func commandScope(c fiber.Ctx) (*uuid.UUID, error) {
+	raw := c.Params("serverId")
+	if raw == "" {
+		return nil, nil
+	}
+	serverID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &serverID, nil
+}
+
+// RegisterCommand creates or replaces a command for the calling bot.
+func (h *InteractionHandler) RegisterCommand(c fiber.Ctx) error {
+	serverID, err := commandScope(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+
+	var body service.CommandInput
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	cmd, err := h.interactionService.RegisterCommand(c.Context(), auth.GetUserID(c), serverID, body)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(cmd)
+}
+
+// ListCommands lists the calling bot's global or server commands.
+func (h *InteractionHandler) ListCommands(c fiber.Ctx) error {
+	serverID, err := commandScope(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+
+	cmds, err := h.interactionService.ListBotCommands(c.Context(), auth.GetUserID(c), serverID)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+	return c.JSON(cmds)
+}
+
+// DeleteCommand deletes one of the calling bot's commands.
+func (h *InteractionHandler) DeleteCommand(c fiber.Ctx) error {
+	commandID, err := uuid.Parse(c.Params("commandId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid command ID"})
+	}
+
+	if err := h.interactionService.DeleteCommand(c.Context(), auth.GetUserID(c), commandID); err != nil {
+		return handleInteractionError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "command deleted"})
+}
+
+// GetChannelCommands lists the commands the user can invoke in a channel.
+func (h *InteractionHandler) GetChannelCommands(c fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+	}
+
+	cmds, err := h.interactionService.GetChannelCommands(c.Context(), channelID, auth.GetUserID(c))
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+	return c.JSON(cmds)
+}
+
+// CreateInteraction invokes a command and delivers it to its bot, over the
+// gateway or to the bot's HTTP endpoint.
+func (h *InteractionHandler) CreateInteraction(c fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+	}
+
+	var body struct {
+		CommandID string                      `json:"command_id"`
+		Options   []service.InteractionOption `json:"options"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	commandID, err := uuid.Parse(body.CommandID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid command ID"})
+	}
+
+	payload, target, err := h.interactionService.CreateCommandInteraction(c.Context(), channelID, auth.GetUserID(c),
+		commandID, auth.GetUsername(c), body.Options)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+
+	return h.dispatch(c, target, payload)
+}
+
+// ComponentInteraction handles a click on a button or a selection in a select
+// menu, delivering it to the bot or webhook application that owns the message.
+func (h *InteractionHandler) ComponentInteraction(c fiber.Ctx) error {
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid message ID"})
+	}
+
+	var body struct {
+		CustomID string   `json:"custom_id"`
+		Values   []string `json:"values"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	payload, target, err := h.interactionService.CreateComponentInteraction(c.Context(), messageID, auth.GetUserID(c),
+		auth.GetUsername(c), body.CustomID, body.Values)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+
+	return h.dispatch(c, target, payload)
+}
+
+// dispatch delivers a new interaction and acknowledges it to the invoker.
+func (h *InteractionHandler) dispatch(c fiber.Ctx, target *service.InteractionTarget, payload *service.InteractionPayload) error {
+	if err := h.deliver(c.Context(), target, payload); err != nil {
+		return handleInteractionError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"id":         payload.ID,
+		"type":       payload.Type,
+		"channel_id": payload.ChannelID,
+		"message_id": payload.MessageID,
+		"created_at": payload.CreatedAt,
+	})
+}
+
+// deliver sends an interaction to its bot or webhook application. Bots
+// without an HTTP endpoint receive it over the gateway; HTTP endpoints may
+// answer inline, which is applied as their response.
+func (h *InteractionHandler) deliver(ctx context.Context, target *service.InteractionTarget, payload *service.InteractionPayload) error {
+	if target.Bot != nil && target.Bot.InteractionsURL == "" {
+		if !h.hub.IsOnline(target.Bot.ID) {
+			return errBotOffline
+		}
+		event, err := ws.NewEvent(ws.EventInteractionCreate, payload)
+		if err != nil {
+			return err
+		}
+		h.hub.SendToUser(target.Bot.ID, event)
+		return nil
+	}
+
+	resp, err := h.interactionService.PostInteraction(ctx, target, payload)
+	if err != nil {
+		log.Printf("Interaction %s delivery failed: %v", payload.ID, err)
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+
+	result, err := h.interactionService.Respond(ctx, payload.ID, payload.Token, *resp)
+	if err != nil {
+		log.Printf("Interaction %s inline response rejected: %v", payload.ID, err)
+		return service.ErrInteractionDeliveryFailed
+	}
+	h.publish(ctx, result)
+	return nil
+}
+
+// Callback is the bot's initial response to an interaction (public endpoint,
+// authenticated by the interaction token).
+func (h *InteractionHandler) Callback(c fiber.Ctx) error {
+	interactionID, err := uuid.Parse(c.Params("interactionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid interaction ID"})
+	}
+
+	var body service.InteractionResponse
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	result, err := h.interactionService.Respond(c.Context(), interactionID, c.Params("token"), body)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+	if result.Message == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	h.publish(c.Context(), result)
+	if result.Updated {
+		return c.JSON(result.Message)
+	}
+	return c.Status(fiber.StatusCreated).JSON(result.Message)
+}
+
+// FollowUp posts a follow-up message to an acknowledged interaction (public
+// endpoint, authenticated by the interaction token).
+func (h *InteractionHandler) FollowUp(c fiber.Ctx) error {
+	interactionID, err := uuid.Parse(c.Params("interactionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid interaction ID"})
+	}
+
+	var body service.InteractionMessage
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	result, err := h.interactionService.FollowUp(c.Context(), interactionID, c.Params("token"), body)
+	if err != nil {
+		return handleInteractionError(c, err)
+	}
+
+	h.publish(c.Context(), result)
+	return c.Status(fiber.StatusCreated).JSON(result.Message)
+}
+
+// publish sends the outcome of a response: MESSAGE_UPDATE for an edited
+// message, MESSAGE_CREATE to the channel for a new one, or MESSAGE_CREATE to
+// the invoking user alone for an ephemeral one.
+func (h *InteractionHandler) publish(ctx context.Context, result *service.InteractionResult) {
+	msg, interaction := result.Message, result.Interaction
+	if msg == nil {
+		return
+	}
+
+	if result.Updated {
+		if event, _ := ws.NewEvent(ws.EventMessageUpdate, msg); event != nil {
+			h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
+		}
+		return
+	}
+
+	var username string
+	var authorAvatarURL, authorDisplayName interface{}
+	if msg.WebhookID != nil {
+		if webhook, err := h.interactionService.Queries().GetWebhookByID(ctx, *msg.WebhookID); err == nil {
+			username = webhook.Name
+			authorAvatarURL = webhook.AvatarURL
+			authorDisplayName = webhook.Name
+		}
+	} else if author, err := h.interactionService.Queries().GetUserByID(ctx, msg.AuthorID); err == nil {
+		username = author.Username
+		if author.AvatarURL != nil {
+			authorAvatarURL = "/api/files/" + *author.AvatarURL
+		}
+		authorDisplayName = author.DisplayName
+	}
+
+	data := fiber.Map{
+		"id":                  msg.ID,
+		"channel_id":          msg.ChannelID,
+		"author_id":           msg.AuthorID,
+		"content":             msg.Content,
+		"type":                msg.Type,
+		"created_at":          msg.CreatedAt,
+		"username":            username,
+		"author_avatar_url":   authorAvatarURL,
+		"author_display_name": authorDisplayName,
+		"attachments":         []fiber.Map{},
+		"components":          msg.Components,
+		"webhook_id":          msg.WebhookID,
+		"interaction": fiber.Map{
+			"id":      interaction.ID,
+			"name":    service.InteractionName(interaction),
+			"user_id": interaction.UserID,
+		},
+	}
+
+	if result.Ephemeral {
+		data["ephemeral"] = true
+		if event, _ := ws.NewEvent(ws.EventMessageCreate, data); event != nil {
+			h.hub.SendToUser(interaction.UserID, event)
+		}
+		return
+	}
+
+	if event, _ := ws.NewEvent(ws.EventMessageCreate, data); event != nil {
+		h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
+	}
+}
+
+func handleInteractionError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidCommand),
+		errors.Is(err, service.ErrInvalidOptions),
+		errors.Is(err, service.ErrInvalidComponents),
+		errors.Is(err, service.ErrInvalidComponentValues),
+		errors.Is(err, service.ErrNotInteractive),
+		errors.Is(err, service.ErrInvalidInteractionResponse),
+		errors.Is(err, service.ErrEmptyMessage),
+		errors.Is(err, service.ErrMessageTooLong):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrCommandNotFound),
+		errors.Is(err, service.ErrComponentNotFound),
+		errors.Is(err, service.ErrMessageNotFound),
+		errors.Is(err, service.ErrInteractionNotFound),
+		errors.Is(err, service.ErrChannelNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInteractionTokenInvalid):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotBot),
+		errors.Is(err, service.ErrNotMember),
+		errors.Is(err, service.ErrInsufficientRole),
+		errors.Is(err, service.ErrUserTimedOut):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInteractionAcknowledged),
+		errors.Is(err, service.ErrInteractionNotAcknowledged):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInteractionExpired):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errBotOffline):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInteractionDeliveryFailed):
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": service.ErrInteractionDeliveryFailed.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
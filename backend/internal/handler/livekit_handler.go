@@ -1,28 +1,54 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	authPkg "github.com/M-McCallum/thicket/internal/auth"
 	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/ws"
 )
 
+// LiveKit webhook event types that affect voice state.
+const (
+	livekitParticipantJoined = "participant_joined"
+	livekitParticipantLeft   = "participant_left"
+	livekitConnectionAborted = "participant_connection_aborted"
+	livekitTrackPublished    = "track_published"
+	livekitTrackUnpublished  = "track_unpublished"
+	livekitRoomFinished      = "room_finished"
+)
+
+var errInvalidWebhookSignature = errors.New("invalid webhook signature")
+
 type LiveKitHandler struct {
 	serverService *service.ServerService
 	dmService     *service.DMService
+	voiceService  *service.VoiceService
+	hub           *ws.Hub
 	apiKey        string
 	apiSecret     string
 }
 
-func NewLiveKitHandler(ss *service.ServerService, ds *service.DMService, apiKey, apiSecret string) *LiveKitHandler {
+func NewLiveKitHandler(ss *service.ServerService, ds *service.DMService, vs *service.VoiceService, hub *ws.Hub, apiKey, apiSecret string) *LiveKitHandler {
 	return &LiveKitHandler{
 		serverService: ss,
 		dmService:     ds,
+		voiceService:  vs,
+		hub:           hub,
 		apiKey:        apiKey,
 		apiSecret:     apiSecret,
 	}
@@ -42,18 +68,22 @@ func (h *LiveKitHandler) GetVoiceToken(c fiber.Ctx) error {
 	userID := authPkg.GetUserID(c)
 	username := authPkg.GetUsername(c)
 
-	// Verify server membership
-	if _, err := h.serverService.GetServer(c.Context(), serverID, userID); err != nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "not a member of this server"})
+	// Membership, channel type, timeouts and voice permissions
+	voiceGrant, err := h.voiceService.ComputeVoiceGrant(c.Context(), serverID, channelID, userID)
+	if err != nil {
+		return handleVoiceError(c, err)
 	}
 
-	roomName := fmt.Sprintf("server:%s:voice:%s", serverID, channelID)
+	roomName := service.VoiceRoomName(serverID, channelID)
 
 	at := auth.NewAccessToken(h.apiKey, h.apiSecret)
 	grant := &auth.VideoGrant{
 		RoomJoin: true,
 		Room:     roomName,
 	}
+	grant.SetCanPublish(voiceGrant.CanPublish)
+	grant.SetCanSubscribe(voiceGrant.CanSubscribe)
+	grant.SetCanPublishData(voiceGrant.CanPublishData)
 	at.AddGrant(grant).
 		SetIdentity(userID.String()).
 		SetName(username).
@@ -103,6 +133,9 @@ func (h *LiveKitHandler) GetDMVoiceToken(c fiber.Ctx) error {
 		RoomJoin: true,
 		Room:     roomName,
 	}
+	grant.SetCanPublish(true)
+	grant.SetCanSubscribe(true)
+	grant.SetCanPublishData(true)
 	at.AddGrant(grant).
 		SetIdentity(userID.String()).
 		SetName(username).
@@ -118,3 +151,166 @@ func (h *LiveKitHandler) GetDMVoiceToken(c fiber.Ctx) error {
 		"room":  roomName,
 	})
 }
+
+// HandleWebhook applies LiveKit room events to the hub's voice state and
+// broadcasts VOICE_STATE_UPDATE. Requests must be signed with the API key
+// and secret.
+func (h *LiveKitHandler) HandleWebhook(c fiber.Ctx) error {
+	event, err := h.verifyWebhook(c.Body(), c.Get("Authorization"))
+	if errors.Is(err, errInvalidWebhookSignature) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid webhook signature"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook payload"})
+	}
+
+	// DM call rooms carry no server voice state
+	serverID, channelID, ok := parseVoiceRoom(event.GetRoom().GetName())
+	if !ok {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	ctx := c.Context()
+	switch event.GetEvent() {
+	case livekitParticipantJoined:
+		userID, err := uuid.Parse(event.GetParticipant().GetIdentity())
+		if err != nil {
+			break
+		}
+		state := ws.VoiceState{
+			UserID:    userID,
+			ChannelID: channelID.String(),
+			ServerID:  serverID.String(),
+			Username:  event.GetParticipant().GetName(),
+		}
+		h.hub.JoinVoiceChannel(state)
+		h.hub.PublishVoiceState(state, true)
+		h.broadcastVoiceState(ctx, state, true)
+
+	case livekitParticipantLeft, livekitConnectionAborted:
+		userID, err := uuid.Parse(event.GetParticipant().GetIdentity())
+		if err != nil {
+			break
+		}
+		state := ws.VoiceState{
+			UserID:    userID,
+			ChannelID: channelID.String(),
+			ServerID:  serverID.String(),
+			Username:  event.GetParticipant().GetName(),
+		}
+		h.hub.LeaveVoiceChannel(userID, channelID.String())
+		h.hub.PublishVoiceState(state, false)
+		h.broadcastVoiceState(ctx, state, false)
+
+	case livekitTrackPublished, livekitTrackUnpublished:
+		// Only the microphone track determines mute state
+		if event.GetTrack().GetSource() != livekit.TrackSource_MICROPHONE {
+			break
+		}
+		userID, err := uuid.Parse(event.GetParticipant().GetIdentity())
+		if err != nil {
+			break
+		}
+		muted := event.GetEvent() == livekitTrackUnpublished || event.GetTrack().GetMuted()
+		if state, ok := h.hub.SetVoiceMuted(userID, channelID.String(), muted); ok {
+			h.hub.PublishVoiceState(state, true)
+			h.broadcastVoiceState(ctx, state, true)
+		}
+
+	case livekitRoomFinished:
+		for _, state := range h.hub.ClearVoiceChannel(channelID.String()) {
+			h.broadcastVoiceState(ctx, state, false)
+		}
+		h.hub.PublishVoiceClear(channelID.String())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// verifyWebhook checks that the Authorization header is a token signed with
+// our API secret whose sha256 claim matches the body, then decodes the event.
+func (h *LiveKitHandler) verifyWebhook(body []byte, token string) (*livekit.WebhookEvent, error) {
+	if token == "" {
+		return nil, errInvalidWebhookSignature
+	}
+	verifier, err := auth.ParseAPIToken(token)
+	if err != nil || verifier.APIKey() != h.apiKey {
+		return nil, errInvalidWebhookSignature
+	}
+	_, claims, err := verifier.Verify(h.apiSecret)
+	if err != nil {
+		return nil, errInvalidWebhookSignature
+	}
+
+	sum := sha256.Sum256(body)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(claims.Sha256), []byte(hash)) != 1 {
+		return nil, errInvalidWebhookSignature
+	}
+
+	var event livekit.WebhookEvent
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true, AllowPartial: true}
+	if err := opts.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (h *LiveKitHandler) broadcastVoiceState(ctx context.Context, state ws.VoiceState, joined bool) {
+	serverID, err := uuid.Parse(state.ServerID)
+	if err != nil {
+		return
+	}
+	memberIDs, err := h.serverService.GetServerMemberUserIDs(ctx, serverID)
+	if err != nil {
+		log.Printf("livekit webhook: get members of %s: %v", serverID, err)
+		return
+	}
+	event, err := ws.NewEvent(ws.EventVoiceStateUpdate, ws.VoiceStateData{
+		UserID:    state.UserID.String(),
+		Username:  state.Username,
+		ChannelID: state.ChannelID,
+		ServerID:  state.ServerID,
+		Joined:    joined,
+		Muted:     state.Muted,
+		Deafened:  state.Deafened,
+	})
+	if err != nil {
+		return
+	}
+	ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
+}
+
+// parseVoiceRoom extracts the IDs from a "server:<id>:voice:<id>" room name.
+func parseVoiceRoom(room string) (serverID, channelID uuid.UUID, ok bool) {
+	parts := strings.Split(room, ":")
+	if len(parts) != 4 || parts[0] != "server" || parts[2] != "voice" {
+		return uuid.Nil, uuid.Nil, false
+	}
+	serverID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	channelID, err = uuid.Parse(parts[3])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return serverID, channelID, true
+}
+
+func handleVoiceError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrChannelNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotVoiceChannel):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNotMember):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrUserTimedOut):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientRole):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "missing permission to connect to this channel"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
+	}
+}
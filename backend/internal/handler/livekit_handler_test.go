@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	authPkg "github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/testutil"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+const (
+	testLiveKitKey    = "testkey"
+	testLiveKitSecret = "testsecret"
+)
+
+func setupLiveKitApp(t *testing.T) (*fiber.App, *ws.Hub) {
+	t.Helper()
+	q := queries()
+	permSvc := service.NewPermissionService(q)
+	hub := ws.NewHub()
+	hub.SetExternalVoiceState(true)
+	go hub.Run()
+
+	voiceSvc := service.NewVoiceService(q, permSvc, nil)
+	h := NewLiveKitHandler(service.NewServerService(q, permSvc), service.NewDMService(q), voiceSvc, hub, testLiveKitKey, testLiveKitSecret)
+	app := fiber.New()
+	app.Post("/api/livekit/webhook", h.HandleWebhook)
+	protected := app.Group("/api", authPkg.Middleware(jwksMgr))
+	protected.Post("/servers/:serverId/channels/:channelId/voice-token", h.GetVoiceToken)
+	return app, hub
+}
+
+// voiceTokenGrant requests a voice token and returns its verified grant.
+func voiceTokenGrant(t *testing.T, app *fiber.App, user *testutil.TestUser, serverID, channelID uuid.UUID) *auth.VideoGrant {
+	t.Helper()
+	req := authRequest(http.MethodPost, fmt.Sprintf("/api/servers/%s/channels/%s/voice-token", serverID, channelID), user.AccessToken, nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	parseJSON(t, resp, &body)
+	verifier, err := auth.ParseAPIToken(body.Token)
+	require.NoError(t, err)
+	_, claims, err := verifier.Verify(testLiveKitSecret)
+	require.NoError(t, err)
+	return claims.Video
+}
+
+func createVoiceServer(t *testing.T, owner *testutil.TestUser) (*models.Server, models.Channel) {
+	t.Helper()
+	ctx := context.Background()
+	server, _, err := service.NewServerService(queries(), service.NewPermissionService(queries())).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	voice, err := queries().CreateChannel(ctx, models.CreateChannelParams{ServerID: server.ID, Name: "voice", Type: "voice"})
+	require.NoError(t, err)
+	return server, voice
+}
+
+func TestGetVoiceToken_GrantsMatchPermissions(t *testing.T) {
+	app, _ := setupLiveKitApp(t)
+	owner := createUser(t)
+	member := createUser(t)
+	server, voice := createVoiceServer(t, owner)
+	ctx := context.Background()
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	grant := voiceTokenGrant(t, app, member, server.ID, voice.ID)
+	assert.True(t, grant.GetCanPublish())
+	assert.True(t, grant.GetCanSubscribe())
+	assert.True(t, grant.GetCanPublishData())
+
+	// During a stage, non-speakers only listen
+	_, err := service.NewStageService(queries(), service.NewPermissionService(queries())).StartStage(ctx, voice.ID, owner.User.ID, "")
+	require.NoError(t, err)
+	grant = voiceTokenGrant(t, app, member, server.ID, voice.ID)
+	assert.False(t, grant.GetCanPublish())
+	assert.True(t, grant.GetCanSubscribe())
+	assert.False(t, grant.GetCanPublishData())
+}
+
+func TestGetVoiceToken_403_NoVoiceConnect(t *testing.T) {
+	app, _ := setupLiveKitApp(t)
+	owner := createUser(t)
+	member := createUser(t)
+	server, voice := createVoiceServer(t, owner)
+	ctx := context.Background()
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, voice.ID, everyone.ID, 0, models.PermVoiceConnect)
+	require.NoError(t, err)
+
+	req := authRequest(http.MethodPost, fmt.Sprintf("/api/servers/%s/channels/%s/voice-token", server.ID, voice.ID), member.AccessToken, nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestGetVoiceToken_400_TextChannel(t *testing.T) {
+	app, _ := setupLiveKitApp(t)
+	owner := createUser(t)
+	server, text, err := testutil.CreateTestServer(context.Background(), queries(), owner.User.ID)
+	require.NoError(t, err)
+
+	req := authRequest(http.MethodPost, fmt.Sprintf("/api/servers/%s/channels/%s/voice-token", server.ID, text.ID), owner.AccessToken, nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// webhookRequest builds a LiveKit webhook request signed the way LiveKit
+// signs them: a JWT carrying the base64 sha256 of the body.
+func webhookRequest(t *testing.T, event *livekit.WebhookEvent, key, secret string) *http.Request {
+	t.Helper()
+	body, err := protojson.Marshal(event)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(body)
+	token, err := auth.NewAccessToken(key, secret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/livekit/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/webhook+json")
+	req.Header.Set("Authorization", token)
+	return req
+}
+
+func voiceRoomEvent(kind string, serverID, channelID, userID uuid.UUID) *livekit.WebhookEvent {
+	return &livekit.WebhookEvent{
+		Event: kind,
+		Room:  &livekit.Room{Name: fmt.Sprintf("server:%s:voice:%s", serverID, channelID)},
+		Participant: &livekit.ParticipantInfo{
+			Identity: userID.String(),
+			Name:     "alice",
+		},
+	}
+}
+
+func TestLiveKitWebhook_ParticipantJoinedAndLeft(t *testing.T) {
+	app, hub := setupLiveKitApp(t)
+	user := createUser(t)
+	server, _, err := testutil.CreateTestServer(context.Background(), queries(), user.User.ID)
+	require.NoError(t, err)
+	channelID := uuid.New()
+
+	joined := voiceRoomEvent("participant_joined", server.ID, channelID, user.User.ID)
+	resp, err := app.Test(webhookRequest(t, joined, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	states := hub.GetVoiceParticipants(channelID.String())
+	require.Len(t, states, 1)
+	assert.Equal(t, user.User.ID, states[0].UserID)
+	assert.Equal(t, server.ID.String(), states[0].ServerID)
+	assert.Equal(t, "alice", states[0].Username)
+
+	left := voiceRoomEvent("participant_left", server.ID, channelID, user.User.ID)
+	resp, err = app.Test(webhookRequest(t, left, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, hub.GetVoiceParticipants(channelID.String()))
+}
+
+func TestLiveKitWebhook_TrackPublishedUpdatesMute(t *testing.T) {
+	app, hub := setupLiveKitApp(t)
+	user := createUser(t)
+	server, _, err := testutil.CreateTestServer(context.Background(), queries(), user.User.ID)
+	require.NoError(t, err)
+	channelID := uuid.New()
+	hub.JoinVoiceChannel(ws.VoiceState{UserID: user.User.ID, ChannelID: channelID.String(), ServerID: server.ID.String()})
+
+	unpublished := voiceRoomEvent("track_unpublished", server.ID, channelID, user.User.ID)
+	unpublished.Track = &livekit.TrackInfo{Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE}
+	resp, err := app.Test(webhookRequest(t, unpublished, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, hub.GetVoiceParticipants(channelID.String())[0].Muted)
+
+	published := voiceRoomEvent("track_published", server.ID, channelID, user.User.ID)
+	published.Track = &livekit.TrackInfo{Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE}
+	resp, err = app.Test(webhookRequest(t, published, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, hub.GetVoiceParticipants(channelID.String())[0].Muted)
+}
+
+func TestLiveKitWebhook_RoomFinishedClearsChannel(t *testing.T) {
+	app, hub := setupLiveKitApp(t)
+	user := createUser(t)
+	server, _, err := testutil.CreateTestServer(context.Background(), queries(), user.User.ID)
+	require.NoError(t, err)
+	channelID := uuid.New()
+	hub.JoinVoiceChannel(ws.VoiceState{UserID: user.User.ID, ChannelID: channelID.String(), ServerID: server.ID.String()})
+	hub.JoinVoiceChannel(ws.VoiceState{UserID: uuid.New(), ChannelID: channelID.String(), ServerID: server.ID.String()})
+
+	finished := &livekit.WebhookEvent{
+		Event: "room_finished",
+		Room:  &livekit.Room{Name: fmt.Sprintf("server:%s:voice:%s", server.ID, channelID)},
+	}
+	resp, err := app.Test(webhookRequest(t, finished, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, hub.GetVoiceParticipants(channelID.String()))
+}
+
+func TestLiveKitWebhook_401_BadSignature(t *testing.T) {
+	app, hub := setupLiveKitApp(t)
+	serverID, channelID, userID := uuid.New(), uuid.New(), uuid.New()
+	event := voiceRoomEvent("participant_joined", serverID, channelID, userID)
+
+	resp, err := app.Test(webhookRequest(t, event, testLiveKitKey, "wrongsecret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = app.Test(webhookRequest(t, event, "otherkey", testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	assert.Empty(t, hub.GetVoiceParticipants(channelID.String()))
+}
+
+func TestLiveKitWebhook_401_TamperedBody(t *testing.T) {
+	app, hub := setupLiveKitApp(t)
+	serverID, channelID := uuid.New(), uuid.New()
+	req := webhookRequest(t, voiceRoomEvent("participant_joined", serverID, channelID, uuid.New()), testLiveKitKey, testLiveKitSecret)
+
+	// Same signature, different participant
+	body, err := protojson.Marshal(voiceRoomEvent("participant_joined", serverID, channelID, uuid.New()))
+	require.NoError(t, err)
+	tampered := httptest.NewRequest(http.MethodPost, "/api/livekit/webhook", bytes.NewReader(body))
+	tampered.Header.Set("Authorization", req.Header.Get("Authorization"))
+
+	resp, err := app.Test(tampered)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Empty(t, hub.GetVoiceParticipants(channelID.String()))
+}
+
+func TestLiveKitWebhook_IgnoresDMRooms(t *testing.T) {
+	app, _ := setupLiveKitApp(t)
+	event := &livekit.WebhookEvent{
+		Event:       "participant_joined",
+		Room:        &livekit.Room{Name: fmt.Sprintf("dm:%s", uuid.New())},
+		Participant: &livekit.ParticipantInfo{Identity: uuid.New().String()},
+	}
+
+	resp, err := app.Test(webhookRequest(t, event, testLiveKitKey, testLiveKitSecret))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -2,9 +2,9 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
-	"regexp"
 	"strconv"
 	"time"
 
@@ -18,8 +18,6 @@ import (
 	"github.com/M-McCallum/thicket/internal/ws"
 )
 
-var notifMentionRegex = regexp.MustCompile(`@(\w+)`)
-
 type MessageHandler struct {
 	messageService    *service.MessageService
 	attachmentService *service.AttachmentService
@@ -44,6 +42,7 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 	content := c.FormValue("content")
 	msgType := c.FormValue("type", "text")
 	replyToStr := c.FormValue("reply_to_id")
+	nonce := c.FormValue("nonce")
 
 	// Parse file uploads
 	form, _ := c.MultipartForm()
@@ -64,11 +63,14 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 	}
 
 	// Also check for JSON body if no multipart
+	var components json.RawMessage
 	if form == nil {
 		var body struct {
-			Content   string  `json:"content"`
-			Type      string  `json:"type"`
-			ReplyToID *string `json:"reply_to_id"`
+			Content    string          `json:"content"`
+			Type       string          `json:"type"`
+			ReplyToID  *string         `json:"reply_to_id"`
+			Components json.RawMessage `json:"components"`
+			Nonce      string          `json:"nonce"`
 		}
 		if err := c.Bind().JSON(&body); err == nil {
 			content = body.Content
@@ -78,9 +80,17 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 			if body.ReplyToID != nil {
 				replyToStr = *body.ReplyToID
 			}
+			components = body.Components
+			nonce = body.Nonce
 		}
 	}
 
+	// Only bots post interactive components
+	hasComponents := len(components) > 0 && string(components) != "null"
+	if hasComponents && !auth.IsBot(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "only bots can send message components"})
+	}
+
 	var replyToID *uuid.UUID
 	if replyToStr != "" {
 		parsed, err := uuid.Parse(replyToStr)
@@ -90,20 +100,32 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 		replyToID = &parsed
 	}
 
-	// Allow empty content if files present
-	if content == "" && len(fileInputs) == 0 && msgType == "text" {
+	// Allow empty content if files or components present
+	if content == "" && len(fileInputs) == 0 && msgType == "text" && !hasComponents {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "message content or attachments required"})
 	}
 
-	msg, err := h.messageService.SendMessage(c.Context(), channelID, userID, content, replyToID, msgType)
-	if err != nil {
+	opts := service.SendMessageOptions{
+		Type:      msgType,
+		ReplyToID: replyToID,
+		Nonce:     nonce,
+	}
+	if hasComponents {
+		opts.Type, opts.Components = "text", components
+	}
+	msg, replayed, err := h.messageService.SendMessageWithOptions(c.Context(), channelID, userID, content, opts)
+	if err != nil || replayed {
 		// Close any open file handles
 		for _, fi := range fileInputs {
 			if closer, ok := fi.Reader.(interface{ Close() error }); ok {
 				closer.Close()
 			}
 		}
-		return handleMessageError(c, err)
+		if err != nil {
+			return handleMessageError(c, err)
+		}
+		// A retried send: the first attempt already broadcast the message
+		return c.JSON(msg)
 	}
 
 	// Upload attachments
@@ -176,25 +198,25 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 		"author_avatar_url":   authorAvatarURL,
 		"author_display_name": authorDisplayName,
 		"attachments":         attachments,
+		"components":          msg.Components,
+		"nonce":               msg.Nonce,
 	})
 	if event != nil {
 		h.hub.BroadcastToChannel(channelID.String(), event, nil)
 	}
 
-	// Broadcast mention notifications to mentioned users
-	mentionedIDs := service.ParseMentions(msg.Content)
+	// Broadcast mention notifications to the members the message notified
+	mentionedIDs, _ := h.messageService.Queries().GetMessageMentionedUserIDs(c.Context(), msg.ID)
 	for _, mentionedID := range mentionedIDs {
-		if mentionedID != userID {
-			mentionEvent, _ := ws.NewEvent(ws.EventMentionCreate, fiber.Map{
-				"channel_id": msg.ChannelID,
-				"message_id": msg.ID,
-				"author_id":  msg.AuthorID,
-				"content":    msg.Content,
-				"username":   auth.GetUsername(c),
-			})
-			if mentionEvent != nil {
-				h.hub.SendToUser(mentionedID, mentionEvent)
-			}
+		mentionEvent, _ := ws.NewEvent(ws.EventMentionCreate, fiber.Map{
+			"channel_id": msg.ChannelID,
+			"message_id": msg.ID,
+			"author_id":  msg.AuthorID,
+			"content":    msg.Content,
+			"username":   auth.GetUsername(c),
+		})
+		if mentionEvent != nil {
+			h.hub.SendToUser(mentionedID, mentionEvent)
 		}
 	}
 
@@ -223,36 +245,26 @@ func (h *MessageHandler) SendMessage(c fiber.Ctx) error {
 
 	// Send NOTIFICATION events based on notification prefs.
 	// This runs in a goroutine to avoid blocking the response.
-	go h.sendNotifications(msg, channelID, userID, auth.GetUsername(c))
+	go h.sendNotifications(msg, channelID, userID, auth.GetUsername(c), mentionedIDs)
 
 	return c.Status(fiber.StatusCreated).JSON(msg)
 }
 
+// GetMessages returns a page of channel history, newest first. before,
+// after and around take a message ID or, for older clients, a timestamp.
 func (h *MessageHandler) GetMessages(c fiber.Ctx) error {
 	channelID, err := uuid.Parse(c.Params("channelId"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
 	}
 
-	var before *time.Time
-	if b := c.Query("before"); b != "" {
-		t, err := time.Parse(time.RFC3339, b)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid before timestamp"})
-		}
-		before = &t
-	}
-
-	limitVal := 50
-	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil {
-			limitVal = parsed
-		}
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	limit := int32(limitVal)
 	userID := auth.GetUserID(c)
 
-	messages, err := h.messageService.GetMessages(c.Context(), channelID, userID, before, limit)
+	messages, err := h.messageService.GetMessageHistory(c.Context(), channelID, userID, query)
 	if err != nil {
 		return handleMessageError(c, err)
 	}
@@ -260,6 +272,7 @@ func (h *MessageHandler) GetMessages(c fiber.Ctx) error {
 	// Attach attachments and reactions
 	_ = h.attachmentService.AttachToMessages(c.Context(), messages)
 	_ = h.messageService.AttachReactionsToMessages(c.Context(), messages, userID)
+	clearTombstones(messages)
 	resolveMessageAvatars(messages)
 
 	return c.JSON(messages)
@@ -274,6 +287,17 @@ func resolveMessageAvatars(messages []models.MessageWithAuthor) {
 	}
 }
 
+// clearTombstones drops the attachments and reactions of deleted messages
+// in history, which are shown only as placeholders.
+func clearTombstones(messages []models.MessageWithAuthor) {
+	for i := range messages {
+		if messages[i].DeletedAt != nil {
+			messages[i].Attachments = []models.Attachment{}
+			messages[i].Reactions = []models.ReactionCount{}
+		}
+	}
+}
+
 func (h *MessageHandler) GetMessagesAround(c fiber.Ctx) error {
 	channelID, err := uuid.Parse(c.Params("channelId"))
 	if err != nil {
@@ -292,38 +316,26 @@ func (h *MessageHandler) GetMessagesAround(c fiber.Ctx) error {
 		}
 	}
 
-	limitVal := 25
+	var limit int32
 	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 50 {
-			limitVal = parsed
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = int32(parsed)
 		}
 	}
-	limit := int32(limitVal)
 	userID := auth.GetUserID(c)
 
-	// Get messages before the timestamp
-	beforeTs := ts
-	before, err := h.messageService.GetMessages(c.Context(), channelID, userID, &beforeTs, limit)
-	if err != nil {
-		return handleMessageError(c, err)
-	}
-
-	// Get messages after the timestamp
-	after, err := h.messageService.GetMessagesAfter(c.Context(), channelID, userID, ts, limit)
+	merged, err := h.messageService.GetMessageHistory(c.Context(), channelID, userID, service.HistoryQuery{
+		Around: &service.HistoryAnchor{Time: ts},
+		Limit:  limit,
+	})
 	if err != nil {
 		return handleMessageError(c, err)
 	}
 
-	// Merge: after (reversed to DESC) + before — all in descending order
-	// after comes back in ASC, so reverse it
-	for i, j := 0, len(after)-1; i < j; i, j = i+1, j-1 {
-		after[i], after[j] = after[j], after[i]
-	}
-	merged := append(after, before...)
-
 	// Attach attachments and reactions to the merged set
 	_ = h.attachmentService.AttachToMessages(c.Context(), merged)
 	_ = h.messageService.AttachReactionsToMessages(c.Context(), merged, userID)
+	clearTombstones(merged)
 	resolveMessageAvatars(merged)
 
 	return c.JSON(merged)
@@ -384,6 +396,144 @@ func (h *MessageHandler) DeleteMessage(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "deleted"})
 }
 
+// BulkDeleteMessages deletes up to service.MaxBulkDelete messages from a
+// channel in one request and broadcasts a single MESSAGE_DELETE_BULK.
+func (h *MessageHandler) BulkDeleteMessages(c fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+	}
+
+	var body struct {
+		MessageIDs []uuid.UUID `json:"message_ids"`
+		Reason     string      `json:"reason"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	deleted, err := h.messageService.BulkDeleteMessages(c.Context(), channelID, userID, body.MessageIDs, body.Reason)
+	if err != nil {
+		return handleMessageError(c, err)
+	}
+
+	h.broadcastBulkDelete(channelID, deleted)
+	return c.JSON(fiber.Map{"deleted": deleted})
+}
+
+// PurgeMessages deletes the newest channel messages matching an author,
+// time range or content filter and broadcasts a single MESSAGE_DELETE_BULK.
+func (h *MessageHandler) PurgeMessages(c fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+	}
+
+	var body struct {
+		AuthorID *uuid.UUID `json:"author_id"`
+		After    *time.Time `json:"after"`
+		Before   *time.Time `json:"before"`
+		Contains string     `json:"contains"`
+		Limit    int32      `json:"limit"`
+		Reason   string     `json:"reason"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	deleted, err := h.messageService.PurgeMessages(c.Context(), channelID, userID, service.PurgeFilter{
+		AuthorID: body.AuthorID,
+		After:    body.After,
+		Before:   body.Before,
+		Contains: body.Contains,
+		Limit:    body.Limit,
+	}, body.Reason)
+	if err != nil {
+		return handleMessageError(c, err)
+	}
+
+	h.broadcastBulkDelete(channelID, deleted)
+	return c.JSON(fiber.Map{"deleted": deleted})
+}
+
+func (h *MessageHandler) broadcastBulkDelete(channelID uuid.UUID, ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	event, _ := ws.NewEvent(ws.EventMessageDeleteBulk, fiber.Map{
+		"ids":        ids,
+		"channel_id": channelID,
+	})
+	if event != nil {
+		h.hub.BroadcastToChannel(channelID.String(), event, nil)
+	}
+}
+
+// GetDeletedMessages lists a channel's deleted messages, with their content
+// and attachments, for moderators. before pages back by deletion time.
+func (h *MessageHandler) GetDeletedMessages(c fiber.Ctx) error {
+	channelID, err := uuid.Parse(c.Params("channelId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+	}
+
+	var query service.DeletedMessagesQuery
+	if b := c.Query("before"); b != "" {
+		before, err := time.Parse(time.RFC3339Nano, b)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid before timestamp"})
+		}
+		query.Before = &before
+	}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			query.Limit = int32(parsed)
+		}
+	}
+	userID := auth.GetUserID(c)
+
+	messages, err := h.messageService.GetDeletedMessages(c.Context(), channelID, userID, query)
+	if err != nil {
+		return handleMessageError(c, err)
+	}
+
+	_ = h.attachmentService.AttachToMessages(c.Context(), messages)
+	resolveMessageAvatars(messages)
+
+	return c.JSON(messages)
+}
+
+// RestoreMessage undoes a message's deletion and broadcasts MESSAGE_RESTORE
+// with the message as history shows it, so clients put it back.
+func (h *MessageHandler) RestoreMessage(c fiber.Ctx) error {
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid message ID"})
+	}
+
+	userID := auth.GetUserID(c)
+	msg, err := h.messageService.RestoreMessage(c.Context(), messageID, userID)
+	if err != nil {
+		return handleMessageError(c, err)
+	}
+
+	// Reactions are counted without a viewer, since the event goes to everyone
+	restored := []models.MessageWithAuthor{*msg}
+	_ = h.attachmentService.AttachToMessages(c.Context(), restored)
+	_ = h.messageService.AttachReactionsToMessages(c.Context(), restored, uuid.Nil)
+	resolveMessageAvatars(restored)
+	msg = &restored[0]
+
+	event, _ := ws.NewEvent(ws.EventMessageRestore, msg)
+	if event != nil {
+		h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
+	}
+
+	return c.JSON(msg)
+}
+
 // Pin endpoints
 
 func (h *MessageHandler) PinMessage(c fiber.Ctx) error {
@@ -542,8 +692,10 @@ func (h *MessageHandler) GetEditHistory(c fiber.Ctx) error {
 }
 
 // sendNotifications sends NOTIFICATION WS events to channel members based on
-// their notification preferences. Called asynchronously after message creation.
-func (h *MessageHandler) sendNotifications(msg *models.Message, channelID, authorID uuid.UUID, authorUsername string) {
+// their notification preferences. mentionedIDs are the members the message
+// notified, which satisfy the "mentions" level. Called asynchronously after
+// message creation.
+func (h *MessageHandler) sendNotifications(msg *models.Message, channelID, authorID uuid.UUID, authorUsername string, mentionedIDs []uuid.UUID) {
 	ctx := context.Background()
 	queries := h.messageService.Queries()
 
@@ -561,12 +713,9 @@ func (h *MessageHandler) sendNotifications(msg *models.Message, channelID, autho
 		return
 	}
 
-	// Parse @mentions from message content
-	mentionedUsernames := make(map[string]bool)
-	for _, match := range notifMentionRegex.FindAllStringSubmatch(msg.Content, -1) {
-		if len(match) > 1 {
-			mentionedUsernames[match[1]] = true
-		}
+	mentioned := make(map[uuid.UUID]bool, len(mentionedIDs))
+	for _, id := range mentionedIDs {
+		mentioned[id] = true
 	}
 
 	for _, memberID := range memberIDs {
@@ -590,11 +739,7 @@ func (h *MessageHandler) sendNotifications(msg *models.Message, channelID, autho
 			// User wants notifications for all messages -- send it
 		case "mentions":
 			// Only notify if this user was @mentioned
-			user, err := queries.GetUserByID(ctx, memberID)
-			if err != nil {
-				continue
-			}
-			if !mentionedUsernames[user.Username] {
+			if !mentioned[memberID] {
 				continue
 			}
 		default:
@@ -641,12 +786,21 @@ func handleMessageError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrMessageTooLong):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidNonce):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrNoMessagesToDelete), errors.Is(err, service.ErrTooManyMessages),
+		errors.Is(err, service.ErrEmptyPurgeFilter), errors.Is(err, service.ErrInvalidPurgeRange):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidComponents):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrTooManyPins):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrMessageNotInChannel):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrReplyNotInChannel):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidHistoryQuery):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrGifsDisabled):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrUserTimedOut):
@@ -46,11 +46,13 @@ func (h *ModerationHandler) BanUser(c fiber.Ctx) error {
 
 	// Get member IDs before ban (target is still a member)
 	memberIDs, _ := h.serverService.GetServerMemberUserIDs(c.Context(), serverID)
+	managed := h.serverService.GetManagedRole(c.Context(), serverID, targetID)
 
 	ban, err := h.modService.BanUser(c.Context(), serverID, targetID, actorID, body.Reason)
 	if err != nil {
 		return handleModerationError(c, err)
 	}
+	h.hub.RevalidateUser(targetID)
 
 	// Broadcast MEMBER_LEAVE to all server members
 	event, _ := ws.NewEvent(ws.EventMemberLeave, fiber.Map{
@@ -60,6 +62,14 @@ func (h *ModerationHandler) BanUser(c fiber.Ctx) error {
 	if event != nil {
 		ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
 	}
+	banEvent, _ := ws.NewEvent(ws.EventMemberBan, fiber.Map{
+		"server_id": serverID,
+		"user_id":   targetID,
+	})
+	if banEvent != nil {
+		ws.BroadcastToServerMembers(h.hub, memberIDs, banEvent, nil)
+	}
+	broadcastManagedRoleDelete(h.hub, memberIDs, managed)
 
 	return c.Status(fiber.StatusCreated).JSON(ban)
 }
@@ -118,10 +128,12 @@ func (h *ModerationHandler) KickUser(c fiber.Ctx) error {
 
 	// Get member IDs before kick
 	memberIDs, _ := h.serverService.GetServerMemberUserIDs(c.Context(), serverID)
+	managed := h.serverService.GetManagedRole(c.Context(), serverID, targetID)
 
 	if err := h.modService.KickUser(c.Context(), serverID, targetID, actorID, body.Reason); err != nil {
 		return handleModerationError(c, err)
 	}
+	h.hub.RevalidateUser(targetID)
 
 	event, _ := ws.NewEvent(ws.EventMemberLeave, fiber.Map{
 		"server_id": serverID,
@@ -130,6 +142,7 @@ func (h *ModerationHandler) KickUser(c fiber.Ctx) error {
 	if event != nil {
 		ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
 	}
+	broadcastManagedRoleDelete(h.hub, memberIDs, managed)
 
 	return c.JSON(fiber.Map{"message": "user kicked"})
 }
@@ -163,7 +176,7 @@ func (h *ModerationHandler) TimeoutUser(c fiber.Ctx) error {
 
 	// Broadcast timeout to server members
 	if memberIDs, err := h.serverService.GetServerMemberUserIDs(c.Context(), serverID); err == nil {
-		event, _ := ws.NewEvent("MEMBER_TIMEOUT", fiber.Map{
+		event, _ := ws.NewEvent(ws.EventMemberTimeout, fiber.Map{
 			"server_id":  serverID,
 			"user_id":    targetID,
 			"expires_at": timeout.ExpiresAt,
@@ -96,6 +96,7 @@ type OryHandler struct {
 	loginTmpl        *template.Template
 	registrationTmpl *template.Template
 	errorTmpl        *template.Template
+	botAuthorization *BotAuthorizationHandler
 }
 
 // mustParsePages parses the base template together with a specific page template
@@ -132,6 +133,12 @@ func NewOryHandler(hydraClient *ory.HydraClient, kratosClient *ory.KratosClient,
 	}
 }
 
+// SetBotAuthorization hands consent requests from bot clients to the bot
+// add-to-server flow.
+func (h *OryHandler) SetBotAuthorization(bh *BotAuthorizationHandler) {
+	h.botAuthorization = bh
+}
+
 // signChallenge returns "challenge.hex(hmac)" so the cookie value is tamper-proof.
 func (h *OryHandler) signChallenge(challenge string) string {
 	mac := hmac.New(sha256.New, h.cookieHMACKey)
@@ -349,7 +356,8 @@ func (h *OryHandler) GetRegistration(c fiber.Ctx) error {
 }
 
 // GetConsent handles GET /auth/consent — Hydra redirects here with a consent_challenge.
-// First-party clients (metadata.is_first_party = true) are auto-accepted.
+// First-party clients (metadata.is_first_party = true) are auto-accepted; bot clients
+// (metadata.bot_id) go to the add-to-server page.
 //
 // Two-phase redirect: Hydra's consent_challenge tokens are ~1KB which triggers
 // Google Safe Browsing phishing heuristics. Phase 1 stores the challenge under
@@ -380,6 +388,13 @@ func (h *OryHandler) GetConsent(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "failed to fetch consent request"})
 	}
 
+	// Bot clients ask an admin to add the bot to a server.
+	if h.botAuthorization != nil {
+		if _, ok := botClientID(cr.Client); ok {
+			return h.botAuthorization.RenderConsent(c, challenge, cr)
+		}
+	}
+
 	// Auto-accept for first-party clients or previously consented scopes.
 	if cr.Skip || isFirstParty(cr.Client) {
 		// Find or create local user from Kratos identity.
@@ -99,6 +99,9 @@ func (h *RoleHandler) UpdateRole(c fiber.Ctx) error {
 	}
 
 	h.broadcastToServer(c, serverID, ws.EventRoleUpdate, role)
+	if perms != nil {
+		h.revalidateServerChannels(c, serverID)
+	}
 	return c.JSON(role)
 }
 
@@ -122,6 +125,7 @@ func (h *RoleHandler) DeleteRole(c fiber.Ctx) error {
 		"id":        roleID,
 		"server_id": serverID,
 	})
+	h.revalidateServerChannels(c, serverID)
 	return c.JSON(fiber.Map{"message": "role deleted"})
 }
 
@@ -207,6 +211,7 @@ func (h *RoleHandler) RemoveRoleFromMember(c fiber.Ctx) error {
 		"role_id":   roleID,
 		"action":    "remove",
 	})
+	h.hub.RevalidateUser(targetUserID)
 	return c.JSON(fiber.Map{"message": "role removed"})
 }
 
@@ -257,6 +262,7 @@ func (h *RoleHandler) SetChannelOverride(c fiber.Ctx) error {
 		return handleRoleError(c, err)
 	}
 
+	h.hub.RevalidateChannel(channelID.String())
 	return c.JSON(override)
 }
 
@@ -281,6 +287,7 @@ func (h *RoleHandler) DeleteChannelOverride(c fiber.Ctx) error {
 		return handleRoleError(c, err)
 	}
 
+	h.hub.RevalidateChannel(channelID.String())
 	return c.JSON(fiber.Map{"message": "override deleted"})
 }
 
@@ -312,6 +319,19 @@ func (h *RoleHandler) broadcastToServer(c fiber.Ctx, serverID uuid.UUID, eventTy
 	ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
 }
 
+// revalidateServerChannels drops live subscriptions that a role change has
+// made invisible.
+func (h *RoleHandler) revalidateServerChannels(c fiber.Ctx, serverID uuid.UUID) {
+	channels, err := h.serverSvc.GetServerChannels(c.Context(), serverID)
+	if err != nil {
+		log.Printf("Failed to get channels for subscription revalidation: %v", err)
+		return
+	}
+	for _, ch := range channels {
+		h.hub.RevalidateChannel(ch.ID.String())
+	}
+}
+
 func parsePermissions(s string) int64 {
 	if s == "" {
 		return 0
@@ -329,7 +349,7 @@ func handleRoleError(c fiber.Ctx, err error) error {
 	switch {
 	case errors.Is(err, service.ErrRoleNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrCannotDeleteEveryone):
+	case errors.Is(err, service.ErrCannotDeleteEveryone), errors.Is(err, service.ErrManagedRole):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrCannotModifyHigherRole):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
@@ -109,10 +109,13 @@ func (h *ServerHandler) LeaveServer(c fiber.Ctx) error {
 
 	// Query member IDs BEFORE leave (user is still a member)
 	memberIDs, _ := h.serverService.GetServerMemberUserIDs(c.Context(), serverID)
+	// A bot leaving takes its managed role with it
+	managed := h.serverService.GetManagedRole(c.Context(), serverID, userID)
 
 	if err := h.serverService.LeaveServer(c.Context(), serverID, userID); err != nil {
 		return handleServerError(c, err)
 	}
+	h.hub.RevalidateUser(userID)
 
 	event, _ := ws.NewEvent(ws.EventMemberLeave, fiber.Map{
 		"server_id": serverID,
@@ -121,6 +124,7 @@ func (h *ServerHandler) LeaveServer(c fiber.Ctx) error {
 	if event != nil {
 		ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
 	}
+	broadcastManagedRoleDelete(h.hub, memberIDs, managed)
 
 	return c.JSON(fiber.Map{"message": "left server"})
 }
@@ -252,13 +256,14 @@ func (h *ServerHandler) UpdateServer(c fiber.Ctx) error {
 		Description                 *string `json:"description"`
 		GifsEnabled                 *bool   `json:"gifs_enabled"`
 		DefaultMessageRetentionDays *int    `json:"default_message_retention_days"`
+		DeletedMessageDisplay       *string `json:"deleted_message_display"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
 	userID := auth.GetUserID(c)
-	server, err := h.serverService.UpdateServer(c.Context(), serverID, userID, body.Name, body.IconURL, body.IsPublic, body.Description, body.GifsEnabled, body.DefaultMessageRetentionDays)
+	server, err := h.serverService.UpdateServer(c.Context(), serverID, userID, body.Name, body.IconURL, body.IsPublic, body.Description, body.GifsEnabled, body.DefaultMessageRetentionDays, body.DeletedMessageDisplay)
 	if err != nil {
 		return handleServerError(c, err)
 	}
@@ -489,6 +494,8 @@ func handleServerError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrInvalidCategoryName):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidDeletedMessageDisplay):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrUserBanned):
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrUserTimedOut):
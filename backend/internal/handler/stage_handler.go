@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -15,11 +17,12 @@ import (
 type StageHandler struct {
 	stageService  *service.StageService
 	serverService *service.ServerService
+	voiceService  *service.VoiceService
 	hub           *ws.Hub
 }
 
-func NewStageHandler(ss *service.StageService, serverSvc *service.ServerService, hub *ws.Hub) *StageHandler {
-	return &StageHandler{stageService: ss, serverService: serverSvc, hub: hub}
+func NewStageHandler(ss *service.StageService, serverSvc *service.ServerService, vs *service.VoiceService, hub *ws.Hub) *StageHandler {
+	return &StageHandler{stageService: ss, serverService: serverSvc, voiceService: vs, hub: hub}
 }
 
 // StartStage creates a new stage instance on a voice channel.
@@ -47,6 +50,9 @@ func (h *StageHandler) StartStage(c fiber.Ctx) error {
 		"started_by": userID,
 	})
 
+	// Everyone already connected except the starter becomes audience
+	h.syncVoicePermissions(channelID, h.voiceParticipantIDs(channelID)...)
+
 	return c.Status(fiber.StatusCreated).JSON(instance)
 }
 
@@ -65,6 +71,7 @@ func (h *StageHandler) EndStage(c fiber.Ctx) error {
 	h.broadcastStageEvent(c, channelID, ws.EventStageEnd, fiber.Map{
 		"channel_id": channelID,
 	})
+	h.syncVoicePermissions(channelID, h.voiceParticipantIDs(channelID)...)
 
 	return c.JSON(fiber.Map{"message": "stage ended"})
 }
@@ -104,6 +111,7 @@ func (h *StageHandler) AddSpeaker(c fiber.Ctx) error {
 		"username":   username,
 		"invited":    speaker.Invited,
 	})
+	h.syncVoicePermissions(channelID, userID)
 
 	return c.Status(fiber.StatusCreated).JSON(speaker)
 }
@@ -129,6 +137,7 @@ func (h *StageHandler) RemoveSpeaker(c fiber.Ctx) error {
 		"channel_id": channelID,
 		"user_id":    targetUserID,
 	})
+	h.syncVoicePermissions(channelID, targetUserID)
 
 	return c.JSON(fiber.Map{"message": "speaker removed"})
 }
@@ -226,6 +235,33 @@ func (h *StageHandler) broadcastStageEvent(c fiber.Ctx, channelID uuid.UUID, eve
 	ws.BroadcastToServerMembers(h.hub, memberIDs, event, nil)
 }
 
+// voiceParticipantIDs returns the users currently connected to the channel.
+func (h *StageHandler) voiceParticipantIDs(channelID uuid.UUID) []uuid.UUID {
+	states := h.hub.GetVoiceParticipants(channelID.String())
+	ids := make([]uuid.UUID, len(states))
+	for i, st := range states {
+		ids[i] = st.UserID
+	}
+	return ids
+}
+
+// syncVoicePermissions pushes recomputed publish rights to the users' live
+// LiveKit sessions in the background.
+func (h *StageHandler) syncVoicePermissions(channelID uuid.UUID, userIDs ...uuid.UUID) {
+	if h.voiceService == nil || len(userIDs) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, userID := range userIDs {
+			if err := h.voiceService.SyncParticipant(ctx, channelID, userID); err != nil {
+				log.Printf("Failed to sync voice permissions for %s in %s: %v", userID, channelID, err)
+			}
+		}
+	}()
+}
+
 func handleStageError(c fiber.Ctx, err error) error {
 	switch {
 	case errors.Is(err, service.ErrStageAlreadyActive):
@@ -2,8 +2,6 @@ package handler
 
 import (
 	"errors"
-	"strconv"
-	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -144,6 +142,7 @@ func (h *ThreadHandler) SendMessage(c fiber.Ctx) error {
 	var body struct {
 		Content   string  `json:"content"`
 		ReplyToID *string `json:"reply_to_id"`
+		Nonce     string  `json:"nonce"`
 	}
 	if err := c.Bind().JSON(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
@@ -159,10 +158,14 @@ func (h *ThreadHandler) SendMessage(c fiber.Ctx) error {
 	}
 
 	userID := auth.GetUserID(c)
-	msg, err := h.threadService.SendThreadMessage(c.Context(), threadID, userID, body.Content, replyToID)
+	msg, replayed, err := h.threadService.SendThreadMessageWithNonce(c.Context(), threadID, userID, body.Content, replyToID, body.Nonce)
 	if err != nil {
 		return handleThreadError(c, err)
 	}
+	if replayed {
+		// A retried send: the first attempt already broadcast the message
+		return c.JSON(msg)
+	}
 
 	// Get the thread to find the channel for broadcast
 	thread, _ := h.threadService.GetThread(c.Context(), threadID)
@@ -180,6 +183,7 @@ func (h *ThreadHandler) SendMessage(c fiber.Ctx) error {
 			"author_avatar_url":   msg.AuthorAvatarURL,
 			"channel_id":          thread.ChannelID,
 			"message_count":       thread.MessageCount,
+			"nonce":               msg.Nonce,
 		})
 		if event != nil {
 			h.hub.BroadcastToChannel(thread.ChannelID.String(), event, nil)
@@ -217,30 +221,20 @@ func (h *ThreadHandler) DeleteMessage(c fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// GetMessages returns a page of thread history, newest first. before, after
+// and around take a message ID or, for older clients, a timestamp.
 func (h *ThreadHandler) GetMessages(c fiber.Ctx) error {
 	threadID, err := uuid.Parse(c.Params("threadId"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid thread ID"})
 	}
 
-	var before *time.Time
-	if b := c.Query("before"); b != "" {
-		t, err := time.Parse(time.RFC3339, b)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid before timestamp"})
-		}
-		before = &t
-	}
-
-	limitVal := 50
-	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil {
-			limitVal = parsed
-		}
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	limit := int32(limitVal)
 
-	messages, err := h.threadService.GetThreadMessages(c.Context(), threadID, before, limit)
+	messages, err := h.threadService.GetThreadMessageHistory(c.Context(), threadID, query)
 	if err != nil {
 		return handleThreadError(c, err)
 	}
@@ -288,9 +282,9 @@ func handleThreadError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrMessageNotFound):
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrMessageNotInChannel):
+	case errors.Is(err, service.ErrMessageNotInChannel), errors.Is(err, service.ErrInvalidHistoryQuery):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	case errors.Is(err, service.ErrEmptyMessage):
+	case errors.Is(err, service.ErrEmptyMessage), errors.Is(err, service.ErrInvalidNonce):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	default:
 		if err.Error() == "thread already exists for this message" {
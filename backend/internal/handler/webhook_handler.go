@@ -1,23 +1,26 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 
 	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/models"
 	"github.com/M-McCallum/thicket/internal/service"
 	"github.com/M-McCallum/thicket/internal/ws"
 )
 
 type WebhookHandler struct {
-	webhookService *service.WebhookService
-	hub            *ws.Hub
+	webhookService    *service.WebhookService
+	attachmentService *service.AttachmentService
+	hub               *ws.Hub
 }
 
-func NewWebhookHandler(ws *service.WebhookService, hub *ws.Hub) *WebhookHandler {
-	return &WebhookHandler{webhookService: ws, hub: hub}
+func NewWebhookHandler(ws *service.WebhookService, as *service.AttachmentService, hub *ws.Hub) *WebhookHandler {
+	return &WebhookHandler{webhookService: ws, attachmentService: as, hub: hub}
 }
 
 // CreateWebhook creates a webhook for a channel.
@@ -42,7 +45,12 @@ func (h *WebhookHandler) CreateWebhook(c fiber.Ctx) error {
 	}
 
 	// Return the webhook with the plaintext token visible (shown once on creation only)
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	return c.Status(fiber.StatusCreated).JSON(webhookWithToken(webhook, token))
+}
+
+// webhookWithToken is a webhook together with its plaintext token and URL.
+func webhookWithToken(webhook *models.Webhook, token string) fiber.Map {
+	return fiber.Map{
 		"id":         webhook.ID,
 		"channel_id": webhook.ChannelID,
 		"name":       webhook.Name,
@@ -51,7 +59,7 @@ func (h *WebhookHandler) CreateWebhook(c fiber.Ctx) error {
 		"creator_id": webhook.CreatorID,
 		"created_at": webhook.CreatedAt,
 		"url":        "/api/webhooks/" + webhook.ID.String() + "/" + token,
-	})
+	}
 }
 
 // ListWebhooks lists all webhooks for a channel.
@@ -70,6 +78,73 @@ func (h *WebhookHandler) ListWebhooks(c fiber.Ctx) error {
 	return c.JSON(webhooks)
 }
 
+// ListServerWebhooks lists all webhooks in a server, for its admins.
+func (h *WebhookHandler) ListServerWebhooks(c fiber.Ctx) error {
+	serverID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid server ID"})
+	}
+
+	userID := auth.GetUserID(c)
+	webhooks, err := h.webhookService.ListServerWebhooks(c.Context(), serverID, userID)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	return c.JSON(webhooks)
+}
+
+// UpdateWebhook renames a webhook, changes its avatar or moves it to another channel.
+func (h *WebhookHandler) UpdateWebhook(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+
+	var body struct {
+		Name      *string `json:"name"`
+		AvatarURL *string `json:"avatar_url"`
+		ChannelID *string `json:"channel_id"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	params := service.UpdateWebhookParams{Name: body.Name, AvatarURL: body.AvatarURL}
+	if body.ChannelID != nil {
+		channelID, err := uuid.Parse(*body.ChannelID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid channel ID"})
+		}
+		params.ChannelID = &channelID
+	}
+
+	userID := auth.GetUserID(c)
+	webhook, err := h.webhookService.UpdateWebhook(c.Context(), webhookID, userID, params)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	return c.JSON(webhook)
+}
+
+// RotateWebhookToken issues a new token for a webhook, invalidating the old URL.
+func (h *WebhookHandler) RotateWebhookToken(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+
+	userID := auth.GetUserID(c)
+	webhook, token, err := h.webhookService.RotateWebhookToken(c.Context(), webhookID, userID)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	// The new token is shown once, like on creation
+	return c.JSON(webhookWithToken(webhook, token))
+}
+
 // DeleteWebhook deletes a webhook by ID.
 func (h *WebhookHandler) DeleteWebhook(c fiber.Ctx) error {
 	webhookID, err := uuid.Parse(c.Params("webhookId"))
@@ -85,7 +160,20 @@ func (h *WebhookHandler) DeleteWebhook(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "webhook deleted"})
 }
 
+// webhookExecuteBody is the JSON body of a webhook execution. Multipart
+// requests send it as the payload_json field alongside files[].
+type webhookExecuteBody struct {
+	Content    string          `json:"content"`
+	Username   string          `json:"username"`
+	AvatarURL  string          `json:"avatar_url"`
+	Embeds     json.RawMessage `json:"embeds"`
+	Components json.RawMessage `json:"components"`
+	ThreadID   string          `json:"thread_id"`
+}
+
 // ExecuteWebhook executes a webhook (public endpoint, no auth required).
+// With ?wait=true the created message is returned; otherwise the response is
+// empty.
 func (h *WebhookHandler) ExecuteWebhook(c fiber.Ctx) error {
 	webhookID, err := uuid.Parse(c.Params("webhookId"))
 	if err != nil {
@@ -96,14 +184,38 @@ func (h *WebhookHandler) ExecuteWebhook(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing token"})
 	}
 
-	var body struct {
-		Content   string `json:"content"`
-		Username  string `json:"username"`
-		AvatarURL string `json:"avatar_url"`
-	}
-	if err := c.Bind().JSON(&body); err != nil {
+	var body webhookExecuteBody
+	var fileInputs []service.AttachmentInput
+	form, _ := c.MultipartForm()
+	if form != nil {
+		if payload := c.FormValue("payload_json"); payload != "" {
+			if err := json.Unmarshal([]byte(payload), &body); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload_json"})
+			}
+		} else {
+			body.Content = c.FormValue("content")
+			body.Username = c.FormValue("username")
+			body.AvatarURL = c.FormValue("avatar_url")
+		}
+		for _, fh := range form.File["files[]"] {
+			f, err := fh.Open()
+			if err != nil {
+				continue
+			}
+			fileInputs = append(fileInputs, service.AttachmentInput{
+				Reader:      f,
+				Filename:    fh.Filename,
+				ContentType: fh.Header.Get("Content-Type"),
+				Size:        fh.Size,
+			})
+		}
+	} else if err := c.Bind().JSON(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	defer closeAttachmentInputs(fileInputs)
+	if len(fileInputs) > 10 {
+		return handleWebhookError(c, service.ErrTooManyFiles)
+	}
 
 	if len(body.Content) > 4000 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "content must be 4000 characters or fewer"})
@@ -115,39 +227,308 @@ func (h *WebhookHandler) ExecuteWebhook(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "avatar_url must be 2048 characters or fewer"})
 	}
 
-	webhook, msg, err := h.webhookService.ExecuteWebhook(c.Context(), webhookID, token, body.Content)
+	// thread_id may come as a query parameter or in the body
+	threadStr := c.Query("thread_id", body.ThreadID)
+	var threadID *uuid.UUID
+	if threadStr != "" {
+		parsed, err := uuid.Parse(threadStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid thread_id"})
+		}
+		threadID = &parsed
+	}
+
+	exec, err := h.webhookService.ExecuteWebhook(c.Context(), webhookID, token, service.ExecuteWebhookParams{
+		Content:    body.Content,
+		Username:   body.Username,
+		AvatarURL:  body.AvatarURL,
+		Embeds:     body.Embeds,
+		Components: body.Components,
+		ThreadID:   threadID,
+		HasFiles:   len(fileInputs) > 0,
+	})
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	var payload fiber.Map
+	if exec.ThreadMessage != nil {
+		payload = h.broadcastThreadMessage(exec)
+	} else {
+		payload, err = h.broadcastMessage(c, exec, fileInputs)
+		if err != nil {
+			return handleWebhookError(c, err)
+		}
+	}
+
+	if c.Query("wait") != "true" {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	return c.JSON(payload)
+}
+
+// ExecuteAdapter executes a webhook with a GitHub, GitLab or Slack payload
+// (public endpoint, no auth required).
+func (h *WebhookHandler) ExecuteAdapter(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+	token := c.Params("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing token"})
+	}
+
+	req := service.AdapterRequest{
+		Provider: c.Params("adapter"),
+		Body:     append([]byte(nil), c.Body()...),
+	}
+	switch req.Provider {
+	case service.AdapterGitHub:
+		req.Event = c.Get("X-GitHub-Event")
+		req.Signature = c.Get("X-Hub-Signature-256")
+	case service.AdapterGitLab:
+		req.Event = c.Get("X-Gitlab-Event")
+		req.Signature = c.Get("X-Gitlab-Token")
+	case service.AdapterSlack:
+		// Slack clients may also send the JSON form-encoded as payload=
+		if payload := c.FormValue("payload"); payload != "" {
+			req.Body = []byte(payload)
+		}
+	}
+
+	exec, err := h.webhookService.ExecuteAdapter(c.Context(), webhookID, token, req)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+	if exec != nil {
+		if _, err := h.broadcastMessage(c, exec, nil); err != nil {
+			return handleWebhookError(c, err)
+		}
+	}
+
+	// Slack clients expect a plain "ok"
+	if req.Provider == service.AdapterSlack {
+		return c.SendString("ok")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UpdateAdapterSecret sets the secret GitHub and GitLab deliveries to the
+// webhook's adapters are verified with. An empty secret turns verification off.
+func (h *WebhookHandler) UpdateAdapterSecret(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+
+	var body struct {
+		Secret string `json:"secret"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	webhook, err := h.webhookService.SetAdapterSecret(c.Context(), webhookID, userID, body.Secret)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+	return c.JSON(webhook)
+}
+
+// EditWebhookMessage edits a message the webhook posted (public endpoint,
+// authenticated by the webhook token).
+func (h *WebhookHandler) EditWebhookMessage(c fiber.Ctx) error {
+	webhookID, messageID, token, err := parseWebhookMessageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body struct {
+		Content    *string         `json:"content"`
+		Embeds     json.RawMessage `json:"embeds"`
+		Components json.RawMessage `json:"components"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	msg, err := h.webhookService.EditWebhookMessage(c.Context(), webhookID, token, messageID, service.EditWebhookMessageParams{
+		Content:    body.Content,
+		Embeds:     body.Embeds,
+		Components: body.Components,
+	})
 	if err != nil {
 		return handleWebhookError(c, err)
 	}
 
-	// Determine display name and avatar for the broadcast
-	displayName := webhook.Name
-	if body.Username != "" {
-		displayName = body.Username
+	event, _ := ws.NewEvent(ws.EventMessageUpdate, msg)
+	if event != nil {
+		h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
+	}
+
+	return c.JSON(msg)
+}
+
+// DeleteWebhookMessage deletes a message the webhook posted (public endpoint,
+// authenticated by the webhook token).
+func (h *WebhookHandler) DeleteWebhookMessage(c fiber.Ctx) error {
+	webhookID, messageID, token, err := parseWebhookMessageParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	msg, err := h.webhookService.DeleteWebhookMessage(c.Context(), webhookID, token, messageID)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	event, _ := ws.NewEvent(ws.EventMessageDelete, fiber.Map{
+		"id":         msg.ID,
+		"channel_id": msg.ChannelID,
+	})
+	if event != nil {
+		h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RequireToken rejects requests whose URL token doesn't match the webhook,
+// and stores the webhook ID for the per-webhook rate limit that follows.
+func (h *WebhookHandler) RequireToken(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+	if _, err := h.webhookService.Authenticate(c.Context(), webhookID, c.Params("token")); err != nil {
+		return handleWebhookError(c, err)
+	}
+	c.Locals("webhookID", webhookID)
+	return c.Next()
+}
+
+func parseWebhookMessageParams(c fiber.Ctx) (uuid.UUID, uuid.UUID, string, error) {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", errors.New("invalid webhook ID")
+	}
+	messageID, err := uuid.Parse(c.Params("messageId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, "", errors.New("invalid message ID")
 	}
-	avatarURL := webhook.AvatarURL
-	if body.AvatarURL != "" {
-		avatarURL = body.AvatarURL
+	token := c.Params("token")
+	if token == "" {
+		return uuid.Nil, uuid.Nil, "", errors.New("missing token")
 	}
+	return webhookID, messageID, token, nil
+}
+
+// broadcastMessage uploads a channel message's attachments and broadcasts
+// it, returning the broadcast payload.
+func (h *WebhookHandler) broadcastMessage(c fiber.Ctx, exec *service.WebhookExecution, fileInputs []service.AttachmentInput) (fiber.Map, error) {
+	msg := exec.Message
 
-	// Broadcast via WebSocket like a normal message
-	event, _ := ws.NewEvent(ws.EventMessageCreate, fiber.Map{
+	attachments := []models.Attachment{}
+	if len(fileInputs) > 0 {
+		atts, err := h.attachmentService.CreateAttachments(c.Context(), &msg.ID, nil, fileInputs)
+		if err != nil {
+			return nil, err
+		}
+		h.attachmentService.ResolveURLs(c.Context(), atts)
+		attachments = atts
+	}
+
+	// Broadcast via WebSocket like a normal message, under the webhook's name
+	payload := fiber.Map{
 		"id":                  msg.ID,
 		"channel_id":          msg.ChannelID,
 		"author_id":           msg.AuthorID,
 		"content":             msg.Content,
 		"type":                msg.Type,
 		"created_at":          msg.CreatedAt,
-		"username":            displayName,
-		"author_avatar_url":   avatarURL,
-		"author_display_name": displayName,
-		"webhook_id":          webhook.ID,
-	})
+		"username":            msg.WebhookUsername,
+		"author_avatar_url":   msg.WebhookAvatarURL,
+		"author_display_name": msg.WebhookUsername,
+		"webhook_id":          msg.WebhookID,
+		"webhook_username":    msg.WebhookUsername,
+		"webhook_avatar_url":  msg.WebhookAvatarURL,
+		"components":          msg.Components,
+		"embeds":              msg.Embeds,
+		"attachments":         attachments,
+	}
+	event, _ := ws.NewEvent(ws.EventMessageCreate, payload)
 	if event != nil {
 		h.hub.BroadcastToChannel(msg.ChannelID.String(), event, nil)
 	}
+	return payload, nil
+}
 
-	return c.Status(fiber.StatusCreated).JSON(msg)
+// broadcastThreadMessage broadcasts a webhook message posted in a thread,
+// returning the broadcast payload.
+func (h *WebhookHandler) broadcastThreadMessage(exec *service.WebhookExecution) fiber.Map {
+	msg, thread := exec.ThreadMessage, exec.Thread
+	payload := fiber.Map{
+		"id":                  msg.ID,
+		"thread_id":           msg.ThreadID,
+		"author_id":           msg.AuthorID,
+		"content":             msg.Content,
+		"reply_to_id":         msg.ReplyToID,
+		"created_at":          msg.CreatedAt,
+		"updated_at":          msg.UpdatedAt,
+		"author_username":     msg.WebhookUsername,
+		"author_display_name": msg.WebhookUsername,
+		"author_avatar_url":   msg.WebhookAvatarURL,
+		"webhook_id":          msg.WebhookID,
+		"webhook_username":    msg.WebhookUsername,
+		"webhook_avatar_url":  msg.WebhookAvatarURL,
+		"embeds":              msg.Embeds,
+		"channel_id":          thread.ChannelID,
+		"message_count":       thread.MessageCount,
+	}
+	event, _ := ws.NewEvent(ws.EventThreadMessageCreate, payload)
+	if event != nil {
+		h.hub.BroadcastToChannel(thread.ChannelID.String(), event, nil)
+	}
+	return payload
+}
+
+func closeAttachmentInputs(inputs []service.AttachmentInput) {
+	for _, fi := range inputs {
+		if closer, ok := fi.Reader.(interface{ Close() error }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// UpdateInteractionsEndpoint sets the URL that receives component
+// interactions on the webhook's messages. An empty URL makes its components
+// inert again.
+func (h *WebhookHandler) UpdateInteractionsEndpoint(c fiber.Ctx) error {
+	webhookID, err := uuid.Parse(c.Params("webhookId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid webhook ID"})
+	}
+
+	var body struct {
+		InteractionsURL string `json:"interactions_url"`
+	}
+	if err := c.Bind().JSON(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	userID := auth.GetUserID(c)
+	webhook, secret, err := h.webhookService.SetInteractionsURL(c.Context(), webhookID, userID, body.InteractionsURL)
+	if err != nil {
+		return handleWebhookError(c, err)
+	}
+
+	// The signing secret is shown once, when the endpoint is set
+	return c.JSON(fiber.Map{
+		"webhook":             webhook,
+		"interactions_secret": secret,
+	})
 }
 
 func handleWebhookError(c fiber.Ctx, err error) error {
@@ -166,6 +547,33 @@ func handleWebhookError(c fiber.Ctx, err error) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
 	case errors.Is(err, service.ErrEmptyMessage):
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidComponents):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidInteractionsURL):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidEmbeds),
+		errors.Is(err, service.ErrInvalidWebhookAvatar),
+		errors.Is(err, service.ErrInvalidWebhookTarget),
+		errors.Is(err, service.ErrThreadAttachments):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrUnknownAdapter):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrAdapterSignature):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAdapterPayload), errors.Is(err, service.ErrInvalidAdapterSecret):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrMessageNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrMessageTooLong):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrThreadNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrThreadLocked), errors.Is(err, service.ErrThreadArchived):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrTooManyFiles),
+		errors.Is(err, service.ErrFileTooLarge),
+		errors.Is(err, service.ErrInvalidFileType):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
 	}
@@ -104,3 +104,13 @@ func UserChannelKeyFunc(c fiber.Ctx) string {
 	}
 	return "u:" + uid.String() + ":ch:" + channelID
 }
+
+// WebhookKeyFunc returns the webhook ID set by the webhook token check, so
+// each webhook has its own execution limit wherever it is called from.
+// Requests that haven't passed the token check fall back to IP.
+func WebhookKeyFunc(c fiber.Ctx) string {
+	if id, ok := c.Locals("webhookID").(uuid.UUID); ok && id != uuid.Nil {
+		return "wh:" + id.String()
+	}
+	return "ip:" + c.IP()
+}
@@ -6,17 +6,24 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
-// BotUser represents a bot account.
+// BotUser represents a bot account. Each bot has a users row with the same
+// ID, so it can join servers, author messages and hold roles.
 type BotUser struct {
 	ID          uuid.UUID `json:"id"`
 	OwnerID     uuid.UUID `json:"owner_id"`
 	Username    string    `json:"username"`
 	AvatarURL   string    `json:"avatar_url"`
+	TokenID     *string   `json:"-"`
 	TokenHash   string    `json:"-"`
 	Permissions int64     `json:"permissions"`
-	CreatedAt   time.Time `json:"created_at"`
+	// InteractionsURL, when set, receives interactions as signed HTTP
+	// callbacks instead of gateway events.
+	InteractionsURL    string    `json:"interactions_url"`
+	InteractionsSecret string    `json:"-"`
+	CreatedAt          time.Time `json:"created_at"`
 }
 
 // Webhook represents a channel webhook.
@@ -27,7 +34,18 @@ type Webhook struct {
 	AvatarURL string    `json:"avatar_url"`
 	TokenHash string    `json:"-"`
 	CreatorID uuid.UUID `json:"creator_id"`
-	CreatedAt time.Time `json:"created_at"`
+	// InteractionsURL receives component interactions on the webhook's
+	// messages, signed with InteractionsSecret.
+	InteractionsURL    string `json:"interactions_url"`
+	InteractionsSecret string `json:"-"`
+	// AdapterSecret verifies GitHub and GitLab deliveries to the webhook's
+	// provider adapters.
+	AdapterSecret    string `json:"-"`
+	HasAdapterSecret bool   `json:"has_adapter_secret"`
+	// LastUsedAt and MessageCount track executions.
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	MessageCount int64      `json:"message_count"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 // SlashCommand represents a bot slash command.
@@ -43,36 +61,57 @@ type SlashCommand struct {
 
 // --- Bot CRUD ---
 
+const botUserColumns = `id, owner_id, username, avatar_url, token_id, token_hash, permissions, interactions_url, interactions_secret, created_at`
+
+func scanBotUser(row pgx.Row) (BotUser, error) {
+	var b BotUser
+	err := row.Scan(&b.ID, &b.OwnerID, &b.Username, &b.AvatarURL, &b.TokenID, &b.TokenHash, &b.Permissions,
+		&b.InteractionsURL, &b.InteractionsSecret, &b.CreatedAt)
+	return b, err
+}
+
 type CreateBotUserParams struct {
+	ID        uuid.UUID
 	OwnerID   uuid.UUID
 	Username  string
+	TokenID   string
 	TokenHash string
 }
 
+// CreateBotUser inserts the bot and its backing users row in one statement.
 func (q *Queries) CreateBotUser(ctx context.Context, arg CreateBotUserParams) (BotUser, error) {
-	var b BotUser
-	err := q.db.QueryRow(ctx,
-		`INSERT INTO bot_users (owner_id, username, token_hash)
-		VALUES ($1, $2, $3)
-		RETURNING id, owner_id, username, avatar_url, token_hash, permissions, created_at`,
-		arg.OwnerID, arg.Username, arg.TokenHash,
-	).Scan(&b.ID, &b.OwnerID, &b.Username, &b.AvatarURL, &b.TokenHash, &b.Permissions, &b.CreatedAt)
-	return b, err
+	row := q.db.QueryRow(ctx,
+		`WITH account AS (
+			INSERT INTO users (id, username, email, is_bot)
+			VALUES ($1::uuid, $3, $1::uuid::text || '@bots.invalid', true)
+			RETURNING id
+		)
+		INSERT INTO bot_users (id, owner_id, username, token_id, token_hash)
+		SELECT id, $2, $3, $4, $5 FROM account
+		RETURNING `+botUserColumns,
+		arg.ID, arg.OwnerID, arg.Username, arg.TokenID, arg.TokenHash,
+	)
+	return scanBotUser(row)
 }
 
 func (q *Queries) GetBotUserByID(ctx context.Context, id uuid.UUID) (BotUser, error) {
-	var b BotUser
-	err := q.db.QueryRow(ctx,
-		`SELECT id, owner_id, username, avatar_url, token_hash, permissions, created_at
-		FROM bot_users WHERE id = $1`, id,
-	).Scan(&b.ID, &b.OwnerID, &b.Username, &b.AvatarURL, &b.TokenHash, &b.Permissions, &b.CreatedAt)
-	return b, err
+	row := q.db.QueryRow(ctx,
+		`SELECT `+botUserColumns+` FROM bot_users WHERE id = $1`, id,
+	)
+	return scanBotUser(row)
+}
+
+// GetBotUserByTokenID looks a bot up by the public half of its token.
+func (q *Queries) GetBotUserByTokenID(ctx context.Context, tokenID string) (BotUser, error) {
+	row := q.db.QueryRow(ctx,
+		`SELECT `+botUserColumns+` FROM bot_users WHERE token_id = $1`, tokenID,
+	)
+	return scanBotUser(row)
 }
 
 func (q *Queries) GetBotUsersByOwner(ctx context.Context, ownerID uuid.UUID) ([]BotUser, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT id, owner_id, username, avatar_url, token_hash, permissions, created_at
-		FROM bot_users WHERE owner_id = $1 ORDER BY created_at DESC`, ownerID,
+		`SELECT `+botUserColumns+` FROM bot_users WHERE owner_id = $1 ORDER BY created_at DESC`, ownerID,
 	)
 	if err != nil {
 		return nil, err
@@ -81,8 +120,8 @@ func (q *Queries) GetBotUsersByOwner(ctx context.Context, ownerID uuid.UUID) ([]
 
 	var bots []BotUser
 	for rows.Next() {
-		var b BotUser
-		if err := rows.Scan(&b.ID, &b.OwnerID, &b.Username, &b.AvatarURL, &b.TokenHash, &b.Permissions, &b.CreatedAt); err != nil {
+		b, err := scanBotUser(rows)
+		if err != nil {
 			return nil, err
 		}
 		bots = append(bots, b)
@@ -93,42 +132,71 @@ func (q *Queries) GetBotUsersByOwner(ctx context.Context, ownerID uuid.UUID) ([]
 	return bots, rows.Err()
 }
 
-func (q *Queries) UpdateBotTokenHash(ctx context.Context, id uuid.UUID, tokenHash string) error {
+func (q *Queries) UpdateBotToken(ctx context.Context, id uuid.UUID, tokenID, tokenHash string) error {
 	_, err := q.db.Exec(ctx,
-		`UPDATE bot_users SET token_hash = $2 WHERE id = $1`,
-		id, tokenHash,
+		`UPDATE bot_users SET token_id = $2, token_hash = $3 WHERE id = $1`,
+		id, tokenID, tokenHash,
 	)
 	return err
 }
 
-func (q *Queries) DeleteBotUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, `DELETE FROM bot_users WHERE id = $1`, id)
-	return err
+// UpdateBotInteractionsEndpoint sets (or, with an empty URL, clears) the
+// bot's HTTP interactions endpoint and its signing secret.
+func (q *Queries) UpdateBotInteractionsEndpoint(ctx context.Context, id uuid.UUID, url, secret string) (BotUser, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE bot_users SET interactions_url = $2, interactions_secret = $3 WHERE id = $1
+		RETURNING `+botUserColumns,
+		id, url, secret,
+	)
+	return scanBotUser(row)
 }
 
-// GetAllBotUsers returns all bot users (for token validation lookup).
-func (q *Queries) GetAllBotUsers(ctx context.Context) ([]BotUser, error) {
-	rows, err := q.db.Query(ctx,
-		`SELECT id, owner_id, username, avatar_url, token_hash, permissions, created_at
-		FROM bot_users`,
+// UpdateBotPermissions sets the permissions the bot requests by default
+// when it is authorized into a server.
+func (q *Queries) UpdateBotPermissions(ctx context.Context, id uuid.UUID, permissions int64) (BotUser, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE bot_users SET permissions = $2 WHERE id = $1
+		RETURNING `+botUserColumns,
+		id, permissions,
 	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	return scanBotUser(row)
+}
 
-	var bots []BotUser
-	for rows.Next() {
-		var b BotUser
-		if err := rows.Scan(&b.ID, &b.OwnerID, &b.Username, &b.AvatarURL, &b.TokenHash, &b.Permissions, &b.CreatedAt); err != nil {
-			return nil, err
-		}
-		bots = append(bots, b)
-	}
-	if bots == nil {
-		bots = []BotUser{}
-	}
-	return bots, rows.Err()
+type AddBotMemberParams struct {
+	ServerID    uuid.UUID
+	BotID       uuid.UUID
+	RoleName    string
+	Permissions int64
+}
+
+// AddBotMember adds a bot to a server together with its managed role, placed
+// above the server's existing roles, in one statement.
+func (q *Queries) AddBotMember(ctx context.Context, arg AddBotMemberParams) (Role, error) {
+	row := q.db.QueryRow(ctx,
+		`WITH member AS (
+			INSERT INTO server_members (server_id, user_id, role)
+			VALUES ($1, $2, 'member')
+			RETURNING server_id, user_id
+		), managed AS (
+			INSERT INTO roles (server_id, name, position, permissions, managed_bot_id)
+			SELECT server_id, $3, (SELECT COALESCE(MAX(position), 0) + 1 FROM roles WHERE server_id = $1), $4, user_id
+			FROM member
+			RETURNING id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at
+		), assigned AS (
+			INSERT INTO member_roles (server_id, user_id, role_id)
+			SELECT server_id, managed_bot_id, id FROM managed
+		)
+		SELECT id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at FROM managed`,
+		arg.ServerID, arg.BotID, arg.RoleName, arg.Permissions,
+	)
+	return scanRole(row)
+}
+
+// DeleteBotUser removes the bot's users row; bot_users and everything the
+// bot owns cascade from it.
+func (q *Queries) DeleteBotUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, `DELETE FROM users WHERE id = $1 AND is_bot`, id)
+	return err
 }
 
 // --- Webhook CRUD ---
@@ -140,40 +208,119 @@ type CreateWebhookParams struct {
 	CreatorID uuid.UUID
 }
 
-func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+const webhookColumns = `id, channel_id, name, avatar_url, token_hash, creator_id, interactions_url, interactions_secret, adapter_secret, last_used_at, message_count, created_at`
+
+func scanWebhook(row pgx.Row) (Webhook, error) {
 	var w Webhook
-	err := q.db.QueryRow(ctx,
+	err := row.Scan(&w.ID, &w.ChannelID, &w.Name, &w.AvatarURL, &w.TokenHash, &w.CreatorID,
+		&w.InteractionsURL, &w.InteractionsSecret, &w.AdapterSecret, &w.LastUsedAt, &w.MessageCount, &w.CreatedAt)
+	w.HasAdapterSecret = w.AdapterSecret != ""
+	return w, err
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx,
 		`INSERT INTO webhooks (channel_id, name, token_hash, creator_id)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, channel_id, name, avatar_url, token_hash, creator_id, created_at`,
+		RETURNING `+webhookColumns,
 		arg.ChannelID, arg.Name, arg.Token, arg.CreatorID,
-	).Scan(&w.ID, &w.ChannelID, &w.Name, &w.AvatarURL, &w.TokenHash, &w.CreatorID, &w.CreatedAt)
-	return w, err
+	)
+	return scanWebhook(row)
 }
 
 func (q *Queries) GetWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error) {
-	var w Webhook
-	err := q.db.QueryRow(ctx,
-		`SELECT id, channel_id, name, avatar_url, token_hash, creator_id, created_at
-		FROM webhooks WHERE id = $1`, id,
-	).Scan(&w.ID, &w.ChannelID, &w.Name, &w.AvatarURL, &w.TokenHash, &w.CreatorID, &w.CreatedAt)
-	return w, err
+	row := q.db.QueryRow(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id,
+	)
+	return scanWebhook(row)
+}
+
+// UpdateWebhookInteractionsEndpoint sets (or clears) where component
+// interactions on the webhook's messages are sent.
+func (q *Queries) UpdateWebhookInteractionsEndpoint(ctx context.Context, id uuid.UUID, url, secret string) (Webhook, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE webhooks SET interactions_url = $2, interactions_secret = $3 WHERE id = $1
+		RETURNING `+webhookColumns,
+		id, url, secret,
+	)
+	return scanWebhook(row)
+}
+
+// UpdateWebhookAdapterSecret sets (or clears) the secret provider adapters
+// verify deliveries with.
+func (q *Queries) UpdateWebhookAdapterSecret(ctx context.Context, id uuid.UUID, secret string) (Webhook, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE webhooks SET adapter_secret = $2 WHERE id = $1
+		RETURNING `+webhookColumns,
+		id, secret,
+	)
+	return scanWebhook(row)
+}
+
+type UpdateWebhookParams struct {
+	ID        uuid.UUID
+	ChannelID uuid.UUID
+	Name      string
+	AvatarURL string
+}
+
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE webhooks SET channel_id = $2, name = $3, avatar_url = $4 WHERE id = $1
+		RETURNING `+webhookColumns,
+		arg.ID, arg.ChannelID, arg.Name, arg.AvatarURL,
+	)
+	return scanWebhook(row)
+}
+
+// UpdateWebhookToken replaces a webhook's token hash, invalidating the old token.
+func (q *Queries) UpdateWebhookToken(ctx context.Context, id uuid.UUID, tokenHash string) (Webhook, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE webhooks SET token_hash = $2 WHERE id = $1
+		RETURNING `+webhookColumns,
+		id, tokenHash,
+	)
+	return scanWebhook(row)
+}
+
+// RecordWebhookUse counts a message posted by the webhook.
+func (q *Queries) RecordWebhookUse(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx,
+		`UPDATE webhooks SET last_used_at = NOW(), message_count = message_count + 1 WHERE id = $1`, id,
+	)
+	return err
 }
 
 func (q *Queries) GetWebhooksByChannel(ctx context.Context, channelID uuid.UUID) ([]Webhook, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT id, channel_id, name, avatar_url, token_hash, creator_id, created_at
-		FROM webhooks WHERE channel_id = $1 ORDER BY created_at DESC`, channelID,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE channel_id = $1 ORDER BY created_at DESC`, channelID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectWebhooks(rows)
+}
+
+// GetWebhooksByServer lists the webhooks of every channel in a server.
+func (q *Queries) GetWebhooksByServer(ctx context.Context, serverID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks
+		WHERE channel_id IN (SELECT id FROM channels WHERE server_id = $1)
+		ORDER BY created_at DESC`, serverID,
 	)
 	if err != nil {
 		return nil, err
 	}
+	return collectWebhooks(rows)
+}
+
+func collectWebhooks(rows pgx.Rows) ([]Webhook, error) {
 	defer rows.Close()
 
 	var webhooks []Webhook
 	for rows.Next() {
-		var w Webhook
-		if err := rows.Scan(&w.ID, &w.ChannelID, &w.Name, &w.AvatarURL, &w.TokenHash, &w.CreatorID, &w.CreatedAt); err != nil {
+		w, err := scanWebhook(rows)
+		if err != nil {
 			return nil, err
 		}
 		webhooks = append(webhooks, w)
@@ -191,7 +338,32 @@ func (q *Queries) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
 
 // --- Slash Command CRUD ---
 
-type CreateSlashCommandParams struct {
+const slashCommandColumns = `id, bot_id, name, description, options, server_id, created_at`
+
+func scanSlashCommand(row pgx.Row) (SlashCommand, error) {
+	var sc SlashCommand
+	err := row.Scan(&sc.ID, &sc.BotID, &sc.Name, &sc.Description, &sc.Options, &sc.ServerID, &sc.CreatedAt)
+	return sc, err
+}
+
+func collectSlashCommands(rows pgx.Rows) ([]SlashCommand, error) {
+	defer rows.Close()
+
+	var cmds []SlashCommand
+	for rows.Next() {
+		sc, err := scanSlashCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, sc)
+	}
+	if cmds == nil {
+		cmds = []SlashCommand{}
+	}
+	return cmds, rows.Err()
+}
+
+type UpsertSlashCommandParams struct {
 	BotID       uuid.UUID
 	Name        string
 	Description string
@@ -199,43 +371,69 @@ type CreateSlashCommandParams struct {
 	ServerID    *uuid.UUID
 }
 
-func (q *Queries) CreateSlashCommand(ctx context.Context, arg CreateSlashCommandParams) (SlashCommand, error) {
+// UpsertSlashCommand registers a command. A command with the same name in the
+// same scope (global, or the same server) is replaced in place.
+func (q *Queries) UpsertSlashCommand(ctx context.Context, arg UpsertSlashCommandParams) (SlashCommand, error) {
 	opts := arg.Options
 	if opts == nil {
 		opts = json.RawMessage("[]")
 	}
-	var sc SlashCommand
-	err := q.db.QueryRow(ctx,
+	row := q.db.QueryRow(ctx,
 		`INSERT INTO slash_commands (bot_id, name, description, options, server_id)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, bot_id, name, description, options, server_id, created_at`,
+		ON CONFLICT (bot_id, COALESCE(server_id, '00000000-0000-0000-0000-000000000000'::uuid), name)
+		DO UPDATE SET description = EXCLUDED.description, options = EXCLUDED.options
+		RETURNING `+slashCommandColumns,
 		arg.BotID, arg.Name, arg.Description, opts, arg.ServerID,
-	).Scan(&sc.ID, &sc.BotID, &sc.Name, &sc.Description, &sc.Options, &sc.ServerID, &sc.CreatedAt)
-	return sc, err
+	)
+	return scanSlashCommand(row)
+}
+
+func (q *Queries) GetSlashCommandByID(ctx context.Context, id uuid.UUID) (SlashCommand, error) {
+	row := q.db.QueryRow(ctx,
+		`SELECT `+slashCommandColumns+` FROM slash_commands WHERE id = $1`, id,
+	)
+	return scanSlashCommand(row)
 }
 
 func (q *Queries) GetSlashCommandsByBot(ctx context.Context, botID uuid.UUID) ([]SlashCommand, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT id, bot_id, name, description, options, server_id, created_at
-		FROM slash_commands WHERE bot_id = $1 ORDER BY name`, botID,
+		`SELECT `+slashCommandColumns+` FROM slash_commands WHERE bot_id = $1 ORDER BY name`, botID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return collectSlashCommands(rows)
+}
 
-	var cmds []SlashCommand
-	for rows.Next() {
-		var sc SlashCommand
-		if err := rows.Scan(&sc.ID, &sc.BotID, &sc.Name, &sc.Description, &sc.Options, &sc.ServerID, &sc.CreatedAt); err != nil {
-			return nil, err
-		}
-		cmds = append(cmds, sc)
+// GetSlashCommandsByBotScope returns a bot's global commands (serverID nil)
+// or its commands for one server.
+func (q *Queries) GetSlashCommandsByBotScope(ctx context.Context, botID uuid.UUID, serverID *uuid.UUID) ([]SlashCommand, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+slashCommandColumns+` FROM slash_commands
+		WHERE bot_id = $1 AND server_id IS NOT DISTINCT FROM $2
+		ORDER BY name`, botID, serverID,
+	)
+	if err != nil {
+		return nil, err
 	}
-	if cmds == nil {
-		cmds = []SlashCommand{}
+	return collectSlashCommands(rows)
+}
+
+// GetServerSlashCommands returns the commands usable in a server: global and
+// server-scoped commands of bots that are members of it.
+func (q *Queries) GetServerSlashCommands(ctx context.Context, serverID uuid.UUID) ([]SlashCommand, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT sc.id, sc.bot_id, sc.name, sc.description, sc.options, sc.server_id, sc.created_at
+		FROM slash_commands sc
+		JOIN server_members sm ON sm.user_id = sc.bot_id AND sm.server_id = $1
+		WHERE sc.server_id IS NULL OR sc.server_id = $1
+		ORDER BY sc.name, sc.server_id NULLS LAST`, serverID,
+	)
+	if err != nil {
+		return nil, err
 	}
-	return cmds, rows.Err()
+	return collectSlashCommands(rows)
 }
 
 func (q *Queries) DeleteSlashCommand(ctx context.Context, id uuid.UUID) error {
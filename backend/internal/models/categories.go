@@ -47,6 +47,32 @@ func (q *Queries) GetServerCategories(ctx context.Context, serverID uuid.UUID) (
 	return categories, rows.Err()
 }
 
+// GetUserServersCategories returns the categories of every server the user is a member of.
+func (q *Queries) GetUserServersCategories(ctx context.Context, userID uuid.UUID) ([]ChannelCategory, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT cc.id, cc.server_id, cc.name, cc.position, cc.created_at
+		FROM channel_categories cc JOIN server_members sm ON cc.server_id = sm.server_id
+		WHERE sm.user_id = $1 ORDER BY cc.position, cc.name`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []ChannelCategory
+	for rows.Next() {
+		var cat ChannelCategory
+		if err := rows.Scan(&cat.ID, &cat.ServerID, &cat.Name, &cat.Position, &cat.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, cat)
+	}
+	if categories == nil {
+		categories = []ChannelCategory{}
+	}
+	return categories, rows.Err()
+}
+
 func (q *Queries) UpdateCategory(ctx context.Context, id uuid.UUID, name *string, position *int32) (ChannelCategory, error) {
 	var cat ChannelCategory
 	err := q.db.QueryRow(ctx,
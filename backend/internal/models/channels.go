@@ -61,6 +61,32 @@ func (q *Queries) GetServerChannels(ctx context.Context, serverID uuid.UUID) ([]
 	return channels, rows.Err()
 }
 
+// GetUserServersChannels returns the channels of every server the user is a member of.
+func (q *Queries) GetUserServersChannels(ctx context.Context, userID uuid.UUID) ([]Channel, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT c.id, c.server_id, c.name, c.type, c.position, c.topic, c.category_id, c.slow_mode_interval, c.voice_status, c.is_announcement, c.created_at, c.updated_at
+		FROM channels c JOIN server_members sm ON c.server_id = sm.server_id
+		WHERE sm.user_id = $1 ORDER BY c.position, c.name`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []Channel
+	for rows.Next() {
+		var ch Channel
+		if err := rows.Scan(&ch.ID, &ch.ServerID, &ch.Name, &ch.Type, &ch.Position, &ch.Topic, &ch.CategoryID, &ch.SlowModeInterval, &ch.VoiceStatus, &ch.IsAnnouncement, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+	if channels == nil {
+		channels = []Channel{}
+	}
+	return channels, rows.Err()
+}
+
 type UpdateChannelParams struct {
 	ID               uuid.UUID
 	Name             *string
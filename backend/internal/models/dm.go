@@ -98,8 +98,12 @@ type CreateDMMessageParams struct {
 	Content        string
 	Type           string
 	ReplyToID      *uuid.UUID
+	Nonce          *string
 }
 
+// CreateDMMessage inserts a DM. If the author already has a message in the
+// conversation with the same nonce, nothing is inserted and pgx.ErrNoRows is
+// returned.
 func (q *Queries) CreateDMMessage(ctx context.Context, arg CreateDMMessageParams) (DMMessage, error) {
 	msgType := arg.Type
 	if msgType == "" {
@@ -107,21 +111,40 @@ func (q *Queries) CreateDMMessage(ctx context.Context, arg CreateDMMessageParams
 	}
 	var m DMMessage
 	err := q.db.QueryRow(ctx,
-		`INSERT INTO dm_messages (conversation_id, author_id, content, type, reply_to_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, conversation_id, author_id, content, type, reply_to_id, created_at, updated_at`,
-		arg.ConversationID, arg.AuthorID, arg.Content, msgType, arg.ReplyToID,
-	).Scan(&m.ID, &m.ConversationID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+		`INSERT INTO dm_messages (conversation_id, author_id, content, type, reply_to_id, nonce)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (conversation_id, author_id, nonce) WHERE nonce IS NOT NULL DO NOTHING
+		RETURNING id, conversation_id, author_id, content, type, reply_to_id, nonce, created_at, updated_at`,
+		arg.ConversationID, arg.AuthorID, arg.Content, msgType, arg.ReplyToID, arg.Nonce,
+	).Scan(&m.ID, &m.ConversationID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// GetDMMessageByNonce returns the author's message in the conversation sent
+// with nonce since the given time. Older messages give up the nonce so that
+// it can be sent again.
+func (q *Queries) GetDMMessageByNonce(ctx context.Context, conversationID, authorID uuid.UUID, nonce string, since time.Time) (DMMessage, error) {
+	var m DMMessage
+	err := q.db.QueryRow(ctx,
+		`WITH released AS (
+			UPDATE dm_messages SET nonce = NULL
+			WHERE conversation_id = $1 AND author_id = $2 AND nonce = $3 AND created_at < $4
+		)
+		SELECT id, conversation_id, author_id, content, type, reply_to_id, nonce, created_at, updated_at
+		FROM dm_messages WHERE conversation_id = $1 AND author_id = $2 AND nonce = $3 AND created_at >= $4`,
+		conversationID, authorID, nonce, since,
+	).Scan(&m.ID, &m.ConversationID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
 type GetDMMessagesParams struct {
 	ConversationID uuid.UUID
-	Before         *time.Time
+	Before         *MessageBound
 	Limit          int32
 }
 
 func (q *Queries) GetDMMessages(ctx context.Context, arg GetDMMessagesParams) ([]DMMessageWithAuthor, error) {
+	beforeTime, beforeID := boundArgs(arg.Before)
 	rows, err := q.db.Query(ctx,
 		`SELECT dm.id, dm.conversation_id, dm.author_id, dm.content, dm.type, dm.reply_to_id, dm.created_at, dm.updated_at,
 		        u.username, u.display_name, u.avatar_url,
@@ -129,9 +152,9 @@ func (q *Queries) GetDMMessages(ctx context.Context, arg GetDMMessagesParams) ([
 		FROM dm_messages dm JOIN users u ON dm.author_id = u.id
 		LEFT JOIN dm_messages r ON dm.reply_to_id = r.id
 		LEFT JOIN users ru ON r.author_id = ru.id
-		WHERE dm.conversation_id = $1 AND ($2::timestamptz IS NULL OR dm.created_at < $2)
-		ORDER BY dm.created_at DESC LIMIT $3`,
-		arg.ConversationID, arg.Before, arg.Limit,
+		WHERE dm.conversation_id = $1 AND ($2::timestamptz IS NULL OR (dm.created_at, dm.id) < ($2, $3::uuid))
+		ORDER BY dm.created_at DESC, dm.id DESC LIMIT $4`,
+		arg.ConversationID, beforeTime, beforeID, arg.Limit,
 	)
 	if err != nil {
 		return nil, err
@@ -172,7 +195,7 @@ func (q *Queries) GetDMMessages(ctx context.Context, arg GetDMMessagesParams) ([
 
 type GetDMMessagesAfterParams struct {
 	ConversationID uuid.UUID
-	After          time.Time
+	After          MessageBound
 	Limit          int32
 }
 
@@ -184,9 +207,9 @@ func (q *Queries) GetDMMessagesAfter(ctx context.Context, arg GetDMMessagesAfter
 		FROM dm_messages dm JOIN users u ON dm.author_id = u.id
 		LEFT JOIN dm_messages r ON dm.reply_to_id = r.id
 		LEFT JOIN users ru ON r.author_id = ru.id
-		WHERE dm.conversation_id = $1 AND dm.created_at > $2
-		ORDER BY dm.created_at ASC LIMIT $3`,
-		arg.ConversationID, arg.After, arg.Limit,
+		WHERE dm.conversation_id = $1 AND (dm.created_at, dm.id) > ($2, $3)
+		ORDER BY dm.created_at ASC, dm.id ASC LIMIT $4`,
+		arg.ConversationID, arg.After.CreatedAt, arg.After.ID, arg.Limit,
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,293 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// EventSubscription is an external endpoint subscribed to a server's events.
+type EventSubscription struct {
+	ID                  uuid.UUID `json:"id"`
+	ServerID            uuid.UUID `json:"server_id"`
+	CreatorID           uuid.UUID `json:"creator_id"`
+	URL                 string    `json:"url"`
+	Secret              string    `json:"-"`
+	EventTypes          []string  `json:"event_types"`
+	Enabled             bool      `json:"enabled"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledReason      string    `json:"disabled_reason"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// EventDelivery is one event queued for, or delivered to, a subscription.
+type EventDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      string          `json:"last_error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+const eventSubscriptionColumns = `id, server_id, creator_id, url, secret, event_types, enabled, consecutive_failures, disabled_reason, created_at, updated_at`
+
+func scanEventSubscription(row pgx.Row) (EventSubscription, error) {
+	var s EventSubscription
+	err := row.Scan(&s.ID, &s.ServerID, &s.CreatorID, &s.URL, &s.Secret, &s.EventTypes, &s.Enabled,
+		&s.ConsecutiveFailures, &s.DisabledReason, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+func collectEventSubscriptions(rows pgx.Rows) ([]EventSubscription, error) {
+	defer rows.Close()
+	subs := []EventSubscription{}
+	for rows.Next() {
+		s, err := scanEventSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+const eventDeliveryColumns = `id, subscription_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at`
+
+func scanEventDelivery(row pgx.Row) (EventDelivery, error) {
+	var d EventDelivery
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+	return d, err
+}
+
+func collectEventDeliveries(rows pgx.Rows) ([]EventDelivery, error) {
+	defer rows.Close()
+	deliveries := []EventDelivery{}
+	for rows.Next() {
+		d, err := scanEventDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+type CreateEventSubscriptionParams struct {
+	ServerID   uuid.UUID
+	CreatorID  uuid.UUID
+	URL        string
+	Secret     string
+	EventTypes []string
+}
+
+func (q *Queries) CreateEventSubscription(ctx context.Context, arg CreateEventSubscriptionParams) (EventSubscription, error) {
+	row := q.db.QueryRow(ctx,
+		`INSERT INTO event_subscriptions (server_id, creator_id, url, secret, event_types)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+eventSubscriptionColumns,
+		arg.ServerID, arg.CreatorID, arg.URL, arg.Secret, arg.EventTypes,
+	)
+	return scanEventSubscription(row)
+}
+
+func (q *Queries) GetEventSubscriptionByID(ctx context.Context, id uuid.UUID) (EventSubscription, error) {
+	row := q.db.QueryRow(ctx,
+		`SELECT `+eventSubscriptionColumns+` FROM event_subscriptions WHERE id = $1`, id,
+	)
+	return scanEventSubscription(row)
+}
+
+func (q *Queries) GetEventSubscriptionsByServer(ctx context.Context, serverID uuid.UUID) ([]EventSubscription, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+eventSubscriptionColumns+` FROM event_subscriptions WHERE server_id = $1 ORDER BY created_at`, serverID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEventSubscriptions(rows)
+}
+
+// GetEnabledEventSubscriptions returns the server's enabled subscriptions
+// that include the event type.
+func (q *Queries) GetEnabledEventSubscriptions(ctx context.Context, serverID uuid.UUID, eventType string) ([]EventSubscription, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+eventSubscriptionColumns+` FROM event_subscriptions
+		WHERE server_id = $1 AND enabled AND $2 = ANY(event_types)`,
+		serverID, eventType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEventSubscriptions(rows)
+}
+
+// GetEnabledEventSubscriptionsByChannel is GetEnabledEventSubscriptions for
+// the server that owns the channel.
+func (q *Queries) GetEnabledEventSubscriptionsByChannel(ctx context.Context, channelID uuid.UUID, eventType string) ([]EventSubscription, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+eventSubscriptionColumns+` FROM event_subscriptions
+		WHERE server_id = (SELECT server_id FROM channels WHERE id = $1)
+			AND enabled AND $2 = ANY(event_types)`,
+		channelID, eventType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEventSubscriptions(rows)
+}
+
+type UpdateEventSubscriptionParams struct {
+	ID         uuid.UUID
+	URL        string
+	EventTypes []string
+	Enabled    bool
+}
+
+// UpdateEventSubscription replaces a subscription's settings. Re-enabling a
+// subscription clears its failure count and disabled reason.
+func (q *Queries) UpdateEventSubscription(ctx context.Context, arg UpdateEventSubscriptionParams) (EventSubscription, error) {
+	row := q.db.QueryRow(ctx,
+		`UPDATE event_subscriptions SET url = $2, event_types = $3, enabled = $4,
+			consecutive_failures = CASE WHEN $4 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			disabled_reason = CASE WHEN $4 THEN '' ELSE disabled_reason END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+eventSubscriptionColumns,
+		arg.ID, arg.URL, arg.EventTypes, arg.Enabled,
+	)
+	return scanEventSubscription(row)
+}
+
+func (q *Queries) DeleteEventSubscription(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, `DELETE FROM event_subscriptions WHERE id = $1`, id)
+	return err
+}
+
+func (q *Queries) CreateEventDelivery(ctx context.Context, subscriptionID uuid.UUID, eventType string, payload json.RawMessage) (EventDelivery, error) {
+	row := q.db.QueryRow(ctx,
+		`INSERT INTO event_deliveries (subscription_id, event_type, payload)
+		VALUES ($1, $2, $3)
+		RETURNING `+eventDeliveryColumns,
+		subscriptionID, eventType, payload,
+	)
+	return scanEventDelivery(row)
+}
+
+// ClaimDueEventDeliveries picks pending deliveries that are due, for enabled
+// subscriptions, and pushes their next attempt out to leaseUntil so another
+// worker does not pick them up while they are in flight. A worker that dies
+// mid-delivery leaves them to be retried once the lease passes.
+func (q *Queries) ClaimDueEventDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]EventDelivery, error) {
+	rows, err := q.db.Query(ctx,
+		`UPDATE event_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT d.id FROM event_deliveries d
+			JOIN event_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND s.enabled
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING `+eventDeliveryColumns,
+		limit, leaseUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEventDeliveries(rows)
+}
+
+// RecordEventDeliverySuccess marks a delivery as delivered and resets the
+// subscription's consecutive failure count.
+func (q *Queries) RecordEventDeliverySuccess(ctx context.Context, id uuid.UUID, responseStatus int) error {
+	_, err := q.db.Exec(ctx,
+		`WITH d AS (
+			UPDATE event_deliveries SET status = 'succeeded', attempts = attempts + 1,
+				response_status = $2, last_error = '', delivered_at = NOW()
+			WHERE id = $1
+			RETURNING subscription_id
+		)
+		UPDATE event_subscriptions SET consecutive_failures = 0
+		WHERE id IN (SELECT subscription_id FROM d)`,
+		id, responseStatus,
+	)
+	return err
+}
+
+// RecordEventDeliveryFailure records a failed attempt. The delivery is
+// retried at nextAttemptAt, or marked failed when that is nil. The
+// subscription is disabled once it reaches disableAfter consecutive failed
+// attempts; the returned flag reports whether this attempt disabled it.
+func (q *Queries) RecordEventDeliveryFailure(ctx context.Context, id uuid.UUID, responseStatus *int, lastError string, nextAttemptAt *time.Time, disableAfter int) (bool, error) {
+	var disabled bool
+	err := q.db.QueryRow(ctx,
+		`WITH d AS (
+			UPDATE event_deliveries SET attempts = attempts + 1, response_status = $2, last_error = $3,
+				status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+				next_attempt_at = COALESCE($4, next_attempt_at)
+			WHERE id = $1
+			RETURNING subscription_id
+		)
+		UPDATE event_subscriptions s SET consecutive_failures = s.consecutive_failures + 1,
+			enabled = s.enabled AND s.consecutive_failures + 1 < $5,
+			disabled_reason = CASE WHEN s.enabled AND s.consecutive_failures + 1 >= $5
+				THEN 'disabled after repeated delivery failures' ELSE s.disabled_reason END,
+			updated_at = NOW()
+		FROM d WHERE s.id = d.subscription_id
+		RETURNING NOT s.enabled AND s.consecutive_failures = $5`,
+		id, responseStatus, lastError, nextAttemptAt, disableAfter,
+	).Scan(&disabled)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return disabled, err
+}
+
+// FailPendingEventDeliveries gives up on a subscription's queued deliveries,
+// as when it is disabled.
+func (q *Queries) FailPendingEventDeliveries(ctx context.Context, subscriptionID uuid.UUID, reason string) error {
+	_, err := q.db.Exec(ctx,
+		`UPDATE event_deliveries SET status = 'failed', last_error = $2
+		WHERE subscription_id = $1 AND status = 'pending'`,
+		subscriptionID, reason,
+	)
+	return err
+}
+
+// GetEventDeliveries returns a subscription's most recent deliveries.
+func (q *Queries) GetEventDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]EventDelivery, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT `+eventDeliveryColumns+` FROM event_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		subscriptionID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectEventDeliveries(rows)
+}
+
+// DeleteOldEventDeliveries trims the delivery log of finished deliveries
+// created before the cutoff.
+func (q *Queries) DeleteOldEventDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx,
+		`DELETE FROM event_deliveries WHERE created_at < $1 AND status <> 'pending'`, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // ForumPost represents a forum thread/post within a forum channel.
@@ -48,6 +49,7 @@ type ForumPostMessage struct {
 	PostID    uuid.UUID `json:"post_id"`
 	AuthorID  uuid.UUID `json:"author_id"`
 	Content   string    `json:"content"`
+	Nonce     *string   `json:"nonce,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -322,16 +324,38 @@ type CreateForumPostMessageParams struct {
 	PostID   uuid.UUID
 	AuthorID uuid.UUID
 	Content  string
+	Nonce    *string
 }
 
+// CreateForumPostMessage inserts a reply to a post. If the author already
+// has a reply on the post with the same nonce, nothing is inserted and
+// pgx.ErrNoRows is returned.
 func (q *Queries) CreateForumPostMessage(ctx context.Context, arg CreateForumPostMessageParams) (ForumPostMessage, error) {
 	var m ForumPostMessage
 	err := q.db.QueryRow(ctx,
-		`INSERT INTO forum_post_messages (post_id, author_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, post_id, author_id, content, created_at, updated_at`,
-		arg.PostID, arg.AuthorID, arg.Content,
-	).Scan(&m.ID, &m.PostID, &m.AuthorID, &m.Content, &m.CreatedAt, &m.UpdatedAt)
+		`INSERT INTO forum_post_messages (post_id, author_id, content, nonce)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (post_id, author_id, nonce) WHERE nonce IS NOT NULL DO NOTHING
+		RETURNING id, post_id, author_id, content, nonce, created_at, updated_at`,
+		arg.PostID, arg.AuthorID, arg.Content, arg.Nonce,
+	).Scan(&m.ID, &m.PostID, &m.AuthorID, &m.Content, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// GetForumPostMessageByNonce returns the author's reply on the post sent
+// with nonce since the given time. Older replies give up the nonce so that
+// it can be sent again.
+func (q *Queries) GetForumPostMessageByNonce(ctx context.Context, postID, authorID uuid.UUID, nonce string, since time.Time) (ForumPostMessage, error) {
+	var m ForumPostMessage
+	err := q.db.QueryRow(ctx,
+		`WITH released AS (
+			UPDATE forum_post_messages SET nonce = NULL
+			WHERE post_id = $1 AND author_id = $2 AND nonce = $3 AND created_at < $4
+		)
+		SELECT id, post_id, author_id, content, nonce, created_at, updated_at
+		FROM forum_post_messages WHERE post_id = $1 AND author_id = $2 AND nonce = $3 AND created_at >= $4`,
+		postID, authorID, nonce, since,
+	).Scan(&m.ID, &m.PostID, &m.AuthorID, &m.Content, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
@@ -342,13 +366,56 @@ func (q *Queries) GetForumPostMessages(ctx context.Context, postID uuid.UUID, li
 		FROM forum_post_messages m
 		JOIN users u ON m.author_id = u.id
 		WHERE m.post_id = $1
-		ORDER BY m.created_at ASC
+		ORDER BY m.created_at ASC, m.id ASC
 		LIMIT $2 OFFSET $3`,
 		postID, limit, offset,
 	)
 	if err != nil {
 		return nil, err
 	}
+	return scanForumPostMessages(rows)
+}
+
+// GetForumPostMessagesBefore returns a post's messages before an optional
+// bound, newest first.
+func (q *Queries) GetForumPostMessagesBefore(ctx context.Context, postID uuid.UUID, before *MessageBound, limit int) ([]ForumPostMessageWithAuthor, error) {
+	beforeTime, beforeID := boundArgs(before)
+	rows, err := q.db.Query(ctx,
+		`SELECT m.id, m.post_id, m.author_id, m.content, m.created_at, m.updated_at,
+		        u.username, u.display_name, u.avatar_url
+		FROM forum_post_messages m
+		JOIN users u ON m.author_id = u.id
+		WHERE m.post_id = $1 AND ($2::timestamptz IS NULL OR (m.created_at, m.id) < ($2, $3::uuid))
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $4`,
+		postID, beforeTime, beforeID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanForumPostMessages(rows)
+}
+
+// GetForumPostMessagesAfter returns a post's messages after a bound, oldest
+// first.
+func (q *Queries) GetForumPostMessagesAfter(ctx context.Context, postID uuid.UUID, after MessageBound, limit int) ([]ForumPostMessageWithAuthor, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT m.id, m.post_id, m.author_id, m.content, m.created_at, m.updated_at,
+		        u.username, u.display_name, u.avatar_url
+		FROM forum_post_messages m
+		JOIN users u ON m.author_id = u.id
+		WHERE m.post_id = $1 AND (m.created_at, m.id) > ($2, $3)
+		ORDER BY m.created_at ASC, m.id ASC
+		LIMIT $4`,
+		postID, after.CreatedAt, after.ID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanForumPostMessages(rows)
+}
+
+func scanForumPostMessages(rows pgx.Rows) ([]ForumPostMessageWithAuthor, error) {
 	defer rows.Close()
 
 	var messages []ForumPostMessageWithAuthor
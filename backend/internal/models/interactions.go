@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Interaction is a user's invocation of a bot command or click on a message
+// component. The bot or webhook application answers it with the token it was
+// delivered with.
+type Interaction struct {
+	ID                uuid.UUID       `json:"id"`
+	Type              string          `json:"type"`
+	BotID             *uuid.UUID      `json:"bot_id"`
+	WebhookID         *uuid.UUID      `json:"webhook_id"`
+	CommandID         *uuid.UUID      `json:"command_id"`
+	MessageID         *uuid.UUID      `json:"message_id"`
+	ServerID          uuid.UUID       `json:"server_id"`
+	ChannelID         uuid.UUID       `json:"channel_id"`
+	UserID            uuid.UUID       `json:"user_id"`
+	Data              json.RawMessage `json:"data"`
+	TokenHash         string          `json:"-"`
+	Status            string          `json:"status"`
+	ResponseMessageID *uuid.UUID      `json:"response_message_id"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+const interactionColumns = `id, type, bot_id, webhook_id, command_id, message_id, server_id, channel_id, user_id, data, token_hash, status, response_message_id, created_at`
+
+func scanInteraction(row pgx.Row) (Interaction, error) {
+	var i Interaction
+	err := row.Scan(&i.ID, &i.Type, &i.BotID, &i.WebhookID, &i.CommandID, &i.MessageID, &i.ServerID, &i.ChannelID, &i.UserID,
+		&i.Data, &i.TokenHash, &i.Status, &i.ResponseMessageID, &i.CreatedAt)
+	return i, err
+}
+
+type CreateInteractionParams struct {
+	Type      string
+	BotID     *uuid.UUID
+	WebhookID *uuid.UUID
+	CommandID *uuid.UUID
+	MessageID *uuid.UUID
+	ServerID  uuid.UUID
+	ChannelID uuid.UUID
+	UserID    uuid.UUID
+	Data      json.RawMessage
+	TokenHash string
+}
+
+func (q *Queries) CreateInteraction(ctx context.Context, arg CreateInteractionParams) (Interaction, error) {
+	row := q.db.QueryRow(ctx,
+		`INSERT INTO interactions (type, bot_id, webhook_id, command_id, message_id, server_id, channel_id, user_id, data, token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+interactionColumns,
+		arg.Type, arg.BotID, arg.WebhookID, arg.CommandID, arg.MessageID, arg.ServerID, arg.ChannelID, arg.UserID, arg.Data, arg.TokenHash,
+	)
+	return scanInteraction(row)
+}
+
+func (q *Queries) GetInteractionByID(ctx context.Context, id uuid.UUID) (Interaction, error) {
+	row := q.db.QueryRow(ctx,
+		`SELECT `+interactionColumns+` FROM interactions WHERE id = $1`, id,
+	)
+	return scanInteraction(row)
+}
+
+// TransitionInteraction moves an interaction from one status to another and
+// reports whether it was still in the expected status, so two responses
+// racing for the same interaction cannot both win.
+func (q *Queries) TransitionInteraction(ctx context.Context, id uuid.UUID, from, to string, responseMessageID *uuid.UUID) (bool, error) {
+	tag, err := q.db.Exec(ctx,
+		`UPDATE interactions SET status = $3, response_message_id = COALESCE($4, response_message_id)
+		WHERE id = $1 AND status = $2`,
+		id, from, to, responseMessageID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteExpiredInteractions removes interactions older than the cutoff.
+func (q *Queries) DeleteExpiredInteractions(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, `DELETE FROM interactions WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -2,19 +2,31 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type CreateMessageParams struct {
-	ChannelID uuid.UUID
-	AuthorID  uuid.UUID
-	Content   string
-	Type      string
-	ReplyToID *uuid.UUID
+	ChannelID  uuid.UUID
+	AuthorID   uuid.UUID
+	Content    string
+	Type       string
+	ReplyToID  *uuid.UUID
+	Components json.RawMessage
+	Embeds     json.RawMessage
+	WebhookID  *uuid.UUID
+	// Name and avatar shown on webhook messages
+	WebhookUsername  *string
+	WebhookAvatarURL *string
+	Nonce            *string
 }
 
+// CreateMessage inserts a message. If the author already has a message in
+// the channel with the same nonce, nothing is inserted and pgx.ErrNoRows is
+// returned.
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
 	msgType := arg.Type
 	if msgType == "" {
@@ -22,41 +34,114 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 	}
 	var m Message
 	err := q.db.QueryRow(ctx,
-		`INSERT INTO messages (channel_id, author_id, content, type, reply_to_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, channel_id, author_id, content, type, reply_to_id, created_at, updated_at`,
-		arg.ChannelID, arg.AuthorID, arg.Content, msgType, arg.ReplyToID,
-	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+		`INSERT INTO messages (channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, nonce)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (channel_id, author_id, nonce) WHERE nonce IS NOT NULL DO NOTHING
+		RETURNING id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, nonce, created_at, updated_at`,
+		arg.ChannelID, arg.AuthorID, arg.Content, msgType, arg.ReplyToID, arg.Components, arg.Embeds, arg.WebhookID,
+		arg.WebhookUsername, arg.WebhookAvatarURL, arg.Nonce,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// GetMessageByNonce returns the author's message in the channel sent with
+// nonce since the given time. Older messages give up the nonce so that it
+// can be sent again.
+func (q *Queries) GetMessageByNonce(ctx context.Context, channelID, authorID uuid.UUID, nonce string, since time.Time) (Message, error) {
+	var m Message
+	err := q.db.QueryRow(ctx,
+		`WITH released AS (
+			UPDATE messages SET nonce = NULL
+			WHERE channel_id = $1 AND author_id = $2 AND nonce = $3 AND created_at < $4
+		)
+		SELECT id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, nonce, created_at, updated_at
+		FROM messages WHERE channel_id = $1 AND author_id = $2 AND nonce = $3 AND created_at >= $4`,
+		channelID, authorID, nonce, since,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
+// ReleaseExpiredNonces clears send nonces from messages of every kind sent
+// before the given time, keeping the nonce indexes small.
+func (q *Queries) ReleaseExpiredNonces(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"messages", "dm_messages", "thread_messages", "forum_post_messages"} {
+		tag, err := q.db.Exec(ctx,
+			`UPDATE `+table+` SET nonce = NULL WHERE nonce IS NOT NULL AND created_at < $1`, before,
+		)
+		if err != nil {
+			return total, err
+		}
+		total += tag.RowsAffected()
+	}
+	return total, nil
+}
+
+// GetMessageByID returns a message unless it has been deleted.
 func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error) {
 	var m Message
 	err := q.db.QueryRow(ctx,
-		`SELECT id, channel_id, author_id, content, type, reply_to_id, created_at, updated_at
-		FROM messages WHERE id = $1`, id,
-	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+		`SELECT id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at
+		FROM messages WHERE id = $1 AND deleted_at IS NULL`, id,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
+// MessageBound is an exclusive position in message history. History is
+// ordered by (created_at, id), so paging from a message's bound neither
+// skips nor repeats messages that share its timestamp.
+type MessageBound struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// BoundBeforeTime excludes every message at or after t, as paging back from
+// a bare timestamp did before messages could be used as cursors.
+func BoundBeforeTime(t time.Time) MessageBound {
+	return MessageBound{CreatedAt: t, ID: uuid.Nil}
+}
+
+// BoundAfterTime excludes every message at or before t.
+func BoundAfterTime(t time.Time) MessageBound {
+	return MessageBound{CreatedAt: t, ID: uuid.Max}
+}
+
+// boundArgs splits an optional bound into nullable query arguments.
+func boundArgs(b *MessageBound) (*time.Time, *uuid.UUID) {
+	if b == nil {
+		return nil, nil
+	}
+	return &b.CreatedAt, &b.ID
+}
+
+// GetChannelMessagesParams selects a page of history. Deleted messages are
+// left out unless IncludeDeleted is set, in which case they come back as
+// tombstones: DeletedAt set and content, components and embeds blanked.
 type GetChannelMessagesParams struct {
-	ChannelID uuid.UUID
-	Before    *time.Time
-	Limit     int32
+	ChannelID      uuid.UUID
+	Before         *MessageBound
+	Limit          int32
+	IncludeDeleted bool
 }
 
 func (q *Queries) GetChannelMessages(ctx context.Context, arg GetChannelMessagesParams) ([]MessageWithAuthor, error) {
+	beforeTime, beforeID := boundArgs(arg.Before)
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id,
+		        CASE WHEN m.deleted_at IS NULL THEN m.content ELSE '' END, m.type, m.reply_to_id,
+		        CASE WHEN m.deleted_at IS NULL THEN m.components END,
+		        CASE WHEN m.deleted_at IS NULL THEN m.embeds END,
+		        m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.deleted_at, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url,
 		        rm.id, rm.author_id, ru.username, rm.content
 		FROM messages m
 		JOIN users u ON m.author_id = u.id
-		LEFT JOIN messages rm ON m.reply_to_id = rm.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
 		LEFT JOIN users ru ON rm.author_id = ru.id
-		WHERE m.channel_id = $1 AND ($2::timestamptz IS NULL OR m.created_at < $2)
-		ORDER BY m.created_at DESC LIMIT $3`,
-		arg.ChannelID, arg.Before, arg.Limit,
+		WHERE m.channel_id = $1 AND ($2::timestamptz IS NULL OR (m.created_at, m.id) < ($2, $3::uuid))
+		  AND ($5 OR m.deleted_at IS NULL)
+		ORDER BY m.created_at DESC, m.id DESC LIMIT $4`,
+		arg.ChannelID, beforeTime, beforeID, arg.Limit, arg.IncludeDeleted,
 	)
 	if err != nil {
 		return nil, err
@@ -69,7 +154,7 @@ func (q *Queries) GetChannelMessages(ctx context.Context, arg GetChannelMessages
 		var replyID, replyAuthorID *uuid.UUID
 		var replyUsername, replyContent *string
 		if err := rows.Scan(
-			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt,
+			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.DeletedAt, &m.CreatedAt, &m.UpdatedAt,
 			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
 			&replyID, &replyAuthorID, &replyUsername, &replyContent,
 		); err != nil {
@@ -94,23 +179,29 @@ func (q *Queries) GetChannelMessages(ctx context.Context, arg GetChannelMessages
 }
 
 type GetChannelMessagesAfterParams struct {
-	ChannelID uuid.UUID
-	After     time.Time
-	Limit     int32
+	ChannelID      uuid.UUID
+	After          MessageBound
+	Limit          int32
+	IncludeDeleted bool
 }
 
 func (q *Queries) GetChannelMessagesAfter(ctx context.Context, arg GetChannelMessagesAfterParams) ([]MessageWithAuthor, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id,
+		        CASE WHEN m.deleted_at IS NULL THEN m.content ELSE '' END, m.type, m.reply_to_id,
+		        CASE WHEN m.deleted_at IS NULL THEN m.components END,
+		        CASE WHEN m.deleted_at IS NULL THEN m.embeds END,
+		        m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.deleted_at, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url,
 		        rm.id, rm.author_id, ru.username, rm.content
 		FROM messages m
 		JOIN users u ON m.author_id = u.id
-		LEFT JOIN messages rm ON m.reply_to_id = rm.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
 		LEFT JOIN users ru ON rm.author_id = ru.id
-		WHERE m.channel_id = $1 AND m.created_at > $2
-		ORDER BY m.created_at ASC LIMIT $3`,
-		arg.ChannelID, arg.After, arg.Limit,
+		WHERE m.channel_id = $1 AND (m.created_at, m.id) > ($2, $3)
+		  AND ($5 OR m.deleted_at IS NULL)
+		ORDER BY m.created_at ASC, m.id ASC LIMIT $4`,
+		arg.ChannelID, arg.After.CreatedAt, arg.After.ID, arg.Limit, arg.IncludeDeleted,
 	)
 	if err != nil {
 		return nil, err
@@ -123,7 +214,7 @@ func (q *Queries) GetChannelMessagesAfter(ctx context.Context, arg GetChannelMes
 		var replyID, replyAuthorID *uuid.UUID
 		var replyUsername, replyContent *string
 		if err := rows.Scan(
-			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt,
+			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.DeletedAt, &m.CreatedAt, &m.UpdatedAt,
 			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
 			&replyID, &replyAuthorID, &replyUsername, &replyContent,
 		); err != nil {
@@ -151,18 +242,240 @@ func (q *Queries) UpdateMessage(ctx context.Context, id uuid.UUID, content strin
 	var m Message
 	err := q.db.QueryRow(ctx,
 		`UPDATE messages SET content = $2, updated_at = NOW()
-		WHERE id = $1
-		RETURNING id, channel_id, author_id, content, type, reply_to_id, created_at, updated_at`,
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at`,
 		id, content,
-	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// UpdateMessageComponents replaces a message's content and components, as
+// when a bot updates the message a component was clicked on.
+func (q *Queries) UpdateMessageComponents(ctx context.Context, id uuid.UUID, content string, components json.RawMessage) (Message, error) {
+	var m Message
+	err := q.db.QueryRow(ctx,
+		`UPDATE messages SET content = $2, components = $3, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at`,
+		id, content, components,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// UpdateWebhookMessageParams replaces what a webhook can change on one of
+// its messages.
+type UpdateWebhookMessageParams struct {
+	ID         uuid.UUID
+	Content    string
+	Embeds     json.RawMessage
+	Components json.RawMessage
+}
+
+// UpdateWebhookMessage replaces a webhook message's content, embeds and
+// components.
+func (q *Queries) UpdateWebhookMessage(ctx context.Context, arg UpdateWebhookMessageParams) (Message, error) {
+	var m Message
+	err := q.db.QueryRow(ctx,
+		`UPDATE messages SET content = $2, embeds = $3, components = $4, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at`,
+		arg.ID, arg.Content, arg.Embeds, arg.Components,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
-func (q *Queries) DeleteMessage(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, `DELETE FROM messages WHERE id = $1`, id)
+// DeleteMessage soft-deletes a message, recording who deleted it. deletedBy
+// is nil when no user did, as for webhook deletes. The send nonce is released
+// so that a retried send is not answered with the deleted message.
+func (q *Queries) DeleteMessage(ctx context.Context, id uuid.UUID, deletedBy *uuid.UUID) error {
+	_, err := q.db.Exec(ctx,
+		`UPDATE messages SET deleted_at = NOW(), deleted_by = $2, nonce = NULL
+		WHERE id = $1 AND deleted_at IS NULL`,
+		id, deletedBy,
+	)
 	return err
 }
 
+// DeleteChannelMessages soft-deletes those of the given messages that are
+// in the channel and returns the IDs it deleted.
+func (q *Queries) DeleteChannelMessages(ctx context.Context, channelID uuid.UUID, ids []uuid.UUID, deletedBy uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx,
+		`UPDATE messages SET deleted_at = NOW(), deleted_by = $3, nonce = NULL
+		WHERE channel_id = $1 AND id = ANY($2) AND deleted_at IS NULL
+		RETURNING id`,
+		channelID, ids, deletedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessageIDs(rows)
+}
+
+// PurgeChannelMessagesParams selects the messages a purge deletes. Nil and
+// empty fields match everything; Contains is a LIKE pattern fragment whose
+// wildcards the caller has escaped.
+type PurgeChannelMessagesParams struct {
+	ChannelID uuid.UUID
+	DeletedBy uuid.UUID
+	AuthorID  *uuid.UUID
+	After     *time.Time
+	Before    *time.Time
+	Contains  string
+	Limit     int32
+}
+
+// PurgeChannelMessages soft-deletes up to Limit of the newest matching
+// messages and returns the IDs it deleted.
+func (q *Queries) PurgeChannelMessages(ctx context.Context, arg PurgeChannelMessagesParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx,
+		`UPDATE messages SET deleted_at = NOW(), deleted_by = $7, nonce = NULL
+		WHERE id IN (
+			SELECT id FROM messages
+			WHERE channel_id = $1 AND deleted_at IS NULL
+			  AND ($2::uuid IS NULL OR author_id = $2)
+			  AND ($3::timestamptz IS NULL OR created_at >= $3)
+			  AND ($4::timestamptz IS NULL OR created_at < $4)
+			  AND ($5 = '' OR content ILIKE '%' || $5 || '%')
+			ORDER BY created_at DESC, id DESC
+			LIMIT $6
+		)
+		RETURNING id`,
+		arg.ChannelID, arg.AuthorID, arg.After, arg.Before, arg.Contains, arg.Limit, arg.DeletedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessageIDs(rows)
+}
+
+// GetDeletedMessageByID returns a soft-deleted message deleted since the
+// given time.
+func (q *Queries) GetDeletedMessageByID(ctx context.Context, id uuid.UUID, since time.Time) (Message, error) {
+	var m Message
+	err := q.db.QueryRow(ctx,
+		`SELECT id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, deleted_at, deleted_by, created_at, updated_at
+		FROM messages WHERE id = $1 AND deleted_at >= $2`, id, since,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.DeletedAt, &m.DeletedBy, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// RestoreMessage undoes a soft delete. pgx.ErrNoRows is returned if the
+// message is not deleted.
+func (q *Queries) RestoreMessage(ctx context.Context, id uuid.UUID) (Message, error) {
+	var m Message
+	err := q.db.QueryRow(ctx,
+		`UPDATE messages SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, channel_id, author_id, content, type, reply_to_id, components, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at`,
+		id,
+	).Scan(&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// GetMessageWithAuthor returns a message as history shows it, with its
+// author and reply snippet. Deleted messages are not returned.
+func (q *Queries) GetMessageWithAuthor(ctx context.Context, id uuid.UUID) (MessageWithAuthor, error) {
+	var m MessageWithAuthor
+	var replyID, replyAuthorID *uuid.UUID
+	var replyUsername, replyContent *string
+	err := q.db.QueryRow(ctx,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.created_at, m.updated_at,
+		        u.username, u.display_name, u.avatar_url,
+		        rm.id, rm.author_id, ru.username, rm.content
+		FROM messages m
+		JOIN users u ON m.author_id = u.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
+		LEFT JOIN users ru ON rm.author_id = ru.id
+		WHERE m.id = $1 AND m.deleted_at IS NULL`, id,
+	).Scan(
+		&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt,
+		&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
+		&replyID, &replyAuthorID, &replyUsername, &replyContent,
+	)
+	if err != nil {
+		return m, err
+	}
+	m.Attachments = []Attachment{}
+	m.Reactions = []ReactionCount{}
+	if replyID != nil {
+		m.ReplyTo = &ReplySnippet{
+			ID:             *replyID,
+			AuthorID:       *replyAuthorID,
+			AuthorUsername: *replyUsername,
+			Content:        *replyContent,
+		}
+	}
+	return m, nil
+}
+
+// GetDeletedChannelMessagesParams selects a page of a channel's deleted
+// messages, most recently deleted first. Messages deleted before Since are
+// left out; Before pages back by deletion time.
+type GetDeletedChannelMessagesParams struct {
+	ChannelID uuid.UUID
+	Since     time.Time
+	Before    *time.Time
+	Limit     int32
+}
+
+// GetDeletedChannelMessages returns deleted messages with their full
+// content, for moderators.
+func (q *Queries) GetDeletedChannelMessages(ctx context.Context, arg GetDeletedChannelMessagesParams) ([]MessageWithAuthor, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.deleted_at, m.deleted_by, m.created_at, m.updated_at,
+		        u.username, u.display_name, u.avatar_url
+		FROM messages m
+		JOIN users u ON m.author_id = u.id
+		WHERE m.channel_id = $1 AND m.deleted_at >= $2
+		  AND ($3::timestamptz IS NULL OR m.deleted_at < $3)
+		ORDER BY m.deleted_at DESC, m.id DESC LIMIT $4`,
+		arg.ChannelID, arg.Since, arg.Before, arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []MessageWithAuthor{}
+	for rows.Next() {
+		var m MessageWithAuthor
+		if err := rows.Scan(
+			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.DeletedAt, &m.DeletedBy, &m.CreatedAt, &m.UpdatedAt,
+			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
+		); err != nil {
+			return nil, err
+		}
+		m.Attachments = []Attachment{}
+		m.Reactions = []ReactionCount{}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// PurgeDeletedMessages permanently removes messages soft-deleted before the
+// given time.
+func (q *Queries) PurgeDeletedMessages(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, `DELETE FROM messages WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanMessageIDs(rows pgx.Rows) ([]uuid.UUID, error) {
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // Edit history
 
 func (q *Queries) InsertMessageEdit(ctx context.Context, messageID uuid.UUID, content string) error {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +20,7 @@ type User struct {
 	CustomStatusText      string     `json:"custom_status_text"`
 	CustomStatusEmoji     string     `json:"custom_status_emoji"`
 	CustomStatusExpiresAt *time.Time `json:"custom_status_expires_at"`
+	IsBot                 bool       `json:"is_bot"`
 	CreatedAt             time.Time  `json:"created_at"`
 	UpdatedAt             time.Time  `json:"updated_at"`
 }
@@ -35,6 +37,9 @@ type Server struct {
 	WelcomeMessage              string      `json:"welcome_message"`
 	WelcomeChannels             []uuid.UUID `json:"welcome_channels"`
 	DefaultMessageRetentionDays *int        `json:"default_message_retention_days"`
+	// DeletedMessageDisplay is how members see deleted messages:
+	// DeletedMessageTombstone or DeletedMessageHidden
+	DeletedMessageDisplay       string      `json:"deleted_message_display"`
 	CreatedAt                   time.Time   `json:"created_at"`
 	UpdatedAt                   time.Time   `json:"updated_at"`
 }
@@ -72,14 +77,26 @@ type ChannelFollow struct {
 }
 
 type Message struct {
-	ID         uuid.UUID  `json:"id"`
-	ChannelID  uuid.UUID  `json:"channel_id"`
-	AuthorID   uuid.UUID  `json:"author_id"`
-	Content    string     `json:"content"`
-	Type       string     `json:"type"`
-	ReplyToID  *uuid.UUID `json:"reply_to_id"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID         uuid.UUID       `json:"id"`
+	ChannelID  uuid.UUID       `json:"channel_id"`
+	AuthorID   uuid.UUID       `json:"author_id"`
+	Content    string          `json:"content"`
+	Type       string          `json:"type"`
+	ReplyToID  *uuid.UUID      `json:"reply_to_id"`
+	Components json.RawMessage `json:"components"` // button and select rows on bot/webhook messages
+	Embeds     json.RawMessage `json:"embeds"`
+	WebhookID  *uuid.UUID      `json:"webhook_id"`
+	// Webhook messages show these instead of the creator's name and avatar
+	WebhookUsername  *string `json:"webhook_username"`
+	WebhookAvatarURL *string `json:"webhook_avatar_url"`
+	// Nonce is the client's send nonce, set only on the create response
+	Nonce      *string         `json:"nonce,omitempty"`
+	// DeletedAt is set on soft-deleted messages: tombstones in history and
+	// the moderator view. DeletedBy is only shown to moderators.
+	DeletedAt  *time.Time      `json:"deleted_at,omitempty"`
+	DeletedBy  *uuid.UUID      `json:"deleted_by,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
 }
 
 type ReplySnippet struct {
@@ -135,6 +152,7 @@ type DMMessage struct {
 	Content        string     `json:"content"`
 	Type           string     `json:"type"`
 	ReplyToID      *uuid.UUID `json:"reply_to_id"`
+	Nonce          *string    `json:"nonce,omitempty"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -177,6 +195,7 @@ type ServerMemberWithUser struct {
 	Status      string    `json:"status"`
 	Role        string    `json:"role"`
 	Nickname    *string   `json:"nickname"`
+	IsBot       bool      `json:"is_bot"`
 }
 
 type DMParticipantUser struct {
@@ -238,14 +257,17 @@ type FriendshipWithUser struct {
 
 // Role represents a server role with permission bitmask.
 type Role struct {
-	ID          uuid.UUID  `json:"id"`
-	ServerID    uuid.UUID  `json:"server_id"`
-	Name        string     `json:"name"`
-	Color       *string    `json:"color"`
-	Position    int        `json:"position"`
-	Permissions int64      `json:"permissions,string"`
-	Hoist       bool       `json:"hoist"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	Name        string    `json:"name"`
+	Color       *string   `json:"color"`
+	Position    int       `json:"position"`
+	Permissions int64     `json:"permissions,string"`
+	Hoist       bool      `json:"hoist"`
+	// ManagedBotID is set on the role created when a bot is authorized into
+	// the server. The role is deleted with the bot's membership.
+	ManagedBotID *uuid.UUID `json:"managed_bot_id"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
 
 // ChannelPermissionOverride represents a per-channel permission override for a role.
@@ -266,6 +288,7 @@ type MemberWithRoles struct {
 	Status      string    `json:"status"`
 	Role        string    `json:"role"`
 	Nickname    *string   `json:"nickname"`
+	IsBot       bool      `json:"is_bot"`
 	Roles       []Role    `json:"roles"`
 }
 
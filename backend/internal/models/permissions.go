@@ -2,26 +2,31 @@ package models
 
 // Permission bitmask constants.
 const (
-	PermViewChannels   int64 = 1 << 0
-	PermSendMessages   int64 = 1 << 1
-	PermManageMessages int64 = 1 << 2
-	PermManageChannels int64 = 1 << 3
-	PermManageRoles    int64 = 1 << 4
-	PermKickMembers    int64 = 1 << 5
-	PermBanMembers     int64 = 1 << 6
-	PermManageServer   int64 = 1 << 7
-	PermAddReactions   int64 = 1 << 8
-	PermAttachFiles    int64 = 1 << 9
-	PermCreateInvite   int64 = 1 << 10
-	PermPinMessages    int64 = 1 << 12
-	PermVoiceConnect   int64 = 1 << 13
-	PermVoiceSpeak     int64 = 1 << 14
-	PermAdministrator  int64 = 1 << 30
+	PermViewChannels    int64 = 1 << 0
+	PermSendMessages    int64 = 1 << 1
+	PermManageMessages  int64 = 1 << 2
+	PermManageChannels  int64 = 1 << 3
+	PermManageRoles     int64 = 1 << 4
+	PermKickMembers     int64 = 1 << 5
+	PermBanMembers      int64 = 1 << 6
+	PermManageServer    int64 = 1 << 7
+	PermAddReactions    int64 = 1 << 8
+	PermAttachFiles     int64 = 1 << 9
+	PermCreateInvite    int64 = 1 << 10
+	PermMentionEveryone int64 = 1 << 11
+	PermPinMessages     int64 = 1 << 12
+	PermVoiceConnect    int64 = 1 << 13
+	PermVoiceSpeak      int64 = 1 << 14
+	PermAdministrator   int64 = 1 << 30
 )
 
 // PermAllDefault is the default permission set for @everyone.
 var PermAllDefault int64 = PermViewChannels | PermSendMessages | PermAddReactions | PermAttachFiles | PermCreateInvite | PermPinMessages | PermVoiceConnect | PermVoiceSpeak
 
+// PermAll is every defined permission bit. Bitmasks supplied from outside,
+// like a bot's requested permissions, must not carry anything else.
+var PermAll int64 = PermViewChannels | PermSendMessages | PermManageMessages | PermManageChannels | PermManageRoles | PermKickMembers | PermBanMembers | PermManageServer | PermAddReactions | PermAttachFiles | PermCreateInvite | PermMentionEveryone | PermPinMessages | PermVoiceConnect | PermVoiceSpeak | PermAdministrator
+
 // HasPermission checks if `perms` includes `check`. Administrator bypasses all.
 func HasPermission(perms, check int64) bool {
 	if perms&PermAdministrator != 0 {
@@ -25,10 +25,10 @@ func (q *Queries) UnpinMessage(ctx context.Context, channelID, messageID uuid.UU
 
 func (q *Queries) GetPinnedMessages(ctx context.Context, channelID uuid.UUID) ([]MessageWithAuthor, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url
 		FROM pinned_messages pm
-		JOIN messages m ON pm.message_id = m.id
+		JOIN messages m ON pm.message_id = m.id AND m.deleted_at IS NULL
 		JOIN users u ON m.author_id = u.id
 		WHERE pm.channel_id = $1
 		ORDER BY pm.pinned_at DESC`, channelID,
@@ -42,7 +42,7 @@ func (q *Queries) GetPinnedMessages(ctx context.Context, channelID uuid.UUID) ([
 	for rows.Next() {
 		var m MessageWithAuthor
 		if err := rows.Scan(
-			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt,
+			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt,
 			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
 		); err != nil {
 			return nil, err
@@ -69,7 +69,9 @@ func (q *Queries) IsMessagePinned(ctx context.Context, channelID, messageID uuid
 func (q *Queries) GetPinnedMessageCount(ctx context.Context, channelID uuid.UUID) (int, error) {
 	var count int
 	err := q.db.QueryRow(ctx,
-		`SELECT COUNT(*) FROM pinned_messages WHERE channel_id = $1`, channelID,
+		`SELECT COUNT(*) FROM pinned_messages pm
+		JOIN messages m ON pm.message_id = m.id AND m.deleted_at IS NULL
+		WHERE pm.channel_id = $1`, channelID,
 	).Scan(&count)
 	return count, err
 }
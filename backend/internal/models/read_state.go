@@ -63,12 +63,12 @@ func (q *Queries) UpsertDMReadState(ctx context.Context, userID, conversationID
 func (q *Queries) GetChannelUnreadCounts(ctx context.Context, userID uuid.UUID) ([]UnreadCount, error) {
 	rows, err := q.db.Query(ctx,
 		`SELECT c.id AS channel_id,
-		        COUNT(m.id) FILTER (WHERE m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz)) AS unread_count,
-		        COUNT(mn.id) FILTER (WHERE mn.seen = false) AS mention_count
+		        COUNT(DISTINCT m.id) FILTER (WHERE m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz)) AS unread_count,
+		        COUNT(DISTINCT mn.id) FILTER (WHERE mn.seen = false) AS mention_count
 		FROM server_members sm
 		JOIN channels c ON c.server_id = sm.server_id AND c.type = 'text'
 		LEFT JOIN channel_read_state rs ON rs.user_id = $1 AND rs.channel_id = c.id
-		LEFT JOIN messages m ON m.channel_id = c.id AND m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz) AND m.author_id != $1
+		LEFT JOIN messages m ON m.channel_id = c.id AND m.created_at > COALESCE(rs.last_read_at, '1970-01-01'::timestamptz) AND m.author_id != $1 AND m.deleted_at IS NULL
 		LEFT JOIN mention_notifications mn ON mn.channel_id = c.id AND mn.user_id = $1 AND mn.seen = false
 		WHERE sm.user_id = $1
 		GROUP BY c.id
@@ -136,6 +136,39 @@ func (q *Queries) CreateMentionNotification(ctx context.Context, userID, message
 	return err
 }
 
+// CreateMentionNotifications creates a mention notification for each user
+// in one statement.
+func (q *Queries) CreateMentionNotifications(ctx context.Context, userIDs []uuid.UUID, messageID, channelID, serverID uuid.UUID) error {
+	_, err := q.db.Exec(ctx,
+		`INSERT INTO mention_notifications (user_id, message_id, channel_id, server_id)
+		SELECT unnest($1::uuid[]), $2, $3, $4`,
+		userIDs, messageID, channelID, serverID,
+	)
+	return err
+}
+
+// GetMessageMentionedUserIDs returns the users a mention notification was
+// created for when the message was sent.
+func (q *Queries) GetMessageMentionedUserIDs(ctx context.Context, messageID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT user_id FROM mention_notifications WHERE message_id = $1`, messageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (q *Queries) MarkMentionsSeen(ctx context.Context, userID, channelID uuid.UUID) error {
 	_, err := q.db.Exec(ctx,
 		`UPDATE mention_notifications SET seen = true WHERE user_id = $1 AND channel_id = $2 AND seen = false`,
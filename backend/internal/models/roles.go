@@ -16,21 +16,23 @@ type CreateRoleParams struct {
 	Position    int
 	Permissions int64
 	Hoist       bool
+	// ManagedBotID marks the role as the managed role of a bot member.
+	ManagedBotID *uuid.UUID
 }
 
 func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error) {
 	row := q.db.QueryRow(ctx,
-		`INSERT INTO roles (server_id, name, color, position, permissions, hoist)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, server_id, name, color, position, permissions, hoist, created_at`,
-		arg.ServerID, arg.Name, arg.Color, arg.Position, arg.Permissions, arg.Hoist,
+		`INSERT INTO roles (server_id, name, color, position, permissions, hoist, managed_bot_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at`,
+		arg.ServerID, arg.Name, arg.Color, arg.Position, arg.Permissions, arg.Hoist, arg.ManagedBotID,
 	)
 	return scanRole(row)
 }
 
 func (q *Queries) GetServerRoles(ctx context.Context, serverID uuid.UUID) ([]Role, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT id, server_id, name, color, position, permissions, hoist, created_at
+		`SELECT id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at
 		FROM roles WHERE server_id = $1 ORDER BY position ASC`, serverID,
 	)
 	if err != nil {
@@ -54,7 +56,7 @@ func (q *Queries) GetServerRoles(ctx context.Context, serverID uuid.UUID) ([]Rol
 
 func (q *Queries) GetRoleByID(ctx context.Context, roleID uuid.UUID) (Role, error) {
 	row := q.db.QueryRow(ctx,
-		`SELECT id, server_id, name, color, position, permissions, hoist, created_at
+		`SELECT id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at
 		FROM roles WHERE id = $1`, roleID,
 	)
 	return scanRole(row)
@@ -62,12 +64,21 @@ func (q *Queries) GetRoleByID(ctx context.Context, roleID uuid.UUID) (Role, erro
 
 func (q *Queries) GetEveryoneRole(ctx context.Context, serverID uuid.UUID) (Role, error) {
 	row := q.db.QueryRow(ctx,
-		`SELECT id, server_id, name, color, position, permissions, hoist, created_at
+		`SELECT id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at
 		FROM roles WHERE server_id = $1 AND name = '@everyone' AND position = 0`, serverID,
 	)
 	return scanRole(row)
 }
 
+// GetManagedRole returns the managed role of a bot in a server.
+func (q *Queries) GetManagedRole(ctx context.Context, serverID, botID uuid.UUID) (Role, error) {
+	row := q.db.QueryRow(ctx,
+		`SELECT id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at
+		FROM roles WHERE server_id = $1 AND managed_bot_id = $2`, serverID, botID,
+	)
+	return scanRole(row)
+}
+
 type UpdateRoleParams struct {
 	ID          uuid.UUID
 	Name        *string
@@ -84,7 +95,7 @@ func (q *Queries) UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, e
 			permissions = COALESCE($4, permissions),
 			hoist = COALESCE($5, hoist)
 		WHERE id = $1
-		RETURNING id, server_id, name, color, position, permissions, hoist, created_at`,
+		RETURNING id, server_id, name, color, position, permissions, hoist, managed_bot_id, created_at`,
 		arg.ID, arg.Name, arg.Color, arg.Permissions, arg.Hoist,
 	)
 	return scanRole(row)
@@ -135,7 +146,7 @@ func (q *Queries) RemoveRole(ctx context.Context, serverID, userID, roleID uuid.
 
 func (q *Queries) GetMemberRoles(ctx context.Context, serverID, userID uuid.UUID) ([]Role, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT r.id, r.server_id, r.name, r.color, r.position, r.permissions, r.hoist, r.created_at
+		`SELECT r.id, r.server_id, r.name, r.color, r.position, r.permissions, r.hoist, r.managed_bot_id, r.created_at
 		FROM roles r JOIN member_roles mr ON r.id = mr.role_id
 		WHERE mr.server_id = $1 AND mr.user_id = $2
 		ORDER BY r.position DESC`, serverID, userID,
@@ -159,15 +170,120 @@ func (q *Queries) GetMemberRoles(ctx context.Context, serverID, userID uuid.UUID
 	return roles, rows.Err()
 }
 
+// GetUserServersRoles returns the roles of every server the user is a member of.
+func (q *Queries) GetUserServersRoles(ctx context.Context, userID uuid.UUID) ([]Role, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT r.id, r.server_id, r.name, r.color, r.position, r.permissions, r.hoist, r.managed_bot_id, r.created_at
+		FROM roles r JOIN server_members sm ON r.server_id = sm.server_id
+		WHERE sm.user_id = $1 ORDER BY r.position ASC`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		r, err := scanRoleFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	if roles == nil {
+		roles = []Role{}
+	}
+	return roles, rows.Err()
+}
+
+// MemberRoleRef is a role assignment of a single member.
+type MemberRoleRef struct {
+	ServerID uuid.UUID
+	RoleID   uuid.UUID
+}
+
+// GetUserMemberRoleIDs returns the user's role assignments across all servers.
+func (q *Queries) GetUserMemberRoleIDs(ctx context.Context, userID uuid.UUID) ([]MemberRoleRef, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT server_id, role_id FROM member_roles WHERE user_id = $1`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []MemberRoleRef
+	for rows.Next() {
+		var r MemberRoleRef
+		if err := rows.Scan(&r.ServerID, &r.RoleID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// MemberRolePermissions is a role held by a member, with the role's permissions.
+type MemberRolePermissions struct {
+	UserID      uuid.UUID
+	RoleID      uuid.UUID
+	Permissions int64
+}
+
+// GetMembersRolePermissions returns the role assignments of the given users
+// in a server, for working out many members' permissions at once.
+func (q *Queries) GetMembersRolePermissions(ctx context.Context, serverID uuid.UUID, userIDs []uuid.UUID) ([]MemberRolePermissions, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT mr.user_id, r.id, r.permissions
+		FROM member_roles mr JOIN roles r ON r.id = mr.role_id
+		WHERE mr.server_id = $1 AND mr.user_id = ANY($2)`, serverID, userIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []MemberRolePermissions
+	for rows.Next() {
+		var r MemberRolePermissions
+		if err := rows.Scan(&r.UserID, &r.RoleID, &r.Permissions); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// GetRoleMemberIDs returns the users holding a role in a server.
+func (q *Queries) GetRoleMemberIDs(ctx context.Context, serverID, roleID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT user_id FROM member_roles WHERE server_id = $1 AND role_id = $2`, serverID, roleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // GetMembersWithRoles returns all server members with their assigned roles.
 func (q *Queries) GetMembersWithRoles(ctx context.Context, serverID uuid.UUID) ([]MemberWithRoles, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname,
+		`SELECT u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname, u.is_bot,
 			COALESCE(
 				json_agg(json_build_object(
 					'id', r.id, 'server_id', r.server_id, 'name', r.name,
 					'color', r.color, 'position', r.position, 'permissions', r.permissions,
-					'hoist', r.hoist, 'created_at', r.created_at
+					'hoist', r.hoist, 'managed_bot_id', r.managed_bot_id, 'created_at', r.created_at
 				)) FILTER (WHERE r.id IS NOT NULL), '[]'
 			) as roles
 		FROM server_members sm
@@ -175,7 +291,7 @@ func (q *Queries) GetMembersWithRoles(ctx context.Context, serverID uuid.UUID) (
 		LEFT JOIN member_roles mr ON mr.server_id = sm.server_id AND mr.user_id = sm.user_id
 		LEFT JOIN roles r ON r.id = mr.role_id
 		WHERE sm.server_id = $1
-		GROUP BY u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname
+		GROUP BY u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname, u.is_bot
 		ORDER BY u.username`, serverID,
 	)
 	if err != nil {
@@ -187,7 +303,7 @@ func (q *Queries) GetMembersWithRoles(ctx context.Context, serverID uuid.UUID) (
 	for rows.Next() {
 		var m MemberWithRoles
 		var rolesJSON []byte
-		if err := rows.Scan(&m.ID, &m.Username, &m.DisplayName, &m.AvatarURL, &m.Status, &m.Role, &m.Nickname, &rolesJSON); err != nil {
+		if err := rows.Scan(&m.ID, &m.Username, &m.DisplayName, &m.AvatarURL, &m.Status, &m.Role, &m.Nickname, &m.IsBot, &rolesJSON); err != nil {
 			return nil, err
 		}
 		m.Roles = []Role{}
@@ -274,25 +390,26 @@ func (q *Queries) GetMaxRolePosition(ctx context.Context, serverID uuid.UUID) (i
 
 func scanRole(row pgx.Row) (Role, error) {
 	var r Role
-	err := row.Scan(&r.ID, &r.ServerID, &r.Name, &r.Color, &r.Position, &r.Permissions, &r.Hoist, &r.CreatedAt)
+	err := row.Scan(&r.ID, &r.ServerID, &r.Name, &r.Color, &r.Position, &r.Permissions, &r.Hoist, &r.ManagedBotID, &r.CreatedAt)
 	return r, err
 }
 
 func scanRoleFromRows(rows pgx.Rows) (Role, error) {
 	var r Role
-	err := rows.Scan(&r.ID, &r.ServerID, &r.Name, &r.Color, &r.Position, &r.Permissions, &r.Hoist, &r.CreatedAt)
+	err := rows.Scan(&r.ID, &r.ServerID, &r.Name, &r.Color, &r.Position, &r.Permissions, &r.Hoist, &r.ManagedBotID, &r.CreatedAt)
 	return r, err
 }
 
 func parseRolesJSON(data []byte) ([]Role, error) {
 	type roleJSON struct {
-		ID          uuid.UUID `json:"id"`
-		ServerID    uuid.UUID `json:"server_id"`
-		Name        string    `json:"name"`
-		Color       *string   `json:"color"`
-		Position    int       `json:"position"`
-		Permissions int64     `json:"permissions"`
-		Hoist       bool      `json:"hoist"`
+		ID           uuid.UUID  `json:"id"`
+		ServerID     uuid.UUID  `json:"server_id"`
+		Name         string     `json:"name"`
+		Color        *string    `json:"color"`
+		Position     int        `json:"position"`
+		Permissions  int64      `json:"permissions"`
+		Hoist        bool       `json:"hoist"`
+		ManagedBotID *uuid.UUID `json:"managed_bot_id"`
 	}
 
 	var rjs []roleJSON
@@ -303,13 +420,14 @@ func parseRolesJSON(data []byte) ([]Role, error) {
 	roles := make([]Role, len(rjs))
 	for i, rj := range rjs {
 		roles[i] = Role{
-			ID:          rj.ID,
-			ServerID:    rj.ServerID,
-			Name:        rj.Name,
-			Color:       rj.Color,
-			Position:    rj.Position,
-			Permissions: rj.Permissions,
-			Hoist:       rj.Hoist,
+			ID:           rj.ID,
+			ServerID:     rj.ServerID,
+			Name:         rj.Name,
+			Color:        rj.Color,
+			Position:     rj.Position,
+			Permissions:  rj.Permissions,
+			Hoist:        rj.Hoist,
+			ManagedBotID: rj.ManagedBotID,
 		}
 	}
 	return roles, nil
@@ -26,14 +26,14 @@ type SearchChannelMessagesParams struct {
 
 func (q *Queries) SearchChannelMessages(ctx context.Context, arg SearchChannelMessagesParams) ([]MessageWithAuthor, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url,
 		        rm.id, rm.author_id, ru.username, rm.content
 		FROM messages m
 		JOIN users u ON m.author_id = u.id
-		LEFT JOIN messages rm ON m.reply_to_id = rm.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
 		LEFT JOIN users ru ON rm.author_id = ru.id
-		WHERE m.channel_id = $1
+		WHERE m.channel_id = $1 AND m.deleted_at IS NULL
 		  AND m.search_vec @@ plainto_tsquery('english', $2)
 		  AND ($3::text IS NULL OR m.created_at < $3::timestamptz)
 		  AND ($5::uuid IS NULL OR m.author_id = $5)
@@ -64,15 +64,15 @@ type SearchServerMessagesParams struct {
 
 func (q *Queries) SearchServerMessages(ctx context.Context, arg SearchServerMessagesParams) ([]MessageWithAuthor, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url,
 		        rm.id, rm.author_id, ru.username, rm.content
 		FROM messages m
 		JOIN users u ON m.author_id = u.id
 		JOIN channels c ON m.channel_id = c.id
-		LEFT JOIN messages rm ON m.reply_to_id = rm.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
 		LEFT JOIN users ru ON rm.author_id = ru.id
-		WHERE c.server_id = $1
+		WHERE c.server_id = $1 AND m.deleted_at IS NULL
 		  AND m.search_vec @@ plainto_tsquery('english', $2)
 		  AND ($3::text IS NULL OR m.created_at < $3::timestamptz)
 		  AND ($5::uuid IS NULL OR m.author_id = $5)
@@ -103,16 +103,17 @@ type SearchUserMessagesParams struct {
 
 func (q *Queries) SearchUserMessages(ctx context.Context, arg SearchUserMessagesParams) ([]MessageWithAuthor, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.created_at, m.updated_at,
+		`SELECT m.id, m.channel_id, m.author_id, m.content, m.type, m.reply_to_id, m.components, m.embeds, m.webhook_id, m.webhook_username, m.webhook_avatar_url, m.created_at, m.updated_at,
 		        u.username, u.display_name, u.avatar_url,
 		        rm.id, rm.author_id, ru.username, rm.content
 		FROM messages m
 		JOIN users u ON m.author_id = u.id
 		JOIN channels c ON m.channel_id = c.id
 		JOIN server_members sm ON c.server_id = sm.server_id AND sm.user_id = $1
-		LEFT JOIN messages rm ON m.reply_to_id = rm.id
+		LEFT JOIN messages rm ON m.reply_to_id = rm.id AND rm.deleted_at IS NULL
 		LEFT JOIN users ru ON rm.author_id = ru.id
-		WHERE m.search_vec @@ plainto_tsquery('english', $2)
+		WHERE m.deleted_at IS NULL
+		  AND m.search_vec @@ plainto_tsquery('english', $2)
 		  AND ($3::text IS NULL OR m.created_at < $3::timestamptz)
 		  AND ($5::uuid IS NULL OR m.author_id = $5)
 		  AND ($6::boolean = FALSE OR EXISTS (SELECT 1 FROM attachments WHERE message_id = m.id))
@@ -198,7 +199,7 @@ func scanMessagesWithAuthor(rows interface {
 		var replyID, replyAuthorID *uuid.UUID
 		var replyUsername, replyContent *string
 		if err := rows.Scan(
-			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt,
+			&m.ID, &m.ChannelID, &m.AuthorID, &m.Content, &m.Type, &m.ReplyToID, &m.Components, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt,
 			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
 			&replyID, &replyAuthorID, &replyUsername, &replyContent,
 		); err != nil {
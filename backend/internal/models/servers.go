@@ -7,6 +7,12 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// Values of Server.DeletedMessageDisplay.
+const (
+	DeletedMessageTombstone = "tombstone" // a placeholder stays in history
+	DeletedMessageHidden    = "hidden"    // the message disappears
+)
+
 type CreateServerParams struct {
 	Name       string
 	OwnerID    uuid.UUID
@@ -17,7 +23,7 @@ func (q *Queries) CreateServer(ctx context.Context, arg CreateServerParams) (Ser
 	row := q.db.QueryRow(ctx,
 		`INSERT INTO servers (name, owner_id, invite_code)
 		VALUES ($1, $2, $3)
-		RETURNING id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, created_at, updated_at`,
+		RETURNING id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, deleted_message_display, created_at, updated_at`,
 		arg.Name, arg.OwnerID, arg.InviteCode,
 	)
 	return scanServer(row)
@@ -25,7 +31,7 @@ func (q *Queries) CreateServer(ctx context.Context, arg CreateServerParams) (Ser
 
 func (q *Queries) GetServerByID(ctx context.Context, id uuid.UUID) (Server, error) {
 	row := q.db.QueryRow(ctx,
-		`SELECT id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, created_at, updated_at
+		`SELECT id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, deleted_message_display, created_at, updated_at
 		FROM servers WHERE id = $1`, id,
 	)
 	return scanServer(row)
@@ -33,7 +39,7 @@ func (q *Queries) GetServerByID(ctx context.Context, id uuid.UUID) (Server, erro
 
 func (q *Queries) GetServerByInviteCode(ctx context.Context, inviteCode string) (Server, error) {
 	row := q.db.QueryRow(ctx,
-		`SELECT id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, created_at, updated_at
+		`SELECT id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, deleted_message_display, created_at, updated_at
 		FROM servers WHERE invite_code = $1`, inviteCode,
 	)
 	return scanServer(row)
@@ -41,7 +47,7 @@ func (q *Queries) GetServerByInviteCode(ctx context.Context, inviteCode string)
 
 func (q *Queries) GetUserServers(ctx context.Context, userID uuid.UUID) ([]Server, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT s.id, s.name, s.icon_url, s.owner_id, s.invite_code, s.is_public, s.description, s.gifs_enabled, s.welcome_message, s.welcome_channels, s.default_message_retention_days, s.created_at, s.updated_at
+		`SELECT s.id, s.name, s.icon_url, s.owner_id, s.invite_code, s.is_public, s.description, s.gifs_enabled, s.welcome_message, s.welcome_channels, s.default_message_retention_days, s.deleted_message_display, s.created_at, s.updated_at
 		FROM servers s JOIN server_members sm ON s.id = sm.server_id
 		WHERE sm.user_id = $1 ORDER BY s.name`, userID,
 	)
@@ -72,6 +78,7 @@ type UpdateServerParams struct {
 	Description                 *string
 	GifsEnabled                 *bool
 	DefaultMessageRetentionDays *int
+	DeletedMessageDisplay       *string
 }
 
 func (q *Queries) UpdateServer(ctx context.Context, arg UpdateServerParams) (Server, error) {
@@ -80,10 +87,12 @@ func (q *Queries) UpdateServer(ctx context.Context, arg UpdateServerParams) (Ser
 		 is_public = COALESCE($4, is_public), description = COALESCE($5, description),
 		 gifs_enabled = COALESCE($6, gifs_enabled),
 		 default_message_retention_days = COALESCE($7, default_message_retention_days),
+		 deleted_message_display = COALESCE($8, deleted_message_display),
 		 updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, created_at, updated_at`,
+		RETURNING id, name, icon_url, owner_id, invite_code, is_public, description, gifs_enabled, welcome_message, welcome_channels, default_message_retention_days, deleted_message_display, created_at, updated_at`,
 		arg.ID, arg.Name, arg.IconURL, arg.IsPublic, arg.Description, arg.GifsEnabled, arg.DefaultMessageRetentionDays,
+		arg.DeletedMessageDisplay,
 	)
 	return scanServer(row)
 }
@@ -127,7 +136,7 @@ func (q *Queries) GetServerMember(ctx context.Context, serverID, userID uuid.UUI
 
 func (q *Queries) GetServerMembers(ctx context.Context, serverID uuid.UUID) ([]ServerMemberWithUser, error) {
 	rows, err := q.db.Query(ctx,
-		`SELECT u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname
+		`SELECT u.id, u.username, u.display_name, u.avatar_url, u.status, sm.role, sm.nickname, u.is_bot
 		FROM server_members sm JOIN users u ON sm.user_id = u.id
 		WHERE sm.server_id = $1 ORDER BY u.username`, serverID,
 	)
@@ -139,7 +148,7 @@ func (q *Queries) GetServerMembers(ctx context.Context, serverID uuid.UUID) ([]S
 	var members []ServerMemberWithUser
 	for rows.Next() {
 		var m ServerMemberWithUser
-		if err := rows.Scan(&m.ID, &m.Username, &m.DisplayName, &m.AvatarURL, &m.Status, &m.Role, &m.Nickname); err != nil {
+		if err := rows.Scan(&m.ID, &m.Username, &m.DisplayName, &m.AvatarURL, &m.Status, &m.Role, &m.Nickname, &m.IsBot); err != nil {
 			return nil, err
 		}
 		members = append(members, m)
@@ -211,7 +220,7 @@ func (q *Queries) GetUserCoMemberIDs(ctx context.Context, userID uuid.UUID) ([]u
 
 func scanServer(row pgx.Row) (Server, error) {
 	var s Server
-	err := row.Scan(&s.ID, &s.Name, &s.IconURL, &s.OwnerID, &s.InviteCode, &s.IsPublic, &s.Description, &s.GifsEnabled, &s.WelcomeMessage, &s.WelcomeChannels, &s.DefaultMessageRetentionDays, &s.CreatedAt, &s.UpdatedAt)
+	err := row.Scan(&s.ID, &s.Name, &s.IconURL, &s.OwnerID, &s.InviteCode, &s.IsPublic, &s.Description, &s.GifsEnabled, &s.WelcomeMessage, &s.WelcomeChannels, &s.DefaultMessageRetentionDays, &s.DeletedMessageDisplay, &s.CreatedAt, &s.UpdatedAt)
 	if s.WelcomeChannels == nil {
 		s.WelcomeChannels = []uuid.UUID{}
 	}
@@ -220,7 +229,7 @@ func scanServer(row pgx.Row) (Server, error) {
 
 func scanServerFromRows(rows pgx.Rows) (Server, error) {
 	var s Server
-	err := rows.Scan(&s.ID, &s.Name, &s.IconURL, &s.OwnerID, &s.InviteCode, &s.IsPublic, &s.Description, &s.GifsEnabled, &s.WelcomeMessage, &s.WelcomeChannels, &s.DefaultMessageRetentionDays, &s.CreatedAt, &s.UpdatedAt)
+	err := rows.Scan(&s.ID, &s.Name, &s.IconURL, &s.OwnerID, &s.InviteCode, &s.IsPublic, &s.Description, &s.GifsEnabled, &s.WelcomeMessage, &s.WelcomeChannels, &s.DefaultMessageRetentionDays, &s.DeletedMessageDisplay, &s.CreatedAt, &s.UpdatedAt)
 	if s.WelcomeChannels == nil {
 		s.WelcomeChannels = []uuid.UUID{}
 	}
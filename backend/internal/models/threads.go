@@ -2,9 +2,11 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type Thread struct {
@@ -22,13 +24,18 @@ type Thread struct {
 }
 
 type ThreadMessage struct {
-	ID        uuid.UUID  `json:"id"`
-	ThreadID  uuid.UUID  `json:"thread_id"`
-	AuthorID  uuid.UUID  `json:"author_id"`
-	Content   string     `json:"content"`
-	ReplyToID *uuid.UUID `json:"reply_to_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID               uuid.UUID       `json:"id"`
+	ThreadID         uuid.UUID       `json:"thread_id"`
+	AuthorID         uuid.UUID       `json:"author_id"`
+	Content          string          `json:"content"`
+	ReplyToID        *uuid.UUID      `json:"reply_to_id"`
+	Embeds           json.RawMessage `json:"embeds"`
+	WebhookID        *uuid.UUID      `json:"webhook_id"`
+	WebhookUsername  *string         `json:"webhook_username"`
+	WebhookAvatarURL *string         `json:"webhook_avatar_url"`
+	Nonce            *string         `json:"nonce,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        *time.Time      `json:"updated_at"`
 }
 
 type ThreadMessageWithAuthor struct {
@@ -114,15 +121,58 @@ func (q *Queries) UpdateThread(ctx context.Context, id uuid.UUID, name string, a
 	return t, err
 }
 
-// CreateThreadMessage inserts a new message into a thread.
-func (q *Queries) CreateThreadMessage(ctx context.Context, threadID, authorID uuid.UUID, content string, replyToID *uuid.UUID) (ThreadMessage, error) {
+// CreateThreadMessage inserts a new message into a thread. If the author
+// already has a message in the thread with the same nonce, nothing is
+// inserted and pgx.ErrNoRows is returned.
+func (q *Queries) CreateThreadMessage(ctx context.Context, threadID, authorID uuid.UUID, content string, replyToID *uuid.UUID, nonce *string) (ThreadMessage, error) {
 	var m ThreadMessage
 	err := q.db.QueryRow(ctx,
-		`INSERT INTO thread_messages (thread_id, author_id, content, reply_to_id)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, thread_id, author_id, content, reply_to_id, created_at, updated_at`,
-		threadID, authorID, content, replyToID,
-	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+		`INSERT INTO thread_messages (thread_id, author_id, content, reply_to_id, nonce)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (thread_id, author_id, nonce) WHERE nonce IS NOT NULL DO NOTHING
+		RETURNING id, thread_id, author_id, content, reply_to_id, embeds, webhook_id, webhook_username, webhook_avatar_url, nonce, created_at, updated_at`,
+		threadID, authorID, content, replyToID, nonce,
+	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// GetThreadMessageByNonce returns the author's message in the thread sent
+// with nonce since the given time. Older messages give up the nonce so that
+// it can be sent again.
+func (q *Queries) GetThreadMessageByNonce(ctx context.Context, threadID, authorID uuid.UUID, nonce string, since time.Time) (ThreadMessage, error) {
+	var m ThreadMessage
+	err := q.db.QueryRow(ctx,
+		`WITH released AS (
+			UPDATE thread_messages SET nonce = NULL
+			WHERE thread_id = $1 AND author_id = $2 AND nonce = $3 AND created_at < $4
+		)
+		SELECT id, thread_id, author_id, content, reply_to_id, embeds, webhook_id, webhook_username, webhook_avatar_url, nonce, created_at, updated_at
+		FROM thread_messages WHERE thread_id = $1 AND author_id = $2 AND nonce = $3 AND created_at >= $4`,
+		threadID, authorID, nonce, since,
+	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.Nonce, &m.CreatedAt, &m.UpdatedAt)
+	return m, err
+}
+
+// CreateWebhookThreadMessageParams is a webhook's post into a thread.
+type CreateWebhookThreadMessageParams struct {
+	ThreadID         uuid.UUID
+	AuthorID         uuid.UUID
+	Content          string
+	Embeds           json.RawMessage
+	WebhookID        uuid.UUID
+	WebhookUsername  string
+	WebhookAvatarURL string
+}
+
+// CreateWebhookThreadMessage inserts a webhook message into a thread.
+func (q *Queries) CreateWebhookThreadMessage(ctx context.Context, arg CreateWebhookThreadMessageParams) (ThreadMessage, error) {
+	var m ThreadMessage
+	err := q.db.QueryRow(ctx,
+		`INSERT INTO thread_messages (thread_id, author_id, content, embeds, webhook_id, webhook_username, webhook_avatar_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, thread_id, author_id, content, reply_to_id, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at`,
+		arg.ThreadID, arg.AuthorID, arg.Content, arg.Embeds, arg.WebhookID, arg.WebhookUsername, arg.WebhookAvatarURL,
+	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
@@ -135,27 +185,51 @@ func (q *Queries) IncrementThreadMessageCount(ctx context.Context, threadID uuid
 	return err
 }
 
-// GetThreadMessages returns paginated messages for a thread.
-func (q *Queries) GetThreadMessages(ctx context.Context, threadID uuid.UUID, before *time.Time, limit int32) ([]ThreadMessageWithAuthor, error) {
+// GetThreadMessages returns a thread's messages before an optional bound,
+// newest first.
+func (q *Queries) GetThreadMessages(ctx context.Context, threadID uuid.UUID, before *MessageBound, limit int32) ([]ThreadMessageWithAuthor, error) {
+	beforeTime, beforeID := boundArgs(before)
 	rows, err := q.db.Query(ctx,
-		`SELECT tm.id, tm.thread_id, tm.author_id, tm.content, tm.reply_to_id, tm.created_at, tm.updated_at,
+		`SELECT tm.id, tm.thread_id, tm.author_id, tm.content, tm.reply_to_id, tm.embeds, tm.webhook_id, tm.webhook_username, tm.webhook_avatar_url, tm.created_at, tm.updated_at,
 		        u.username, u.display_name, u.avatar_url
 		FROM thread_messages tm
 		JOIN users u ON tm.author_id = u.id
-		WHERE tm.thread_id = $1 AND ($2::timestamptz IS NULL OR tm.created_at < $2)
-		ORDER BY tm.created_at DESC LIMIT $3`,
-		threadID, before, limit,
+		WHERE tm.thread_id = $1 AND ($2::timestamptz IS NULL OR (tm.created_at, tm.id) < ($2, $3::uuid))
+		ORDER BY tm.created_at DESC, tm.id DESC LIMIT $4`,
+		threadID, beforeTime, beforeID, limit,
 	)
 	if err != nil {
 		return nil, err
 	}
+	return scanThreadMessages(rows)
+}
+
+// GetThreadMessagesAfter returns a thread's messages after a bound, oldest
+// first.
+func (q *Queries) GetThreadMessagesAfter(ctx context.Context, threadID uuid.UUID, after MessageBound, limit int32) ([]ThreadMessageWithAuthor, error) {
+	rows, err := q.db.Query(ctx,
+		`SELECT tm.id, tm.thread_id, tm.author_id, tm.content, tm.reply_to_id, tm.embeds, tm.webhook_id, tm.webhook_username, tm.webhook_avatar_url, tm.created_at, tm.updated_at,
+		        u.username, u.display_name, u.avatar_url
+		FROM thread_messages tm
+		JOIN users u ON tm.author_id = u.id
+		WHERE tm.thread_id = $1 AND (tm.created_at, tm.id) > ($2, $3)
+		ORDER BY tm.created_at ASC, tm.id ASC LIMIT $4`,
+		threadID, after.CreatedAt, after.ID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanThreadMessages(rows)
+}
+
+func scanThreadMessages(rows pgx.Rows) ([]ThreadMessageWithAuthor, error) {
 	defer rows.Close()
 
 	var messages []ThreadMessageWithAuthor
 	for rows.Next() {
 		var m ThreadMessageWithAuthor
 		if err := rows.Scan(
-			&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt,
+			&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt,
 			&m.AuthorUsername, &m.AuthorDisplayName, &m.AuthorAvatarURL,
 		); err != nil {
 			return nil, err
@@ -196,9 +270,9 @@ func (q *Queries) GetThreadSubscription(ctx context.Context, threadID, userID uu
 func (q *Queries) GetThreadMessageByID(ctx context.Context, id uuid.UUID) (ThreadMessage, error) {
 	var m ThreadMessage
 	err := q.db.QueryRow(ctx,
-		`SELECT id, thread_id, author_id, content, reply_to_id, created_at, updated_at
+		`SELECT id, thread_id, author_id, content, reply_to_id, embeds, webhook_id, webhook_username, webhook_avatar_url, created_at, updated_at
 		FROM thread_messages WHERE id = $1`, id,
-	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.CreatedAt, &m.UpdatedAt)
+	).Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.Content, &m.ReplyToID, &m.Embeds, &m.WebhookID, &m.WebhookUsername, &m.WebhookAvatarURL, &m.CreatedAt, &m.UpdatedAt)
 	return m, err
 }
 
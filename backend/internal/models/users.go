@@ -8,9 +8,11 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-const userColumns = `id, username, email, avatar_url, display_name, status, kratos_id,
+// Bot accounts have no Kratos identity; kratos_id reads as the zero UUID.
+const userColumns = `id, username, email, avatar_url, display_name, status,
+	COALESCE(kratos_id, '00000000-0000-0000-0000-000000000000') AS kratos_id,
 	bio, pronouns, custom_status_text, custom_status_emoji, custom_status_expires_at,
-	created_at, updated_at`
+	is_bot, created_at, updated_at`
 
 type CreateUserParams struct {
 	Username    string
@@ -167,7 +169,7 @@ func scanUser(row pgx.Row) (User, error) {
 		&u.ID, &u.Username, &u.Email,
 		&u.AvatarURL, &u.DisplayName, &u.Status, &u.KratosID,
 		&u.Bio, &u.Pronouns, &u.CustomStatusText, &u.CustomStatusEmoji, &u.CustomStatusExpiresAt,
-		&u.CreatedAt, &u.UpdatedAt,
+		&u.IsBot, &u.CreatedAt, &u.UpdatedAt,
 	)
 	return u, err
 }
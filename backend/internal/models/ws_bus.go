@@ -0,0 +1,33 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// InsertWSBusPayload stores an oversized bus message and returns its row ID.
+func (q *Queries) InsertWSBusPayload(ctx context.Context, payload json.RawMessage) (int64, error) {
+	var id int64
+	err := q.db.QueryRow(ctx,
+		`INSERT INTO ws_bus_payloads (payload) VALUES ($1) RETURNING id`, payload,
+	).Scan(&id)
+	return id, err
+}
+
+func (q *Queries) GetWSBusPayload(ctx context.Context, id int64) (json.RawMessage, error) {
+	var payload json.RawMessage
+	err := q.db.QueryRow(ctx,
+		`SELECT payload FROM ws_bus_payloads WHERE id = $1`, id,
+	).Scan(&payload)
+	return payload, err
+}
+
+// DeleteWSBusPayloadsBefore prunes overflow rows older than the cutoff.
+func (q *Queries) DeleteWSBusPayloadsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := q.db.Exec(ctx, `DELETE FROM ws_bus_payloads WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
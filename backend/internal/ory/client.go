@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,9 @@ import (
 
 const defaultTimeout = 10 * time.Second
 
+// ErrNotFound is returned when a Hydra resource does not exist.
+var ErrNotFound = errors.New("not found")
+
 // KratosClient is an HTTP client for the Kratos Admin and Public APIs.
 type KratosClient struct {
 	adminURL  string
@@ -187,6 +191,60 @@ func (c *HydraClient) AcceptConsent(ctx context.Context, challenge string, body
 	return c.putJSON(ctx, u, body)
 }
 
+// RejectConsent rejects a consent request and returns the redirect URL,
+// which carries the error back to the client.
+func (c *HydraClient) RejectConsent(ctx context.Context, challenge string, body RejectRequest) (*CompletedRequest, error) {
+	u := fmt.Sprintf("%s/admin/oauth2/auth/requests/consent/reject?consent_challenge=%s", c.adminURL, url.QueryEscape(challenge))
+	return c.putJSON(ctx, u, body)
+}
+
+// GetOAuth2Client fetches an OAuth2 client. Returns ErrNotFound if Hydra
+// does not know the client.
+func (c *HydraClient) GetOAuth2Client(ctx context.Context, clientID string) (*OAuth2Client, error) {
+	u := fmt.Sprintf("%s/admin/clients/%s", c.adminURL, url.PathEscape(clientID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	var client OAuth2Client
+	if err := c.doJSON(req, http.StatusOK, &client); err != nil {
+		return nil, fmt.Errorf("get client %s: %w", clientID, err)
+	}
+	return &client, nil
+}
+
+// CreateOAuth2Client registers an OAuth2 client.
+func (c *HydraClient) CreateOAuth2Client(ctx context.Context, client OAuth2Client) (*OAuth2Client, error) {
+	jsonBytes, err := json.Marshal(client)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request body: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/admin/clients", c.adminURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var created OAuth2Client
+	if err := c.doJSON(req, http.StatusCreated, &created); err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	return &created, nil
+}
+
+// DeleteOAuth2Client removes an OAuth2 client.
+func (c *HydraClient) DeleteOAuth2Client(ctx context.Context, clientID string) error {
+	u := fmt.Sprintf("%s/admin/clients/%s", c.adminURL, url.PathEscape(clientID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	return c.doNoContent(req)
+}
+
 // GetLogoutRequest fetches the logout request for the given challenge.
 func (c *HydraClient) GetLogoutRequest(ctx context.Context, challenge string) (*LogoutRequest, error) {
 	u := fmt.Sprintf("%s/admin/oauth2/auth/requests/logout?logout_challenge=%s", c.adminURL, url.QueryEscape(challenge))
@@ -261,6 +319,9 @@ func (c *HydraClient) doJSON(req *http.Request, expectedStatus int, out interfac
 
 	if resp.StatusCode != expectedStatus {
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: unexpected status %d: %s", ErrNotFound, resp.StatusCode, string(body))
+		}
 		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
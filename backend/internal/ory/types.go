@@ -19,10 +19,14 @@ type LoginRequest struct {
 
 // OAuth2Client represents a Hydra OAuth2 client.
 type OAuth2Client struct {
-	ClientID   string                 `json:"client_id"`
-	ClientName string                 `json:"client_name,omitempty"`
-	Scope      string                 `json:"scope,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	ClientID                string                 `json:"client_id"`
+	ClientName              string                 `json:"client_name,omitempty"`
+	Scope                   string                 `json:"scope,omitempty"`
+	Metadata                map[string]interface{} `json:"metadata,omitempty"`
+	GrantTypes              []string               `json:"grant_types,omitempty"`
+	ResponseTypes           []string               `json:"response_types,omitempty"`
+	RedirectURIs            []string               `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string                 `json:"token_endpoint_auth_method,omitempty"`
 }
 
 // OIDCContext contains OpenID Connect context for the login/consent request.
@@ -22,6 +22,8 @@ type Config struct {
 	DMHandler          *handler.DMHandler
 	OryHandler         *handler.OryHandler
 	LiveKitHandler     *handler.LiveKitHandler
+	// LiveKitWebhooks mounts the LiveKit webhook; off, clients report voice state
+	LiveKitWebhooks    bool
 	UserHandler        *handler.UserHandler
 	EmojiHandler       *handler.EmojiHandler
 	GifHandler         *handler.GifHandler
@@ -47,11 +49,15 @@ type Config struct {
 	StageHandler       *handler.StageHandler
 	SoundboardHandler  *handler.SoundboardHandler
 	BotHandler         *handler.BotHandler
+	BotAuthorizationHandler *handler.BotAuthorizationHandler
+	InteractionHandler *handler.InteractionHandler
 	WebhookHandler     *handler.WebhookHandler
+	EventSubscriptionHandler *handler.EventSubscriptionHandler
 	ExportHandler      *handler.ExportHandler
 	UploadHandler      *handler.UploadHandler
 	KeysHandler        *handler.KeysHandler
 	JWKSManager        *auth.JWKSManager
+	BotValidator       auth.BotValidator
 	Hub                *ws.Hub
 	CoMemberIDsFn      ws.CoMemberIDsFn
 	ServerMemberIDsFn  ws.ServerMemberIDsFn
@@ -98,10 +104,19 @@ func Setup(app *fiber.App, cfg Config) {
 		Window: time.Minute,
 		KeyFunc: middleware.UserKeyFunc,
 	})
-	webhookExecRateLimit := middleware.RateLimit(middleware.RateLimitConfig{
+	webhookIPRateLimit := middleware.RateLimit(middleware.RateLimitConfig{
 		Max:    10,
 		Window: time.Second,
 	})
+	webhookExecRateLimit := middleware.RateLimit(middleware.RateLimitConfig{
+		Max:     5,
+		Window:  2 * time.Second,
+		KeyFunc: middleware.WebhookKeyFunc,
+	})
+	interactionCallbackRateLimit := middleware.RateLimit(middleware.RateLimitConfig{
+		Max:    20,
+		Window: time.Second,
+	})
 	webhookCrudRateLimit := middleware.RateLimit(middleware.RateLimitConfig{
 		Max:     10,
 		Window:  time.Minute,
@@ -132,6 +147,15 @@ func Setup(app *fiber.App, cfg Config) {
 		oryAuth.Get("/error", cfg.OryHandler.GetError)
 	}
 
+	// Bot authorization: server admins add a bot through an OAuth2 consent
+	// flow that grants it a managed role
+	if cfg.BotAuthorizationHandler != nil {
+		botAuth := app.Group("/auth/bot", authRateLimit)
+		botAuth.Get("/authorize", cfg.BotAuthorizationHandler.Authorize)
+		botAuth.Post("/consent", cfg.BotAuthorizationHandler.SubmitConsent)
+		botAuth.Get("/authorized", cfg.BotAuthorizationHandler.Authorized)
+	}
+
 	api := app.Group("/api", apiRateLimit)
 
 	// Public routes (no auth)
@@ -146,13 +170,39 @@ func Setup(app *fiber.App, cfg Config) {
 		app.Get("/api/files/+", cfg.AttachmentHandler.ServeFile)
 	}
 
-	// Webhook execute (public — no auth, token in URL)
+	// Auth for protected routes; bots authenticate with "Bot <token>" when enabled
+	authMiddleware := auth.Middleware(cfg.JWKSManager)
+	if cfg.BotValidator != nil {
+		authMiddleware = auth.BotOrUserMiddleware(cfg.JWKSManager, cfg.BotValidator)
+	}
+
+	// Webhook execute (public — no auth, token in URL). Every request counts
+	// against the caller's IP; only token-authenticated ones reach the
+	// per-webhook limit, so a bad token can't exhaust a webhook's budget.
 	if cfg.WebhookHandler != nil {
-		app.Post("/api/webhooks/:webhookId/:token", webhookExecRateLimit, cfg.WebhookHandler.ExecuteWebhook)
+		// Token rotation shares the execute route's shape, so it must be
+		// registered first or ":token" would match "token"
+		app.Post("/api/webhooks/:webhookId/token", authMiddleware, webhookCrudRateLimit, cfg.WebhookHandler.RotateWebhookToken)
+		requireToken := cfg.WebhookHandler.RequireToken
+		app.Post("/api/webhooks/:webhookId/:token", webhookIPRateLimit, requireToken, webhookExecRateLimit, cfg.WebhookHandler.ExecuteWebhook)
+		app.Post("/api/webhooks/:webhookId/:token/:adapter", webhookIPRateLimit, requireToken, webhookExecRateLimit, cfg.WebhookHandler.ExecuteAdapter)
+		app.Patch("/api/webhooks/:webhookId/:token/messages/:messageId", webhookIPRateLimit, requireToken, webhookExecRateLimit, cfg.WebhookHandler.EditWebhookMessage)
+		app.Delete("/api/webhooks/:webhookId/:token/messages/:messageId", webhookIPRateLimit, requireToken, webhookExecRateLimit, cfg.WebhookHandler.DeleteWebhookMessage)
+	}
+
+	// Interaction responses (public — authenticated by the interaction token)
+	if cfg.InteractionHandler != nil {
+		app.Post("/api/interactions/:interactionId/:token/callback", interactionCallbackRateLimit, cfg.InteractionHandler.Callback)
+		app.Post("/api/interactions/:interactionId/:token/followup", interactionCallbackRateLimit, cfg.InteractionHandler.FollowUp)
+	}
+
+	// LiveKit webhook (public — authenticated by the LiveKit signature)
+	if cfg.LiveKitHandler != nil && cfg.LiveKitWebhooks {
+		app.Post("/api/livekit/webhook", cfg.LiveKitHandler.HandleWebhook)
 	}
 
 	// Protected routes
-	protected := api.Group("", auth.Middleware(cfg.JWKSManager))
+	protected := api.Group("", authMiddleware)
 
 	// User
 	protected.Get("/me", func(c fiber.Ctx) error {
@@ -215,8 +265,12 @@ func Setup(app *fiber.App, cfg Config) {
 	protected.Post("/channels/:channelId/messages", messageSendRateLimit, cfg.MessageHandler.SendMessage)
 	protected.Get("/channels/:channelId/messages", cfg.MessageHandler.GetMessages)
 	protected.Get("/channels/:channelId/messages/around", cfg.MessageHandler.GetMessagesAround)
+	protected.Post("/channels/:channelId/messages/bulk-delete", cfg.MessageHandler.BulkDeleteMessages)
+	protected.Post("/channels/:channelId/messages/purge", cfg.MessageHandler.PurgeMessages)
+	protected.Get("/channels/:channelId/messages/deleted", cfg.MessageHandler.GetDeletedMessages)
 	protected.Put("/messages/:id", cfg.MessageHandler.UpdateMessage)
 	protected.Delete("/messages/:id", cfg.MessageHandler.DeleteMessage)
+	protected.Post("/messages/:id/restore", cfg.MessageHandler.RestoreMessage)
 
 	// Pins
 	protected.Put("/channels/:channelId/pins/:messageId", cfg.MessageHandler.PinMessage)
@@ -446,17 +500,51 @@ func Setup(app *fiber.App, cfg Config) {
 
 	// Bots
 	if cfg.BotHandler != nil {
-		protected.Post("/bots", cfg.BotHandler.CreateBot)
-		protected.Get("/bots", cfg.BotHandler.ListBots)
-		protected.Delete("/bots/:botId", cfg.BotHandler.DeleteBot)
-		protected.Post("/bots/:botId/regenerate-token", cfg.BotHandler.RegenerateToken)
+		// Bots cannot create or manage other bots
+		bots := protected.Group("/bots", auth.RequireUser())
+		bots.Post("", cfg.BotHandler.CreateBot)
+		bots.Get("", cfg.BotHandler.ListBots)
+		bots.Delete("/:botId", cfg.BotHandler.DeleteBot)
+		bots.Post("/:botId/regenerate-token", cfg.BotHandler.RegenerateToken)
+		bots.Put("/:botId/interactions-endpoint", cfg.BotHandler.UpdateInteractionsEndpoint)
+		bots.Put("/:botId/permissions", cfg.BotHandler.UpdatePermissions)
+	}
+	if cfg.BotAuthorizationHandler != nil {
+		protected.Delete("/servers/:id/bots/:botId", auth.RequireUser(), cfg.BotAuthorizationHandler.RemoveBot)
+	}
+
+	// Slash commands and message components: bots register commands,
+	// members invoke them and click components
+	if cfg.InteractionHandler != nil {
+		botOnly := auth.RequireBot()
+		protected.Get("/commands", botOnly, cfg.InteractionHandler.ListCommands)
+		protected.Post("/commands", botOnly, cfg.InteractionHandler.RegisterCommand)
+		protected.Delete("/commands/:commandId", botOnly, cfg.InteractionHandler.DeleteCommand)
+		protected.Get("/servers/:serverId/commands", botOnly, cfg.InteractionHandler.ListCommands)
+		protected.Post("/servers/:serverId/commands", botOnly, cfg.InteractionHandler.RegisterCommand)
+		protected.Get("/channels/:channelId/commands", cfg.InteractionHandler.GetChannelCommands)
+		protected.Post("/channels/:channelId/interactions", cfg.InteractionHandler.CreateInteraction)
+		protected.Post("/messages/:id/components", cfg.InteractionHandler.ComponentInteraction)
 	}
 
 	// Webhooks (CRUD — protected)
 	if cfg.WebhookHandler != nil {
 		protected.Get("/channels/:channelId/webhooks", cfg.WebhookHandler.ListWebhooks)
 		protected.Post("/channels/:channelId/webhooks", webhookCrudRateLimit, cfg.WebhookHandler.CreateWebhook)
+		protected.Get("/servers/:id/webhooks", cfg.WebhookHandler.ListServerWebhooks)
+		protected.Patch("/webhooks/:webhookId", webhookCrudRateLimit, cfg.WebhookHandler.UpdateWebhook)
 		protected.Delete("/webhooks/:webhookId", webhookCrudRateLimit, cfg.WebhookHandler.DeleteWebhook)
+		protected.Put("/webhooks/:webhookId/interactions-endpoint", webhookCrudRateLimit, cfg.WebhookHandler.UpdateInteractionsEndpoint)
+		protected.Put("/webhooks/:webhookId/adapter-secret", webhookCrudRateLimit, cfg.WebhookHandler.UpdateAdapterSecret)
+	}
+
+	// Event subscriptions (outgoing event webhooks — protected)
+	if cfg.EventSubscriptionHandler != nil {
+		protected.Get("/servers/:id/event-subscriptions", cfg.EventSubscriptionHandler.ListSubscriptions)
+		protected.Post("/servers/:id/event-subscriptions", webhookCrudRateLimit, cfg.EventSubscriptionHandler.CreateSubscription)
+		protected.Patch("/event-subscriptions/:subscriptionId", webhookCrudRateLimit, cfg.EventSubscriptionHandler.UpdateSubscription)
+		protected.Delete("/event-subscriptions/:subscriptionId", webhookCrudRateLimit, cfg.EventSubscriptionHandler.DeleteSubscription)
+		protected.Get("/event-subscriptions/:subscriptionId/deliveries", cfg.EventSubscriptionHandler.ListDeliveries)
 	}
 
 	// Exports
@@ -495,3 +583,12 @@ func Setup(app *fiber.App, cfg Config) {
 	// WebSocket
 	app.Get("/ws", wsConnRateLimit, ws.Handler(cfg.Hub, cfg.JWKSManager, cfg.CoMemberIDsFn, cfg.ServerMemberIDsFn))
 }
+
+// SetupInternal registers operator endpoints on the internal listener. They
+// have no auth, so the listener must not be reachable from outside.
+func SetupInternal(app *fiber.App, hub *ws.Hub) {
+	app.Use(recover.New())
+	app.Get("/health/ws", func(c fiber.Ctx) error {
+		return c.JSON(hub.Stats())
+	})
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/handler"
+	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	hub := ws.NewHub()
+	app := fiber.New()
+	Setup(app, Config{
+		// Requests in these tests never reach the database
+		WebhookHandler: handler.NewWebhookHandler(service.NewWebhookService(nil, nil), nil, hub),
+		JWKSManager:    auth.NewJWKSManager("http://127.0.0.1:0/.well-known/jwks.json"),
+		Hub:            hub,
+		CORSOrigin:     "http://localhost:5173",
+	})
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path string) (int, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(method, path, nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body.Error
+}
+
+func TestRouter_WebhookTokenRotationIsNotAnExecute(t *testing.T) {
+	app := newTestApp(t)
+
+	// Rotation is a protected route, so it must reach the auth middleware
+	// rather than the public execute route's token check
+	status, msg := doRequest(t, app, http.MethodPost, "/api/webhooks/"+uuid.NewString()+"/token")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "missing authorization header", msg)
+
+	// Any other token still goes to the execute route
+	status, msg = doRequest(t, app, http.MethodPost, "/api/webhooks/not-a-uuid/abc123")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid webhook ID", msg)
+}
@@ -3,8 +3,14 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -19,14 +25,19 @@ var (
 	ErrBotNameTaken    = errors.New("bot username is already taken")
 	ErrInvalidBotName  = errors.New("bot username must be 1-32 characters")
 	ErrInvalidBotToken = errors.New("invalid bot token")
+
+	ErrInvalidInteractionsURL = errors.New("interactions_url must be a public http(s) URL")
+	ErrInvalidBotPermissions  = errors.New("permissions contain unknown bits")
+	ErrNotABot                = errors.New("user is not a bot")
 )
 
 type BotService struct {
 	queries *models.Queries
+	permSvc *PermissionService
 }
 
-func NewBotService(q *models.Queries) *BotService {
-	return &BotService{queries: q}
+func NewBotService(q *models.Queries, permSvc *PermissionService) *BotService {
+	return &BotService{queries: q, permSvc: permSvc}
 }
 
 // GenerateToken creates a cryptographically random token (32 bytes, hex-encoded).
@@ -52,26 +63,47 @@ func ValidateToken(token, hash string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) == nil
 }
 
+// generateBotToken returns a bot token of the form "<token_id>.<secret>"
+// along with its parts. The token ID is stored for lookup; only a hash of
+// the secret is stored.
+func generateBotToken() (token, tokenID, secret string, err error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret, err = GenerateToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	tokenID = hex.EncodeToString(id)
+	return tokenID + "." + secret, tokenID, secret, nil
+}
+
+// hashSecret hashes a random token secret (bot and interaction tokens). The
+// secrets are 256 random bits, so a fast hash is enough and keeps
+// per-request validation cheap.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
 // CreateBot creates a new bot user and returns the bot + plaintext token (shown once).
 func (s *BotService) CreateBot(ctx context.Context, ownerID uuid.UUID, username string) (*models.BotUser, string, error) {
 	if len(username) < 1 || len(username) > 32 {
 		return nil, "", ErrInvalidBotName
 	}
 
-	token, err := GenerateToken()
-	if err != nil {
-		return nil, "", err
-	}
-
-	tokenHash, err := HashToken(token)
+	token, tokenID, secret, err := generateBotToken()
 	if err != nil {
 		return nil, "", err
 	}
 
 	bot, err := s.queries.CreateBotUser(ctx, models.CreateBotUserParams{
+		ID:        uuid.New(),
 		OwnerID:   ownerID,
 		Username:  username,
-		TokenHash: tokenHash,
+		TokenID:   tokenID,
+		TokenHash: hashSecret(secret),
 	})
 	if err != nil {
 		// Check for unique constraint violation on username
@@ -84,6 +116,18 @@ func (s *BotService) CreateBot(ctx context.Context, ownerID uuid.UUID, username
 	return &bot, token, nil
 }
 
+// GetBot returns a bot by ID.
+func (s *BotService) GetBot(ctx context.Context, botID uuid.UUID) (*models.BotUser, error) {
+	bot, err := s.queries.GetBotUserByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	return &bot, nil
+}
+
 // ListBots returns all bots owned by the given user.
 func (s *BotService) ListBots(ctx context.Context, ownerID uuid.UUID) ([]models.BotUser, error) {
 	return s.queries.GetBotUsersByOwner(ctx, ownerID)
@@ -117,45 +161,283 @@ func (s *BotService) RegenerateToken(ctx context.Context, botID, ownerID uuid.UU
 		return "", ErrBotNotOwner
 	}
 
-	token, err := GenerateToken()
+	token, tokenID, secret, err := generateBotToken()
 	if err != nil {
 		return "", err
 	}
 
-	tokenHash, err := HashToken(token)
-	if err != nil {
+	if err := s.queries.UpdateBotToken(ctx, botID, tokenID, hashSecret(secret)); err != nil {
 		return "", err
 	}
 
-	if err := s.queries.UpdateBotTokenHash(ctx, botID, tokenHash); err != nil {
-		return "", err
+	return token, nil
+}
+
+// SetInteractionsURL points the bot's interactions at an HTTP endpoint, or
+// back to the gateway when rawURL is empty. A new signing secret is issued
+// with every endpoint and returned once.
+func (s *BotService) SetInteractionsURL(ctx context.Context, botID, ownerID uuid.UUID, rawURL string) (*models.BotUser, string, error) {
+	bot, err := s.queries.GetBotUserByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", ErrBotNotFound
+		}
+		return nil, "", err
+	}
+	if bot.OwnerID != ownerID {
+		return nil, "", ErrBotNotOwner
 	}
 
-	return token, nil
+	secret, err := newInteractionsSecret(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	updated, err := s.queries.UpdateBotInteractionsEndpoint(ctx, botID, rawURL, secret)
+	if err != nil {
+		return nil, "", err
+	}
+	return &updated, secret, nil
 }
 
-// ValidateBotToken checks a token against all bots and returns the matching bot.
-func (s *BotService) ValidateBotToken(ctx context.Context, token string) (*models.BotUser, error) {
-	bots, err := s.queries.GetAllBotUsers(ctx)
+// newInteractionsSecret validates an interactions endpoint URL and returns a
+// fresh signing secret for it, or no secret when the URL is being cleared.
+func newInteractionsSecret(rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", nil
+	}
+	if !isPublicEndpoint(rawURL) {
+		return "", ErrInvalidInteractionsURL
+	}
+	return GenerateToken()
+}
+
+// isPublicEndpoint reports whether rawURL is an http(s) URL we may POST
+// callbacks to: not too long and not on a private network.
+func isPublicEndpoint(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(rawURL) > 2048 {
+		return false
+	}
+	return !isPrivateHost(parsed.Hostname())
+}
+
+// SetPermissions sets the permissions a bot requests by default when an
+// authorization link does not name any.
+func (s *BotService) SetPermissions(ctx context.Context, botID, ownerID uuid.UUID, permissions int64) (*models.BotUser, error) {
+	if permissions&^models.PermAll != 0 {
+		return nil, ErrInvalidBotPermissions
+	}
+
+	bot, err := s.queries.GetBotUserByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if bot.OwnerID != ownerID {
+		return nil, ErrBotNotOwner
+	}
+
+	updated, err := s.queries.UpdateBotPermissions(ctx, botID, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// BotAuthorization is the outcome of authorizing a bot into a server.
+type BotAuthorization struct {
+	Bot  *models.BotUser
+	Role *models.Role
+	// Joined is false when the bot was already a member and only its managed
+	// role's permissions changed.
+	Joined bool
+}
+
+// AuthorizableServers returns the servers the user may add bots to.
+func (s *BotService) AuthorizableServers(ctx context.Context, userID uuid.UUID) ([]models.Server, error) {
+	servers, err := s.queries.GetUserServers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]models.Server, 0, len(servers))
+	for _, server := range servers {
+		ok, err := s.permSvc.HasServerPermission(ctx, server.ID, userID, models.PermManageServer)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			allowed = append(allowed, server)
+		}
+	}
+	return allowed, nil
+}
+
+// AuthorizeBot adds a bot to a server with a managed role holding exactly
+// the approved permissions. The actor needs MANAGE_SERVER and cannot grant
+// permissions they do not have. Authorizing a bot that is already a member
+// replaces its managed role's permissions.
+func (s *BotService) AuthorizeBot(ctx context.Context, botID, serverID, actorID uuid.UUID, permissions int64) (*BotAuthorization, error) {
+	if permissions&^models.PermAll != 0 {
+		return nil, ErrInvalidBotPermissions
+	}
+
+	bot, err := s.GetBot(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	actorPerms, err := s.permSvc.ComputePermissions(ctx, serverID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.HasPermission(actorPerms, models.PermManageServer) || !models.HasPermission(actorPerms, permissions) {
+		return nil, ErrInsufficientRole
+	}
+
+	if banned, err := s.queries.IsUserBanned(ctx, serverID, botID); err == nil && banned {
+		return nil, ErrUserBanned
+	}
+
+	targetType := "bot"
+	changes, _ := json.Marshal(map[string]string{"permissions": strconv.FormatInt(permissions, 10)})
+
+	_, err = s.queries.GetServerMember(ctx, serverID, botID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		role, err := s.queries.AddBotMember(ctx, models.AddBotMemberParams{
+			ServerID:    serverID,
+			BotID:       botID,
+			RoleName:    bot.Username,
+			Permissions: permissions,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_ = s.queries.InsertAuditLog(ctx, serverID, actorID, "BOT_ADD", &botID, &targetType, changes, "")
+		return &BotAuthorization{Bot: bot, Role: &role, Joined: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.queries.GetManagedRole(ctx, serverID, botID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		maxPos, err := s.queries.GetMaxRolePosition(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		role, err = s.queries.CreateRole(ctx, models.CreateRoleParams{
+			ServerID:     serverID,
+			Name:         bot.Username,
+			Position:     maxPos + 1,
+			Permissions:  permissions,
+			ManagedBotID: &botID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.queries.AssignRole(ctx, serverID, botID, role.ID); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		role, err = s.queries.UpdateRole(ctx, models.UpdateRoleParams{ID: role.ID, Permissions: &permissions})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_ = s.queries.InsertAuditLog(ctx, serverID, actorID, "BOT_PERMISSIONS_UPDATE", &botID, &targetType, changes, "")
+	return &BotAuthorization{Bot: bot, Role: &role}, nil
+}
+
+// RemoveBot removes a bot from a server. Its managed role goes with the
+// membership and is returned so callers can announce the deletion.
+func (s *BotService) RemoveBot(ctx context.Context, serverID, botID, actorID uuid.UUID) (*models.Role, error) {
+	ok, err := s.permSvc.HasServerPermission(ctx, serverID, actorID, models.PermManageServer)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, ErrInsufficientRole
+	}
+
+	user, err := s.queries.GetUserByID(ctx, botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if !user.IsBot {
+		return nil, ErrNotABot
+	}
+
+	if _, err := s.queries.GetServerMember(ctx, serverID, botID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+
+	var managed *models.Role
+	if role, err := s.queries.GetManagedRole(ctx, serverID, botID); err == nil {
+		managed = &role
+	}
+
+	if err := s.queries.RemoveServerMember(ctx, serverID, botID); err != nil {
+		return nil, err
+	}
+
+	targetType := "bot"
+	_ = s.queries.InsertAuditLog(ctx, serverID, actorID, "BOT_REMOVE", &botID, &targetType, nil, "")
+
+	return managed, nil
+}
+
+// ValidateBotToken looks the bot up by the token's ID prefix and checks the
+// secret against the stored hash.
+func (s *BotService) ValidateBotToken(ctx context.Context, token string) (*models.BotUser, error) {
+	tokenID, secret, ok := strings.Cut(token, ".")
+	if !ok || tokenID == "" || secret == "" {
+		return nil, ErrInvalidBotToken
+	}
 
-	for _, bot := range bots {
-		if ValidateToken(token, bot.TokenHash) {
-			return &bot, nil
+	bot, err := s.queries.GetBotUserByTokenID(ctx, tokenID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidBotToken
 		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(bot.TokenHash)) != 1 {
+		return nil, ErrInvalidBotToken
 	}
+	return &bot, nil
+}
 
-	return nil, ErrInvalidBotToken
+// AuthenticateBot validates a bot token for auth.BotOrUserMiddleware and the
+// gateway, returning the bot's user ID and username.
+func (s *BotService) AuthenticateBot(ctx context.Context, token string) (uuid.UUID, string, error) {
+	bot, err := s.ValidateBotToken(ctx, token)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return bot.ID, bot.Username, nil
 }
 
 // isDuplicateKeyError checks if a pgx error is a unique constraint violation.
 func isDuplicateKeyError(err error) bool {
 	// pgconn.PgError code 23505 = unique_violation
-	var pgErr interface{ Code() string }
+	var pgErr interface{ SQLState() string }
 	if errors.As(err, &pgErr) {
-		return pgErr.Code() == "23505"
+		return pgErr.SQLState() == "23505"
 	}
 	return false
 }
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+)
+
+func TestBotService_TokenLifecycle(t *testing.T) {
+	svc := NewBotService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	bot, token, err := svc.CreateBot(ctx, owner.User.ID, "helper_"+owner.User.ID.String()[:8])
+	require.NoError(t, err)
+	assert.Contains(t, token, ".")
+
+	// The bot is backed by a users row so it can join servers and author messages
+	user, err := queries().GetUserByID(ctx, bot.ID)
+	require.NoError(t, err)
+	assert.True(t, user.IsBot)
+	assert.Equal(t, bot.Username, user.Username)
+
+	botID, username, err := svc.AuthenticateBot(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, bot.ID, botID)
+	assert.Equal(t, bot.Username, username)
+
+	tokenID, _, _ := strings.Cut(token, ".")
+	for _, bad := range []string{"", "nodot", tokenID + ".wrong", "unknown." + strings.Repeat("a", 64)} {
+		_, err := svc.ValidateBotToken(ctx, bad)
+		assert.ErrorIs(t, err, ErrInvalidBotToken, bad)
+	}
+
+	newToken, err := svc.RegenerateToken(ctx, bot.ID, owner.User.ID)
+	require.NoError(t, err)
+	_, err = svc.ValidateBotToken(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidBotToken, "old token is revoked")
+	_, err = svc.ValidateBotToken(ctx, newToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.DeleteBot(ctx, bot.ID, owner.User.ID))
+	_, err = queries().GetUserByID(ctx, bot.ID)
+	assert.Error(t, err)
+	_, err = svc.ValidateBotToken(ctx, newToken)
+	assert.ErrorIs(t, err, ErrInvalidBotToken)
+}
+
+func TestBotService_DuplicateName(t *testing.T) {
+	svc := NewBotService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+	name := "dupe_" + owner.User.ID.String()[:8]
+
+	_, _, err := svc.CreateBot(ctx, owner.User.ID, name)
+	require.NoError(t, err)
+	_, _, err = svc.CreateBot(ctx, owner.User.ID, name)
+	assert.ErrorIs(t, err, ErrBotNameTaken)
+}
+
+func TestBotService_AuthorizeBot(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewBotService(queries(), permSvc)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	bot, _, err := svc.CreateBot(ctx, owner.User.ID, "authz_"+owner.User.ID.String()[:8])
+	require.NoError(t, err)
+
+	perms := models.PermSendMessages | models.PermManageMessages
+	_, err = svc.AuthorizeBot(ctx, bot.ID, server.ID, member.User.ID, perms)
+	assert.ErrorIs(t, err, ErrInsufficientRole, "members without MANAGE_SERVER cannot add bots")
+	_, err = svc.AuthorizeBot(ctx, bot.ID, server.ID, owner.User.ID, 1<<62)
+	assert.ErrorIs(t, err, ErrInvalidBotPermissions)
+
+	authz, err := svc.AuthorizeBot(ctx, bot.ID, server.ID, owner.User.ID, perms)
+	require.NoError(t, err)
+	assert.True(t, authz.Joined)
+	require.NotNil(t, authz.Role.ManagedBotID)
+	assert.Equal(t, bot.ID, *authz.Role.ManagedBotID)
+	assert.Equal(t, perms, authz.Role.Permissions)
+
+	botPerms, err := permSvc.ComputePermissions(ctx, server.ID, bot.ID)
+	require.NoError(t, err)
+	assert.True(t, models.HasPermission(botPerms, perms))
+
+	members, err := queries().GetServerMembers(ctx, server.ID)
+	require.NoError(t, err)
+	for _, m := range members {
+		assert.Equal(t, m.ID == bot.ID, m.IsBot, m.Username)
+	}
+
+	// Re-authorizing replaces the managed role's permissions in place
+	again, err := svc.AuthorizeBot(ctx, bot.ID, server.ID, owner.User.ID, models.PermSendMessages)
+	require.NoError(t, err)
+	assert.False(t, again.Joined)
+	assert.Equal(t, authz.Role.ID, again.Role.ID)
+	assert.Equal(t, models.PermSendMessages, again.Role.Permissions)
+
+	// Managed roles cannot be edited, deleted or handed to anyone else
+	roleSvc := NewRoleService(queries(), permSvc)
+	allPerms := int64(-1)
+	_, err = roleSvc.UpdateRole(ctx, server.ID, again.Role.ID, owner.User.ID, nil, nil, &allPerms, nil)
+	assert.ErrorIs(t, err, ErrManagedRole)
+	assert.ErrorIs(t, roleSvc.DeleteRole(ctx, server.ID, again.Role.ID, owner.User.ID), ErrManagedRole)
+	assert.ErrorIs(t, roleSvc.AssignRole(ctx, server.ID, member.User.ID, again.Role.ID, owner.User.ID), ErrManagedRole)
+
+	_, err = svc.RemoveBot(ctx, server.ID, member.User.ID, owner.User.ID)
+	assert.ErrorIs(t, err, ErrNotABot)
+	removed, err := svc.RemoveBot(ctx, server.ID, bot.ID, owner.User.ID)
+	require.NoError(t, err)
+	require.NotNil(t, removed)
+	assert.Equal(t, again.Role.ID, removed.ID)
+
+	_, err = queries().GetRoleByID(ctx, removed.ID)
+	assert.Error(t, err, "managed role is deleted with the membership")
+	_, err = svc.RemoveBot(ctx, server.ID, bot.ID, owner.User.ID)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
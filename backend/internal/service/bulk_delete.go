@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+// MaxBulkDelete is the most messages one bulk delete or purge removes.
+const MaxBulkDelete = 250
+
+var (
+	ErrNoMessagesToDelete = errors.New("at least one message ID is required")
+	ErrTooManyMessages    = errors.New("cannot delete more than 250 messages at once")
+	ErrEmptyPurgeFilter   = errors.New("purge needs an author, time range or content filter")
+	ErrInvalidPurgeRange  = errors.New("purge range must end after it starts")
+)
+
+// PurgeFilter selects the channel messages a purge deletes. At least one of
+// AuthorID, After, Before and Contains must be set; Contains matches content
+// case-insensitively. Limit caps the messages deleted, newest first.
+type PurgeFilter struct {
+	AuthorID *uuid.UUID
+	After    *time.Time
+	Before   *time.Time
+	Contains string
+	Limit    int32
+}
+
+// likeEscaper escapes LIKE wildcards so purge content filters match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// BulkDeleteMessages deletes the given messages from a channel and records
+// it in the audit log. IDs of messages in other channels or already deleted
+// are skipped; the IDs actually deleted are returned. Requires
+// ManageMessages in the channel.
+func (s *MessageService) BulkDeleteMessages(ctx context.Context, channelID, userID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(messageIDs))
+	seen := make(map[uuid.UUID]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoMessagesToDelete
+	}
+	if len(ids) > MaxBulkDelete {
+		return nil, ErrTooManyMessages
+	}
+
+	channel, err := s.checkManageMessages(ctx, channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.queries.DeleteChannelMessages(ctx, channelID, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditBulkDelete(ctx, channel, userID, "MESSAGE_BULK_DELETE", map[string]any{"count": len(deleted)}, reason)
+	return deleted, nil
+}
+
+// PurgeMessages deletes the newest channel messages matching a filter and
+// records it in the audit log, returning the IDs deleted. Requires
+// ManageMessages in the channel.
+func (s *MessageService) PurgeMessages(ctx context.Context, channelID, userID uuid.UUID, filter PurgeFilter, reason string) ([]uuid.UUID, error) {
+	filter.Contains = strings.TrimSpace(filter.Contains)
+	if filter.AuthorID == nil && filter.After == nil && filter.Before == nil && filter.Contains == "" {
+		return nil, ErrEmptyPurgeFilter
+	}
+	if filter.After != nil && filter.Before != nil && !filter.Before.After(*filter.After) {
+		return nil, ErrInvalidPurgeRange
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxBulkDelete {
+		filter.Limit = MaxBulkDelete
+	}
+
+	channel, err := s.checkManageMessages(ctx, channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Content is stored HTML-escaped, so the filter is escaped the same way
+	deleted, err := s.queries.PurgeChannelMessages(ctx, models.PurgeChannelMessagesParams{
+		ChannelID: channelID,
+		DeletedBy: userID,
+		AuthorID:  filter.AuthorID,
+		After:     filter.After,
+		Before:    filter.Before,
+		Contains:  likeEscaper.Replace(html.EscapeString(filter.Contains)),
+		Limit:     filter.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]any{"count": len(deleted)}
+	if filter.AuthorID != nil {
+		changes["author_id"] = filter.AuthorID
+	}
+	if filter.After != nil {
+		changes["after"] = filter.After
+	}
+	if filter.Before != nil {
+		changes["before"] = filter.Before
+	}
+	if filter.Contains != "" {
+		changes["contains"] = filter.Contains
+	}
+	s.auditBulkDelete(ctx, channel, userID, "MESSAGE_PURGE", changes, reason)
+	return deleted, nil
+}
+
+// checkManageMessages returns the channel if the user may moderate its
+// messages.
+func (s *MessageService) checkManageMessages(ctx context.Context, channelID, userID uuid.UUID) (models.Channel, error) {
+	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Channel{}, ErrChannelNotFound
+		}
+		return models.Channel{}, err
+	}
+	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Channel{}, ErrNotMember
+		}
+		return models.Channel{}, err
+	}
+
+	ok, err := s.permSvc.HasChannelPermission(ctx, channelID, userID, models.PermManageMessages)
+	if err != nil {
+		return models.Channel{}, err
+	}
+	if !ok {
+		return models.Channel{}, ErrInsufficientRole
+	}
+	return channel, nil
+}
+
+func (s *MessageService) auditBulkDelete(ctx context.Context, channel models.Channel, actorID uuid.UUID, action string, changes map[string]any, reason string) {
+	targetType := "channel"
+	data, _ := json.Marshal(changes)
+	_ = s.queries.InsertAuditLog(ctx, channel.ServerID, actorID, action, &channel.ID, &targetType, data, reason)
+}
@@ -39,6 +39,7 @@ func (s *CleanupService) run() {
 	select {
 	case <-timer.C:
 		s.cleanup()
+		s.cleanupDeletedMessages()
 		s.cleanupPendingUploads()
 	case <-s.done:
 		timer.Stop()
@@ -56,8 +57,12 @@ func (s *CleanupService) run() {
 		select {
 		case <-uploadTicker.C:
 			s.cleanupPendingUploads()
+			s.cleanupInteractions()
+			s.cleanupEventDeliveries()
+			s.cleanupNonces()
 		case <-retentionTicker.C:
 			s.cleanup()
+			s.cleanupDeletedMessages()
 		case <-s.done:
 			return
 		}
@@ -101,6 +106,19 @@ func (s *CleanupService) cleanup() {
 	}
 }
 
+// cleanupDeletedMessages permanently removes deleted messages once
+// moderators can no longer restore them.
+func (s *CleanupService) cleanupDeletedMessages() {
+	purged, err := s.queries.PurgeDeletedMessages(context.Background(), time.Now().Add(-DeletedMessageRetention))
+	if err != nil {
+		log.Printf("[Cleanup] Failed to purge deleted messages: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("[Cleanup] Purged %d deleted messages past retention", purged)
+	}
+}
+
 func (s *CleanupService) cleanupPendingUploads() {
 	ctx := context.Background()
 
@@ -121,3 +139,39 @@ func (s *CleanupService) cleanupPendingUploads() {
 
 	log.Printf("[Cleanup] Cleaned up %d expired pending uploads", len(expired))
 }
+
+// cleanupInteractions removes interactions well past their token lifetime.
+func (s *CleanupService) cleanupInteractions() {
+	deleted, err := s.queries.DeleteExpiredInteractions(context.Background(), time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("[Cleanup] Failed to delete expired interactions: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[Cleanup] Deleted %d expired interactions", deleted)
+	}
+}
+
+// cleanupEventDeliveries trims the event subscription delivery log.
+func (s *CleanupService) cleanupEventDeliveries() {
+	deleted, err := s.queries.DeleteOldEventDeliveries(context.Background(), time.Now().Add(-EventDeliveryRetention))
+	if err != nil {
+		log.Printf("[Cleanup] Failed to delete old event deliveries: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("[Cleanup] Deleted %d old event deliveries", deleted)
+	}
+}
+
+// cleanupNonces releases send nonces once retries can no longer match them.
+func (s *CleanupService) cleanupNonces() {
+	released, err := s.queries.ReleaseExpiredNonces(context.Background(), time.Now().Add(-NonceWindow))
+	if err != nil {
+		log.Printf("[Cleanup] Failed to release expired nonces: %v", err)
+		return
+	}
+	if released > 0 {
+		log.Printf("[Cleanup] Released %d expired message nonces", released)
+	}
+}
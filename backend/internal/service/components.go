@@ -0,0 +1,295 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidComponents      = errors.New("invalid message components")
+	ErrInvalidComponentValues = errors.New("invalid component values")
+)
+
+// Component types. Rows hold either up to five buttons or a single select.
+const (
+	ComponentRow           = "row"
+	ComponentButton        = "button"
+	ComponentStringSelect  = "string_select"
+	ComponentUserSelect    = "user_select"
+	ComponentRoleSelect    = "role_select"
+	ComponentChannelSelect = "channel_select"
+)
+
+// Button styles. Link buttons open their URL and never create interactions.
+const (
+	ButtonPrimary   = "primary"
+	ButtonSecondary = "secondary"
+	ButtonSuccess   = "success"
+	ButtonDanger    = "danger"
+	ButtonLink      = "link"
+)
+
+const (
+	maxComponentRows     = 5
+	maxButtonsPerRow     = 5
+	maxSelectOptions     = 25
+	maxCustomIDLength    = 100
+	maxComponentLabel    = 80
+	maxSelectPlaceholder = 150
+)
+
+// ActionRow is one row of message components.
+type ActionRow struct {
+	Type       string      `json:"type"`
+	Components []Component `json:"components"`
+}
+
+// Component is a button or select menu on a message.
+type Component struct {
+	Type        string         `json:"type"`
+	CustomID    string         `json:"custom_id,omitempty"`
+	Label       string         `json:"label,omitempty"`
+	Style       string         `json:"style,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Disabled    bool           `json:"disabled,omitempty"`
+	Placeholder string         `json:"placeholder,omitempty"`
+	Options     []SelectOption `json:"options,omitempty"`
+	MinValues   *int           `json:"min_values,omitempty"`
+	MaxValues   *int           `json:"max_values,omitempty"`
+}
+
+// SelectOption is one choice in a string select.
+type SelectOption struct {
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default,omitempty"`
+}
+
+func (c *Component) isSelect() bool {
+	switch c.Type {
+	case ComponentStringSelect, ComponentUserSelect, ComponentRoleSelect, ComponentChannelSelect:
+		return true
+	}
+	return false
+}
+
+// valueBounds returns how many values a select accepts (default exactly one).
+func (c *Component) valueBounds() (int, int) {
+	lo, hi := 1, 1
+	if c.MinValues != nil {
+		lo = *c.MinValues
+	}
+	if c.MaxValues != nil {
+		hi = *c.MaxValues
+	}
+	return lo, hi
+}
+
+// ParseComponents validates component rows and returns them re-encoded, or
+// nil when there are none.
+func ParseComponents(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var rows []ActionRow
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidComponents, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	if err := validateComponents(rows); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rows)
+}
+
+func validateComponents(rows []ActionRow) error {
+	if len(rows) > maxComponentRows {
+		return fmt.Errorf("%w: at most %d rows", ErrInvalidComponents, maxComponentRows)
+	}
+
+	customIDs := make(map[string]bool)
+	for i, row := range rows {
+		if row.Type != ComponentRow {
+			return fmt.Errorf("%w: row %d must have type %q", ErrInvalidComponents, i, ComponentRow)
+		}
+		if len(row.Components) == 0 {
+			return fmt.Errorf("%w: row %d is empty", ErrInvalidComponents, i)
+		}
+
+		for _, c := range row.Components {
+			switch {
+			case c.Type == ComponentButton:
+				if len(row.Components) > maxButtonsPerRow {
+					return fmt.Errorf("%w: row %d has more than %d buttons", ErrInvalidComponents, i, maxButtonsPerRow)
+				}
+				if err := validateButton(c); err != nil {
+					return err
+				}
+			case c.isSelect():
+				if len(row.Components) != 1 {
+					return fmt.Errorf("%w: a select must be alone in its row", ErrInvalidComponents)
+				}
+				if err := validateSelect(c); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%w: unknown component type %q", ErrInvalidComponents, c.Type)
+			}
+
+			if c.CustomID == "" {
+				continue
+			}
+			if customIDs[c.CustomID] {
+				return fmt.Errorf("%w: duplicate custom_id %q", ErrInvalidComponents, c.CustomID)
+			}
+			customIDs[c.CustomID] = true
+		}
+	}
+	return nil
+}
+
+func validateButton(c Component) error {
+	if c.Label == "" || len(c.Label) > maxComponentLabel {
+		return fmt.Errorf("%w: button label must be 1-%d characters", ErrInvalidComponents, maxComponentLabel)
+	}
+
+	switch c.Style {
+	case ButtonLink:
+		if c.CustomID != "" {
+			return fmt.Errorf("%w: link buttons cannot have a custom_id", ErrInvalidComponents)
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: link buttons need an http(s) url", ErrInvalidComponents)
+		}
+		return nil
+	case ButtonPrimary, ButtonSecondary, ButtonSuccess, ButtonDanger:
+	case "":
+		return fmt.Errorf("%w: button style is required", ErrInvalidComponents)
+	default:
+		return fmt.Errorf("%w: unknown button style %q", ErrInvalidComponents, c.Style)
+	}
+
+	if c.URL != "" {
+		return fmt.Errorf("%w: only link buttons can have a url", ErrInvalidComponents)
+	}
+	return validateCustomID(c.CustomID)
+}
+
+func validateSelect(c Component) error {
+	if err := validateCustomID(c.CustomID); err != nil {
+		return err
+	}
+	if len(c.Placeholder) > maxSelectPlaceholder {
+		return fmt.Errorf("%w: placeholder must be at most %d characters", ErrInvalidComponents, maxSelectPlaceholder)
+	}
+
+	lo, hi := c.valueBounds()
+	if lo < 0 || hi < 1 || lo > hi || hi > maxSelectOptions {
+		return fmt.Errorf("%w: min_values and max_values must satisfy 0 <= min <= max <= %d", ErrInvalidComponents, maxSelectOptions)
+	}
+
+	if c.Type != ComponentStringSelect {
+		if len(c.Options) > 0 {
+			return fmt.Errorf("%w: only string selects have options", ErrInvalidComponents)
+		}
+		return nil
+	}
+
+	if len(c.Options) == 0 || len(c.Options) > maxSelectOptions {
+		return fmt.Errorf("%w: string selects need 1-%d options", ErrInvalidComponents, maxSelectOptions)
+	}
+	if hi > len(c.Options) {
+		return fmt.Errorf("%w: max_values exceeds the number of options", ErrInvalidComponents)
+	}
+	values := make(map[string]bool, len(c.Options))
+	for _, opt := range c.Options {
+		if opt.Label == "" || len(opt.Label) > maxComponentLabel || opt.Value == "" || len(opt.Value) > maxCustomIDLength {
+			return fmt.Errorf("%w: select options need a label and value", ErrInvalidComponents)
+		}
+		if values[opt.Value] {
+			return fmt.Errorf("%w: duplicate select option value %q", ErrInvalidComponents, opt.Value)
+		}
+		values[opt.Value] = true
+	}
+	return nil
+}
+
+func validateCustomID(id string) error {
+	if id == "" || len(id) > maxCustomIDLength {
+		return fmt.Errorf("%w: custom_id must be 1-%d characters", ErrInvalidComponents, maxCustomIDLength)
+	}
+	return nil
+}
+
+// findComponent locates the interactive component with the given custom ID.
+func findComponent(raw json.RawMessage, customID string) (*Component, bool) {
+	var rows []ActionRow
+	if len(raw) == 0 || json.Unmarshal(raw, &rows) != nil {
+		return nil, false
+	}
+	for _, row := range rows {
+		for i := range row.Components {
+			if row.Components[i].CustomID == customID && customID != "" {
+				return &row.Components[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// checkSelectValues validates the values submitted for a component. Buttons
+// take none; string selects take option values; user, role and channel
+// selects take IDs, which are returned parsed for further checks.
+func checkSelectValues(c *Component, values []string) ([]uuid.UUID, error) {
+	if c.Type == ComponentButton {
+		if len(values) > 0 {
+			return nil, fmt.Errorf("%w: buttons take no values", ErrInvalidComponentValues)
+		}
+		return nil, nil
+	}
+
+	lo, hi := c.valueBounds()
+	if len(values) < lo || len(values) > hi {
+		return nil, fmt.Errorf("%w: select takes %d-%d values", ErrInvalidComponentValues, lo, hi)
+	}
+
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v] {
+			return nil, fmt.Errorf("%w: duplicate value %q", ErrInvalidComponentValues, v)
+		}
+		seen[v] = true
+	}
+
+	if c.Type == ComponentStringSelect {
+		allowed := make(map[string]bool, len(c.Options))
+		for _, opt := range c.Options {
+			allowed[opt.Value] = true
+		}
+		for _, v := range values {
+			if !allowed[v] {
+				return nil, fmt.Errorf("%w: %q is not an option", ErrInvalidComponentValues, v)
+			}
+		}
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an ID", ErrInvalidComponentValues, v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -119,10 +119,13 @@ func (s *DMService) CreateConversation(ctx context.Context, creatorID, participa
 type SendDMOptions struct {
 	MsgType   string
 	ReplyToID *uuid.UUID
+	// Nonce makes the send idempotent: repeating it within NonceWindow
+	// returns the original message.
+	Nonce string
 }
 
 func (s *DMService) SendDM(ctx context.Context, conversationID, authorID uuid.UUID, content string, msgType ...string) (*models.DMMessage, error) {
-	return s.SendDMWithOptions(ctx, conversationID, authorID, content, SendDMOptions{
+	msg, _, err := s.SendDMWithOptions(ctx, conversationID, authorID, content, SendDMOptions{
 		MsgType: func() string {
 			if len(msgType) > 0 && msgType[0] != "" {
 				return msgType[0]
@@ -130,6 +133,7 @@ func (s *DMService) SendDM(ctx context.Context, conversationID, authorID uuid.UU
 			return "text"
 		}(),
 	})
+	return msg, err
 }
 
 // isEncryptedPayload detects E2EE ciphertext envelope ({"v":1,"ct":"..."}).
@@ -137,14 +141,16 @@ func isEncryptedPayload(content string) bool {
 	return len(content) > 10 && strings.HasPrefix(content, `{"v":1,`)
 }
 
-func (s *DMService) SendDMWithOptions(ctx context.Context, conversationID, authorID uuid.UUID, content string, opts SendDMOptions) (*models.DMMessage, error) {
+// SendDMWithOptions sends a DM. replayed reports that msg is an earlier
+// message returned for a repeated nonce, so nothing new was sent.
+func (s *DMService) SendDMWithOptions(ctx context.Context, conversationID, authorID uuid.UUID, content string, opts SendDMOptions) (msg *models.DMMessage, replayed bool, err error) {
 	// Skip sanitization for encrypted payloads — ciphertext is opaque base64
 	if !isEncryptedPayload(content) {
 		content = s.sanitizer.Sanitize(strings.TrimSpace(content))
 	}
 
 	if len(content) > 4000 {
-		return nil, ErrMessageTooLong
+		return nil, false, ErrMessageTooLong
 	}
 
 	mt := opts.MsgType
@@ -153,31 +159,48 @@ func (s *DMService) SendDMWithOptions(ctx context.Context, conversationID, autho
 	}
 
 	if content == "" && mt == "text" {
-		return nil, ErrEmptyMessage
+		return nil, false, ErrEmptyMessage
+	}
+
+	nonce, err := parseNonce(opts.Nonce)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Verify conversation exists
-	_, err := s.queries.GetDMConversationByID(ctx, conversationID)
+	_, err = s.queries.GetDMConversationByID(ctx, conversationID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrConversationNotFound
+			return nil, false, ErrConversationNotFound
 		}
-		return nil, err
+		return nil, false, err
 	}
 
 	// Verify author is a participant
 	_, err = s.queries.GetDMParticipant(ctx, conversationID, authorID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotDMParticipant
+			return nil, false, ErrNotDMParticipant
 		}
-		return nil, err
+		return nil, false, err
+	}
+
+	// A retried send returns the message the first attempt created
+	findSent := func(ctx context.Context, nonce string, since time.Time) (models.DMMessage, error) {
+		return s.queries.GetDMMessageByNonce(ctx, conversationID, authorID, nonce, since)
+	}
+	sent, ok, err := findByNonce(ctx, nonce, findSent)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return &sent, true, nil
 	}
 
 	// Check if any participant has blocked the author (or vice versa)
 	participants, pErr := s.queries.GetDMParticipants(ctx, conversationID)
 	if pErr != nil {
-		return nil, pErr
+		return nil, false, pErr
 	}
 	for _, p := range participants {
 		if p.ID == authorID {
@@ -185,25 +208,28 @@ func (s *DMService) SendDMWithOptions(ctx context.Context, conversationID, autho
 		}
 		blocked, bErr := s.queries.IsBlocked(ctx, authorID, p.ID)
 		if bErr != nil {
-			return nil, bErr
+			return nil, false, bErr
 		}
 		if blocked {
-			return nil, ErrUserBlocked
+			return nil, false, ErrUserBlocked
 		}
 	}
 
-	msg, err := s.queries.CreateDMMessage(ctx, models.CreateDMMessageParams{
-		ConversationID: conversationID,
-		AuthorID:       authorID,
-		Content:        content,
-		Type:           mt,
-		ReplyToID:      opts.ReplyToID,
-	})
+	created, replayed, err := createOnce(ctx, nonce, func() (models.DMMessage, error) {
+		return s.queries.CreateDMMessage(ctx, models.CreateDMMessageParams{
+			ConversationID: conversationID,
+			AuthorID:       authorID,
+			Content:        content,
+			Type:           mt,
+			ReplyToID:      opts.ReplyToID,
+			Nonce:          nonce,
+		})
+	}, findSent)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return &msg, nil
+	return &created, replayed, nil
 }
 
 func (s *DMService) GetConversations(ctx context.Context, userID uuid.UUID) ([]ConversationWithParticipants, error) {
@@ -228,36 +254,11 @@ func (s *DMService) GetConversations(ctx context.Context, userID uuid.UUID) ([]C
 }
 
 func (s *DMService) GetDMMessages(ctx context.Context, conversationID, userID uuid.UUID, before *time.Time, limit int32) ([]models.DMMessageWithAuthor, error) {
-	// Verify conversation exists
-	_, err := s.queries.GetDMConversationByID(ctx, conversationID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrConversationNotFound
-		}
-		return nil, err
-	}
-
-	// Verify user is a participant
-	_, err = s.queries.GetDMParticipant(ctx, conversationID, userID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotDMParticipant
-		}
-		return nil, err
-	}
-
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	return s.queries.GetDMMessages(ctx, models.GetDMMessagesParams{
-		ConversationID: conversationID,
-		Before:         before,
-		Limit:          limit,
-	})
+	return s.GetDMMessageHistory(ctx, conversationID, userID, HistoryQuery{Before: AtTime(before), Limit: limit})
 }
 
-func (s *DMService) GetDMMessagesAfter(ctx context.Context, conversationID, userID uuid.UUID, after time.Time, limit int32) ([]models.DMMessageWithAuthor, error) {
+// GetDMMessageHistory returns a page of conversation history, newest first.
+func (s *DMService) GetDMMessageHistory(ctx context.Context, conversationID, userID uuid.UUID, q HistoryQuery) ([]models.DMMessageWithAuthor, error) {
 	// Verify conversation exists
 	_, err := s.queries.GetDMConversationByID(ctx, conversationID)
 	if err != nil {
@@ -276,15 +277,22 @@ func (s *DMService) GetDMMessagesAfter(ctx context.Context, conversationID, user
 		return nil, err
 	}
 
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	return s.queries.GetDMMessagesAfter(ctx, models.GetDMMessagesAfterParams{
-		ConversationID: conversationID,
-		After:          after,
-		Limit:          limit,
-	})
+	pager := historyPager[models.DMMessageWithAuthor]{
+		resolve: func(ctx context.Context, messageID uuid.UUID) (models.MessageBound, error) {
+			msg, err := s.queries.GetDMMessageByID(ctx, messageID)
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && msg.ConversationID != conversationID) {
+				return models.MessageBound{}, ErrDMMessageNotFound
+			}
+			return models.MessageBound{CreatedAt: msg.CreatedAt, ID: msg.ID}, err
+		},
+		before: func(ctx context.Context, bound *models.MessageBound, limit int32) ([]models.DMMessageWithAuthor, error) {
+			return s.queries.GetDMMessages(ctx, models.GetDMMessagesParams{ConversationID: conversationID, Before: bound, Limit: limit})
+		},
+		after: func(ctx context.Context, bound models.MessageBound, limit int32) ([]models.DMMessageWithAuthor, error) {
+			return s.queries.GetDMMessagesAfter(ctx, models.GetDMMessagesAfterParams{ConversationID: conversationID, After: bound, Limit: limit})
+		},
+	}
+	return pager.page(ctx, q)
 }
 
 func (s *DMService) AcceptMessageRequest(ctx context.Context, conversationID, userID uuid.UUID) error {
@@ -77,6 +77,31 @@ func TestSendDM_Success(t *testing.T) {
 	assert.Equal(t, user1.User.ID, msg.AuthorID)
 }
 
+func TestSendDMWithOptions_NonceReplay(t *testing.T) {
+	svc := NewDMService(queries())
+	user1 := createUser(t)
+	user2 := createUser(t)
+	ctx := context.Background()
+
+	conv, err := svc.CreateConversation(ctx, user1.User.ID, user2.User.ID)
+	require.NoError(t, err)
+
+	first, replayed, err := svc.SendDMWithOptions(ctx, conv.ID, user1.User.ID, "hello", SendDMOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	retry, replayed, err := svc.SendDMWithOptions(ctx, conv.ID, user1.User.ID, "hello", SendDMOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, retry.ID)
+
+	// The same nonce from another participant is a different send
+	other, replayed, err := svc.SendDMWithOptions(ctx, conv.ID, user2.User.ID, "hello", SendDMOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, first.ID, other.ID)
+}
+
 func TestSendDM_NotParticipant(t *testing.T) {
 	svc := NewDMService(queries())
 	user1 := createUser(t)
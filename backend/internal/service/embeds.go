@@ -0,0 +1,172 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidEmbeds = errors.New("invalid embeds")
+
+const (
+	maxEmbeds               = 10
+	maxEmbedTitle           = 256
+	maxEmbedDescription     = 4096
+	maxEmbedFields          = 25
+	maxEmbedFieldName       = 256
+	maxEmbedFieldValue      = 1024
+	maxEmbedFooter          = 2048
+	maxEmbedAuthorName      = 256
+	maxEmbedTotalCharacters = 6000
+	maxEmbedColor           = 0xFFFFFF
+)
+
+// Embed is a rich content block on a webhook message.
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       *int         `json:"color,omitempty"`
+	Timestamp   *time.Time   `json:"timestamp,omitempty"`
+	Author      *EmbedAuthor `json:"author,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+	Image       *EmbedMedia  `json:"image,omitempty"`
+	Thumbnail   *EmbedMedia  `json:"thumbnail,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+}
+
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type EmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type EmbedMedia struct {
+	URL string `json:"url"`
+}
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// ParseEmbeds validates embeds and returns them re-encoded, or nil when
+// there are none.
+func ParseEmbeds(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var embeds []Embed
+	if err := json.Unmarshal(raw, &embeds); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmbeds, err)
+	}
+	if len(embeds) == 0 {
+		return nil, nil
+	}
+	if len(embeds) > maxEmbeds {
+		return nil, fmt.Errorf("%w: at most %d embeds", ErrInvalidEmbeds, maxEmbeds)
+	}
+
+	total := 0
+	for i, e := range embeds {
+		n, err := validateEmbed(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w: embed %d: %v", ErrInvalidEmbeds, i, err)
+		}
+		total += n
+	}
+	if total > maxEmbedTotalCharacters {
+		return nil, fmt.Errorf("%w: embeds may hold at most %d characters of text", ErrInvalidEmbeds, maxEmbedTotalCharacters)
+	}
+	return json.Marshal(embeds)
+}
+
+// validateEmbed checks one embed and returns how many characters of text it
+// holds.
+func validateEmbed(e Embed) (int, error) {
+	total := 0
+	text := func(field, s string, max int, required bool) error {
+		n := utf8.RuneCountInString(s)
+		if required && n == 0 {
+			return fmt.Errorf("%s is required", field)
+		}
+		if n > max {
+			return fmt.Errorf("%s must be at most %d characters", field, max)
+		}
+		total += n
+		return nil
+	}
+	link := func(field, s string) error {
+		if s != "" && !isHTTPURL(s) {
+			return fmt.Errorf("%s must be an http(s) URL", field)
+		}
+		return nil
+	}
+
+	if err := text("title", e.Title, maxEmbedTitle, false); err != nil {
+		return 0, err
+	}
+	if err := text("description", e.Description, maxEmbedDescription, false); err != nil {
+		return 0, err
+	}
+	if err := link("url", e.URL); err != nil {
+		return 0, err
+	}
+	if e.Color != nil && (*e.Color < 0 || *e.Color > maxEmbedColor) {
+		return 0, errors.New("color must be an RGB integer")
+	}
+	if e.Author != nil {
+		if err := text("author.name", e.Author.Name, maxEmbedAuthorName, true); err != nil {
+			return 0, err
+		}
+		if err := link("author.url", e.Author.URL); err != nil {
+			return 0, err
+		}
+		if err := link("author.icon_url", e.Author.IconURL); err != nil {
+			return 0, err
+		}
+	}
+	if e.Footer != nil {
+		if err := text("footer.text", e.Footer.Text, maxEmbedFooter, true); err != nil {
+			return 0, err
+		}
+		if err := link("footer.icon_url", e.Footer.IconURL); err != nil {
+			return 0, err
+		}
+	}
+	if e.Image != nil {
+		if err := link("image.url", e.Image.URL); err != nil || e.Image.URL == "" {
+			return 0, errors.New("image.url must be an http(s) URL")
+		}
+	}
+	if e.Thumbnail != nil {
+		if err := link("thumbnail.url", e.Thumbnail.URL); err != nil || e.Thumbnail.URL == "" {
+			return 0, errors.New("thumbnail.url must be an http(s) URL")
+		}
+	}
+	if len(e.Fields) > maxEmbedFields {
+		return 0, fmt.Errorf("at most %d fields", maxEmbedFields)
+	}
+	for _, f := range e.Fields {
+		if err := text("field name", f.Name, maxEmbedFieldName, true); err != nil {
+			return 0, err
+		}
+		if err := text("field value", f.Value, maxEmbedFieldValue, true); err != nil {
+			return 0, err
+		}
+	}
+
+	if total == 0 && e.Image == nil && e.Thumbnail == nil {
+		return 0, errors.New("embed is empty")
+	}
+	return total, nil
+}
@@ -0,0 +1,526 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+var (
+	ErrSubscriptionNotFound     = errors.New("event subscription not found")
+	ErrInvalidSubscription      = errors.New("invalid event subscription")
+	ErrTooManySubscriptions     = errors.New("server has reached the event subscription limit (10)")
+	ErrInvalidSubscriptionEvent = errors.New("unsupported event type")
+)
+
+// SubscribableEvents are the gateway events an event subscription can
+// receive. Payloads are the same as on the gateway.
+var SubscribableEvents = map[string]bool{
+	ws.EventMessageCreate:     true,
+	ws.EventMessageUpdate:     true,
+	ws.EventMessageDelete:     true,
+	ws.EventMessageDeleteBulk: true,
+	ws.EventMessageRestore:    true,
+	ws.EventMemberJoin:        true,
+	ws.EventMemberLeave:       true,
+	ws.EventMemberBan:         true,
+	ws.EventReactionAdd:       true,
+	ws.EventReactionRemove:    true,
+	ws.EventPollCreate:        true,
+	ws.EventPollVote:          true,
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	maxEventSubscriptionsPerServer = 10
+
+	// A delivery is attempted up to this many times, backing off
+	// exponentially from eventRetryBase, before it is marked failed.
+	eventDeliveryMaxAttempts = 6
+	eventRetryBase           = 10 * time.Second
+	eventRetryMax            = 30 * time.Minute
+
+	// Subscriptions are disabled after this many failed attempts in a row.
+	eventDisableAfterFailures = 15
+
+	eventDeliveryTimeout  = 10 * time.Second
+	eventDeliveryLease    = time.Minute
+	eventDeliveryBatch    = 50
+	eventDeliveryWorkers  = 8
+	eventDeliveryInterval = 5 * time.Second
+	eventQueueSize        = 1024
+
+	// Delivery log entries are kept this long.
+	EventDeliveryRetention = 7 * 24 * time.Hour
+)
+
+// EventSubscriptionInput is a subscription as created or updated by an
+// admin. Enabled is ignored on create.
+type EventSubscriptionInput struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// EventDeliveryBody is the JSON body POSTed to a subscription. Data is the
+// event's gateway payload.
+type EventDeliveryBody struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	ServerID  uuid.UUID       `json:"server_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type observedEvent struct {
+	channelID string
+	event     *ws.Event
+}
+
+// EventSubscriptionService manages outgoing event webhooks and delivers
+// events to them. Deliveries are queued in the database, so retries
+// survive restarts and replicas share the work.
+type EventSubscriptionService struct {
+	queries    *models.Queries
+	permSvc    *PermissionService
+	httpClient *http.Client
+	retryBase  time.Duration
+	events     chan observedEvent
+	wake       chan struct{}
+	done       chan struct{}
+}
+
+func NewEventSubscriptionService(q *models.Queries, permSvc *PermissionService) *EventSubscriptionService {
+	return &EventSubscriptionService{
+		queries:    q,
+		permSvc:    permSvc,
+		httpClient: newOutboundClient(eventDeliveryTimeout),
+		retryBase:  eventRetryBase,
+		events:     make(chan observedEvent, eventQueueSize),
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+// --- Management (admins) ---
+
+// CreateSubscription subscribes an endpoint to server events. It returns the
+// signing secret, shown once. The caller must have ManageServer.
+func (s *EventSubscriptionService) CreateSubscription(ctx context.Context, serverID, userID uuid.UUID, input EventSubscriptionInput) (*models.EventSubscription, string, error) {
+	if err := s.checkManageServer(ctx, serverID, userID); err != nil {
+		return nil, "", err
+	}
+	if err := validateSubscriptionURL(input.URL); err != nil {
+		return nil, "", err
+	}
+	eventTypes, err := validateEventTypes(input.EventTypes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	existing, err := s.queries.GetEventSubscriptionsByServer(ctx, serverID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(existing) >= maxEventSubscriptionsPerServer {
+		return nil, "", ErrTooManySubscriptions
+	}
+
+	secret, err := GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sub, err := s.queries.CreateEventSubscription(ctx, models.CreateEventSubscriptionParams{
+		ServerID:   serverID,
+		CreatorID:  userID,
+		URL:        input.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &sub, secret, nil
+}
+
+// ListSubscriptions lists a server's event subscriptions.
+func (s *EventSubscriptionService) ListSubscriptions(ctx context.Context, serverID, userID uuid.UUID) ([]models.EventSubscription, error) {
+	if err := s.checkManageServer(ctx, serverID, userID); err != nil {
+		return nil, err
+	}
+	return s.queries.GetEventSubscriptionsByServer(ctx, serverID)
+}
+
+// UpdateSubscription changes a subscription's URL, events or enabled state.
+// Empty fields keep their current value. Disabling drops queued deliveries;
+// enabling clears the failure count.
+func (s *EventSubscriptionService) UpdateSubscription(ctx context.Context, subscriptionID, userID uuid.UUID, input EventSubscriptionInput) (*models.EventSubscription, error) {
+	sub, err := s.getManaged(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	url := sub.URL
+	if input.URL != "" {
+		if err := validateSubscriptionURL(input.URL); err != nil {
+			return nil, err
+		}
+		url = input.URL
+	}
+	eventTypes := sub.EventTypes
+	if input.EventTypes != nil {
+		if eventTypes, err = validateEventTypes(input.EventTypes); err != nil {
+			return nil, err
+		}
+	}
+	enabled := sub.Enabled
+	if input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+
+	updated, err := s.queries.UpdateEventSubscription(ctx, models.UpdateEventSubscriptionParams{
+		ID:         sub.ID,
+		URL:        url,
+		EventTypes: eventTypes,
+		Enabled:    enabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated.Enabled {
+		_ = s.queries.FailPendingEventDeliveries(ctx, sub.ID, "subscription disabled")
+	}
+	return &updated, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log.
+func (s *EventSubscriptionService) DeleteSubscription(ctx context.Context, subscriptionID, userID uuid.UUID) error {
+	if _, err := s.getManaged(ctx, subscriptionID, userID); err != nil {
+		return err
+	}
+	return s.queries.DeleteEventSubscription(ctx, subscriptionID)
+}
+
+// ListDeliveries returns a subscription's most recent deliveries.
+func (s *EventSubscriptionService) ListDeliveries(ctx context.Context, subscriptionID, userID uuid.UUID, limit int) ([]models.EventDelivery, error) {
+	if _, err := s.getManaged(ctx, subscriptionID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.queries.GetEventDeliveries(ctx, subscriptionID, limit)
+}
+
+func (s *EventSubscriptionService) getManaged(ctx context.Context, subscriptionID, userID uuid.UUID) (*models.EventSubscription, error) {
+	sub, err := s.queries.GetEventSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkManageServer(ctx, sub.ServerID, userID); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (s *EventSubscriptionService) checkManageServer(ctx context.Context, serverID, userID uuid.UUID) error {
+	if _, err := s.queries.GetServerMember(ctx, serverID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotMember
+		}
+		return err
+	}
+	ok, err := s.permSvc.HasServerPermission(ctx, serverID, userID, models.PermManageServer)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInsufficientRole
+	}
+	return nil
+}
+
+func validateSubscriptionURL(rawURL string) error {
+	if !isPublicEndpoint(rawURL) {
+		return fmt.Errorf("%w: url must be a public http(s) URL", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// validateEventTypes checks that every event type can be subscribed to,
+// returning them de-duplicated and sorted.
+func validateEventTypes(eventTypes []string) ([]string, error) {
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required", ErrInvalidSubscription)
+	}
+
+	seen := make(map[string]bool, len(eventTypes))
+	types := make([]string, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		if !SubscribableEvents[t] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSubscriptionEvent, t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// --- Delivery ---
+
+// SignEventPayload computes the X-Signature-256 header of an event delivery,
+// signed the same way as interaction callbacks.
+func SignEventPayload(secret, timestamp string, body []byte) string {
+	return SignInteractionPayload(secret, timestamp, body)
+}
+
+// Observe queues a hub event for subscribers. It is the hub's event
+// observer, so it never blocks: when the queue is full the event is dropped.
+func (s *EventSubscriptionService) Observe(channelID string, event *ws.Event) {
+	if !SubscribableEvents[event.Type] {
+		return
+	}
+	select {
+	case s.events <- observedEvent{channelID: channelID, event: event}:
+	default:
+		log.Printf("[EventSubscriptions] Queue full, dropped %s event", event.Type)
+	}
+}
+
+// Start begins queueing observed events and delivering them.
+func (s *EventSubscriptionService) Start() {
+	go s.runQueue()
+	go s.runDelivery()
+}
+
+// Stop signals the background goroutines to stop.
+func (s *EventSubscriptionService) Stop() {
+	close(s.done)
+}
+
+func (s *EventSubscriptionService) runQueue() {
+	for {
+		select {
+		case e := <-s.events:
+			n, err := s.Enqueue(context.Background(), e.channelID, e.event)
+			if err != nil {
+				log.Printf("[EventSubscriptions] Failed to queue %s event: %v", e.event.Type, err)
+			}
+			if n > 0 {
+				s.wakeUp()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *EventSubscriptionService) runDelivery() {
+	ticker := time.NewTicker(eventDeliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+		// Keep going while there is a full backlog
+		for {
+			n, err := s.DeliverDue(context.Background())
+			if err != nil {
+				log.Printf("[EventSubscriptions] Delivery run failed: %v", err)
+			}
+			if n < eventDeliveryBatch {
+				break
+			}
+		}
+	}
+}
+
+func (s *EventSubscriptionService) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue records a delivery of the event for each subscription that wants
+// it. Channel events go to the channel's server; server-wide events (with no
+// channel) name their server in a server_id field. It returns the number of
+// deliveries queued.
+func (s *EventSubscriptionService) Enqueue(ctx context.Context, channelID string, event *ws.Event) (int, error) {
+	var subs []models.EventSubscription
+	var err error
+	if channelID != "" {
+		id, parseErr := uuid.Parse(channelID)
+		if parseErr != nil {
+			return 0, nil
+		}
+		subs, err = s.queries.GetEnabledEventSubscriptionsByChannel(ctx, id, event.Type)
+	} else {
+		var scope struct {
+			ServerID uuid.UUID `json:"server_id"`
+		}
+		if json.Unmarshal(event.Data, &scope) != nil || scope.ServerID == uuid.Nil {
+			return 0, nil
+		}
+		subs, err = s.queries.GetEnabledEventSubscriptions(ctx, scope.ServerID, event.Type)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, sub := range subs {
+		if _, err := s.queries.CreateEventDelivery(ctx, sub.ID, event.Type, event.Data); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// DeliverDue attempts every delivery that is due, up to one batch, and
+// returns how many it attempted.
+func (s *EventSubscriptionService) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := s.queries.ClaimDueEventDeliveries(ctx, eventDeliveryBatch, time.Now().Add(eventDeliveryLease))
+	if err != nil {
+		return 0, err
+	}
+
+	subs := make(map[uuid.UUID]*models.EventSubscription)
+	for _, d := range deliveries {
+		if _, ok := subs[d.SubscriptionID]; ok {
+			continue
+		}
+		sub, err := s.queries.GetEventSubscriptionByID(ctx, d.SubscriptionID)
+		if err != nil {
+			return 0, err
+		}
+		subs[d.SubscriptionID] = &sub
+	}
+
+	sem := make(chan struct{}, eventDeliveryWorkers)
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d models.EventDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.attempt(ctx, subs[d.SubscriptionID], d)
+		}(d)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// attempt POSTs one delivery and records the outcome.
+func (s *EventSubscriptionService) attempt(ctx context.Context, sub *models.EventSubscription, d models.EventDelivery) {
+	status, err := s.post(ctx, sub, d)
+	if err == nil {
+		if err := s.queries.RecordEventDeliverySuccess(ctx, d.ID, status); err != nil {
+			log.Printf("[EventSubscriptions] Failed to record delivery %s: %v", d.ID, err)
+		}
+		return
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+	var next *time.Time
+	if d.Attempts+1 < eventDeliveryMaxAttempts {
+		at := time.Now().Add(s.backoff(d.Attempts + 1))
+		next = &at
+	}
+
+	disabled, recErr := s.queries.RecordEventDeliveryFailure(ctx, d.ID, responseStatus, err.Error(), next, eventDisableAfterFailures)
+	if recErr != nil {
+		log.Printf("[EventSubscriptions] Failed to record delivery %s: %v", d.ID, recErr)
+		return
+	}
+	if disabled {
+		log.Printf("[EventSubscriptions] Disabled subscription %s after %d consecutive failures", sub.ID, eventDisableAfterFailures)
+		_ = s.queries.FailPendingEventDeliveries(ctx, sub.ID, "subscription disabled")
+	}
+}
+
+// backoff returns the delay before the next attempt: retryBase doubled for
+// each failed attempt so far, capped at eventRetryMax.
+func (s *EventSubscriptionService) backoff(failedAttempts int) time.Duration {
+	delay := s.retryBase << (failedAttempts - 1)
+	if delay > eventRetryMax {
+		return eventRetryMax
+	}
+	return delay
+}
+
+// post sends a delivery and returns the response status. Any 2xx is success.
+func (s *EventSubscriptionService) post(ctx context.Context, sub *models.EventSubscription, d models.EventDelivery) (int, error) {
+	body, err := json.Marshal(EventDeliveryBody{
+		ID:        d.ID,
+		Type:      d.EventType,
+		ServerID:  sub.ServerID,
+		Timestamp: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eventDeliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Thicket-Event", d.EventType)
+	req.Header.Set("X-Thicket-Delivery", d.ID.String())
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-256", SignEventPayload(sub.Secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+type receivedEvent struct {
+	header http.Header
+	body   []byte
+}
+
+// eventReceiver is an httptest endpoint that records deliveries and answers
+// with the given status.
+type eventReceiver struct {
+	srv    *httptest.Server
+	mu     sync.Mutex
+	status int
+	got    []receivedEvent
+}
+
+func newEventReceiver(t *testing.T) *eventReceiver {
+	t.Helper()
+	r := &eventReceiver{status: http.StatusNoContent}
+	r.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.got = append(r.got, receivedEvent{header: req.Header.Clone(), body: body})
+		status := r.status
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.srv.Close)
+	return r
+}
+
+func (r *eventReceiver) setStatus(status int) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+}
+
+func (r *eventReceiver) received() []receivedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedEvent(nil), r.got...)
+}
+
+// redirect points sub and svc's delivery client at the receiver. The URL is
+// written directly because CreateSubscription and the delivery client both
+// reject loopback addresses.
+func (r *eventReceiver) redirect(t *testing.T, svc *EventSubscriptionService, sub *models.EventSubscription) {
+	t.Helper()
+	svc.httpClient = r.srv.Client()
+	_, err := queries().UpdateEventSubscription(context.Background(), models.UpdateEventSubscriptionParams{
+		ID: sub.ID, URL: r.srv.URL, EventTypes: sub.EventTypes, Enabled: true,
+	})
+	require.NoError(t, err)
+}
+
+func TestEventSubscription_Validation(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewEventSubscriptionService(queries(), permSvc)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Events", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	sub, _, err := svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL:        "https://hooks.example.com/thicket",
+		EventTypes: []string{ws.EventMessageCreate, ws.EventMemberJoin},
+	})
+	require.NoError(t, err)
+
+	_, _, err = svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL: "http://127.0.0.1/hook", EventTypes: []string{ws.EventMessageCreate},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+	_, _, err = svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL: "https://hooks.example.com", EventTypes: []string{ws.EventTypingStartBcast},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSubscriptionEvent)
+
+	_, _, err = svc.CreateSubscription(ctx, server.ID, member.User.ID, EventSubscriptionInput{
+		URL: "https://hooks.example.com", EventTypes: []string{ws.EventMessageCreate},
+	})
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+
+	_, err = svc.ListDeliveries(ctx, sub.ID, member.User.ID, 10)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+}
+
+func TestEventSubscription_DeliversSignedEvent(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewEventSubscriptionService(queries(), permSvc)
+	svc.retryBase = 0
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Events", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	sub, secret, err := svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL:        "https://hooks.example.com/thicket",
+		EventTypes: []string{ws.EventMessageCreate, ws.EventMemberJoin},
+	})
+	require.NoError(t, err)
+
+	receiver := newEventReceiver(t)
+	receiver.redirect(t, svc, sub)
+
+	event, err := ws.NewEvent(ws.EventMessageCreate, map[string]any{
+		"id": uuid.New(), "channel_id": channel.ID, "content": "hello",
+	})
+	require.NoError(t, err)
+	queued, err := svc.Enqueue(ctx, channel.ID.String(), event)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+
+	// Not subscribed to typing; server-wide events resolve by server_id
+	typing, _ := ws.NewEvent(ws.EventTypingStartBcast, map[string]any{"channel_id": channel.ID})
+	svc.Observe(channel.ID.String(), typing)
+	join, _ := ws.NewEvent(ws.EventMemberJoin, map[string]any{"server_id": server.ID, "user_id": member.User.ID})
+	queued, err = svc.Enqueue(ctx, "", join)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+
+	n, err := svc.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	got := receiver.received()
+	require.Len(t, got, 2)
+	for _, r := range got {
+		ts := r.header.Get("X-Signature-Timestamp")
+		assert.Equal(t, SignEventPayload(secret, ts, r.body), r.header.Get("X-Signature-256"))
+		assert.NotEqual(t, SignEventPayload("wrong", ts, r.body), r.header.Get("X-Signature-256"))
+
+		var body EventDeliveryBody
+		require.NoError(t, json.Unmarshal(r.body, &body))
+		assert.Equal(t, server.ID, body.ServerID)
+		assert.Equal(t, body.Type, r.header.Get("X-Thicket-Event"))
+		assert.Equal(t, body.ID.String(), r.header.Get("X-Thicket-Delivery"))
+	}
+
+	// Data is the gateway payload as broadcast
+	for _, r := range got {
+		var body EventDeliveryBody
+		require.NoError(t, json.Unmarshal(r.body, &body))
+		if body.Type == ws.EventMessageCreate {
+			assert.JSONEq(t, string(event.Data), string(body.Data))
+		}
+	}
+
+	deliveries, err := svc.ListDeliveries(ctx, sub.ID, owner.User.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	for _, d := range deliveries {
+		assert.Equal(t, DeliverySucceeded, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		require.NotNil(t, d.ResponseStatus)
+		assert.Equal(t, http.StatusNoContent, *d.ResponseStatus)
+	}
+}
+
+func TestEventSubscription_RetriesThenFails(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewEventSubscriptionService(queries(), permSvc)
+	svc.retryBase = 0
+	owner := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Events", owner.User.ID)
+	require.NoError(t, err)
+	sub, _, err := svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL:        "https://hooks.example.com/thicket",
+		EventTypes: []string{ws.EventMessageCreate, ws.EventMemberJoin},
+	})
+	require.NoError(t, err)
+
+	receiver := newEventReceiver(t)
+	receiver.redirect(t, svc, sub)
+	receiver.setStatus(http.StatusInternalServerError)
+
+	event, _ := ws.NewEvent(ws.EventMessageCreate, map[string]any{"channel_id": channel.ID})
+	_, err = svc.Enqueue(ctx, channel.ID.String(), event)
+	require.NoError(t, err)
+
+	for i := 0; i < eventDeliveryMaxAttempts; i++ {
+		n, err := svc.DeliverDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "attempt %d", i+1)
+	}
+	n, err := svc.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Len(t, receiver.received(), eventDeliveryMaxAttempts)
+
+	deliveries, err := svc.ListDeliveries(ctx, sub.ID, owner.User.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, eventDeliveryMaxAttempts, deliveries[0].Attempts)
+	assert.Contains(t, deliveries[0].LastError, "500")
+
+	// A later success resets the failure count
+	receiver.setStatus(http.StatusOK)
+	_, err = svc.Enqueue(ctx, channel.ID.String(), event)
+	require.NoError(t, err)
+	_, err = svc.DeliverDue(ctx)
+	require.NoError(t, err)
+	stored, err := queries().GetEventSubscriptionByID(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.ConsecutiveFailures)
+	assert.True(t, stored.Enabled)
+}
+
+func TestEventSubscription_BackoffIsExponential(t *testing.T) {
+	svc := &EventSubscriptionService{retryBase: eventRetryBase}
+	assert.Equal(t, eventRetryBase, svc.backoff(1))
+	assert.Equal(t, 2*eventRetryBase, svc.backoff(2))
+	assert.Equal(t, 8*eventRetryBase, svc.backoff(4))
+	assert.Equal(t, eventRetryMax, svc.backoff(20))
+}
+
+func TestEventSubscription_DisabledAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewEventSubscriptionService(queries(), permSvc)
+	svc.retryBase = 0
+	owner := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Events", owner.User.ID)
+	require.NoError(t, err)
+	sub, _, err := svc.CreateSubscription(ctx, server.ID, owner.User.ID, EventSubscriptionInput{
+		URL:        "https://hooks.example.com/thicket",
+		EventTypes: []string{ws.EventMessageCreate, ws.EventMemberJoin},
+	})
+	require.NoError(t, err)
+
+	receiver := newEventReceiver(t)
+	receiver.redirect(t, svc, sub)
+	receiver.setStatus(http.StatusBadGateway)
+
+	event, _ := ws.NewEvent(ws.EventMessageCreate, map[string]any{"channel_id": channel.ID})
+	for i := 0; i < eventDisableAfterFailures; i++ {
+		_, err := svc.Enqueue(ctx, channel.ID.String(), event)
+		require.NoError(t, err)
+	}
+	for {
+		n, err := svc.DeliverDue(ctx)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+
+	stored, err := queries().GetEventSubscriptionByID(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Enabled)
+	assert.NotEmpty(t, stored.DisabledReason)
+	assert.Len(t, receiver.received(), eventDisableAfterFailures)
+
+	// Disabled subscriptions queue nothing more
+	queued, err := svc.Enqueue(ctx, channel.ID.String(), event)
+	require.NoError(t, err)
+	assert.Equal(t, 0, queued)
+
+	// Re-enabling clears the failure state
+	enabled := true
+	updated, err := svc.UpdateSubscription(ctx, sub.ID, owner.User.ID, EventSubscriptionInput{Enabled: &enabled})
+	require.NoError(t, err)
+	assert.True(t, updated.Enabled)
+	assert.Equal(t, 0, updated.ConsecutiveFailures)
+	assert.Empty(t, updated.DisabledReason)
+}
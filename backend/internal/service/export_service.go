@@ -100,7 +100,7 @@ func (s *ExportService) ExportChannelMessages(ctx context.Context, channelID, us
 // getAllChannelMessages fetches all messages for a channel in chronological order.
 func (s *ExportService) getAllChannelMessages(ctx context.Context, channelID uuid.UUID) ([]models.MessageWithAuthor, error) {
 	var all []models.MessageWithAuthor
-	var before *time.Time
+	var before *models.MessageBound
 	batchSize := int32(100)
 
 	for {
@@ -117,8 +117,8 @@ func (s *ExportService) getAllChannelMessages(ctx context.Context, channelID uui
 		}
 		all = append(all, batch...)
 		// GetChannelMessages returns DESC order, so last item is oldest
-		oldest := batch[len(batch)-1].CreatedAt
-		before = &oldest
+		oldest := batch[len(batch)-1]
+		before = &models.MessageBound{CreatedAt: oldest.CreatedAt, ID: oldest.ID}
 		if len(batch) < int(batchSize) {
 			break
 		}
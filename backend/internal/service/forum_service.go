@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -403,7 +405,13 @@ func (s *ForumService) GetPostMessages(ctx context.Context, postID, userID uuid.
 	return s.queries.GetForumPostMessages(ctx, postID, limit, offset)
 }
 
-func (s *ForumService) CreatePostMessage(ctx context.Context, postID, userID uuid.UUID, content string) (*models.ForumPostMessageWithAuthor, error) {
+// GetPostMessageHistory returns the page of a post's messages around a
+// message or timestamp, oldest first like the rest of the post.
+func (s *ForumService) GetPostMessageHistory(ctx context.Context, postID, userID uuid.UUID, q HistoryQuery) ([]models.ForumPostMessageWithAuthor, error) {
+	if !q.HasAnchor() {
+		return s.GetPostMessages(ctx, postID, userID, int(q.Limit), 0)
+	}
+
 	post, err := s.queries.GetForumPostByID(ctx, postID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -423,36 +431,107 @@ func (s *ForumService) CreatePostMessage(ctx context.Context, postID, userID uui
 		return nil, err
 	}
 
+	pager := historyPager[models.ForumPostMessageWithAuthor]{
+		resolve: func(ctx context.Context, messageID uuid.UUID) (models.MessageBound, error) {
+			msg, err := s.queries.GetForumPostMessageByID(ctx, messageID)
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && msg.PostID != postID) {
+				return models.MessageBound{}, ErrMessageNotFound
+			}
+			return models.MessageBound{CreatedAt: msg.CreatedAt, ID: msg.ID}, err
+		},
+		before: func(ctx context.Context, bound *models.MessageBound, limit int32) ([]models.ForumPostMessageWithAuthor, error) {
+			return s.queries.GetForumPostMessagesBefore(ctx, postID, bound, int(limit))
+		},
+		after: func(ctx context.Context, bound models.MessageBound, limit int32) ([]models.ForumPostMessageWithAuthor, error) {
+			return s.queries.GetForumPostMessagesAfter(ctx, postID, bound, int(limit))
+		},
+	}
+	messages, err := pager.page(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func (s *ForumService) CreatePostMessage(ctx context.Context, postID, userID uuid.UUID, content string) (*models.ForumPostMessageWithAuthor, error) {
+	msg, _, err := s.CreatePostMessageWithNonce(ctx, postID, userID, content, "")
+	return msg, err
+}
+
+// CreatePostMessageWithNonce replies to a post. Repeating a nonce within
+// NonceWindow returns the original reply with replayed set instead of
+// posting another.
+func (s *ForumService) CreatePostMessageWithNonce(ctx context.Context, postID, userID uuid.UUID, content, nonce string) (result *models.ForumPostMessageWithAuthor, replayed bool, err error) {
+	post, err := s.queries.GetForumPostByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, ErrForumPostNotFound
+		}
+		return nil, false, err
+	}
+
+	channel, err := s.queries.GetChannelByID(ctx, post.ChannelID)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, ErrNotMember
+		}
+		return nil, false, err
+	}
+
 	content = s.sanitizer.Sanitize(strings.TrimSpace(content))
 	if content == "" {
-		return nil, ErrEmptyMessage
+		return nil, false, ErrEmptyMessage
 	}
 
-	msg, err := s.queries.CreateForumPostMessage(ctx, models.CreateForumPostMessageParams{
-		PostID:   postID,
-		AuthorID: userID,
-		Content:  content,
-	})
+	nonceArg, err := parseNonce(nonce)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	// Update post's updated_at to reflect new activity
-	_ = s.queries.TouchForumPostUpdatedAt(ctx, postID)
+	// A retried send returns the reply the first attempt created
+	findSent := func(ctx context.Context, nonce string, since time.Time) (models.ForumPostMessage, error) {
+		return s.queries.GetForumPostMessageByNonce(ctx, postID, userID, nonce, since)
+	}
+	msg, replayed, err := findByNonce(ctx, nonceArg, findSent)
+	if err != nil {
+		return nil, false, err
+	}
+	if !replayed {
+		msg, replayed, err = createOnce(ctx, nonceArg, func() (models.ForumPostMessage, error) {
+			return s.queries.CreateForumPostMessage(ctx, models.CreateForumPostMessageParams{
+				PostID:   postID,
+				AuthorID: userID,
+				Content:  content,
+				Nonce:    nonceArg,
+			})
+		}, findSent)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if !replayed {
+		// Update post's updated_at to reflect new activity
+		_ = s.queries.TouchForumPostUpdatedAt(ctx, postID)
+	}
 
 	author, err := s.queries.GetUserByID(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	result := &models.ForumPostMessageWithAuthor{
+	result = &models.ForumPostMessageWithAuthor{
 		ForumPostMessage: msg,
 		AuthorUsername:    author.Username,
 		AuthorDisplayName: author.DisplayName,
 		AuthorAvatarURL:  author.AvatarURL,
 	}
 
-	return result, nil
+	return result, replayed, nil
 }
 
 // DeletePostMessage deletes a forum post message if the user is the author.
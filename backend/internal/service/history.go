@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+var ErrInvalidHistoryQuery = errors.New("only one of before, after and around may be given")
+
+// HistoryAnchor is a point in message history: a message, or a timestamp
+// for clients that still page by time.
+type HistoryAnchor struct {
+	MessageID *uuid.UUID
+	Time      time.Time
+}
+
+// AtTime anchors history at a timestamp; nil stays nil.
+func AtTime(t *time.Time) *HistoryAnchor {
+	if t == nil {
+		return nil
+	}
+	return &HistoryAnchor{Time: *t}
+}
+
+// HistoryQuery selects a page of message history relative to at most one
+// anchor. Around returns up to Limit messages on each side of the anchor,
+// plus the anchor message itself when it is given by ID.
+type HistoryQuery struct {
+	Before *HistoryAnchor
+	After  *HistoryAnchor
+	Around *HistoryAnchor
+	Limit  int32
+}
+
+// HasAnchor reports whether the query pages from an anchor rather than the
+// latest message.
+func (q HistoryQuery) HasAnchor() bool {
+	return q.Before != nil || q.After != nil || q.Around != nil
+}
+
+func (q HistoryQuery) validate() error {
+	n := 0
+	for _, a := range []*HistoryAnchor{q.Before, q.After, q.Around} {
+		if a != nil {
+			n++
+		}
+	}
+	if n > 1 {
+		return ErrInvalidHistoryQuery
+	}
+	return nil
+}
+
+// historyPager pages through one conversation's history with its own
+// queries. resolve finds a message's bound and must fail for messages from
+// other conversations; before returns messages newest first and after oldest
+// first.
+type historyPager[T any] struct {
+	resolve func(ctx context.Context, messageID uuid.UUID) (models.MessageBound, error)
+	before  func(ctx context.Context, bound *models.MessageBound, limit int32) ([]T, error)
+	after   func(ctx context.Context, bound models.MessageBound, limit int32) ([]T, error)
+}
+
+// page returns the messages a query selects, newest first.
+func (p historyPager[T]) page(ctx context.Context, q HistoryQuery) ([]T, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case q.Around != nil:
+		limit := q.Limit
+		if limit <= 0 || limit > 50 {
+			limit = 25
+		}
+		older, newer := models.BoundBeforeTime(q.Around.Time), models.BoundAfterTime(q.Around.Time)
+		if q.Around.MessageID != nil {
+			bound, err := p.resolve(ctx, *q.Around.MessageID)
+			if err != nil {
+				return nil, err
+			}
+			older, newer = boundThrough(bound), bound
+		}
+		before, err := p.before(ctx, &older, limit)
+		if err != nil {
+			return nil, err
+		}
+		after, err := p.after(ctx, newer, limit)
+		if err != nil {
+			return nil, err
+		}
+		slices.Reverse(after)
+		return append(after, before...), nil
+
+	case q.After != nil:
+		bound, err := p.bound(ctx, q.After, models.BoundAfterTime)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := p.after(ctx, bound, historyLimit(q.Limit))
+		if err != nil {
+			return nil, err
+		}
+		slices.Reverse(msgs)
+		return msgs, nil
+
+	default:
+		var bound *models.MessageBound
+		if q.Before != nil {
+			b, err := p.bound(ctx, q.Before, models.BoundBeforeTime)
+			if err != nil {
+				return nil, err
+			}
+			bound = &b
+		}
+		return p.before(ctx, bound, historyLimit(q.Limit))
+	}
+}
+
+func (p historyPager[T]) bound(ctx context.Context, a *HistoryAnchor, fromTime func(time.Time) models.MessageBound) (models.MessageBound, error) {
+	if a.MessageID != nil {
+		return p.resolve(ctx, *a.MessageID)
+	}
+	return fromTime(a.Time), nil
+}
+
+func historyLimit(limit int32) int32 {
+	if limit <= 0 || limit > 100 {
+		return 50
+	}
+	return limit
+}
+
+// boundThrough returns the bound just past b, so that paging back from it
+// includes the message at b. Postgres compares UUIDs bytewise, so the next
+// UUID is b.ID incremented as a big-endian integer.
+func boundThrough(b models.MessageBound) models.MessageBound {
+	for i := len(b.ID) - 1; i >= 0; i-- {
+		b.ID[i]++
+		if b.ID[i] != 0 {
+			break
+		}
+	}
+	return b
+}
@@ -0,0 +1,981 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+var (
+	ErrNotBot                     = errors.New("only bots can manage commands")
+	ErrCommandNotFound            = errors.New("command not found")
+	ErrInvalidCommand             = errors.New("invalid command")
+	ErrInvalidOptions             = errors.New("invalid command options")
+	ErrInteractionNotFound        = errors.New("interaction not found")
+	ErrInteractionTokenInvalid    = errors.New("invalid interaction token")
+	ErrInteractionExpired         = errors.New("interaction has expired")
+	ErrInteractionAcknowledged    = errors.New("interaction has already been acknowledged")
+	ErrInteractionNotAcknowledged = errors.New("interaction must be acknowledged before sending follow-ups")
+	ErrInvalidInteractionResponse = errors.New("invalid interaction response")
+	ErrInteractionDeliveryFailed  = errors.New("bot did not accept the interaction")
+	ErrComponentNotFound          = errors.New("component not found")
+	ErrNotInteractive             = errors.New("message does not accept interactions")
+)
+
+// Command option types.
+const (
+	OptionString  = "string"
+	OptionInteger = "integer"
+	OptionNumber  = "number"
+	OptionBoolean = "boolean"
+	OptionUser    = "user"
+	OptionChannel = "channel"
+	OptionRole    = "role"
+)
+
+// Interaction types and lifecycle.
+const (
+	InteractionCommand   = "command"
+	InteractionComponent = "component"
+
+	InteractionPending   = "pending"
+	InteractionDeferred  = "deferred"
+	InteractionResponded = "responded"
+
+	// ResponseMessage answers with a message; ResponseUpdate edits the
+	// message a component was clicked on; ResponseDeferred acknowledges now
+	// and promises a follow-up.
+	ResponseMessage  = "message"
+	ResponseUpdate   = "update"
+	ResponseDeferred = "deferred"
+)
+
+// InteractionTTL is how long a bot may respond to an interaction or send
+// follow-ups with its token.
+const InteractionTTL = 15 * time.Minute
+
+// interactionCallbackTimeout bounds the HTTP delivery of an interaction.
+const interactionCallbackTimeout = 3 * time.Second
+
+const (
+	maxCommandOptions     = 25
+	maxOptionChoices      = 25
+	maxCommandDescription = 100
+)
+
+var commandNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// CommandOption is one typed parameter of a slash command.
+type CommandOption struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Type        string         `json:"type"`
+	Required    bool           `json:"required,omitempty"`
+	Choices     []OptionChoice `json:"choices,omitempty"`
+}
+
+// OptionChoice restricts a string, integer or number option to fixed values.
+type OptionChoice struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// CommandInput is a command definition as registered by a bot.
+type CommandInput struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options"`
+}
+
+// InteractionOption is an option value supplied by the invoking user.
+type InteractionOption struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ResolvedOption is a validated option value as delivered to the bot.
+type ResolvedOption struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// CommandInteractionData is the data of a command interaction.
+type CommandInteractionData struct {
+	CommandID uuid.UUID        `json:"command_id"`
+	Name      string           `json:"name"`
+	Options   []ResolvedOption `json:"options"`
+}
+
+// ComponentInteractionData is the data of a component interaction. Values
+// holds the selected option values or IDs for selects.
+type ComponentInteractionData struct {
+	CustomID      string   `json:"custom_id"`
+	ComponentType string   `json:"component_type"`
+	Values        []string `json:"values"`
+}
+
+// InteractionPayload is what a bot receives, either as the data of an
+// INTERACTION_CREATE gateway event or as the body of an HTTP callback.
+// Webhook applications only receive HTTP callbacks.
+type InteractionPayload struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	Token     string          `json:"token"`
+	BotID     *uuid.UUID      `json:"bot_id,omitempty"`
+	WebhookID *uuid.UUID      `json:"webhook_id,omitempty"`
+	MessageID *uuid.UUID      `json:"message_id,omitempty"`
+	ServerID  uuid.UUID       `json:"server_id"`
+	ChannelID uuid.UUID       `json:"channel_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Username  string          `json:"username"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// InteractionMessage is a message sent in answer to an interaction.
+// Ephemeral messages are shown only to the invoking user and never stored.
+type InteractionMessage struct {
+	Content    string          `json:"content"`
+	Components json.RawMessage `json:"components,omitempty"`
+	Ephemeral  bool            `json:"ephemeral,omitempty"`
+}
+
+// InteractionResponse is a bot's answer to an interaction. For updates,
+// empty content keeps the message's content and absent components keep its
+// components.
+type InteractionResponse struct {
+	Type       string          `json:"type"`
+	Content    string          `json:"content"`
+	Components json.RawMessage `json:"components,omitempty"`
+	Ephemeral  bool            `json:"ephemeral,omitempty"`
+}
+
+// InteractionTarget is the bot or webhook application an interaction is
+// delivered to. Exactly one of Bot and Webhook is set.
+type InteractionTarget struct {
+	Bot     *models.BotUser
+	Webhook *models.Webhook
+}
+
+// endpoint returns the HTTP endpoint and signing secret of the target.
+func (t *InteractionTarget) endpoint() (string, string) {
+	if t.Bot != nil {
+		return t.Bot.InteractionsURL, t.Bot.InteractionsSecret
+	}
+	return t.Webhook.InteractionsURL, t.Webhook.InteractionsSecret
+}
+
+// InteractionResult describes what a response did. Message is nil for
+// deferred responses; Updated means Message is the edited original, and
+// Ephemeral means Message was not stored and is for the invoking user only.
+type InteractionResult struct {
+	Interaction *models.Interaction
+	Message     *models.Message
+	Updated     bool
+	Ephemeral   bool
+}
+
+type InteractionService struct {
+	queries    *models.Queries
+	permSvc    *PermissionService
+	msgSvc     *MessageService
+	httpClient *http.Client
+}
+
+func NewInteractionService(q *models.Queries, permSvc *PermissionService, msgSvc *MessageService) *InteractionService {
+	return &InteractionService{
+		queries:    q,
+		permSvc:    permSvc,
+		msgSvc:     msgSvc,
+		httpClient: newOutboundClient(interactionCallbackTimeout),
+	}
+}
+
+func (s *InteractionService) Queries() *models.Queries {
+	return s.queries
+}
+
+// --- Command registration (bots) ---
+
+// RegisterCommand creates or replaces a bot command, globally when serverID
+// is nil or for one server the bot is a member of.
+func (s *InteractionService) RegisterCommand(ctx context.Context, botID uuid.UUID, serverID *uuid.UUID, input CommandInput) (*models.SlashCommand, error) {
+	if err := validateCommand(input); err != nil {
+		return nil, err
+	}
+	if err := s.checkBotScope(ctx, botID, serverID); err != nil {
+		return nil, err
+	}
+
+	options := input.Options
+	if options == nil {
+		options = []CommandOption{}
+	}
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := s.queries.UpsertSlashCommand(ctx, models.UpsertSlashCommandParams{
+		BotID:       botID,
+		Name:        input.Name,
+		Description: input.Description,
+		Options:     optionsJSON,
+		ServerID:    serverID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// ListBotCommands returns a bot's global commands, or its commands for one server.
+func (s *InteractionService) ListBotCommands(ctx context.Context, botID uuid.UUID, serverID *uuid.UUID) ([]models.SlashCommand, error) {
+	if err := s.checkBotScope(ctx, botID, serverID); err != nil {
+		return nil, err
+	}
+	return s.queries.GetSlashCommandsByBotScope(ctx, botID, serverID)
+}
+
+// DeleteCommand removes one of the bot's commands.
+func (s *InteractionService) DeleteCommand(ctx context.Context, botID, commandID uuid.UUID) error {
+	cmd, err := s.queries.GetSlashCommandByID(ctx, commandID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCommandNotFound
+		}
+		return err
+	}
+	if cmd.BotID != botID {
+		return ErrCommandNotFound
+	}
+	return s.queries.DeleteSlashCommand(ctx, commandID)
+}
+
+// checkBotScope verifies the caller is a bot and, for server commands, a
+// member of the server.
+func (s *InteractionService) checkBotScope(ctx context.Context, botID uuid.UUID, serverID *uuid.UUID) error {
+	if _, err := s.queries.GetBotUserByID(ctx, botID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotBot
+		}
+		return err
+	}
+	if serverID == nil {
+		return nil
+	}
+	if _, err := s.queries.GetServerMember(ctx, *serverID, botID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotMember
+		}
+		return err
+	}
+	return nil
+}
+
+func validateCommand(input CommandInput) error {
+	if !commandNameRegex.MatchString(input.Name) {
+		return fmt.Errorf("%w: name must be 1-32 lowercase letters, digits, '-' or '_'", ErrInvalidCommand)
+	}
+	if len(input.Description) < 1 || len(input.Description) > maxCommandDescription {
+		return fmt.Errorf("%w: description must be 1-%d characters", ErrInvalidCommand, maxCommandDescription)
+	}
+	if len(input.Options) > maxCommandOptions {
+		return fmt.Errorf("%w: at most %d options", ErrInvalidCommand, maxCommandOptions)
+	}
+
+	seen := make(map[string]bool, len(input.Options))
+	optionalSeen := false
+	for _, opt := range input.Options {
+		if !commandNameRegex.MatchString(opt.Name) {
+			return fmt.Errorf("%w: option name %q is invalid", ErrInvalidCommand, opt.Name)
+		}
+		if seen[opt.Name] {
+			return fmt.Errorf("%w: duplicate option %q", ErrInvalidCommand, opt.Name)
+		}
+		seen[opt.Name] = true
+		if len(opt.Description) < 1 || len(opt.Description) > maxCommandDescription {
+			return fmt.Errorf("%w: option %q description must be 1-%d characters", ErrInvalidCommand, opt.Name, maxCommandDescription)
+		}
+
+		switch opt.Type {
+		case OptionString, OptionInteger, OptionNumber:
+		case OptionBoolean, OptionUser, OptionChannel, OptionRole:
+			if len(opt.Choices) > 0 {
+				return fmt.Errorf("%w: option %q of type %s cannot have choices", ErrInvalidCommand, opt.Name, opt.Type)
+			}
+		default:
+			return fmt.Errorf("%w: option %q has unknown type %q", ErrInvalidCommand, opt.Name, opt.Type)
+		}
+		if len(opt.Choices) > maxOptionChoices {
+			return fmt.Errorf("%w: option %q has more than %d choices", ErrInvalidCommand, opt.Name, maxOptionChoices)
+		}
+		for _, choice := range opt.Choices {
+			if choice.Name == "" {
+				return fmt.Errorf("%w: option %q has a choice without a name", ErrInvalidCommand, opt.Name)
+			}
+			if _, err := parseScalar(opt.Type, choice.Value); err != nil {
+				return fmt.Errorf("%w: option %q choice %q: %v", ErrInvalidCommand, opt.Name, choice.Name, err)
+			}
+		}
+
+		// Required options come first so clients can prompt in order
+		if opt.Required && optionalSeen {
+			return fmt.Errorf("%w: required option %q must come before optional ones", ErrInvalidCommand, opt.Name)
+		}
+		if !opt.Required {
+			optionalSeen = true
+		}
+	}
+	return nil
+}
+
+// --- Invocation (users) ---
+
+// GetChannelCommands lists the commands a user can invoke in a channel.
+func (s *InteractionService) GetChannelCommands(ctx context.Context, channelID, userID uuid.UUID) ([]models.SlashCommand, error) {
+	channel, err := s.checkInvoker(ctx, channelID, userID, models.PermViewChannels|models.PermSendMessages)
+	if err != nil {
+		return nil, err
+	}
+	return s.queries.GetServerSlashCommands(ctx, channel.ServerID)
+}
+
+// CreateCommandInteraction validates an invocation and records it. It
+// returns the payload to deliver (carrying the plaintext token) and the bot
+// it goes to.
+func (s *InteractionService) CreateCommandInteraction(ctx context.Context, channelID, userID, commandID uuid.UUID, username string, options []InteractionOption) (*InteractionPayload, *InteractionTarget, error) {
+	channel, err := s.checkInvoker(ctx, channelID, userID, models.PermViewChannels|models.PermSendMessages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd, err := s.queries.GetSlashCommandByID(ctx, commandID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrCommandNotFound
+		}
+		return nil, nil, err
+	}
+	if cmd.ServerID != nil && *cmd.ServerID != channel.ServerID {
+		return nil, nil, ErrCommandNotFound
+	}
+	// Commands are only usable where their bot is a member
+	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, cmd.BotID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrCommandNotFound
+		}
+		return nil, nil, err
+	}
+
+	bot, err := s.queries.GetBotUserByID(ctx, cmd.BotID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var defs []CommandOption
+	if err := json.Unmarshal(cmd.Options, &defs); err != nil {
+		return nil, nil, err
+	}
+	resolved, err := s.resolveOptions(ctx, channel.ServerID, userID, defs, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := json.Marshal(CommandInteractionData{CommandID: cmd.ID, Name: cmd.Name, Options: resolved})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := s.createInteraction(ctx, models.CreateInteractionParams{
+		Type:      InteractionCommand,
+		BotID:     &cmd.BotID,
+		CommandID: &cmd.ID,
+		ServerID:  channel.ServerID,
+		ChannelID: channelID,
+		UserID:    userID,
+		Data:      data,
+	}, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, &InteractionTarget{Bot: &bot}, nil
+}
+
+// CreateComponentInteraction records a click on a message component (or a
+// selection in a select menu). It is routed to the bot that posted the
+// message, or to the webhook application that did.
+func (s *InteractionService) CreateComponentInteraction(ctx context.Context, messageID, userID uuid.UUID, username, customID string, values []string) (*InteractionPayload, *InteractionTarget, error) {
+	msg, err := s.queries.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrMessageNotFound
+		}
+		return nil, nil, err
+	}
+
+	channel, err := s.checkInvoker(ctx, msg.ChannelID, userID, models.PermViewChannels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	component, ok := findComponent(msg.Components, customID)
+	if !ok {
+		return nil, nil, ErrComponentNotFound
+	}
+	if component.Disabled {
+		return nil, nil, fmt.Errorf("%w: component is disabled", ErrInvalidComponentValues)
+	}
+	ids, err := checkSelectValues(component, values)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range ids {
+		if err := s.checkEntityInServer(ctx, channel.ServerID, userID, selectEntityType(component.Type), id); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidComponentValues, err)
+		}
+	}
+	if values == nil {
+		values = []string{}
+	}
+
+	target, err := s.componentTarget(ctx, &msg, channel.ServerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := json.Marshal(ComponentInteractionData{CustomID: customID, ComponentType: component.Type, Values: values})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	arg := models.CreateInteractionParams{
+		Type:      InteractionComponent,
+		MessageID: &msg.ID,
+		ServerID:  channel.ServerID,
+		ChannelID: msg.ChannelID,
+		UserID:    userID,
+		Data:      data,
+	}
+	if target.Bot != nil {
+		arg.BotID = &target.Bot.ID
+	} else {
+		arg.WebhookID = &target.Webhook.ID
+	}
+
+	payload, err := s.createInteraction(ctx, arg, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, target, nil
+}
+
+// componentTarget finds who handles interactions on a message: the bot that
+// posted it (while still in the server), or the webhook that did when it
+// has an interactions endpoint.
+func (s *InteractionService) componentTarget(ctx context.Context, msg *models.Message, serverID uuid.UUID) (*InteractionTarget, error) {
+	if msg.WebhookID != nil {
+		webhook, err := s.queries.GetWebhookByID(ctx, *msg.WebhookID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrNotInteractive
+			}
+			return nil, err
+		}
+		if webhook.InteractionsURL == "" {
+			return nil, ErrNotInteractive
+		}
+		return &InteractionTarget{Webhook: &webhook}, nil
+	}
+
+	bot, err := s.queries.GetBotUserByID(ctx, msg.AuthorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotInteractive
+		}
+		return nil, err
+	}
+	if _, err := s.queries.GetServerMember(ctx, serverID, bot.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotInteractive
+		}
+		return nil, err
+	}
+	return &InteractionTarget{Bot: &bot}, nil
+}
+
+// selectEntityType maps an entity select to the option type it resolves like.
+func selectEntityType(componentType string) string {
+	switch componentType {
+	case ComponentUserSelect:
+		return OptionUser
+	case ComponentRoleSelect:
+		return OptionRole
+	}
+	return OptionChannel
+}
+
+// createInteraction stores an interaction under a fresh token and builds its
+// delivery payload, which carries the plaintext token.
+func (s *InteractionService) createInteraction(ctx context.Context, arg models.CreateInteractionParams, username string) (*InteractionPayload, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	arg.TokenHash = hashSecret(token)
+
+	interaction, err := s.queries.CreateInteraction(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InteractionPayload{
+		ID:        interaction.ID,
+		Type:      interaction.Type,
+		Token:     token,
+		BotID:     interaction.BotID,
+		WebhookID: interaction.WebhookID,
+		MessageID: interaction.MessageID,
+		ServerID:  interaction.ServerID,
+		ChannelID: interaction.ChannelID,
+		UserID:    interaction.UserID,
+		Username:  username,
+		Data:      interaction.Data,
+		CreatedAt: interaction.CreatedAt,
+	}, nil
+}
+
+// checkInvoker verifies the user may interact in the channel: a member who
+// is not timed out and has the needed channel permissions.
+func (s *InteractionService) checkInvoker(ctx context.Context, channelID, userID uuid.UUID, need int64) (*models.Channel, error) {
+	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+
+	if timedOut, err := s.queries.IsUserTimedOut(ctx, channel.ServerID, userID); err == nil && timedOut {
+		return nil, ErrUserTimedOut
+	}
+
+	perms, err := s.permSvc.ComputeChannelPermissions(ctx, channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.HasPermission(perms, need) {
+		return nil, ErrInsufficientRole
+	}
+	return &channel, nil
+}
+
+// resolveOptions checks the supplied values against the command's option
+// definitions and returns them typed, in definition order.
+func (s *InteractionService) resolveOptions(ctx context.Context, serverID, invokerID uuid.UUID, defs []CommandOption, supplied []InteractionOption) ([]ResolvedOption, error) {
+	values := make(map[string]json.RawMessage, len(supplied))
+	for _, opt := range supplied {
+		if _, dup := values[opt.Name]; dup {
+			return nil, fmt.Errorf("%w: option %q given more than once", ErrInvalidOptions, opt.Name)
+		}
+		values[opt.Name] = opt.Value
+	}
+
+	resolved := []ResolvedOption{}
+	for _, def := range defs {
+		raw, ok := values[def.Name]
+		delete(values, def.Name)
+		if !ok || len(raw) == 0 || string(raw) == "null" {
+			if def.Required {
+				return nil, fmt.Errorf("%w: option %q is required", ErrInvalidOptions, def.Name)
+			}
+			continue
+		}
+
+		value, err := s.resolveValue(ctx, serverID, invokerID, def, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: option %q: %v", ErrInvalidOptions, def.Name, err)
+		}
+		resolved = append(resolved, ResolvedOption{Name: def.Name, Type: def.Type, Value: value})
+	}
+
+	for name := range values {
+		return nil, fmt.Errorf("%w: unknown option %q", ErrInvalidOptions, name)
+	}
+	return resolved, nil
+}
+
+func (s *InteractionService) resolveValue(ctx context.Context, serverID, invokerID uuid.UUID, def CommandOption, raw json.RawMessage) (any, error) {
+	switch def.Type {
+	case OptionUser, OptionChannel, OptionRole:
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return nil, errors.New("must be an ID")
+		}
+		id, err := uuid.Parse(str)
+		if err != nil {
+			return nil, errors.New("must be an ID")
+		}
+		if err := s.checkEntityInServer(ctx, serverID, invokerID, def.Type, id); err != nil {
+			return nil, err
+		}
+		return id, nil
+	}
+
+	value, err := parseScalar(def.Type, raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(def.Choices) > 0 {
+		for _, choice := range def.Choices {
+			if c, err := parseScalar(def.Type, choice.Value); err == nil && c == value {
+				return value, nil
+			}
+		}
+		return nil, errors.New("not one of the allowed choices")
+	}
+	return value, nil
+}
+
+// checkEntityInServer makes sure a user, channel or role option refers to
+// something in the server the command was invoked in. Channels must also be
+// visible to the invoker, so a bot is never handed a private channel the
+// invoker can't see.
+func (s *InteractionService) checkEntityInServer(ctx context.Context, serverID, invokerID uuid.UUID, optType string, id uuid.UUID) error {
+	var err error
+	switch optType {
+	case OptionUser:
+		_, err = s.queries.GetServerMember(ctx, serverID, id)
+	case OptionChannel:
+		var channel models.Channel
+		if channel, err = s.queries.GetChannelByID(ctx, id); err == nil && channel.ServerID != serverID {
+			err = pgx.ErrNoRows
+		}
+		if err == nil {
+			var visible bool
+			// Hidden channels are reported as missing, like other servers' channels
+			if visible, err = s.permSvc.CanViewChannel(ctx, id, invokerID); err == nil && !visible {
+				err = pgx.ErrNoRows
+			}
+		}
+	case OptionRole:
+		var role models.Role
+		if role, err = s.queries.GetRoleByID(ctx, id); err == nil && role.ServerID != serverID {
+			err = pgx.ErrNoRows
+		}
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s not found in this server", optType)
+	}
+	return err
+}
+
+// parseScalar decodes a string, integer, number or boolean option value.
+func parseScalar(optType string, raw json.RawMessage) (any, error) {
+	switch optType {
+	case OptionString:
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.New("must be a string")
+		}
+		if len(v) > MaxMessageLength {
+			return nil, fmt.Errorf("must be at most %d characters", MaxMessageLength)
+		}
+		return v, nil
+	case OptionInteger:
+		var n json.Number
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		v, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return v, nil
+	case OptionNumber:
+		var v float64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return v, nil
+	case OptionBoolean:
+		var v bool
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported option type %q", optType)
+}
+
+// --- Delivery and responses (bots) ---
+
+// SignInteractionPayload computes the X-Signature-256 header for an HTTP
+// interaction callback: HMAC-SHA256 over "<timestamp>.<body>".
+func SignInteractionPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PostInteraction delivers an interaction to the target's HTTP endpoint. It
+// may answer inline with an InteractionResponse (returned), or with 202/204
+// and respond later through the callback endpoint (nil response).
+func (s *InteractionService) PostInteraction(ctx context.Context, target *InteractionTarget, payload *InteractionPayload) (*InteractionResponse, error) {
+	endpoint, secret := target.endpoint()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, interactionCallbackTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInteractionDeliveryFailed, err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-256", SignInteractionPayload(secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInteractionDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: endpoint returned %d", ErrInteractionDeliveryFailed, resp.StatusCode)
+	}
+
+	var out InteractionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: invalid response body", ErrInteractionDeliveryFailed)
+	}
+	return &out, nil
+}
+
+// authorize loads an interaction and checks the bot's token for it.
+func (s *InteractionService) authorize(ctx context.Context, interactionID uuid.UUID, token string) (*models.Interaction, error) {
+	interaction, err := s.queries.GetInteractionByID(ctx, interactionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInteractionNotFound
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(token)), []byte(interaction.TokenHash)) != 1 {
+		return nil, ErrInteractionTokenInvalid
+	}
+	if time.Since(interaction.CreatedAt) > InteractionTTL {
+		return nil, ErrInteractionExpired
+	}
+	return &interaction, nil
+}
+
+// Respond records the initial response to an interaction. A message response
+// posts to the channel as the bot or webhook (or only to the invoking user
+// when ephemeral); an update response edits the message a component was
+// clicked on; a deferred response only acknowledges, and the message follows
+// via FollowUp.
+func (s *InteractionService) Respond(ctx context.Context, interactionID uuid.UUID, token string, resp InteractionResponse) (*InteractionResult, error) {
+	interaction, err := s.authorize(ctx, interactionID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Type {
+	case ResponseDeferred:
+		ok, err := s.queries.TransitionInteraction(ctx, interaction.ID, InteractionPending, InteractionDeferred, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrInteractionAcknowledged
+		}
+		interaction.Status = InteractionDeferred
+		return &InteractionResult{Interaction: interaction}, nil
+
+	case ResponseMessage, ResponseUpdate:
+		if resp.Type == ResponseUpdate && interaction.MessageID == nil {
+			return nil, fmt.Errorf("%w: only component interactions can update their message", ErrInvalidInteractionResponse)
+		}
+		ok, err := s.queries.TransitionInteraction(ctx, interaction.ID, InteractionPending, InteractionResponded, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrInteractionAcknowledged
+		}
+
+		var result *InteractionResult
+		if resp.Type == ResponseUpdate {
+			result, err = s.updateMessage(ctx, interaction, resp)
+		} else {
+			result, err = s.sendMessage(ctx, interaction, InteractionMessage{
+				Content:    resp.Content,
+				Components: resp.Components,
+				Ephemeral:  resp.Ephemeral,
+			})
+		}
+		if err != nil {
+			// Let the bot retry with a valid message
+			_, _ = s.queries.TransitionInteraction(ctx, interaction.ID, InteractionResponded, InteractionPending, nil)
+			return nil, err
+		}
+		interaction.Status = InteractionResponded
+		if !result.Ephemeral {
+			_, _ = s.queries.TransitionInteraction(ctx, interaction.ID, InteractionResponded, InteractionResponded, &result.Message.ID)
+			interaction.ResponseMessageID = &result.Message.ID
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("%w: type must be 'message', 'update' or 'deferred'", ErrInvalidInteractionResponse)
+}
+
+// FollowUp posts another message for an acknowledged interaction. The first
+// follow-up to a deferred interaction becomes its response.
+func (s *InteractionService) FollowUp(ctx context.Context, interactionID uuid.UUID, token string, message InteractionMessage) (*InteractionResult, error) {
+	interaction, err := s.authorize(ctx, interactionID, token)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Status == InteractionPending {
+		return nil, ErrInteractionNotAcknowledged
+	}
+
+	result, err := s.sendMessage(ctx, interaction, message)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Status == InteractionDeferred {
+		var responseID *uuid.UUID
+		if !result.Ephemeral {
+			responseID = &result.Message.ID
+		}
+		if ok, _ := s.queries.TransitionInteraction(ctx, interaction.ID, InteractionDeferred, InteractionResponded, responseID); ok {
+			interaction.Status = InteractionResponded
+			interaction.ResponseMessageID = responseID
+		}
+	}
+	return result, nil
+}
+
+// sendMessage posts a response message as the interaction's bot or webhook.
+func (s *InteractionService) sendMessage(ctx context.Context, interaction *models.Interaction, message InteractionMessage) (*InteractionResult, error) {
+	result := &InteractionResult{Interaction: interaction, Ephemeral: message.Ephemeral}
+
+	var err error
+	switch {
+	case message.Ephemeral:
+		authorID := s.responderID(ctx, interaction)
+		if result.Message, err = s.msgSvc.EphemeralMessage(interaction.ChannelID, authorID, message.Content, message.Components); err == nil {
+			result.Message.WebhookID = interaction.WebhookID
+		}
+	case interaction.WebhookID != nil:
+		var webhook models.Webhook
+		if webhook, err = s.queries.GetWebhookByID(ctx, *interaction.WebhookID); err != nil {
+			return nil, err
+		}
+		result.Message, err = s.msgSvc.SendWebhookMessage(ctx, &webhook, message.Content, message.Components)
+	default:
+		result.Message, err = s.msgSvc.SendMessageWithComponents(ctx, interaction.ChannelID, *interaction.BotID,
+			message.Content, nil, message.Components)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// updateMessage edits the message a component interaction came from.
+func (s *InteractionService) updateMessage(ctx context.Context, interaction *models.Interaction, resp InteractionResponse) (*InteractionResult, error) {
+	msg, err := s.queries.GetMessageByID(ctx, *interaction.MessageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	content := msg.Content
+	if resp.Content != "" {
+		content = s.msgSvc.sanitizer.Sanitize(strings.TrimSpace(resp.Content))
+		if len(content) > MaxMessageLength {
+			return nil, ErrMessageTooLong
+		}
+	}
+	components := msg.Components
+	if resp.Components != nil {
+		if components, err = ParseComponents(resp.Components); err != nil {
+			return nil, err
+		}
+	}
+	if content == "" && len(components) == 0 {
+		return nil, ErrEmptyMessage
+	}
+
+	updated, err := s.queries.UpdateMessageComponents(ctx, msg.ID, content, components)
+	if err != nil {
+		return nil, err
+	}
+	return &InteractionResult{Interaction: interaction, Message: &updated, Updated: true}, nil
+}
+
+// responderID is the author shown on responses: the bot, or the user who
+// created the webhook.
+func (s *InteractionService) responderID(ctx context.Context, interaction *models.Interaction) uuid.UUID {
+	if interaction.BotID != nil {
+		return *interaction.BotID
+	}
+	if webhook, err := s.queries.GetWebhookByID(ctx, *interaction.WebhookID); err == nil {
+		return webhook.CreatorID
+	}
+	return uuid.Nil
+}
+
+// InteractionName returns the command name or component custom ID recorded
+// on an interaction.
+func InteractionName(interaction *models.Interaction) string {
+	var data struct {
+		Name     string `json:"name"`
+		CustomID string `json:"custom_id"`
+	}
+	_ = json.Unmarshal(interaction.Data, &data)
+	if data.Name != "" {
+		return data.Name
+	}
+	return data.CustomID
+}
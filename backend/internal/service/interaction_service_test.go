@@ -0,0 +1,460 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+)
+
+var rollCommand = CommandInput{
+	Name:        "roll",
+	Description: "Roll dice",
+	Options: []CommandOption{
+		{Name: "sides", Description: "Sides per die", Type: OptionInteger, Required: true,
+			Choices: []OptionChoice{{Name: "d6", Value: json.RawMessage(`6`)}, {Name: "d20", Value: json.RawMessage(`20`)}}},
+		{Name: "target", Description: "Who rolls", Type: OptionUser},
+	},
+}
+
+func TestRegisterCommand_Validation(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+
+	bad := []CommandInput{
+		{Name: "Roll", Description: "caps"},
+		{Name: "roll", Description: ""},
+		{Name: "roll", Description: "x", Options: []CommandOption{{Name: "a", Description: "a", Type: "date"}}},
+		{Name: "roll", Description: "x", Options: []CommandOption{
+			{Name: "a", Description: "a", Type: OptionString},
+			{Name: "b", Description: "b", Type: OptionString, Required: true},
+		}},
+		{Name: "roll", Description: "x", Options: []CommandOption{
+			{Name: "a", Description: "a", Type: OptionInteger, Choices: []OptionChoice{{Name: "x", Value: json.RawMessage(`"x"`)}}},
+		}},
+	}
+	for _, input := range bad {
+		_, err := svc.RegisterCommand(ctx, bot.ID, nil, input)
+		assert.ErrorIs(t, err, ErrInvalidCommand, input)
+	}
+
+	// Users cannot register commands
+	_, err = svc.RegisterCommand(ctx, owner.User.ID, nil, rollCommand)
+	assert.ErrorIs(t, err, ErrNotBot)
+
+	// Server commands require the bot to be in the server
+	otherServer := uuid.New()
+	_, err = svc.RegisterCommand(ctx, bot.ID, &otherServer, rollCommand)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestRegisterCommand_UpsertsByName(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+
+	first, err := svc.RegisterCommand(ctx, bot.ID, nil, rollCommand)
+	require.NoError(t, err)
+	updated := rollCommand
+	updated.Description = "Roll some dice"
+	second, err := svc.RegisterCommand(ctx, bot.ID, nil, updated)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	// The same name in a server scope is a separate command
+	serverCmd, err := svc.RegisterCommand(ctx, bot.ID, &server.ID, rollCommand)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, serverCmd.ID)
+
+	global, err := svc.ListBotCommands(ctx, bot.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, global, 1)
+	assert.Equal(t, "Roll some dice", global[0].Description)
+
+	available, err := svc.GetChannelCommands(ctx, channel.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Len(t, available, 2)
+
+	require.NoError(t, svc.DeleteCommand(ctx, bot.ID, serverCmd.ID))
+	assert.ErrorIs(t, svc.DeleteCommand(ctx, member.User.ID, first.ID), ErrCommandNotFound)
+}
+
+func TestCreateCommandInteraction_ValidatesOptions(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+	cmd, err := svc.RegisterCommand(ctx, bot.ID, nil, rollCommand)
+	require.NoError(t, err)
+
+	invoke := func(opts ...InteractionOption) (*InteractionPayload, error) {
+		payload, _, err := svc.CreateCommandInteraction(ctx, channel.ID, member.User.ID, cmd.ID, "member", opts)
+		return payload, err
+	}
+	opt := func(name, value string) InteractionOption {
+		return InteractionOption{Name: name, Value: json.RawMessage(value)}
+	}
+
+	for _, opts := range [][]InteractionOption{
+		nil, // missing required
+		{opt("sides", `"six"`)},
+		{opt("sides", `7`)},   // not a choice
+		{opt("sides", `6.5`)}, // not an integer
+		{opt("sides", `6`), opt("target", `"`+uuid.NewString()+`"`)},
+		{opt("sides", `6`), opt("extra", `1`)},
+	} {
+		_, err := invoke(opts...)
+		assert.ErrorIs(t, err, ErrInvalidOptions, opts)
+	}
+
+	payload, err := invoke(opt("sides", `20`), opt("target", `"`+member.User.ID.String()+`"`))
+	require.NoError(t, err)
+	assert.NotEmpty(t, payload.Token)
+	assert.Equal(t, &bot.ID, payload.BotID)
+
+	var data CommandInteractionData
+	require.NoError(t, json.Unmarshal(payload.Data, &data))
+	assert.Equal(t, "roll", data.Name)
+	require.Len(t, data.Options, 2)
+	assert.EqualValues(t, 20, data.Options[0].Value)
+	assert.Equal(t, member.User.ID.String(), data.Options[1].Value)
+
+	// Outsiders cannot invoke commands
+	outsider := createUser(t)
+	_, _, err = svc.CreateCommandInteraction(ctx, channel.ID, outsider.User.ID, cmd.ID, "outsider", nil)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestInteraction_ChannelValuesMustBeVisibleToInvoker(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+
+	private, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "staff", "text", 1)
+	require.NoError(t, err)
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, private.ID, everyone.ID, 0, models.PermViewChannels)
+	require.NoError(t, err)
+
+	cmd, err := svc.RegisterCommand(ctx, bot.ID, nil, CommandInput{
+		Name: "archive", Description: "Archive a channel",
+		Options: []CommandOption{{Name: "where", Description: "Channel", Type: OptionChannel, Required: true}},
+	})
+	require.NoError(t, err)
+	invoke := func(channelID uuid.UUID) error {
+		_, _, err := svc.CreateCommandInteraction(ctx, channel.ID, member.User.ID, cmd.ID, "member",
+			[]InteractionOption{{Name: "where", Value: json.RawMessage(`"` + channelID.String() + `"`)}})
+		return err
+	}
+	assert.ErrorIs(t, invoke(private.ID), ErrInvalidOptions)
+	assert.NoError(t, invoke(channel.ID))
+
+	msg, err := svc.msgSvc.SendMessageWithComponents(ctx, channel.ID, bot.ID, "Where?", nil, json.RawMessage(`[
+		{"type": "row", "components": [{"type": "channel_select", "custom_id": "where"}]}
+	]`))
+	require.NoError(t, err)
+	_, _, err = svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", "where", []string{private.ID.String()})
+	assert.ErrorIs(t, err, ErrInvalidComponentValues)
+	_, _, err = svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", "where", []string{channel.ID.String()})
+	assert.NoError(t, err)
+}
+
+func TestInteraction_RespondAndFollowUp(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+	cmd, err := svc.RegisterCommand(ctx, bot.ID, nil, CommandInput{Name: "ping", Description: "Ping"})
+	require.NoError(t, err)
+
+	payload, _, err := svc.CreateCommandInteraction(ctx, channel.ID, member.User.ID, cmd.ID, "member", nil)
+	require.NoError(t, err)
+
+	_, err = svc.Respond(ctx, payload.ID, "wrong", InteractionResponse{Type: ResponseMessage, Content: "pong"})
+	assert.ErrorIs(t, err, ErrInteractionTokenInvalid)
+	_, err = svc.FollowUp(ctx, payload.ID, payload.Token, InteractionMessage{Content: "too early"})
+	assert.ErrorIs(t, err, ErrInteractionNotAcknowledged)
+	_, err = svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{Type: ResponseUpdate, Content: "edit"})
+	assert.ErrorIs(t, err, ErrInvalidInteractionResponse)
+
+	result, err := svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{Type: ResponseDeferred})
+	require.NoError(t, err)
+	assert.Nil(t, result.Message)
+	assert.Equal(t, InteractionDeferred, result.Interaction.Status)
+
+	_, err = svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{Type: ResponseMessage, Content: "pong"})
+	assert.ErrorIs(t, err, ErrInteractionAcknowledged)
+
+	result, err = svc.FollowUp(ctx, payload.ID, payload.Token, InteractionMessage{Content: "pong"})
+	require.NoError(t, err)
+	msg := result.Message
+	assert.Equal(t, bot.ID, msg.AuthorID)
+	assert.Equal(t, channel.ID, msg.ChannelID)
+	assert.Equal(t, InteractionResponded, result.Interaction.Status)
+	assert.Equal(t, &msg.ID, result.Interaction.ResponseMessageID)
+}
+
+const approvalComponents = `[
+	{"type": "row", "components": [
+		{"type": "button", "custom_id": "approve", "label": "Approve", "style": "success"},
+		{"type": "button", "label": "Docs", "style": "link", "url": "https://example.com/docs"}
+	]},
+	{"type": "row", "components": [
+		{"type": "role_select", "custom_id": "roles", "max_values": 2}
+	]}
+]`
+
+func TestParseComponents_Validation(t *testing.T) {
+	bad := []string{
+		`{"type": "row"}`,
+		`[{"type": "column", "components": [{"type": "button", "custom_id": "a", "label": "A", "style": "primary"}]}]`,
+		`[{"type": "row", "components": []}]`,
+		`[{"type": "row", "components": [{"type": "button", "label": "A", "style": "primary"}]}]`,
+		`[{"type": "row", "components": [{"type": "button", "custom_id": "a", "label": "A", "style": "link", "url": "https://x.io"}]}]`,
+		`[{"type": "row", "components": [{"type": "button", "custom_id": "a", "label": "A", "style": "primary"},
+			{"type": "user_select", "custom_id": "b"}]}]`,
+		`[{"type": "row", "components": [{"type": "button", "custom_id": "a", "label": "A", "style": "primary"},
+			{"type": "button", "custom_id": "a", "label": "B", "style": "primary"}]}]`,
+		`[{"type": "row", "components": [{"type": "string_select", "custom_id": "s", "options": []}]}]`,
+		`[{"type": "row", "components": [{"type": "string_select", "custom_id": "s", "max_values": 2,
+			"options": [{"label": "One", "value": "1"}]}]}]`,
+	}
+	for _, raw := range bad {
+		_, err := ParseComponents(json.RawMessage(raw))
+		assert.ErrorIs(t, err, ErrInvalidComponents, raw)
+	}
+
+	parsed, err := ParseComponents(json.RawMessage(approvalComponents))
+	require.NoError(t, err)
+	assert.NotEmpty(t, parsed)
+
+	parsed, err = ParseComponents(json.RawMessage(`[]`))
+	require.NoError(t, err)
+	assert.Nil(t, parsed)
+}
+
+func TestComponentInteraction_RoutesToBotAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+
+	msg, err := svc.msgSvc.SendMessageWithComponents(ctx, channel.ID, bot.ID, "", nil, json.RawMessage(approvalComponents))
+	require.NoError(t, err)
+
+	click := func(customID string, values ...string) (*InteractionPayload, *InteractionTarget, error) {
+		return svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", customID, values)
+	}
+
+	_, _, err = click("missing")
+	assert.ErrorIs(t, err, ErrComponentNotFound)
+	_, _, err = click("approve", "x")
+	assert.ErrorIs(t, err, ErrInvalidComponentValues)
+	_, _, err = click("roles", uuid.NewString())
+	assert.ErrorIs(t, err, ErrInvalidComponentValues)
+	_, _, err = click("roles")
+	assert.ErrorIs(t, err, ErrInvalidComponentValues)
+
+	payload, target, err := click("approve")
+	require.NoError(t, err)
+	require.NotNil(t, target.Bot)
+	assert.Equal(t, bot.ID, target.Bot.ID)
+	assert.Equal(t, &msg.ID, payload.MessageID)
+
+	var data ComponentInteractionData
+	require.NoError(t, json.Unmarshal(payload.Data, &data))
+	assert.Equal(t, "approve", data.CustomID)
+	assert.Equal(t, ComponentButton, data.ComponentType)
+
+	result, err := svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{
+		Type: ResponseUpdate, Content: "Approved", Components: json.RawMessage(`[]`),
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Updated)
+	assert.Equal(t, msg.ID, result.Message.ID)
+	assert.Equal(t, "Approved", result.Message.Content)
+	assert.Empty(t, result.Message.Components)
+
+	// The components are gone, so the message no longer accepts clicks
+	_, _, err = click("approve")
+	assert.ErrorIs(t, err, ErrComponentNotFound)
+}
+
+func TestComponentInteraction_EphemeralReply(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+
+	msg, err := svc.msgSvc.SendMessageWithComponents(ctx, channel.ID, bot.ID, "Pick", nil, json.RawMessage(approvalComponents))
+	require.NoError(t, err)
+
+	payload, _, err := svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", "approve", nil)
+	require.NoError(t, err)
+
+	result, err := svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{
+		Type: ResponseMessage, Content: "Only you can see this", Ephemeral: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Ephemeral)
+	assert.Equal(t, InteractionResponded, result.Interaction.Status)
+	assert.Nil(t, result.Interaction.ResponseMessageID)
+
+	// Ephemeral replies are never stored
+	_, err = queries().GetMessageByID(ctx, result.Message.ID)
+	assert.Error(t, err)
+}
+
+func TestComponentInteraction_RoutesToWebhook(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+	webhookSvc := NewWebhookService(queries(), permSvc)
+	webhook, token, err := webhookSvc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+	exec, err := webhookSvc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{
+		Content: "Deploy?", Components: json.RawMessage(approvalComponents),
+	})
+	require.NoError(t, err)
+	msg := exec.Message
+	assert.Equal(t, &webhook.ID, msg.WebhookID)
+
+	// Without an interactions endpoint the webhook's components are inert
+	_, _, err = svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", "approve", nil)
+	assert.ErrorIs(t, err, ErrNotInteractive)
+
+	_, err = queries().UpdateWebhookInteractionsEndpoint(ctx, webhook.ID, "https://ci.example.com/interactions", "secret")
+	require.NoError(t, err)
+
+	payload, target, err := svc.CreateComponentInteraction(ctx, msg.ID, member.User.ID, "member", "approve", nil)
+	require.NoError(t, err)
+	require.NotNil(t, target.Webhook)
+	assert.Nil(t, payload.BotID)
+	assert.Equal(t, &webhook.ID, payload.WebhookID)
+
+	result, err := svc.Respond(ctx, payload.ID, payload.Token, InteractionResponse{Type: ResponseMessage, Content: "Deploying"})
+	require.NoError(t, err)
+	assert.Equal(t, &webhook.ID, result.Message.WebhookID)
+	assert.Equal(t, owner.User.ID, result.Message.AuthorID)
+}
+
+func TestPostInteraction_SignsAndAcceptsInlineResponse(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewInteractionService(queries(), permSvc, NewMessageService(queries(), permSvc))
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Bots", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	bot, _, err := NewBotService(queries(), permSvc).CreateBot(ctx, owner.User.ID, "bot_"+uuid.NewString()[:8])
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bot.ID, "member"))
+	cmd, err := svc.RegisterCommand(ctx, bot.ID, nil, CommandInput{Name: "ping", Description: "Ping"})
+	require.NoError(t, err)
+
+	const secret = "shh"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := SignInteractionPayload(secret, r.Header.Get("X-Signature-Timestamp"), body)
+		if r.Header.Get("X-Signature-256") != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(InteractionResponse{Type: ResponseMessage, Content: "pong"})
+	}))
+	defer srv.Close()
+
+	// Set directly: SetInteractionsURL and the callback client both reject
+	// loopback addresses
+	endpoint, err := queries().UpdateBotInteractionsEndpoint(ctx, bot.ID, srv.URL, secret)
+	require.NoError(t, err)
+	svc.httpClient = srv.Client()
+
+	payload, _, err := svc.CreateCommandInteraction(ctx, channel.ID, member.User.ID, cmd.ID, "member", nil)
+	require.NoError(t, err)
+
+	resp, err := svc.PostInteraction(ctx, &InteractionTarget{Bot: &endpoint}, payload)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, "pong", resp.Content)
+
+	endpoint.InteractionsSecret = "wrong"
+	_, err = svc.PostInteraction(ctx, &InteractionTarget{Bot: &endpoint}, payload)
+	assert.ErrorIs(t, err, ErrInteractionDeliveryFailed)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"html"
+	"regexp"
+
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+const uuidPattern = `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+
+var (
+	// mentionRegex matches <@user>, <@&role>, <#channel>, and @everyone or
+	// @here when they do not follow a word character (as in an email address).
+	mentionRegex = regexp.MustCompile(`<(@&|@|#)(` + uuidPattern + `)>|(?:^|[^\w])@(everyone|here)\b`)
+
+	// codeRegex matches code blocks and inline code, where mentions are
+	// shown literally.
+	codeRegex = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// Mentions is what a message mentions. IDs are unique and in order of first
+// appearance.
+type Mentions struct {
+	Users    []uuid.UUID
+	Roles    []uuid.UUID
+	Channels []uuid.UUID
+	Everyone bool
+	Here     bool
+}
+
+// ParseMentions extracts the mentions from message content. Content is
+// stored HTML-escaped, so both escaped and raw forms are understood.
+func ParseMentions(content string) Mentions {
+	content = codeRegex.ReplaceAllString(html.UnescapeString(content), "")
+
+	var m Mentions
+	seen := make(map[string]bool)
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		switch match[3] {
+		case "everyone":
+			m.Everyone = true
+			continue
+		case "here":
+			m.Here = true
+			continue
+		}
+
+		id, err := uuid.Parse(match[2])
+		if err != nil || seen[match[1]+match[2]] {
+			continue
+		}
+		seen[match[1]+match[2]] = true
+		switch match[1] {
+		case "@":
+			m.Users = append(m.Users, id)
+		case "@&":
+			m.Roles = append(m.Roles, id)
+		case "#":
+			m.Channels = append(m.Channels, id)
+		}
+	}
+	return m
+}
+
+// resolveMentions returns the members a message in the channel notifies:
+// mentioned users, holders of mentioned roles and, if the author holds
+// PermMentionEveryone, every member for @everyone or every online member for
+// @here. Mentioning the @everyone role counts as @everyone. The author and
+// members who cannot view the channel are left out.
+func (s *MessageService) resolveMentions(ctx context.Context, channel models.Channel, authorID uuid.UUID, m Mentions) ([]uuid.UUID, error) {
+	if len(m.Users) == 0 && len(m.Roles) == 0 && !m.Everyone && !m.Here {
+		return nil, nil
+	}
+
+	memberIDs, err := s.queries.GetServerMemberUserIDs(ctx, channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		isMember[id] = true
+	}
+
+	everyone, here := m.Everyone, m.Here
+	var roleIDs []uuid.UUID
+	if len(m.Roles) > 0 {
+		everyoneRole, err := s.queries.GetEveryoneRole(ctx, channel.ServerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, roleID := range m.Roles {
+			if roleID == everyoneRole.ID {
+				everyone = true
+			} else {
+				roleIDs = append(roleIDs, roleID)
+			}
+		}
+	}
+	if everyone || here {
+		allowed, err := s.permSvc.HasChannelPermission(ctx, channel.ID, authorID, models.PermMentionEveryone)
+		if err != nil {
+			return nil, err
+		}
+		everyone = everyone && allowed
+		here = here && allowed && s.isOnline != nil
+	}
+
+	var candidates []uuid.UUID
+	switch {
+	case everyone:
+		candidates = memberIDs
+	case here:
+		for _, id := range memberIDs {
+			if s.isOnline(id) {
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	candidates = append(candidates, m.Users...)
+	for _, roleID := range roleIDs {
+		holders, err := s.queries.GetRoleMemberIDs(ctx, channel.ServerID, roleID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, holders...)
+	}
+
+	seen := map[uuid.UUID]bool{authorID: true}
+	var ids []uuid.UUID
+	for _, id := range candidates {
+		if seen[id] || !isMember[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return s.permSvc.FilterChannelViewers(ctx, channel, ids)
+}
@@ -2,10 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"math"
-	"regexp"
 	"strings"
 	"time"
 
@@ -16,28 +17,6 @@ import (
 	"github.com/M-McCallum/thicket/internal/models"
 )
 
-var mentionRegex = regexp.MustCompile(`<@([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})>`)
-
-func ParseMentions(content string) []uuid.UUID {
-	matches := mentionRegex.FindAllStringSubmatch(content, -1)
-	seen := make(map[uuid.UUID]bool)
-	var ids []uuid.UUID
-	for _, match := range matches {
-		if len(match) < 2 {
-			continue
-		}
-		id, err := uuid.Parse(match[1])
-		if err != nil {
-			continue
-		}
-		if !seen[id] {
-			seen[id] = true
-			ids = append(ids, id)
-		}
-	}
-	return ids
-}
-
 // SlowModeError is returned when a user sends messages too quickly in a slow-mode channel.
 type SlowModeError struct {
 	RetryAfter int
@@ -80,6 +59,7 @@ type MessageService struct {
 	queries    *models.Queries
 	permSvc    *PermissionService
 	automodSvc *AutoModService
+	isOnline   func(uuid.UUID) bool
 	sanitizer  *bluemonday.Policy
 }
 
@@ -96,40 +76,179 @@ func (s *MessageService) SetAutoModService(as *AutoModService) {
 	s.automodSvc = as
 }
 
+// SetOnlineChecker sets how @here decides which members are online. Without
+// one, @here notifies nobody.
+func (s *MessageService) SetOnlineChecker(fn func(uuid.UUID) bool) {
+	s.isOnline = fn
+}
+
 func (s *MessageService) Queries() *models.Queries {
 	return s.queries
 }
 
 func (s *MessageService) SendMessage(ctx context.Context, channelID, authorID uuid.UUID, content string, replyToID *uuid.UUID, msgType ...string) (*models.Message, error) {
-	content = s.sanitizer.Sanitize(strings.TrimSpace(content))
+	mt := "text"
+	if len(msgType) > 0 && msgType[0] != "" {
+		mt = msgType[0]
+	}
 
+	msg, _, err := s.SendMessageWithOptions(ctx, channelID, authorID, content, SendMessageOptions{
+		Type:      mt,
+		ReplyToID: replyToID,
+	})
+	return msg, err
+}
+
+// SendMessageWithComponents sends a message carrying button and select rows.
+// Content may be empty when components are present.
+func (s *MessageService) SendMessageWithComponents(ctx context.Context, channelID, authorID uuid.UUID, content string, replyToID *uuid.UUID, components json.RawMessage) (*models.Message, error) {
+	msg, _, err := s.SendMessageWithOptions(ctx, channelID, authorID, content, SendMessageOptions{
+		ReplyToID:  replyToID,
+		Components: components,
+	})
+	return msg, err
+}
+
+// SendMessageOptions are the optional parts of a channel message.
+type SendMessageOptions struct {
+	Type       string
+	ReplyToID  *uuid.UUID
+	Components json.RawMessage
+	// Nonce makes the send idempotent: repeating it within NonceWindow
+	// returns the original message.
+	Nonce string
+}
+
+// SendMessageWithOptions sends a channel message. replayed reports that msg
+// is an earlier message returned for a repeated nonce, so nothing new was
+// sent.
+func (s *MessageService) SendMessageWithOptions(ctx context.Context, channelID, authorID uuid.UUID, content string, opts SendMessageOptions) (msg *models.Message, replayed bool, err error) {
+	mt := opts.Type
+	if mt == "" {
+		mt = "text"
+	}
+
+	components, err := ParseComponents(opts.Components)
+	if err != nil {
+		return nil, false, err
+	}
+
+	nonce, err := parseNonce(opts.Nonce)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return s.send(ctx, models.CreateMessageParams{
+		ChannelID:  channelID,
+		AuthorID:   authorID,
+		Content:    content,
+		Type:       mt,
+		ReplyToID:  opts.ReplyToID,
+		Components: components,
+		Nonce:      nonce,
+	})
+}
+
+// SendWebhookMessage posts a message on behalf of a webhook. Webhook
+// messages are authored by the webhook's creator and carry its ID; they
+// skip member checks since the webhook token authorizes them.
+func (s *MessageService) SendWebhookMessage(ctx context.Context, webhook *models.Webhook, content string, components json.RawMessage) (*models.Message, error) {
+	content, components, err := s.prepareComponentMessage(content, components)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.queries.CreateMessage(ctx, models.CreateMessageParams{
+		ChannelID:  webhook.ChannelID,
+		AuthorID:   webhook.CreatorID,
+		Content:    content,
+		Type:       "text",
+		Components: components,
+		WebhookID:  &webhook.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// EphemeralMessage builds a message that is shown only to one user and never
+// stored, such as a private reply to an interaction.
+func (s *MessageService) EphemeralMessage(channelID, authorID uuid.UUID, content string, components json.RawMessage) (*models.Message, error) {
+	content, components, err := s.prepareComponentMessage(content, components)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.Message{
+		ID:         uuid.New(),
+		ChannelID:  channelID,
+		AuthorID:   authorID,
+		Content:    content,
+		Type:       "text",
+		Components: components,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, nil
+}
+
+// prepareComponentMessage sanitizes and validates a message that may consist
+// of components alone.
+func (s *MessageService) prepareComponentMessage(content string, components json.RawMessage) (string, json.RawMessage, error) {
+	components, err := ParseComponents(components)
+	if err != nil {
+		return "", nil, err
+	}
+	content = s.sanitizer.Sanitize(strings.TrimSpace(content))
 	if len(content) > MaxMessageLength {
-		return nil, ErrMessageTooLong
+		return "", nil, ErrMessageTooLong
+	}
+	if content == "" && len(components) == 0 {
+		return "", nil, ErrEmptyMessage
 	}
+	return content, components, nil
+}
 
-	mt := "text"
-	if len(msgType) > 0 && msgType[0] != "" {
-		mt = msgType[0]
+func (s *MessageService) send(ctx context.Context, arg models.CreateMessageParams) (*models.Message, bool, error) {
+	channelID, authorID, replyToID, mt := arg.ChannelID, arg.AuthorID, arg.ReplyToID, arg.Type
+	content := s.sanitizer.Sanitize(strings.TrimSpace(arg.Content))
+
+	if len(content) > MaxMessageLength {
+		return nil, false, ErrMessageTooLong
 	}
 
-	// Allow empty content for sticker messages or messages with attachments
-	if content == "" && mt == "text" {
-		return nil, ErrEmptyMessage
+	// Allow empty content for sticker messages, messages with attachments
+	// and component-only messages
+	if content == "" && mt == "text" && len(arg.Components) == 0 {
+		return nil, false, ErrEmptyMessage
 	}
 
 	channel, err := s.queries.GetChannelByID(ctx, channelID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrChannelNotFound
+			return nil, false, ErrChannelNotFound
 		}
-		return nil, err
+		return nil, false, err
 	}
 
 	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, authorID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotMember
+			return nil, false, ErrNotMember
 		}
-		return nil, err
+		return nil, false, err
+	}
+
+	// A retried send returns the message the first attempt created
+	findSent := func(ctx context.Context, nonce string, since time.Time) (models.Message, error) {
+		return s.queries.GetMessageByNonce(ctx, channelID, authorID, nonce, since)
+	}
+	sent, ok, err := findByNonce(ctx, arg.Nonce, findSent)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return &sent, true, nil
 	}
 
 	// Enforce slow mode
@@ -147,13 +266,13 @@ func (s *MessageService) SendMessage(ctx context.Context, channelID, authorID uu
 		if !canBypass {
 			lastTime, err := s.queries.GetLastUserMessageTime(ctx, channelID, authorID)
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 			if lastTime != nil {
 				elapsed := time.Since(*lastTime).Seconds()
 				remaining := float64(channel.SlowModeInterval) - elapsed
 				if remaining > 0 {
-					return nil, &SlowModeError{RetryAfter: int(math.Ceil(remaining))}
+					return nil, false, &SlowModeError{RetryAfter: int(math.Ceil(remaining))}
 				}
 			}
 		}
@@ -163,28 +282,28 @@ func (s *MessageService) SendMessage(ctx context.Context, channelID, authorID uu
 	if mt == "gif" {
 		server, err := s.queries.GetServerByID(ctx, channel.ServerID)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		if !server.GifsEnabled {
-			return nil, ErrGifsDisabled
+			return nil, false, ErrGifsDisabled
 		}
 	}
 
 	// Check if user is timed out
 	if timedOut, err := s.queries.IsUserTimedOut(ctx, channel.ServerID, authorID); err == nil && timedOut {
-		return nil, ErrUserTimedOut
+		return nil, false, ErrUserTimedOut
 	}
 
 	// AutoMod check — before persisting the message
 	if s.automodSvc != nil && content != "" {
 		action, err := s.automodSvc.CheckMessage(ctx, channel.ServerID, channelID, authorID, content)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		if action != nil && action.Triggered {
 			if action.Action == "delete" {
 				s.automodSvc.ExecuteAction(ctx, action, channel.ServerID, channelID, authorID, content)
-				return nil, &AutoModBlockedError{RuleName: action.RuleName, Action: action.Action}
+				return nil, false, &AutoModBlockedError{RuleName: action.RuleName, Action: action.Action}
 			}
 			// For timeout and alert, we still save the message but execute the action after
 			defer func() {
@@ -198,37 +317,39 @@ func (s *MessageService) SendMessage(ctx context.Context, channelID, authorID uu
 		replyMsg, err := s.queries.GetMessageByID(ctx, *replyToID)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return nil, ErrMessageNotFound
+				return nil, false, ErrMessageNotFound
 			}
-			return nil, err
+			return nil, false, err
 		}
 		if replyMsg.ChannelID != channelID {
-			return nil, ErrReplyNotInChannel
+			return nil, false, ErrReplyNotInChannel
 		}
 	}
 
-	msg, err := s.queries.CreateMessage(ctx, models.CreateMessageParams{
-		ChannelID: channelID,
-		AuthorID:  authorID,
-		Content:   content,
-		Type:      mt,
-		ReplyToID: replyToID,
-	})
+	arg.Content = content
+	msg, replayed, err := createOnce(ctx, arg.Nonce, func() (models.Message, error) {
+		return s.queries.CreateMessage(ctx, arg)
+	}, findSent)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if replayed {
+		return &msg, true, nil
 	}
 
-	// Parse mentions and create notifications
-	mentionedIDs := ParseMentions(content)
+	// Resolve mentions and create notifications; these drive unread mention
+	// counts and who receives MENTION_CREATE and mention-level NOTIFICATIONs
+	mentionedIDs, err := s.resolveMentions(ctx, channel, authorID, ParseMentions(content))
+	if err != nil {
+		log.Printf("resolve mentions for message %s: %v", msg.ID, err)
+	}
 	if len(mentionedIDs) > 0 {
-		for _, mentionedID := range mentionedIDs {
-			if mentionedID != authorID { // Don't notify yourself
-				_ = s.queries.CreateMentionNotification(ctx, mentionedID, msg.ID, channelID, channel.ServerID)
-			}
+		if err := s.queries.CreateMentionNotifications(ctx, mentionedIDs, msg.ID, channelID, channel.ServerID); err != nil {
+			log.Printf("create mention notifications for message %s: %v", msg.ID, err)
 		}
 	}
 
-	return &msg, nil
+	return &msg, false, nil
 }
 
 // CrossPostMessage creates cross-posted copies in all channels following the given announcement channel.
@@ -266,6 +387,13 @@ func (s *MessageService) CrossPostMessage(ctx context.Context, sourceChannel mod
 }
 
 func (s *MessageService) GetMessages(ctx context.Context, channelID, userID uuid.UUID, before *time.Time, limit int32) ([]models.MessageWithAuthor, error) {
+	return s.GetMessageHistory(ctx, channelID, userID, HistoryQuery{Before: AtTime(before), Limit: limit})
+}
+
+// GetMessageHistory returns a page of channel history, newest first.
+// Deleted messages are tombstones or left out, as the server's
+// DeletedMessageDisplay setting says.
+func (s *MessageService) GetMessageHistory(ctx context.Context, channelID, userID uuid.UUID, q HistoryQuery) ([]models.MessageWithAuthor, error) {
 	channel, err := s.queries.GetChannelByID(ctx, channelID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -281,47 +409,33 @@ func (s *MessageService) GetMessages(ctx context.Context, channelID, userID uuid
 		return nil, err
 	}
 
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	msgs, err := s.queries.GetChannelMessages(ctx, models.GetChannelMessagesParams{
-		ChannelID: channelID,
-		Before:    before,
-		Limit:     limit,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return s.filterBlockedMessages(ctx, userID, msgs)
-}
-
-func (s *MessageService) GetMessagesAfter(ctx context.Context, channelID, userID uuid.UUID, after time.Time, limit int32) ([]models.MessageWithAuthor, error) {
-	// Verify membership
-	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	server, err := s.queries.GetServerByID(ctx, channel.ServerID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrChannelNotFound
-		}
-		return nil, err
-	}
-	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotMember
-		}
 		return nil, err
 	}
+	tombstones := server.DeletedMessageDisplay == models.DeletedMessageTombstone
 
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	msgs, err := s.queries.GetChannelMessagesAfter(ctx, models.GetChannelMessagesAfterParams{
-		ChannelID: channelID,
-		After:     after,
-		Limit:     limit,
-	})
+	pager := historyPager[models.MessageWithAuthor]{
+		resolve: func(ctx context.Context, messageID uuid.UUID) (models.MessageBound, error) {
+			msg, err := s.queries.GetMessageByID(ctx, messageID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Paging from a message deleted since it was fetched keeps
+				// working; only its position is used
+				msg, err = s.queries.GetDeletedMessageByID(ctx, messageID, time.Time{})
+			}
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && msg.ChannelID != channelID) {
+				return models.MessageBound{}, ErrMessageNotFound
+			}
+			return models.MessageBound{CreatedAt: msg.CreatedAt, ID: msg.ID}, err
+		},
+		before: func(ctx context.Context, bound *models.MessageBound, limit int32) ([]models.MessageWithAuthor, error) {
+			return s.queries.GetChannelMessages(ctx, models.GetChannelMessagesParams{ChannelID: channelID, Before: bound, Limit: limit, IncludeDeleted: tombstones})
+		},
+		after: func(ctx context.Context, bound models.MessageBound, limit int32) ([]models.MessageWithAuthor, error) {
+			return s.queries.GetChannelMessagesAfter(ctx, models.GetChannelMessagesAfterParams{ChannelID: channelID, After: bound, Limit: limit, IncludeDeleted: tombstones})
+		},
+	}
+	msgs, err := pager.page(ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -408,7 +522,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID, userID uu
 
 	// Author can delete their own messages
 	if msg.AuthorID == userID {
-		return s.queries.DeleteMessage(ctx, messageID)
+		return s.queries.DeleteMessage(ctx, messageID, &userID)
 	}
 
 	// Users with MANAGE_MESSAGES can delete any message
@@ -425,7 +539,7 @@ func (s *MessageService) DeleteMessage(ctx context.Context, messageID, userID uu
 		return ErrNotAuthor
 	}
 
-	return s.queries.DeleteMessage(ctx, messageID)
+	return s.queries.DeleteMessage(ctx, messageID, &userID)
 }
 
 // Pin operations
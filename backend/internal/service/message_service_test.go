@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/M-McCallum/thicket/internal/models"
 	"github.com/M-McCallum/thicket/internal/testutil"
 )
 
@@ -150,6 +152,118 @@ func TestGetMessages_CursorPagination(t *testing.T) {
 	assert.Equal(t, "old", messages[0].Content)
 }
 
+func TestGetMessageHistory_SameTimestamp(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	_, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+
+	var sent []uuid.UUID
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		msg, err := svc.SendMessage(ctx, channel.ID, owner.User.ID, content, nil)
+		require.NoError(t, err)
+		sent = append(sent, msg.ID)
+	}
+	_, err = testDB.Pool.Exec(ctx, `UPDATE messages SET created_at = '2026-01-01T00:00:00Z' WHERE channel_id = $1`, channel.ID)
+	require.NoError(t, err)
+
+	// Paging two at a time by ID visits every message exactly once
+	var seen []uuid.UUID
+	query := HistoryQuery{Limit: 2}
+	for {
+		page, err := svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, query)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		for _, m := range page {
+			seen = append(seen, m.ID)
+		}
+		last := page[len(page)-1].ID
+		query.Before = &HistoryAnchor{MessageID: &last}
+	}
+	assert.ElementsMatch(t, sent, seen)
+	assert.Len(t, seen, len(sent))
+
+	// After and around an ID, newest first
+	after, err := svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, HistoryQuery{After: &HistoryAnchor{MessageID: &seen[2]}})
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, seen[:2], []uuid.UUID{after[0].ID, after[1].ID})
+
+	around, err := svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, HistoryQuery{Around: &HistoryAnchor{MessageID: &seen[2]}, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, around, 3)
+	assert.Equal(t, seen[1:4], []uuid.UUID{around[0].ID, around[1].ID, around[2].ID})
+}
+
+func TestGetMessageHistory_AnchorInOtherChannel(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+	other, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "other", "text", 1)
+	require.NoError(t, err)
+
+	msg, err := svc.SendMessage(ctx, other.ID, owner.User.ID, "elsewhere", nil)
+	require.NoError(t, err)
+
+	_, err = svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, HistoryQuery{Before: &HistoryAnchor{MessageID: &msg.ID}})
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	_, err = svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, HistoryQuery{Before: &HistoryAnchor{MessageID: &msg.ID}, After: &HistoryAnchor{MessageID: &msg.ID}})
+	assert.ErrorIs(t, err, ErrInvalidHistoryQuery)
+}
+
+func TestSendMessageWithOptions_NonceReplay(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	_, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+
+	first, replayed, err := svc.SendMessageWithOptions(ctx, channel.ID, owner.User.ID, "hello", SendMessageOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	require.NotNil(t, first.Nonce)
+	assert.Equal(t, "n-1", *first.Nonce)
+
+	// A retry returns the original message without sending another
+	retry, replayed, err := svc.SendMessageWithOptions(ctx, channel.ID, owner.User.ID, "hello", SendMessageOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ID, retry.ID)
+
+	messages, err := svc.GetMessages(ctx, channel.ID, owner.User.ID, nil, 50)
+	require.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// Once the window has passed the nonce sends a new message
+	_, err = testDB.Pool.Exec(ctx, `UPDATE messages SET created_at = $2 WHERE id = $1`, first.ID, time.Now().Add(-NonceWindow-time.Minute))
+	require.NoError(t, err)
+	later, replayed, err := svc.SendMessageWithOptions(ctx, channel.ID, owner.User.ID, "hello", SendMessageOptions{Nonce: "n-1"})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, first.ID, later.ID)
+}
+
+func TestSendMessageWithOptions_NonceTooLong(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	_, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+
+	_, _, err = svc.SendMessageWithOptions(ctx, channel.ID, owner.User.ID, "hello", SendMessageOptions{Nonce: strings.Repeat("n", MaxNonceLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidNonce)
+}
+
 func TestUpdateMessage_Success(t *testing.T) {
 	svc := NewMessageService(queries(), NewPermissionService(queries()))
 	owner := createUser(t)
@@ -278,6 +392,208 @@ func TestDeleteMessage_ByMember_Denied(t *testing.T) {
 	assert.ErrorIs(t, err, ErrNotAuthor)
 }
 
+func TestBulkDeleteMessages(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	member := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	other, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "other", "text", 1)
+	require.NoError(t, err)
+
+	var ids []uuid.UUID
+	for _, content := range []string{"spam 1", "spam 2", "spam 3"} {
+		msg, err := svc.SendMessage(ctx, channel.ID, member.User.ID, content, nil)
+		require.NoError(t, err)
+		ids = append(ids, msg.ID)
+	}
+	elsewhere, err := svc.SendMessage(ctx, other.ID, member.User.ID, "spam elsewhere", nil)
+	require.NoError(t, err)
+
+	// Members without ManageMessages cannot bulk delete
+	_, err = svc.BulkDeleteMessages(ctx, channel.ID, member.User.ID, ids, "")
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+
+	// Messages from other channels are skipped
+	deleted, err := svc.BulkDeleteMessages(ctx, channel.ID, owner.User.ID, append(ids, elsewhere.ID, ids[0]), "raid")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, ids, deleted)
+
+	// Deleted messages stay in history as tombstones
+	remaining, err := svc.GetMessages(ctx, channel.ID, owner.User.ID, nil, 50)
+	require.NoError(t, err)
+	require.Len(t, remaining, 3)
+	for _, m := range remaining {
+		assert.NotNil(t, m.DeletedAt)
+	}
+	_, err = queries().GetMessageByID(ctx, elsewhere.ID)
+	assert.NoError(t, err)
+
+	entries, err := queries().GetAuditLog(ctx, server.ID, 10, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, "MESSAGE_BULK_DELETE", entries[0].Action)
+
+	tooMany := make([]uuid.UUID, MaxBulkDelete+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	_, err = svc.BulkDeleteMessages(ctx, channel.ID, owner.User.ID, tooMany, "")
+	assert.ErrorIs(t, err, ErrTooManyMessages)
+	_, err = svc.BulkDeleteMessages(ctx, channel.ID, owner.User.ID, nil, "")
+	assert.ErrorIs(t, err, ErrNoMessagesToDelete)
+}
+
+func TestPurgeMessages(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	member := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	keep, err := svc.SendMessage(ctx, channel.ID, owner.User.ID, "buy cheap stuff", nil)
+	require.NoError(t, err)
+	spam, err := svc.SendMessage(ctx, channel.ID, member.User.ID, "BUY cheap stuff & more", nil)
+	require.NoError(t, err)
+	_, err = svc.SendMessage(ctx, channel.ID, member.User.ID, "hello", nil)
+	require.NoError(t, err)
+
+	_, err = svc.PurgeMessages(ctx, channel.ID, owner.User.ID, PurgeFilter{}, "")
+	assert.ErrorIs(t, err, ErrEmptyPurgeFilter)
+
+	// Author and content filters combine; content matches escaped text
+	deleted, err := svc.PurgeMessages(ctx, channel.ID, owner.User.ID, PurgeFilter{
+		AuthorID: &member.User.ID,
+		Contains: "cheap stuff &",
+	}, "spam wave")
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{spam.ID}, deleted)
+
+	// LIKE wildcards match literally
+	deleted, err = svc.PurgeMessages(ctx, channel.ID, owner.User.ID, PurgeFilter{Contains: "%"}, "")
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	_, err = queries().GetMessageByID(ctx, keep.ID)
+	assert.NoError(t, err)
+}
+
+func TestDeleteMessage_SoftDelete(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	serverSvc := NewServerService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+
+	kept, err := svc.SendMessage(ctx, channel.ID, owner.User.ID, "kept", nil)
+	require.NoError(t, err)
+	msg, err := svc.SendMessage(ctx, channel.ID, owner.User.ID, "regrettable", nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteMessage(ctx, msg.ID, owner.User.ID))
+
+	_, err = queries().GetMessageByID(ctx, msg.ID)
+	assert.Error(t, err)
+	deleted, err := queries().GetDeletedMessageByID(ctx, msg.ID, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "regrettable", deleted.Content)
+	assert.Equal(t, &owner.User.ID, deleted.DeletedBy)
+
+	// Tombstones by default, with the content withheld
+	history, err := svc.GetMessages(ctx, channel.ID, owner.User.ID, nil, 50)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, msg.ID, history[0].ID)
+	assert.NotNil(t, history[0].DeletedAt)
+	assert.Empty(t, history[0].Content)
+	assert.Nil(t, history[0].DeletedBy)
+
+	// Paging from a tombstone works
+	older, err := svc.GetMessageHistory(ctx, channel.ID, owner.User.ID, HistoryQuery{Before: &HistoryAnchor{MessageID: &msg.ID}})
+	require.NoError(t, err)
+	require.Len(t, older, 1)
+	assert.Equal(t, kept.ID, older[0].ID)
+
+	hidden := models.DeletedMessageHidden
+	_, err = serverSvc.UpdateServer(ctx, server.ID, owner.User.ID, nil, nil, nil, nil, nil, nil, &hidden)
+	require.NoError(t, err)
+	history, err = svc.GetMessages(ctx, channel.ID, owner.User.ID, nil, 50)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, kept.ID, history[0].ID)
+
+	invalid := "blurred"
+	_, err = serverSvc.UpdateServer(ctx, server.ID, owner.User.ID, nil, nil, nil, nil, nil, nil, &invalid)
+	assert.ErrorIs(t, err, ErrInvalidDeletedMessageDisplay)
+
+	// Deleted messages cannot be edited or deleted again
+	_, err = svc.UpdateMessage(ctx, msg.ID, owner.User.ID, "edited")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	assert.ErrorIs(t, svc.DeleteMessage(ctx, msg.ID, owner.User.ID), ErrMessageNotFound)
+}
+
+func TestRestoreMessage(t *testing.T) {
+	svc := NewMessageService(queries(), NewPermissionService(queries()))
+	owner := createUser(t)
+	member := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := testutil.CreateTestServer(ctx, queries(), owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	msg, err := svc.SendMessage(ctx, channel.ID, member.User.ID, "evidence", nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteMessage(ctx, msg.ID, member.User.ID))
+
+	// Only moderators see deleted messages and restore them
+	_, err = svc.GetDeletedMessages(ctx, channel.ID, member.User.ID, DeletedMessagesQuery{})
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+	_, err = svc.RestoreMessage(ctx, msg.ID, member.User.ID)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+
+	deleted, err := svc.GetDeletedMessages(ctx, channel.ID, owner.User.ID, DeletedMessagesQuery{})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "evidence", deleted[0].Content)
+	assert.Equal(t, &member.User.ID, deleted[0].DeletedBy)
+
+	restored, err := svc.RestoreMessage(ctx, msg.ID, owner.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "evidence", restored.Content)
+	assert.Equal(t, member.User.Username, restored.AuthorUsername)
+	_, err = queries().GetMessageByID(ctx, msg.ID)
+	assert.NoError(t, err)
+
+	entries, err := queries().GetAuditLog(ctx, server.ID, 10, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, "MESSAGE_RESTORE", entries[0].Action)
+
+	_, err = svc.RestoreMessage(ctx, msg.ID, owner.User.ID)
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	// Past the retention window the message is gone for good
+	require.NoError(t, svc.DeleteMessage(ctx, msg.ID, owner.User.ID))
+	_, err = testDB.Pool.Exec(ctx, `UPDATE messages SET deleted_at = $2 WHERE id = $1`, msg.ID, time.Now().Add(-DeletedMessageRetention-time.Hour))
+	require.NoError(t, err)
+	_, err = svc.RestoreMessage(ctx, msg.ID, owner.User.ID)
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	purged, err := queries().PurgeDeletedMessages(ctx, time.Now().Add(-DeletedMessageRetention))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, int64(1))
+	_, err = queries().GetDeletedMessageByID(ctx, msg.ID, time.Time{})
+	assert.Error(t, err)
+}
+
 func TestDeleteMessage_NotFound(t *testing.T) {
 	svc := NewMessageService(queries(), NewPermissionService(queries()))
 	user := createUser(t)
@@ -285,3 +601,93 @@ func TestDeleteMessage_NotFound(t *testing.T) {
 	err := svc.DeleteMessage(context.Background(), uuid.New(), user.User.ID)
 	assert.ErrorIs(t, err, ErrMessageNotFound)
 }
+
+func TestParseMentions(t *testing.T) {
+	user, role, channel := uuid.New(), uuid.New(), uuid.New()
+
+	m := ParseMentions("hi <@" + user.String() + "> and <@&" + role.String() + "> in <#" + channel.String() + "> <@" + user.String() + ">")
+	assert.Equal(t, []uuid.UUID{user}, m.Users)
+	assert.Equal(t, []uuid.UUID{role}, m.Roles)
+	assert.Equal(t, []uuid.UUID{channel}, m.Channels)
+	assert.False(t, m.Everyone)
+	assert.False(t, m.Here)
+
+	// Stored content is HTML-escaped
+	m = ParseMentions("&lt;@" + user.String() + "&gt; &lt;@&amp;" + role.String() + "&gt;")
+	assert.Equal(t, []uuid.UUID{user}, m.Users)
+	assert.Equal(t, []uuid.UUID{role}, m.Roles)
+
+	m = ParseMentions("@everyone look, and @here too")
+	assert.True(t, m.Everyone)
+	assert.True(t, m.Here)
+
+	for _, content := range []string{
+		"mail me at someone@here.example",
+		"`@everyone` is how you ping",
+		"```\n<@" + user.String() + ">\n@here\n```",
+		"@everyones",
+	} {
+		m := ParseMentions(content)
+		assert.Empty(t, m.Users, content)
+		assert.False(t, m.Everyone || m.Here, content)
+	}
+}
+
+func TestSendMessage_Mentions(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewMessageService(queries(), permSvc)
+	owner := createUser(t)
+	alice := createUser(t)
+	bob := createUser(t)
+	outsider := createUser(t)
+
+	// Mentions depend on @everyone granting ViewChannels, so create the default roles
+	server, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Mentions", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, alice.User.ID, "member"))
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, bob.User.ID, "member"))
+
+	mentioned := func(content string, author uuid.UUID) []uuid.UUID {
+		t.Helper()
+		msg, err := svc.SendMessage(ctx, channel.ID, author, content, nil)
+		require.NoError(t, err)
+		ids, err := queries().GetMessageMentionedUserIDs(ctx, msg.ID)
+		require.NoError(t, err)
+		return ids
+	}
+
+	// Users outside the server and the author themselves are not notified
+	ids := mentioned("<@"+bob.User.ID.String()+"> <@"+outsider.User.ID.String()+"> <@"+alice.User.ID.String()+">", alice.User.ID)
+	assert.ElementsMatch(t, []uuid.UUID{bob.User.ID}, ids)
+
+	// Mass mentions need PermMentionEveryone, which members lack by default
+	assert.Empty(t, mentioned("@everyone hello", alice.User.ID))
+	everyoneRole, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Empty(t, mentioned("<@&"+everyoneRole.ID.String()+">", alice.User.ID))
+
+	ids = mentioned("@everyone hello", owner.User.ID)
+	assert.ElementsMatch(t, []uuid.UUID{alice.User.ID, bob.User.ID}, ids)
+
+	// @here only reaches members who are online
+	svc.SetOnlineChecker(func(id uuid.UUID) bool { return id == bob.User.ID })
+	ids = mentioned("@here", owner.User.ID)
+	assert.ElementsMatch(t, []uuid.UUID{bob.User.ID}, ids)
+
+	role, err := queries().CreateRole(ctx, models.CreateRoleParams{ServerID: server.ID, Name: "pingable", Position: 1})
+	require.NoError(t, err)
+	require.NoError(t, queries().AssignRole(ctx, server.ID, alice.User.ID, role.ID))
+	ids = mentioned("<@&"+role.ID.String()+">", bob.User.ID)
+	assert.ElementsMatch(t, []uuid.UUID{alice.User.ID}, ids)
+
+	counts, err := queries().GetChannelUnreadCounts(ctx, alice.User.ID)
+	require.NoError(t, err)
+	mentionCount := 0
+	for _, c := range counts {
+		if c.ChannelID == channel.ID {
+			mentionCount = c.MentionCount
+		}
+	}
+	assert.Equal(t, 2, mentionCount, "@everyone and the role mention")
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// MaxNonceLength bounds the client nonce accepted on sends.
+	MaxNonceLength = 64
+
+	// NonceWindow is how long a send nonce stays bound to its message.
+	// Repeating it within the window returns the original message.
+	NonceWindow = 10 * time.Minute
+)
+
+var ErrInvalidNonce = errors.New("nonce cannot exceed 64 characters")
+
+// parseNonce validates a client nonce. An empty nonce is nil and makes the
+// send non-idempotent.
+func parseNonce(nonce string) (*string, error) {
+	if nonce == "" {
+		return nil, nil
+	}
+	if len(nonce) > MaxNonceLength {
+		return nil, ErrInvalidNonce
+	}
+	return &nonce, nil
+}
+
+// findByNonce looks up the message an earlier send with nonce created within
+// NonceWindow. ok is false when there is none, including when nonce is nil.
+func findByNonce[T any](ctx context.Context, nonce *string, find func(ctx context.Context, nonce string, since time.Time) (T, error)) (msg T, ok bool, err error) {
+	if nonce == nil {
+		return msg, false, nil
+	}
+	msg, err = find(ctx, *nonce, time.Now().Add(-NonceWindow))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return msg, false, nil
+	}
+	return msg, err == nil, err
+}
+
+// createOnce inserts a message with create. If a concurrent send with the
+// same nonce won the insert, the winner's message is returned instead with
+// replayed set.
+func createOnce[T any](ctx context.Context, nonce *string, create func() (T, error), find func(ctx context.Context, nonce string, since time.Time) (T, error)) (msg T, replayed bool, err error) {
+	msg, err = create()
+	if nonce == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return msg, false, err
+	}
+	msg, ok, err := findByNonce(ctx, nonce, find)
+	if err == nil && !ok {
+		err = pgx.ErrNoRows
+	}
+	return msg, ok, err
+}
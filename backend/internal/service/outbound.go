@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxOutboundRedirects bounds the redirects followed when calling a
+// user-supplied endpoint.
+const maxOutboundRedirects = 3
+
+// cgnatRange is shared address space (RFC 6598), not reachable publicly.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// newOutboundClient returns an HTTP client for POSTing to endpoints that
+// users register, such as event subscriptions and bot interaction URLs.
+// Endpoints are validated when registered, but a hostname can later resolve
+// somewhere else and a public endpoint can redirect inward, so the client
+// checks the address it actually connects to and every redirect target.
+func newOutboundClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrSSRFBlocked
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the dial check apply to the proxy, not the endpoint
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxOutboundRedirects {
+				return errors.New("too many redirects")
+			}
+			if !isPublicEndpoint(req.URL.String()) {
+				return ErrSSRFBlocked
+			}
+			return nil
+		},
+	}
+}
+
+// isPublicIP reports whether ip is a unicast address on the public internet.
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!cgnatRange.Contains(ip)
+}
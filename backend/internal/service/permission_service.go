@@ -125,3 +125,89 @@ func (s *PermissionService) HasChannelPermission(ctx context.Context, channelID,
 	}
 	return models.HasPermission(perms, perm), nil
 }
+
+// CanViewChannel reports whether a user is a member of the channel's server
+// and holds PermViewChannels there after channel overrides.
+func (s *PermissionService) CanViewChannel(ctx context.Context, channelID, userID uuid.UUID) (bool, error) {
+	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	perms, err := s.ComputeChannelPermissions(ctx, channelID, userID)
+	if err != nil {
+		return false, err
+	}
+	return models.HasPermission(perms, models.PermViewChannels), nil
+}
+
+// FilterChannelViewers returns the members among userIDs who hold
+// PermViewChannels in the channel, in order. It applies the same rules as
+// CanViewChannel but loads the server's roles and the channel's overrides
+// once, so its cost does not grow with the number of users. userIDs must
+// already be members of the channel's server.
+func (s *PermissionService) FilterChannelViewers(ctx context.Context, channel models.Channel, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	server, err := s.queries.GetServerByID(ctx, channel.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	// Like ComputePermissions, a server without @everyone grants nothing by default
+	everyoneRole, err := s.queries.GetEveryoneRole(ctx, channel.ServerID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	overrides, err := s.queries.GetChannelOverrides(ctx, channel.ID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.queries.GetMembersRolePermissions(ctx, channel.ServerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	perms := make(map[uuid.UUID]int64, len(userIDs))
+	roles := make(map[uuid.UUID]map[uuid.UUID]bool, len(userIDs))
+	for _, a := range assignments {
+		perms[a.UserID] |= a.Permissions
+		if roles[a.UserID] == nil {
+			roles[a.UserID] = make(map[uuid.UUID]bool)
+		}
+		roles[a.UserID][a.RoleID] = true
+	}
+
+	var viewers []uuid.UUID
+	for _, id := range userIDs {
+		if id == server.OwnerID {
+			viewers = append(viewers, id)
+			continue
+		}
+		p := everyoneRole.Permissions | perms[id]
+		// Administrator bypasses channel overrides
+		if !models.HasPermission(p, models.PermAdministrator) {
+			for _, o := range overrides {
+				if o.RoleID == everyoneRole.ID || roles[id][o.RoleID] {
+					p &= ^o.Deny
+					p |= o.Allow
+				}
+			}
+		}
+		if models.HasPermission(p, models.PermViewChannels) {
+			viewers = append(viewers, id)
+		}
+	}
+	return viewers, nil
+}
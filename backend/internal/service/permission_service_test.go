@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+)
+
+func TestCanViewChannel_MemberAllowed(t *testing.T) {
+	permSvc := NewPermissionService(queries())
+	serverSvc := NewServerService(queries(), permSvc)
+	owner := createUser(t)
+	member := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := serverSvc.CreateServer(ctx, "Perms", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	ok, err := permSvc.CanViewChannel(ctx, channel.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCanViewChannel_NonMemberDenied(t *testing.T) {
+	permSvc := NewPermissionService(queries())
+	serverSvc := NewServerService(queries(), permSvc)
+	owner := createUser(t)
+	outsider := createUser(t)
+	ctx := context.Background()
+
+	_, channel, err := serverSvc.CreateServer(ctx, "Perms", owner.User.ID)
+	require.NoError(t, err)
+
+	ok, err := permSvc.CanViewChannel(ctx, channel.ID, outsider.User.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCanViewChannel_OverrideDenies(t *testing.T) {
+	permSvc := NewPermissionService(queries())
+	serverSvc := NewServerService(queries(), permSvc)
+	owner := createUser(t)
+	member := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := serverSvc.CreateServer(ctx, "Perms", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, channel.ID, everyone.ID, 0, models.PermViewChannels)
+	require.NoError(t, err)
+
+	ok, err := permSvc.CanViewChannel(ctx, channel.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Owner bypasses overrides
+	ok, err = permSvc.CanViewChannel(ctx, channel.ID, owner.User.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestFilterChannelViewers_MatchesCanViewChannel(t *testing.T) {
+	permSvc := NewPermissionService(queries())
+	serverSvc := NewServerService(queries(), permSvc)
+	owner := createUser(t)
+	hidden := createUser(t)
+	allowed := createUser(t)
+	admin := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := serverSvc.CreateServer(ctx, "Perms", owner.User.ID)
+	require.NoError(t, err)
+	for _, u := range []*testutil.TestUser{hidden, allowed, admin} {
+		require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, u.User.ID, "member"))
+	}
+
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, channel.ID, everyone.ID, 0, models.PermViewChannels)
+	require.NoError(t, err)
+
+	staff, err := queries().CreateRole(ctx, models.CreateRoleParams{ServerID: server.ID, Name: "staff", Position: 1})
+	require.NoError(t, err)
+	require.NoError(t, queries().AssignRole(ctx, server.ID, allowed.User.ID, staff.ID))
+	_, err = queries().SetChannelOverride(ctx, channel.ID, staff.ID, models.PermViewChannels, 0)
+	require.NoError(t, err)
+
+	admins, err := queries().CreateRole(ctx, models.CreateRoleParams{
+		ServerID: server.ID, Name: "admins", Position: 2, Permissions: models.PermAdministrator,
+	})
+	require.NoError(t, err)
+	require.NoError(t, queries().AssignRole(ctx, server.ID, admin.User.ID, admins.ID))
+
+	ids := []uuid.UUID{owner.User.ID, hidden.User.ID, allowed.User.ID, admin.User.ID}
+	viewers, err := permSvc.FilterChannelViewers(ctx, *channel, ids)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{owner.User.ID, allowed.User.ID, admin.User.ID}, viewers)
+
+	for _, id := range ids {
+		ok, err := permSvc.CanViewChannel(ctx, channel.ID, id)
+		require.NoError(t, err)
+		assert.Equal(t, ok, slices.Contains(viewers, id), "user %s", id)
+	}
+}
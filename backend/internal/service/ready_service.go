@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+// ReadyService assembles the initial state snapshot sent in READY.
+type ReadyService struct {
+	queries *models.Queries
+	hub     *ws.Hub
+}
+
+func NewReadyService(q *models.Queries, hub *ws.Hub) *ReadyService {
+	return &ReadyService{queries: q, hub: hub}
+}
+
+// BuildReadyState loads the parts of the user's state named in caps.
+func (s *ReadyService) BuildReadyState(ctx context.Context, userID uuid.UUID, caps []string) (*ws.ReadyState, error) {
+	state := &ws.ReadyState{}
+
+	wantServers := ws.HasCapability(caps, ws.CapServers)
+	wantVoice := ws.HasCapability(caps, ws.CapVoiceStates)
+	if wantServers || wantVoice {
+		servers, err := s.queries.GetUserServers(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if wantServers {
+			state.Servers, err = s.buildServers(ctx, userID, servers)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if wantVoice {
+			serverIDs := make(map[string]bool, len(servers))
+			for _, srv := range servers {
+				serverIDs[srv.ID.String()] = true
+			}
+			state.VoiceStates = s.hub.GetVoiceStatesForServers(serverIDs)
+		}
+	}
+
+	if ws.HasCapability(caps, ws.CapFolders) {
+		folders, err := s.queries.GetUserServerFolders(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		state.Folders = folders
+	}
+
+	if ws.HasCapability(caps, ws.CapPreferences) {
+		prefs, err := s.queries.GetUserPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		state.Preferences = &prefs
+	}
+
+	if ws.HasCapability(caps, ws.CapNotificationPrefs) {
+		prefs, err := s.queries.GetNotificationPrefs(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		state.NotificationPrefs = prefs
+	}
+
+	return state, nil
+}
+
+// buildServers attaches channels, categories, roles and the user's own role
+// IDs to each server, loading each kind in a single query.
+func (s *ReadyService) buildServers(ctx context.Context, userID uuid.UUID, servers []models.Server) ([]ws.ReadyServer, error) {
+	channels, err := s.queries.GetUserServersChannels(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.queries.GetUserServersCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.queries.GetUserServersRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	memberRoles, err := s.queries.GetUserMemberRoleIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ws.ReadyServer, len(servers))
+	index := make(map[uuid.UUID]*ws.ReadyServer, len(servers))
+	for i, srv := range servers {
+		result[i] = ws.ReadyServer{
+			Server:        srv,
+			Channels:      []models.Channel{},
+			Categories:    []models.ChannelCategory{},
+			Roles:         []models.Role{},
+			MemberRoleIDs: []uuid.UUID{},
+		}
+		index[srv.ID] = &result[i]
+	}
+
+	for _, ch := range channels {
+		if rs, ok := index[ch.ServerID]; ok {
+			rs.Channels = append(rs.Channels, ch)
+		}
+	}
+	for _, cat := range categories {
+		if rs, ok := index[cat.ServerID]; ok {
+			rs.Categories = append(rs.Categories, cat)
+		}
+	}
+	for _, role := range roles {
+		if rs, ok := index[role.ServerID]; ok {
+			rs.Roles = append(rs.Roles, role)
+		}
+	}
+	for _, ref := range memberRoles {
+		if rs, ok := index[ref.ServerID]; ok {
+			rs.MemberRoleIDs = append(rs.MemberRoleIDs, ref.RoleID)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+func TestBuildReadyState_Servers(t *testing.T) {
+	permSvc := NewPermissionService(queries())
+	serverSvc := NewServerService(queries(), permSvc)
+	svc := NewReadyService(queries(), ws.NewHub())
+	owner := createUser(t)
+	ctx := context.Background()
+
+	server, channel, err := serverSvc.CreateServer(ctx, "Ready", owner.User.ID)
+	require.NoError(t, err)
+
+	state, err := svc.BuildReadyState(ctx, owner.User.ID, []string{ws.CapServers})
+	require.NoError(t, err)
+	require.Len(t, state.Servers, 1)
+
+	rs := state.Servers[0]
+	assert.Equal(t, server.ID, rs.ID)
+	require.NotEmpty(t, rs.Channels)
+	assert.Equal(t, channel.ID, rs.Channels[0].ID)
+	assert.NotEmpty(t, rs.Roles, "default roles are included")
+	assert.NotNil(t, rs.Categories)
+	assert.NotNil(t, rs.MemberRoleIDs)
+
+	// Parts not requested stay empty
+	assert.Nil(t, state.Preferences)
+	assert.Nil(t, state.Folders)
+}
+
+func TestBuildReadyState_PreferencesAndVoice(t *testing.T) {
+	serverSvc := NewServerService(queries(), NewPermissionService(queries()))
+	hub := ws.NewHub()
+	svc := NewReadyService(queries(), hub)
+	user := createUser(t)
+	ctx := context.Background()
+
+	server, _, err := serverSvc.CreateServer(ctx, "Voice", user.User.ID)
+	require.NoError(t, err)
+	hub.JoinVoiceChannel(ws.VoiceState{UserID: user.User.ID, ChannelID: "vc", ServerID: server.ID.String()})
+	hub.JoinVoiceChannel(ws.VoiceState{UserID: uuid.New(), ChannelID: "other", ServerID: uuid.New().String()})
+
+	state, err := svc.BuildReadyState(ctx, user.User.ID, []string{
+		ws.CapPreferences, ws.CapNotificationPrefs, ws.CapFolders, ws.CapVoiceStates,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, state.Preferences)
+	assert.NotNil(t, state.NotificationPrefs)
+	assert.NotNil(t, state.Folders)
+	require.Len(t, state.VoiceStates, 1)
+	assert.Equal(t, "vc", state.VoiceStates[0].ChannelID)
+	assert.Nil(t, state.Servers)
+}
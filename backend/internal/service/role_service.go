@@ -15,6 +15,7 @@ var (
 	ErrCannotDeleteEveryone = errors.New("cannot delete @everyone role")
 	ErrCannotModifyHigherRole = errors.New("cannot modify a role above yours")
 	ErrInvalidRoleName   = errors.New("role name must be 1-100 characters")
+	ErrManagedRole       = errors.New("managed roles cannot be edited, deleted or assigned")
 )
 
 type RoleService struct {
@@ -89,6 +90,10 @@ func (s *RoleService) UpdateRole(ctx context.Context, serverID, roleID, userID u
 	if role.ServerID != serverID {
 		return nil, ErrRoleNotFound
 	}
+	// A bot's managed role holds exactly the permissions it was authorized with
+	if role.ManagedBotID != nil {
+		return nil, ErrManagedRole
+	}
 
 	updated, err := s.queries.UpdateRole(ctx, models.UpdateRoleParams{
 		ID:          roleID,
@@ -128,6 +133,10 @@ func (s *RoleService) DeleteRole(ctx context.Context, serverID, roleID, userID u
 	if role.Position == 0 && role.Name == "@everyone" {
 		return ErrCannotDeleteEveryone
 	}
+	// A bot's managed role lives as long as the bot is a member
+	if role.ManagedBotID != nil {
+		return ErrManagedRole
+	}
 
 	return s.queries.DeleteRole(ctx, roleID)
 }
@@ -164,6 +173,9 @@ func (s *RoleService) AssignRole(ctx context.Context, serverID, targetUserID, ro
 	if role.ServerID != serverID {
 		return ErrRoleNotFound
 	}
+	if role.ManagedBotID != nil {
+		return ErrManagedRole
+	}
 
 	// Verify the target user is a member
 	if _, err := s.queries.GetServerMember(ctx, serverID, targetUserID); err != nil {
@@ -195,6 +207,9 @@ func (s *RoleService) RemoveRole(ctx context.Context, serverID, targetUserID, ro
 	if role.ServerID != serverID {
 		return ErrRoleNotFound
 	}
+	if role.ManagedBotID != nil {
+		return ErrManagedRole
+	}
 
 	return s.queries.RemoveRole(ctx, serverID, targetUserID, roleID)
 }
@@ -215,6 +215,21 @@ func (s *ServerService) GetServerMemberUserIDs(ctx context.Context, serverID uui
 	return s.queries.GetServerMemberUserIDs(ctx, serverID)
 }
 
+// GetServerChannels returns all channels in a server without a permission check.
+func (s *ServerService) GetServerChannels(ctx context.Context, serverID uuid.UUID) ([]models.Channel, error) {
+	return s.queries.GetServerChannels(ctx, serverID)
+}
+
+// GetManagedRole returns the managed role of a bot member, or nil if the
+// member has none.
+func (s *ServerService) GetManagedRole(ctx context.Context, serverID, userID uuid.UUID) *models.Role {
+	role, err := s.queries.GetManagedRole(ctx, serverID, userID)
+	if err != nil {
+		return nil
+	}
+	return &role
+}
+
 func (s *ServerService) GetUserCoMemberIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
 	return s.queries.GetUserCoMemberIDs(ctx, userID)
 }
@@ -268,9 +283,10 @@ var (
 	ErrInvalidNickname    = errors.New("nickname must be 0-32 characters")
 	ErrCategoryNotFound   = errors.New("category not found")
 	ErrInvalidCategoryName = errors.New("category name must be 1-100 characters")
+	ErrInvalidDeletedMessageDisplay = errors.New("deleted_message_display must be tombstone or hidden")
 )
 
-func (s *ServerService) UpdateServer(ctx context.Context, serverID, userID uuid.UUID, name *string, iconURL *string, isPublic *bool, description *string, gifsEnabled *bool, retentionDays *int) (*models.Server, error) {
+func (s *ServerService) UpdateServer(ctx context.Context, serverID, userID uuid.UUID, name *string, iconURL *string, isPublic *bool, description *string, gifsEnabled *bool, retentionDays *int, deletedMessageDisplay *string) (*models.Server, error) {
 	if _, err := s.queries.GetServerMember(ctx, serverID, userID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotMember
@@ -287,6 +303,9 @@ func (s *ServerService) UpdateServer(ctx context.Context, serverID, userID uuid.
 	if name != nil && (len(*name) < 1 || len(*name) > 100) {
 		return nil, ErrInvalidServerName
 	}
+	if d := deletedMessageDisplay; d != nil && *d != models.DeletedMessageTombstone && *d != models.DeletedMessageHidden {
+		return nil, ErrInvalidDeletedMessageDisplay
+	}
 	server, err := s.queries.UpdateServer(ctx, models.UpdateServerParams{
 		ID:                          serverID,
 		Name:                        name,
@@ -295,6 +314,7 @@ func (s *ServerService) UpdateServer(ctx context.Context, serverID, userID uuid.
 		Description:                 description,
 		GifsEnabled:                 gifsEnabled,
 		DefaultMessageRetentionDays: retentionDays,
+		DeletedMessageDisplay:       deletedMessageDisplay,
 	})
 	if err != nil {
 		return nil, err
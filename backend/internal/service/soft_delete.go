@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+// DeletedMessageRetention is how long deleted messages can be viewed and
+// restored by moderators before CleanupService purges them.
+const DeletedMessageRetention = 30 * 24 * time.Hour
+
+// DeletedMessagesQuery pages back through a channel's deleted messages.
+// Before is a deletion time; Limit defaults to 50 and is capped at 100.
+type DeletedMessagesQuery struct {
+	Before *time.Time
+	Limit  int32
+}
+
+// GetDeletedMessages returns a channel's deleted messages with their
+// content, most recently deleted first. Only messages still within
+// DeletedMessageRetention are returned. Requires ManageMessages in the
+// channel.
+func (s *MessageService) GetDeletedMessages(ctx context.Context, channelID, userID uuid.UUID, q DeletedMessagesQuery) ([]models.MessageWithAuthor, error) {
+	if _, err := s.checkManageMessages(ctx, channelID, userID); err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 50
+	}
+	return s.queries.GetDeletedChannelMessages(ctx, models.GetDeletedChannelMessagesParams{
+		ChannelID: channelID,
+		Since:     time.Now().Add(-DeletedMessageRetention),
+		Before:    q.Before,
+		Limit:     q.Limit,
+	})
+}
+
+// RestoreMessage undoes the deletion of a message deleted within
+// DeletedMessageRetention and records it in the audit log. The message is
+// returned as history shows it. Requires ManageMessages in the message's
+// channel.
+func (s *MessageService) RestoreMessage(ctx context.Context, messageID, userID uuid.UUID) (*models.MessageWithAuthor, error) {
+	deleted, err := s.queries.GetDeletedMessageByID(ctx, messageID, time.Now().Add(-DeletedMessageRetention))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	channel, err := s.checkManageMessages(ctx, deleted.ChannelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.queries.RestoreMessage(ctx, messageID)
+	if err != nil {
+		// Restored by someone else in the meantime
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	targetType := "message"
+	_ = s.queries.InsertAuditLog(ctx, channel.ServerID, userID, "MESSAGE_RESTORE", &msg.ID, &targetType, nil, "")
+
+	restored, err := s.queries.GetMessageWithAuthor(ctx, msg.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
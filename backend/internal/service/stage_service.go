@@ -122,24 +122,19 @@ func (s *StageService) AddSpeaker(ctx context.Context, channelID, userID uuid.UU
 		return nil, err
 	}
 
-	// Check if already a speaker
-	isSpeaker, err := s.queries.IsStageSpeaker(ctx, channelID, userID)
-	if err != nil {
-		return nil, err
-	}
-	if isSpeaker {
-		return nil, ErrAlreadySpeaker
-	}
-
 	// For self-add: must check the speaker row exists with invited=true
-	// We look for an invited entry (set by InviteToSpeak)
+	// We look for an invited entry (set by InviteToSpeak). A row with
+	// invited=false means the user already accepted.
 	speakers, err := s.queries.GetStageSpeakers(ctx, channelID)
 	if err != nil {
 		return nil, err
 	}
 	invited := false
 	for _, sp := range speakers {
-		if sp.UserID == userID && sp.Invited {
+		if sp.UserID == userID {
+			if !sp.Invited {
+				return nil, ErrAlreadySpeaker
+			}
 			invited = true
 			break
 		}
@@ -127,67 +127,94 @@ func (s *ThreadService) GetThreadsByChannel(ctx context.Context, channelID uuid.
 
 // SendThreadMessage sends a message to a thread.
 func (s *ThreadService) SendThreadMessage(ctx context.Context, threadID, authorID uuid.UUID, content string, replyToID *uuid.UUID) (*models.ThreadMessageWithAuthor, error) {
+	msg, _, err := s.SendThreadMessageWithNonce(ctx, threadID, authorID, content, replyToID, "")
+	return msg, err
+}
+
+// SendThreadMessageWithNonce sends a message to a thread. Repeating a nonce
+// within NonceWindow returns the original message with replayed set instead
+// of sending another.
+func (s *ThreadService) SendThreadMessageWithNonce(ctx context.Context, threadID, authorID uuid.UUID, content string, replyToID *uuid.UUID, nonce string) (result *models.ThreadMessageWithAuthor, replayed bool, err error) {
 	thread, err := s.queries.GetThreadByID(ctx, threadID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrThreadNotFound
+			return nil, false, ErrThreadNotFound
 		}
-		return nil, err
+		return nil, false, err
 	}
 
 	if thread.Locked {
-		return nil, ErrThreadLocked
+		return nil, false, ErrThreadLocked
 	}
 	if thread.Archived {
-		return nil, ErrThreadArchived
+		return nil, false, ErrThreadArchived
 	}
 
 	content = s.sanitizer.Sanitize(strings.TrimSpace(content))
 	if content == "" {
-		return nil, ErrEmptyMessage
+		return nil, false, ErrEmptyMessage
+	}
+
+	nonceArg, err := parseNonce(nonce)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Verify user is a member of the server
 	channel, err := s.queries.GetChannelByID(ctx, thread.ChannelID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, authorID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotMember
+			return nil, false, ErrNotMember
 		}
-		return nil, err
+		return nil, false, err
 	}
 
-	msg, err := s.queries.CreateThreadMessage(ctx, threadID, authorID, content, replyToID)
+	// A retried send returns the message the first attempt created
+	findSent := func(ctx context.Context, nonce string, since time.Time) (models.ThreadMessage, error) {
+		return s.queries.GetThreadMessageByNonce(ctx, threadID, authorID, nonce, since)
+	}
+	msg, replayed, err := findByNonce(ctx, nonceArg, findSent)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if !replayed {
+		msg, replayed, err = createOnce(ctx, nonceArg, func() (models.ThreadMessage, error) {
+			return s.queries.CreateThreadMessage(ctx, threadID, authorID, content, replyToID, nonceArg)
+		}, findSent)
+		if err != nil {
+			return nil, false, err
+		}
 	}
 
-	// Increment message count
-	_ = s.queries.IncrementThreadMessageCount(ctx, threadID)
+	if !replayed {
+		// Increment message count
+		_ = s.queries.IncrementThreadMessageCount(ctx, threadID)
 
-	// Auto-subscribe message sender
-	_, _ = s.queries.UpsertThreadSubscription(ctx, threadID, authorID, "all")
+		// Auto-subscribe message sender
+		_, _ = s.queries.UpsertThreadSubscription(ctx, threadID, authorID, "all")
+	}
 
 	// Look up author info
 	author, err := s.queries.GetUserByID(ctx, authorID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	result := &models.ThreadMessageWithAuthor{
+	result = &models.ThreadMessageWithAuthor{
 		ThreadMessage:     msg,
 		AuthorUsername:    author.Username,
 		AuthorDisplayName: author.DisplayName,
 		AuthorAvatarURL:  author.AvatarURL,
 	}
 
-	return result, nil
+	return result, replayed, nil
 }
 
-// GetThreadMessages returns paginated messages for a thread.
-func (s *ThreadService) GetThreadMessages(ctx context.Context, threadID uuid.UUID, before *time.Time, limit int32) ([]models.ThreadMessageWithAuthor, error) {
+// GetThreadMessageHistory returns a page of thread history, newest first.
+func (s *ThreadService) GetThreadMessageHistory(ctx context.Context, threadID uuid.UUID, q HistoryQuery) ([]models.ThreadMessageWithAuthor, error) {
 	_, err := s.queries.GetThreadByID(ctx, threadID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -196,11 +223,22 @@ func (s *ThreadService) GetThreadMessages(ctx context.Context, threadID uuid.UUI
 		return nil, err
 	}
 
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	messages, err := s.queries.GetThreadMessages(ctx, threadID, before, limit)
+	pager := historyPager[models.ThreadMessageWithAuthor]{
+		resolve: func(ctx context.Context, messageID uuid.UUID) (models.MessageBound, error) {
+			msg, err := s.queries.GetThreadMessageByID(ctx, messageID)
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && msg.ThreadID != threadID) {
+				return models.MessageBound{}, ErrMessageNotFound
+			}
+			return models.MessageBound{CreatedAt: msg.CreatedAt, ID: msg.ID}, err
+		},
+		before: func(ctx context.Context, bound *models.MessageBound, limit int32) ([]models.ThreadMessageWithAuthor, error) {
+			return s.queries.GetThreadMessages(ctx, threadID, bound, limit)
+		},
+		after: func(ctx context.Context, bound models.MessageBound, limit int32) ([]models.ThreadMessageWithAuthor, error) {
+			return s.queries.GetThreadMessagesAfter(ctx, threadID, bound, limit)
+		},
+	}
+	messages, err := pager.page(ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go/v2"
+	"github.com/twitchtv/twirp"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+var ErrNotVoiceChannel = errors.New("channel is not a voice channel")
+
+// VoiceGrant is the set of LiveKit publish/subscribe rights a user holds in a
+// voice channel.
+type VoiceGrant struct {
+	CanPublish     bool
+	CanSubscribe   bool
+	CanPublishData bool
+}
+
+type VoiceService struct {
+	queries *models.Queries
+	permSvc *PermissionService
+	rooms   *lksdk.RoomServiceClient
+}
+
+// NewVoiceService creates a VoiceService. rooms may be nil, in which case
+// permission changes only take effect on the participant's next token.
+func NewVoiceService(q *models.Queries, permSvc *PermissionService, rooms *lksdk.RoomServiceClient) *VoiceService {
+	return &VoiceService{queries: q, permSvc: permSvc, rooms: rooms}
+}
+
+// VoiceRoomName returns the LiveKit room backing a server voice channel.
+func VoiceRoomName(serverID, channelID uuid.UUID) string {
+	return fmt.Sprintf("server:%s:voice:%s", serverID, channelID)
+}
+
+// ComputeVoiceGrant checks that the user may connect to the voice channel and
+// returns the rights their token should carry. While a stage is active, only
+// speakers may publish.
+func (s *VoiceService) ComputeVoiceGrant(ctx context.Context, serverID, channelID, userID uuid.UUID) (*VoiceGrant, error) {
+	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, err
+	}
+	if channel.ServerID != serverID {
+		return nil, ErrChannelNotFound
+	}
+	if channel.Type != "voice" {
+		return nil, ErrNotVoiceChannel
+	}
+
+	if _, err := s.queries.GetServerMember(ctx, serverID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+
+	if timedOut, err := s.queries.IsUserTimedOut(ctx, serverID, userID); err == nil && timedOut {
+		return nil, ErrUserTimedOut
+	}
+
+	perms, err := s.permSvc.ComputeChannelPermissions(ctx, channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.HasPermission(perms, models.PermViewChannels) || !models.HasPermission(perms, models.PermVoiceConnect) {
+		return nil, ErrInsufficientRole
+	}
+
+	canSpeak := models.HasPermission(perms, models.PermVoiceSpeak)
+	if canSpeak {
+		audience, err := s.isStageAudience(ctx, channelID, userID)
+		if err != nil {
+			return nil, err
+		}
+		canSpeak = !audience
+	}
+
+	return &VoiceGrant{
+		CanPublish:     canSpeak,
+		CanSubscribe:   true,
+		CanPublishData: canSpeak,
+	}, nil
+}
+
+// isStageAudience reports whether a stage is running on the channel and the
+// user is not one of its (accepted) speakers.
+func (s *VoiceService) isStageAudience(ctx context.Context, channelID, userID uuid.UUID) (bool, error) {
+	if _, err := s.queries.GetStageInstance(ctx, channelID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	speakers, err := s.queries.GetStageSpeakers(ctx, channelID)
+	if err != nil {
+		return false, err
+	}
+	for _, sp := range speakers {
+		if sp.UserID == userID && !sp.Invited {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SyncParticipant recomputes a user's grant and applies it to their live
+// LiveKit session, if any. Users who may no longer connect are removed.
+func (s *VoiceService) SyncParticipant(ctx context.Context, channelID, userID uuid.UUID) error {
+	if s.rooms == nil {
+		return nil
+	}
+
+	channel, err := s.queries.GetChannelByID(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	room := VoiceRoomName(channel.ServerID, channelID)
+
+	grant, err := s.ComputeVoiceGrant(ctx, channel.ServerID, channelID, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) || errors.Is(err, ErrInsufficientRole) || errors.Is(err, ErrUserTimedOut) {
+			_, err = s.rooms.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{
+				Room:     room,
+				Identity: userID.String(),
+			})
+			return ignoreParticipantNotFound(err)
+		}
+		return err
+	}
+
+	_, err = s.rooms.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:     room,
+		Identity: userID.String(),
+		Permission: &livekit.ParticipantPermission{
+			CanPublish:     grant.CanPublish,
+			CanSubscribe:   grant.CanSubscribe,
+			CanPublishData: grant.CanPublishData,
+		},
+	})
+	return ignoreParticipantNotFound(err)
+}
+
+// ignoreParticipantNotFound treats a user who is not in the room as synced.
+func ignoreParticipantNotFound(err error) error {
+	var terr twirp.Error
+	if errors.As(err, &terr) && terr.Code() == twirp.NotFound {
+		return nil
+	}
+	return err
+}
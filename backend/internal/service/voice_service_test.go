@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+)
+
+func TestComputeVoiceGrant_MemberGetsFullGrant(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	grant, err := svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, VoiceGrant{CanPublish: true, CanSubscribe: true, CanPublishData: true}, *grant)
+}
+
+func TestComputeVoiceGrant_NonMemberDenied(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	outsider := createUser(t)
+
+	_, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, outsider.User.ID)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestComputeVoiceGrant_TextChannelRejected(t *testing.T) {
+	svc := NewVoiceService(queries(), NewPermissionService(queries()), nil)
+	permSvc := NewPermissionService(queries())
+	owner := createUser(t)
+
+	server, text, err := NewServerService(queries(), permSvc).CreateServer(context.Background(), "Voice", owner.User.ID)
+	require.NoError(t, err)
+
+	_, err = svc.ComputeVoiceGrant(context.Background(), server.ID, text.ID, owner.User.ID)
+	assert.ErrorIs(t, err, ErrNotVoiceChannel)
+}
+
+func TestComputeVoiceGrant_ChannelFromOtherServer(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	_, err = svc.ComputeVoiceGrant(ctx, uuid.New(), voice.ID, member.User.ID)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
+
+func TestComputeVoiceGrant_OverrideDeniesConnect(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, voice.ID, everyone.ID, 0, models.PermVoiceConnect)
+	require.NoError(t, err)
+
+	_, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+}
+
+func TestComputeVoiceGrant_OverrideDeniesSpeak(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	everyone, err := queries().GetEveryoneRole(ctx, server.ID)
+	require.NoError(t, err)
+	_, err = queries().SetChannelOverride(ctx, voice.ID, everyone.ID, 0, models.PermVoiceSpeak)
+	require.NoError(t, err)
+
+	grant, err := svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, VoiceGrant{CanPublish: false, CanSubscribe: true, CanPublishData: false}, *grant)
+}
+
+func TestComputeVoiceGrant_TimedOutDenied(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	_, err = queries().CreateTimeout(ctx, server.ID, member.User.ID, owner.User.ID, "spam", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	_, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	assert.ErrorIs(t, err, ErrUserTimedOut)
+}
+
+func TestComputeVoiceGrant_StageAudienceAndSpeakers(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewVoiceService(queries(), permSvc, nil)
+	owner := createUser(t)
+	member := createUser(t)
+
+	server, _, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Voice", owner.User.ID)
+	require.NoError(t, err)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), server.ID, member.User.ID, "member"))
+	voice, err := testutil.CreateTestChannel(ctx, queries(), server.ID, "voice", "voice", 1)
+	require.NoError(t, err)
+
+	stageSvc := NewStageService(queries(), permSvc)
+	_, err = stageSvc.StartStage(ctx, voice.ID, owner.User.ID, "AMA")
+	require.NoError(t, err)
+
+	audience := VoiceGrant{CanPublish: false, CanSubscribe: true, CanPublishData: false}
+	speaker := VoiceGrant{CanPublish: true, CanSubscribe: true, CanPublishData: true}
+
+	grant, err := svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, owner.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, speaker, *grant)
+
+	grant, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, audience, *grant)
+
+	// An invitation alone does not grant publish rights
+	_, err = stageSvc.InviteToSpeak(ctx, voice.ID, member.User.ID, owner.User.ID)
+	require.NoError(t, err)
+	grant, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, audience, *grant)
+
+	_, err = stageSvc.AddSpeaker(ctx, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	grant, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, speaker, *grant)
+
+	require.NoError(t, stageSvc.EndStage(ctx, voice.ID, owner.User.ID))
+	grant, err = svc.ComputeVoiceGrant(ctx, server.ID, voice.ID, member.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, speaker, *grant)
+}
@@ -0,0 +1,689 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownAdapter        = errors.New("unknown webhook adapter")
+	ErrAdapterSignature      = errors.New("invalid adapter signature")
+	ErrInvalidAdapterPayload = errors.New("invalid adapter payload")
+)
+
+// Provider adapters translate another service's webhook payload into a
+// webhook message.
+const (
+	AdapterGitHub = "github"
+	AdapterGitLab = "gitlab"
+	AdapterSlack  = "slack"
+)
+
+// Embed colours used by the adapters.
+const (
+	colorSuccess = 0x2EA043
+	colorFailure = 0xCF222E
+	colorOpen    = 0x0969DA
+	colorMerged  = 0x8250DF
+	colorClosed  = 0x6E7781
+	colorRelease = 0xBF8700
+)
+
+const (
+	maxAdapterCommits     = 5
+	maxAdapterDescription = 500
+)
+
+// AdapterRequest is a delivery from a provider to one of a webhook's adapters.
+type AdapterRequest struct {
+	Provider string
+	// Event is the provider's event header (X-GitHub-Event, X-Gitlab-Event).
+	Event string
+	// Signature is X-Hub-Signature-256 for GitHub, X-Gitlab-Token for GitLab.
+	Signature string
+	Body      []byte
+}
+
+// ExecuteAdapter posts a provider's delivery as a webhook message. When the
+// webhook has an adapter secret, GitHub and GitLab deliveries must be signed
+// with it. Events the adapter does not render (pings, label changes and the
+// like) are accepted without posting anything: the returned execution is nil.
+func (s *WebhookService) ExecuteAdapter(ctx context.Context, webhookID uuid.UUID, token string, req AdapterRequest) (*WebhookExecution, error) {
+	webhook, err := s.authenticate(ctx, webhookID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var params *ExecuteWebhookParams
+	switch req.Provider {
+	case AdapterGitHub:
+		if webhook.AdapterSecret != "" && !verifyGitHubSignature(webhook.AdapterSecret, req.Signature, req.Body) {
+			return nil, ErrAdapterSignature
+		}
+		params, err = RenderGitHubEvent(req.Event, req.Body)
+	case AdapterGitLab:
+		if webhook.AdapterSecret != "" && subtle.ConstantTimeCompare([]byte(webhook.AdapterSecret), []byte(req.Signature)) != 1 {
+			return nil, ErrAdapterSignature
+		}
+		params, err = RenderGitLabEvent(req.Body)
+	case AdapterSlack:
+		// Slack's incoming webhooks are unsigned; the URL token is the secret
+		params, err = RenderSlackMessage(req.Body)
+	default:
+		return nil, ErrUnknownAdapter
+	}
+	if err != nil || params == nil {
+		return nil, err
+	}
+	return s.execute(ctx, webhook, *params)
+}
+
+// verifyGitHubSignature checks an X-Hub-Signature-256 header.
+func verifyGitHubSignature(secret, signature string, body []byte) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// --- GitHub ---
+
+type githubUser struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+}
+
+type githubRepository struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type githubEvent struct {
+	Action     string           `json:"action"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+
+	// push
+	Ref     string `json:"ref"`
+	Compare string `json:"compare"`
+	Created bool   `json:"created"`
+	Deleted bool   `json:"deleted"`
+	Forced  bool   `json:"forced"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commits"`
+
+	PullRequest *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		Merged  bool   `json:"merged"`
+	} `json:"pull_request"`
+
+	Issue *struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	} `json:"issue"`
+
+	Release *struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	} `json:"release"`
+
+	WorkflowRun *struct {
+		Name       string `json:"name"`
+		RunNumber  int    `json:"run_number"`
+		HeadBranch string `json:"head_branch"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+	} `json:"workflow_run"`
+}
+
+// RenderGitHubEvent renders a GitHub webhook delivery. It returns nil for
+// events that post nothing.
+func RenderGitHubEvent(event string, body []byte) (*ExecuteWebhookParams, error) {
+	var e githubEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdapterPayload, err)
+	}
+
+	embed := Embed{}
+	if e.Sender.Login != "" {
+		embed.Author = &EmbedAuthor{Name: e.Sender.Login, URL: e.Sender.HTMLURL, IconURL: e.Sender.AvatarURL}
+	}
+	repo := e.Repository.FullName
+
+	switch event {
+	case "push":
+		if e.Deleted || len(e.Commits) == 0 {
+			return nil, nil
+		}
+		branch := refName(e.Ref)
+		embed.Title = fmt.Sprintf("[%s:%s] %s", repo, branch, pluralCommits(len(e.Commits)))
+		if e.Forced {
+			embed.Title += " (force-pushed)"
+		}
+		embed.URL = e.Compare
+		embed.Color = intPtr(colorOpen)
+		var lines []string
+		for i, c := range e.Commits {
+			if i == maxAdapterCommits {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(e.Commits)-maxAdapterCommits))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("[`%s`](%s) %s - %s", shortSHA(c.ID), c.URL, firstLine(c.Message), c.Author.Name))
+		}
+		embed.Description = strings.Join(lines, "\n")
+
+	case "pull_request":
+		pr := e.PullRequest
+		if pr == nil {
+			return nil, fmt.Errorf("%w: missing pull_request", ErrInvalidAdapterPayload)
+		}
+		action, color, ok := githubIssueAction(e.Action, pr.Merged)
+		if !ok {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] Pull request %s: #%d %s", repo, action, pr.Number, pr.Title)
+		embed.URL = pr.HTMLURL
+		embed.Color = intPtr(color)
+		if e.Action == "opened" {
+			embed.Description = truncateRunes(pr.Body, maxAdapterDescription)
+		}
+
+	case "issues":
+		issue := e.Issue
+		if issue == nil {
+			return nil, fmt.Errorf("%w: missing issue", ErrInvalidAdapterPayload)
+		}
+		action, color, ok := githubIssueAction(e.Action, false)
+		if !ok {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] Issue %s: #%d %s", repo, action, issue.Number, issue.Title)
+		embed.URL = issue.HTMLURL
+		embed.Color = intPtr(color)
+		if e.Action == "opened" {
+			embed.Description = truncateRunes(issue.Body, maxAdapterDescription)
+		}
+
+	case "release":
+		rel := e.Release
+		if rel == nil {
+			return nil, fmt.Errorf("%w: missing release", ErrInvalidAdapterPayload)
+		}
+		if e.Action != "published" {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] New release published: %s", repo, firstNonEmpty(rel.Name, rel.TagName))
+		embed.URL = rel.HTMLURL
+		embed.Color = intPtr(colorRelease)
+		embed.Description = truncateRunes(rel.Body, maxAdapterDescription)
+
+	case "workflow_run":
+		run := e.WorkflowRun
+		if run == nil {
+			return nil, fmt.Errorf("%w: missing workflow_run", ErrInvalidAdapterPayload)
+		}
+		if e.Action != "completed" {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] %s #%d %s on %s", repo, run.Name, run.RunNumber, pipelineOutcome(run.Conclusion), run.HeadBranch)
+		embed.URL = run.HTMLURL
+		embed.Color = intPtr(pipelineColor(run.Conclusion))
+
+	default:
+		// ping and everything else
+		return nil, nil
+	}
+
+	return embedParams(embed)
+}
+
+// githubIssueAction describes an issue or pull request action.
+func githubIssueAction(action string, merged bool) (string, int, bool) {
+	switch action {
+	case "opened":
+		return "opened", colorOpen, true
+	case "reopened":
+		return "reopened", colorOpen, true
+	case "ready_for_review":
+		return "ready for review", colorOpen, true
+	case "closed":
+		if merged {
+			return "merged", colorMerged, true
+		}
+		return "closed", colorClosed, true
+	}
+	return "", 0, false
+}
+
+// --- GitLab ---
+
+type gitlabEvent struct {
+	ObjectKind string `json:"object_kind"`
+	UserName   string `json:"user_name"`
+	UserAvatar string `json:"user_avatar"`
+	User       struct {
+		Name      string `json:"name"`
+		Username  string `json:"username"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+
+	// push
+	Ref               string `json:"ref"`
+	Before            string `json:"before"`
+	After             string `json:"after"`
+	TotalCommitsCount int    `json:"total_commits_count"`
+	Commits           []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commits"`
+
+	// release
+	Action      string `json:"action"`
+	Name        string `json:"name"`
+	Tag         string `json:"tag"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+
+	ObjectAttributes struct {
+		ID          int    `json:"id"`
+		IID         int    `json:"iid"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		Action      string `json:"action"`
+		Ref         string `json:"ref"`
+		Status      string `json:"status"`
+	} `json:"object_attributes"`
+}
+
+// RenderGitLabEvent renders a GitLab webhook delivery, keyed by its
+// object_kind. It returns nil for events that post nothing.
+func RenderGitLabEvent(body []byte) (*ExecuteWebhookParams, error) {
+	var e gitlabEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdapterPayload, err)
+	}
+
+	embed := Embed{}
+	if e.User.Username != "" {
+		embed.Author = &EmbedAuthor{Name: e.User.Username, IconURL: e.User.AvatarURL}
+	} else if e.UserName != "" {
+		embed.Author = &EmbedAuthor{Name: e.UserName, IconURL: e.UserAvatar}
+	}
+	repo := e.Project.PathWithNamespace
+	attrs := e.ObjectAttributes
+
+	switch e.ObjectKind {
+	case "push":
+		if len(e.Commits) == 0 {
+			return nil, nil
+		}
+		total := e.TotalCommitsCount
+		if total < len(e.Commits) {
+			total = len(e.Commits)
+		}
+		embed.Title = fmt.Sprintf("[%s:%s] %s", repo, refName(e.Ref), pluralCommits(total))
+		if e.Before != "" && e.After != "" {
+			embed.URL = fmt.Sprintf("%s/-/compare/%s...%s", e.Project.WebURL, shortSHA(e.Before), shortSHA(e.After))
+		}
+		embed.Color = intPtr(colorOpen)
+		var lines []string
+		for i, c := range e.Commits {
+			if i == maxAdapterCommits {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("[`%s`](%s) %s - %s", shortSHA(c.ID), c.URL, firstLine(c.Message), c.Author.Name))
+		}
+		if total > maxAdapterCommits {
+			lines = append(lines, fmt.Sprintf("…and %d more", total-maxAdapterCommits))
+		}
+		embed.Description = strings.Join(lines, "\n")
+
+	case "merge_request":
+		action, color, ok := gitlabAction(attrs.Action)
+		if !ok {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] Merge request %s: !%d %s", repo, action, attrs.IID, attrs.Title)
+		embed.URL = attrs.URL
+		embed.Color = intPtr(color)
+		if attrs.Action == "open" {
+			embed.Description = truncateRunes(attrs.Description, maxAdapterDescription)
+		}
+
+	case "issue":
+		action, color, ok := gitlabAction(attrs.Action)
+		if !ok {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] Issue %s: #%d %s", repo, action, attrs.IID, attrs.Title)
+		embed.URL = attrs.URL
+		embed.Color = intPtr(color)
+		if attrs.Action == "open" {
+			embed.Description = truncateRunes(attrs.Description, maxAdapterDescription)
+		}
+
+	case "release":
+		if e.Action != "create" {
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] New release published: %s", repo, firstNonEmpty(e.Name, e.Tag))
+		embed.URL = e.URL
+		embed.Color = intPtr(colorRelease)
+		embed.Description = truncateRunes(e.Description, maxAdapterDescription)
+
+	case "pipeline":
+		switch attrs.Status {
+		case "success", "failed", "canceled":
+		default:
+			return nil, nil
+		}
+		embed.Title = fmt.Sprintf("[%s] Pipeline #%d %s on %s", repo, attrs.ID, pipelineOutcome(attrs.Status), attrs.Ref)
+		embed.URL = attrs.URL
+		if embed.URL == "" && e.Project.WebURL != "" {
+			embed.URL = e.Project.WebURL + "/-/pipelines/" + strconv.Itoa(attrs.ID)
+		}
+		embed.Color = intPtr(pipelineColor(attrs.Status))
+
+	default:
+		return nil, nil
+	}
+
+	return embedParams(embed)
+}
+
+// gitlabAction describes a merge request or issue action.
+func gitlabAction(action string) (string, int, bool) {
+	switch action {
+	case "open":
+		return "opened", colorOpen, true
+	case "reopen":
+		return "reopened", colorOpen, true
+	case "merge":
+		return "merged", colorMerged, true
+	case "close":
+		return "closed", colorClosed, true
+	}
+	return "", 0, false
+}
+
+// --- Slack ---
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text"`
+	Fields   []slackText `json:"fields"`
+	Elements []slackText `json:"elements"`
+}
+
+type slackAttachment struct {
+	Color      string `json:"color"`
+	Pretext    string `json:"pretext"`
+	AuthorName string `json:"author_name"`
+	AuthorLink string `json:"author_link"`
+	AuthorIcon string `json:"author_icon"`
+	Title      string `json:"title"`
+	TitleLink  string `json:"title_link"`
+	Text       string `json:"text"`
+	Fallback   string `json:"fallback"`
+	Fields     []struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	} `json:"fields"`
+	ImageURL string `json:"image_url"`
+	ThumbURL string `json:"thumb_url"`
+	Footer   string `json:"footer"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments"`
+	Username    string            `json:"username"`
+	IconURL     string            `json:"icon_url"`
+}
+
+// slackLink matches Slack's <url|label> and <url> link markup.
+var slackLink = regexp.MustCompile(`<(https?://[^|>]+)(?:\|([^>]+))?>`)
+
+// RenderSlackMessage renders a Slack incoming-webhook payload: text, blocks
+// and legacy attachments.
+func RenderSlackMessage(body []byte) (*ExecuteWebhookParams, error) {
+	var m slackMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdapterPayload, err)
+	}
+
+	var parts []string
+	for _, b := range m.Blocks {
+		if text := slackBlockText(b); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	// With blocks, Slack shows text only as the notification fallback
+	content := slackMarkdown(m.Text)
+	if len(parts) > 0 {
+		content = strings.Join(parts, "\n")
+	}
+
+	var embeds []Embed
+	for _, a := range m.Attachments {
+		if a.Pretext != "" {
+			content = strings.TrimSpace(content + "\n" + slackMarkdown(a.Pretext))
+		}
+		e := Embed{
+			Title:       a.Title,
+			URL:         a.TitleLink,
+			Description: slackMarkdown(firstNonEmpty(a.Text, a.Fallback)),
+		}
+		if color, ok := slackColors[a.Color]; ok {
+			e.Color = intPtr(color)
+		} else if c, err := strconv.ParseUint(strings.TrimPrefix(a.Color, "#"), 16, 24); err == nil {
+			e.Color = intPtr(int(c))
+		}
+		if a.AuthorName != "" {
+			e.Author = &EmbedAuthor{Name: a.AuthorName, URL: a.AuthorLink, IconURL: a.AuthorIcon}
+		}
+		if a.Footer != "" {
+			e.Footer = &EmbedFooter{Text: a.Footer}
+		}
+		if a.ImageURL != "" {
+			e.Image = &EmbedMedia{URL: a.ImageURL}
+		}
+		if a.ThumbURL != "" {
+			e.Thumbnail = &EmbedMedia{URL: a.ThumbURL}
+		}
+		for _, f := range a.Fields {
+			e.Fields = append(e.Fields, EmbedField{Name: f.Title, Value: slackMarkdown(f.Value), Inline: f.Short})
+		}
+		embeds = append(embeds, e)
+	}
+
+	params := &ExecuteWebhookParams{
+		Content:   truncateRunes(content, MaxMessageLength),
+		Username:  m.Username,
+		AvatarURL: m.IconURL,
+	}
+	if len(embeds) > 0 {
+		raw, err := json.Marshal(embeds)
+		if err != nil {
+			return nil, err
+		}
+		params.Embeds = raw
+	}
+	return params, nil
+}
+
+// slackColors are the named attachment colours Slack accepts.
+var slackColors = map[string]int{
+	"good":    colorSuccess,
+	"warning": colorRelease,
+	"danger":  colorFailure,
+}
+
+// slackBlockText renders the text of a header, section or context block.
+func slackBlockText(b slackBlock) string {
+	switch b.Type {
+	case "header":
+		if b.Text != nil {
+			return "**" + b.Text.Text + "**"
+		}
+	case "section":
+		var lines []string
+		if b.Text != nil {
+			lines = append(lines, slackMarkdown(b.Text.Text))
+		}
+		for _, f := range b.Fields {
+			lines = append(lines, slackMarkdown(f.Text))
+		}
+		return strings.Join(lines, "\n")
+	case "context":
+		var items []string
+		for _, el := range b.Elements {
+			if el.Text != "" {
+				items = append(items, slackMarkdown(el.Text))
+			}
+		}
+		return strings.Join(items, " · ")
+	case "divider":
+		return "---"
+	}
+	return ""
+}
+
+// slackMarkdown converts Slack mrkdwn links and bold to markdown.
+func slackMarkdown(s string) string {
+	s = slackLink.ReplaceAllStringFunc(s, func(m string) string {
+		parts := slackLink.FindStringSubmatch(m)
+		if parts[2] == "" {
+			return parts[1]
+		}
+		return "[" + parts[2] + "](" + parts[1] + ")"
+	})
+	return slackBold.ReplaceAllString(s, "${1}**${2}**")
+}
+
+var slackBold = regexp.MustCompile(`(^|[\s(])\*([^*\n]+)\*`)
+
+// --- Helpers ---
+
+func embedParams(e Embed) (*ExecuteWebhookParams, error) {
+	e.Title = truncateRunes(e.Title, maxEmbedTitle)
+	raw, err := json.Marshal([]Embed{e})
+	if err != nil {
+		return nil, err
+	}
+	return &ExecuteWebhookParams{Embeds: raw}, nil
+}
+
+func pipelineOutcome(conclusion string) string {
+	switch conclusion {
+	case "success":
+		return "passed"
+	case "failure", "failed":
+		return "failed"
+	case "cancelled", "canceled":
+		return "was cancelled"
+	}
+	return conclusion
+}
+
+func pipelineColor(conclusion string) int {
+	switch conclusion {
+	case "success":
+		return colorSuccess
+	case "failure", "failed":
+		return colorFailure
+	}
+	return colorClosed
+}
+
+func refName(ref string) string {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	return strings.TrimPrefix(ref, "refs/tags/")
+}
+
+func pluralCommits(n int) string {
+	if n == 1 {
+		return "1 new commit"
+	}
+	return fmt.Sprintf("%d new commits", n)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return truncateRunes(s, 100)
+}
+
+// truncateRunes shortens s to at most max characters, marking the cut.
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderedEmbed(t *testing.T, params *ExecuteWebhookParams) Embed {
+	t.Helper()
+	require.NotNil(t, params)
+	var embeds []Embed
+	require.NoError(t, json.Unmarshal(params.Embeds, &embeds))
+	require.Len(t, embeds, 1)
+	return embeds[0]
+}
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRenderGitHubEvent(t *testing.T) {
+	push := `{"ref":"refs/heads/main","compare":"https://github.com/acme/app/compare/a...b",
+		"repository":{"full_name":"acme/app"},"sender":{"login":"octocat"},
+		"commits":[{"id":"0123456789abcdef","message":"Fix login\n\nLonger body","url":"https://github.com/acme/app/commit/0123456","author":{"name":"Octo"}}]}`
+	params, err := RenderGitHubEvent("push", []byte(push))
+	require.NoError(t, err)
+	embed := renderedEmbed(t, params)
+	assert.Equal(t, "[acme/app:main] 1 new commit", embed.Title)
+	assert.Equal(t, "[`0123456`](https://github.com/acme/app/commit/0123456) Fix login - Octo", embed.Description)
+	assert.Equal(t, "octocat", embed.Author.Name)
+
+	pr := `{"action":"closed","repository":{"full_name":"acme/app"},
+		"pull_request":{"number":7,"title":"Add dark mode","html_url":"https://github.com/acme/app/pull/7","merged":true}}`
+	params, err = RenderGitHubEvent("pull_request", []byte(pr))
+	require.NoError(t, err)
+	embed = renderedEmbed(t, params)
+	assert.Equal(t, "[acme/app] Pull request merged: #7 Add dark mode", embed.Title)
+	assert.Equal(t, colorMerged, *embed.Color)
+
+	run := `{"action":"completed","repository":{"full_name":"acme/app"},
+		"workflow_run":{"name":"CI","run_number":42,"head_branch":"main","conclusion":"failure","html_url":"https://github.com/acme/app/actions/runs/1"}}`
+	params, err = RenderGitHubEvent("workflow_run", []byte(run))
+	require.NoError(t, err)
+	embed = renderedEmbed(t, params)
+	assert.Equal(t, "[acme/app] CI #42 failed on main", embed.Title)
+	assert.Equal(t, colorFailure, *embed.Color)
+
+	// Pings, labels and in-progress runs post nothing
+	for event, body := range map[string]string{
+		"ping":         `{"zen":"Keep it simple."}`,
+		"issues":       `{"action":"labeled","issue":{"number":1,"title":"x"}}`,
+		"workflow_run": `{"action":"requested","workflow_run":{"name":"CI"}}`,
+	} {
+		params, err := RenderGitHubEvent(event, []byte(body))
+		require.NoError(t, err, event)
+		assert.Nil(t, params, event)
+	}
+
+	_, err = RenderGitHubEvent("push", []byte("not json"))
+	assert.ErrorIs(t, err, ErrInvalidAdapterPayload)
+}
+
+func TestRenderGitLabEvent(t *testing.T) {
+	mr := `{"object_kind":"merge_request","user":{"username":"tanuki"},"project":{"path_with_namespace":"acme/app"},
+		"object_attributes":{"iid":3,"title":"Bump deps","url":"https://gitlab.com/acme/app/-/merge_requests/3","action":"open","description":"Routine"}}`
+	params, err := RenderGitLabEvent([]byte(mr))
+	require.NoError(t, err)
+	embed := renderedEmbed(t, params)
+	assert.Equal(t, "[acme/app] Merge request opened: !3 Bump deps", embed.Title)
+	assert.Equal(t, "Routine", embed.Description)
+	assert.Equal(t, "tanuki", embed.Author.Name)
+
+	pipeline := `{"object_kind":"pipeline","project":{"path_with_namespace":"acme/app","web_url":"https://gitlab.com/acme/app"},
+		"object_attributes":{"id":99,"ref":"main","status":"success"}}`
+	params, err = RenderGitLabEvent([]byte(pipeline))
+	require.NoError(t, err)
+	embed = renderedEmbed(t, params)
+	assert.Equal(t, "[acme/app] Pipeline #99 passed on main", embed.Title)
+	assert.Equal(t, "https://gitlab.com/acme/app/-/pipelines/99", embed.URL)
+	assert.Equal(t, colorSuccess, *embed.Color)
+
+	running := `{"object_kind":"pipeline","object_attributes":{"id":100,"status":"running"}}`
+	params, err = RenderGitLabEvent([]byte(running))
+	require.NoError(t, err)
+	assert.Nil(t, params)
+}
+
+func TestRenderSlackMessage(t *testing.T) {
+	body := `{"text":"fallback","username":"Alerts","icon_url":"https://cdn.example.com/a.png",
+		"blocks":[{"type":"header","text":{"type":"plain_text","text":"Deploy"}},
+			{"type":"section","text":{"type":"mrkdwn","text":"*prod* is live at <https://app.example.com|the app>"}}],
+		"attachments":[{"color":"danger","title":"Error rate","text":"Up 3%","fields":[{"title":"Region","value":"eu","short":true}]}]}`
+	params, err := RenderSlackMessage([]byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, "**Deploy**\n**prod** is live at [the app](https://app.example.com)", params.Content)
+	assert.Equal(t, "Alerts", params.Username)
+	assert.Equal(t, "https://cdn.example.com/a.png", params.AvatarURL)
+
+	embed := renderedEmbed(t, params)
+	assert.Equal(t, "Error rate", embed.Title)
+	assert.Equal(t, colorFailure, *embed.Color)
+	require.Len(t, embed.Fields, 1)
+	assert.True(t, embed.Fields[0].Inline)
+
+	params, err = RenderSlackMessage([]byte(`{"text":"plain"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "plain", params.Content)
+	assert.Nil(t, params.Embeds)
+}
+
+func TestExecuteAdapter_VerifiesSignatures(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	_, err = svc.SetAdapterSecret(ctx, webhook.ID, owner.User.ID, "s3cret")
+	require.NoError(t, err)
+
+	push := []byte(`{"ref":"refs/heads/main","repository":{"full_name":"acme/app"},
+		"commits":[{"id":"abcdef1234","message":"Ship it","url":"https://github.com/acme/app/commit/abcdef1","author":{"name":"Octo"}}]}`)
+	exec, err := svc.ExecuteAdapter(ctx, webhook.ID, token, AdapterRequest{
+		Provider: AdapterGitHub, Event: "push", Signature: githubSignature("s3cret", push), Body: push,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, exec)
+	assert.Equal(t, &webhook.ID, exec.Message.WebhookID)
+	assert.NotEmpty(t, exec.Message.Embeds)
+
+	_, err = svc.ExecuteAdapter(ctx, webhook.ID, token, AdapterRequest{
+		Provider: AdapterGitHub, Event: "push", Signature: githubSignature("wrong", push), Body: push,
+	})
+	assert.ErrorIs(t, err, ErrAdapterSignature)
+
+	_, err = svc.ExecuteAdapter(ctx, webhook.ID, token, AdapterRequest{
+		Provider: AdapterGitLab, Signature: "wrong", Body: []byte(`{"object_kind":"push"}`),
+	})
+	assert.ErrorIs(t, err, ErrAdapterSignature)
+
+	// Ignored events are accepted without posting
+	exec, err = svc.ExecuteAdapter(ctx, webhook.ID, token, AdapterRequest{
+		Provider: AdapterGitHub, Event: "ping", Signature: githubSignature("s3cret", []byte(`{}`)), Body: []byte(`{}`),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, exec)
+
+	_, err = svc.ExecuteAdapter(ctx, webhook.ID, token, AdapterRequest{Provider: "bitbucket", Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, ErrUnknownAdapter)
+	_, err = svc.ExecuteAdapter(ctx, webhook.ID, "wrong", AdapterRequest{Provider: AdapterSlack, Body: []byte(`{"text":"x"}`)})
+	assert.ErrorIs(t, err, ErrWebhookTokenInvalid)
+}
@@ -2,7 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -12,9 +16,13 @@ import (
 )
 
 var (
-	ErrWebhookNotFound     = errors.New("webhook not found")
-	ErrWebhookTokenInvalid = errors.New("invalid webhook token")
-	ErrInvalidWebhookName  = errors.New("webhook name must be 1-80 characters")
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrWebhookTokenInvalid  = errors.New("invalid webhook token")
+	ErrInvalidWebhookName   = errors.New("webhook name must be 1-80 characters")
+	ErrInvalidWebhookAvatar = errors.New("avatar_url must be an http(s) URL")
+	ErrThreadAttachments    = errors.New("attachments are not supported in threads")
+	ErrInvalidAdapterSecret = errors.New("adapter secret must be at most 256 characters")
+	ErrInvalidWebhookTarget = errors.New("webhooks can only post to text channels")
 )
 
 type WebhookService struct {
@@ -104,73 +112,449 @@ func (s *WebhookService) ListWebhooks(ctx context.Context, channelID, userID uui
 	return s.queries.GetWebhooksByChannel(ctx, channelID)
 }
 
+// ListServerWebhooks lists the webhooks of every channel in a server. The
+// caller must have ManageServer.
+func (s *WebhookService) ListServerWebhooks(ctx context.Context, serverID, userID uuid.UUID) ([]models.Webhook, error) {
+	ok, err := s.permSvc.HasServerPermission(ctx, serverID, userID, models.PermManageServer)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInsufficientRole
+	}
+
+	return s.queries.GetWebhooksByServer(ctx, serverID)
+}
+
+// UpdateWebhookParams changes a webhook. Nil fields are left as they are;
+// an empty AvatarURL clears the avatar.
+type UpdateWebhookParams struct {
+	Name      *string
+	AvatarURL *string
+	ChannelID *uuid.UUID
+}
+
+// UpdateWebhook renames a webhook, changes its avatar or moves it to another
+// text channel of the same server. The caller must have ManageChannels.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookID, userID uuid.UUID, params UpdateWebhookParams) (*models.Webhook, error) {
+	webhook, err := s.getManagedWebhook(ctx, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := models.UpdateWebhookParams{
+		ID:        webhook.ID,
+		ChannelID: webhook.ChannelID,
+		Name:      webhook.Name,
+		AvatarURL: webhook.AvatarURL,
+	}
+	if params.Name != nil {
+		if len(*params.Name) < 1 || len(*params.Name) > 80 {
+			return nil, ErrInvalidWebhookName
+		}
+		arg.Name = *params.Name
+	}
+	if params.AvatarURL != nil {
+		if *params.AvatarURL != "" && !isHTTPURL(*params.AvatarURL) {
+			return nil, ErrInvalidWebhookAvatar
+		}
+		arg.AvatarURL = *params.AvatarURL
+	}
+	if params.ChannelID != nil && *params.ChannelID != webhook.ChannelID {
+		current, err := s.queries.GetChannelByID(ctx, webhook.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		target, err := s.queries.GetChannelByID(ctx, *params.ChannelID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrChannelNotFound
+			}
+			return nil, err
+		}
+		// Webhooks stay within their server
+		if target.ServerID != current.ServerID {
+			return nil, ErrChannelNotFound
+		}
+		if target.Type != "text" {
+			return nil, ErrInvalidWebhookTarget
+		}
+		arg.ChannelID = target.ID
+	}
+
+	updated, err := s.queries.UpdateWebhook(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RotateWebhookToken replaces a webhook's token, so the old URL stops
+// working. Returns the webhook with the new plaintext token (shown once).
+// The caller must have ManageChannels.
+func (s *WebhookService) RotateWebhookToken(ctx context.Context, webhookID, userID uuid.UUID) (*models.Webhook, string, error) {
+	if _, err := s.getManagedWebhook(ctx, webhookID, userID); err != nil {
+		return nil, "", err
+	}
+
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	tokenHash, err := HashToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook, err := s.queries.UpdateWebhookToken(ctx, webhookID, tokenHash)
+	if err != nil {
+		return nil, "", err
+	}
+	return &webhook, token, nil
+}
+
 // DeleteWebhook deletes a webhook. The caller must have ManageChannels permission.
 func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID, userID uuid.UUID) error {
+	if _, err := s.getManagedWebhook(ctx, webhookID, userID); err != nil {
+		return err
+	}
+	return s.queries.DeleteWebhook(ctx, webhookID)
+}
+
+// SetInteractionsURL sets (or, with an empty URL, clears) the endpoint that
+// receives component interactions on the webhook's messages. It returns the
+// new signing secret, shown once. The caller must have ManageChannels.
+func (s *WebhookService) SetInteractionsURL(ctx context.Context, webhookID, userID uuid.UUID, rawURL string) (*models.Webhook, string, error) {
+	if _, err := s.getManagedWebhook(ctx, webhookID, userID); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newInteractionsSecret(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook, err := s.queries.UpdateWebhookInteractionsEndpoint(ctx, webhookID, rawURL, secret)
+	if err != nil {
+		return nil, "", err
+	}
+	return &webhook, secret, nil
+}
+
+// getManagedWebhook loads a webhook the user may manage: a member of its
+// server with ManageChannels.
+func (s *WebhookService) getManagedWebhook(ctx context.Context, webhookID, userID uuid.UUID) (*models.Webhook, error) {
 	webhook, err := s.queries.GetWebhookByID(ctx, webhookID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrWebhookNotFound
+			return nil, ErrWebhookNotFound
 		}
-		return err
+		return nil, err
 	}
 
 	channel, err := s.queries.GetChannelByID(ctx, webhook.ChannelID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if _, err := s.queries.GetServerMember(ctx, channel.ServerID, userID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrNotMember
+			return nil, ErrNotMember
 		}
-		return err
+		return nil, err
 	}
 
 	ok, err := s.permSvc.HasServerPermission(ctx, channel.ServerID, userID, models.PermManageChannels)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !ok {
-		return ErrInsufficientRole
+		return nil, ErrInsufficientRole
 	}
+	return &webhook, nil
+}
 
-	return s.queries.DeleteWebhook(ctx, webhookID)
+// ExecuteWebhookParams is one execution of a webhook.
+type ExecuteWebhookParams struct {
+	Content string
+	// Username and AvatarURL override the webhook's name and avatar for this
+	// message only.
+	Username   string
+	AvatarURL  string
+	Embeds     json.RawMessage
+	Components json.RawMessage
+	// ThreadID posts into a thread of the webhook's channel instead.
+	ThreadID *uuid.UUID
+	// HasFiles reports that attachments will be added to the message, so it
+	// may otherwise be empty.
+	HasFiles bool
 }
 
-// ExecuteWebhook validates the webhook token and creates a message in the channel.
-// Returns the created message.
-func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, content string) (*models.Webhook, *models.Message, error) {
-	webhook, err := s.queries.GetWebhookByID(ctx, webhookID)
+// WebhookExecution is the message a webhook execution created: Message in
+// the channel, or ThreadMessage in Thread.
+type WebhookExecution struct {
+	Webhook       *models.Webhook
+	Message       *models.Message
+	Thread        *models.Thread
+	ThreadMessage *models.ThreadMessage
+}
+
+// ExecuteWebhook validates the webhook token and creates a message in the
+// channel, or in one of its threads. The message is posted under the
+// webhook's name and avatar unless the execution overrides them.
+// Components are only interactive once the webhook has an interactions endpoint.
+func (s *WebhookService) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, params ExecuteWebhookParams) (*WebhookExecution, error) {
+	webhook, err := s.authenticate(ctx, webhookID, token)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil, ErrWebhookNotFound
-		}
-		return nil, nil, err
+		return nil, err
 	}
+	return s.execute(ctx, webhook, params)
+}
 
-	if !ValidateToken(token, webhook.TokenHash) {
-		return nil, nil, ErrWebhookTokenInvalid
+// execute posts a message as an authenticated webhook.
+func (s *WebhookService) execute(ctx context.Context, webhook *models.Webhook, params ExecuteWebhookParams) (*WebhookExecution, error) {
+	username := webhook.Name
+	if params.Username != "" {
+		username = strings.TrimSpace(params.Username)
+		if username == "" || len(username) > 80 {
+			return nil, ErrInvalidWebhookName
+		}
+	}
+	avatarURL := webhook.AvatarURL
+	if params.AvatarURL != "" {
+		if !isHTTPURL(params.AvatarURL) {
+			return nil, ErrInvalidWebhookAvatar
+		}
+		avatarURL = params.AvatarURL
 	}
 
-	if content == "" {
-		return nil, nil, ErrEmptyMessage
+	components, err := ParseComponents(params.Components)
+	if err != nil {
+		return nil, err
+	}
+	embeds, err := ParseEmbeds(params.Embeds)
+	if err != nil {
+		return nil, err
 	}
 
 	// Sanitize content the same way normal messages are sanitized
-	content = s.sanitizer.Sanitize(content)
+	content := s.sanitizer.Sanitize(params.Content)
+	if content == "" && len(components) == 0 && len(embeds) == 0 && !params.HasFiles {
+		return nil, ErrEmptyMessage
+	}
+
+	if params.ThreadID != nil {
+		return s.executeInThread(ctx, webhook, *params.ThreadID, content, embeds, components, username, avatarURL, params.HasFiles)
+	}
 
 	// Create message with the webhook's creator as the author
 	msg, err := s.queries.CreateMessage(ctx, models.CreateMessageParams{
-		ChannelID: webhook.ChannelID,
-		AuthorID:  webhook.CreatorID,
-		Content:   content,
-		Type:      "text",
+		ChannelID:        webhook.ChannelID,
+		AuthorID:         webhook.CreatorID,
+		Content:          content,
+		Type:             "text",
+		Components:       components,
+		Embeds:           embeds,
+		WebhookID:        &webhook.ID,
+		WebhookUsername:  &username,
+		WebhookAvatarURL: &avatarURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_ = s.queries.RecordWebhookUse(ctx, webhook.ID)
+
+	return &WebhookExecution{Webhook: webhook, Message: &msg}, nil
+}
+
+// executeInThread posts a webhook message into a thread of its channel.
+// Thread messages carry neither attachments nor components.
+func (s *WebhookService) executeInThread(ctx context.Context, webhook *models.Webhook, threadID uuid.UUID, content string, embeds, components json.RawMessage, username, avatarURL string, hasFiles bool) (*WebhookExecution, error) {
+	thread, err := s.queries.GetThreadByID(ctx, threadID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrThreadNotFound
+		}
+		return nil, err
+	}
+	if thread.ChannelID != webhook.ChannelID {
+		return nil, ErrThreadNotFound
+	}
+	if thread.Locked {
+		return nil, ErrThreadLocked
+	}
+	if thread.Archived {
+		return nil, ErrThreadArchived
+	}
+	if hasFiles {
+		return nil, ErrThreadAttachments
+	}
+	if len(components) > 0 {
+		return nil, fmt.Errorf("%w: threads do not support components", ErrInvalidComponents)
+	}
+
+	msg, err := s.queries.CreateWebhookThreadMessage(ctx, models.CreateWebhookThreadMessageParams{
+		ThreadID:         thread.ID,
+		AuthorID:         webhook.CreatorID,
+		Content:          content,
+		Embeds:           embeds,
+		WebhookID:        webhook.ID,
+		WebhookUsername:  username,
+		WebhookAvatarURL: avatarURL,
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	_ = s.queries.IncrementThreadMessageCount(ctx, thread.ID)
+	thread.MessageCount++
+	_ = s.queries.RecordWebhookUse(ctx, webhook.ID)
+
+	return &WebhookExecution{Webhook: webhook, Thread: &thread, ThreadMessage: &msg}, nil
+}
+
+// SetAdapterSecret sets (or, when empty, clears) the secret GitHub and GitLab
+// deliveries to the webhook's adapters must be signed with. The caller must
+// have ManageChannels.
+func (s *WebhookService) SetAdapterSecret(ctx context.Context, webhookID, userID uuid.UUID, secret string) (*models.Webhook, error) {
+	if _, err := s.getManagedWebhook(ctx, webhookID, userID); err != nil {
+		return nil, err
+	}
+	if len(secret) > 256 {
+		return nil, ErrInvalidAdapterSecret
+	}
+
+	webhook, err := s.queries.UpdateWebhookAdapterSecret(ctx, webhookID, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// EditWebhookMessageParams changes a webhook message. Nil fields are left
+// as they are; an empty embeds or components array removes them.
+type EditWebhookMessageParams struct {
+	Content    *string
+	Embeds     json.RawMessage
+	Components json.RawMessage
+}
+
+// EditWebhookMessage updates a message the webhook posted. Messages posted
+// by anyone else, including other webhooks, are reported as not found.
+func (s *WebhookService) EditWebhookMessage(ctx context.Context, webhookID uuid.UUID, token string, messageID uuid.UUID, params EditWebhookMessageParams) (*models.Message, error) {
+	webhook, err := s.authenticate(ctx, webhookID, token)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.getWebhookMessage(ctx, webhook, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := models.UpdateWebhookMessageParams{
+		ID:         msg.ID,
+		Content:    msg.Content,
+		Embeds:     msg.Embeds,
+		Components: msg.Components,
+	}
+	if params.Content != nil {
+		if len(*params.Content) > MaxMessageLength {
+			return nil, ErrMessageTooLong
+		}
+		update.Content = s.sanitizer.Sanitize(*params.Content)
+	}
+	if params.Embeds != nil {
+		if update.Embeds, err = ParseEmbeds(params.Embeds); err != nil {
+			return nil, err
+		}
+	}
+	if params.Components != nil {
+		if update.Components, err = ParseComponents(params.Components); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Content == "" && len(update.Embeds) == 0 && len(update.Components) == 0 {
+		atts, err := s.queries.GetAttachmentsByMessageID(ctx, msg.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(atts) == 0 {
+			return nil, ErrEmptyMessage
+		}
+	}
+
+	// Save old content to edit history
+	if update.Content != msg.Content {
+		_ = s.queries.InsertMessageEdit(ctx, msg.ID, msg.Content)
+	}
+
+	updated, err := s.queries.UpdateWebhookMessage(ctx, update)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteWebhookMessage deletes a message the webhook posted and returns it.
+func (s *WebhookService) DeleteWebhookMessage(ctx context.Context, webhookID uuid.UUID, token string, messageID uuid.UUID) (*models.Message, error) {
+	webhook, err := s.authenticate(ctx, webhookID, token)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.getWebhookMessage(ctx, webhook, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.queries.DeleteMessage(ctx, msg.ID, nil); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Authenticate returns the webhook if token is its token. The public
+// execute routes call it ahead of their per-webhook rate limit, so only
+// callers holding the token spend that webhook's budget.
+func (s *WebhookService) Authenticate(ctx context.Context, webhookID uuid.UUID, token string) (*models.Webhook, error) {
+	return s.authenticate(ctx, webhookID, token)
+}
+
+// authenticate returns the webhook if token is its token.
+func (s *WebhookService) authenticate(ctx context.Context, webhookID uuid.UUID, token string) (*models.Webhook, error) {
+	webhook, err := s.queries.GetWebhookByID(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	if !ValidateToken(token, webhook.TokenHash) {
+		return nil, ErrWebhookTokenInvalid
+	}
+	return &webhook, nil
+}
+
+// getWebhookMessage returns one of the webhook's own messages.
+func (s *WebhookService) getWebhookMessage(ctx context.Context, webhook *models.Webhook, messageID uuid.UUID) (*models.Message, error) {
+	msg, err := s.queries.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
 	}
+	if msg.WebhookID == nil || *msg.WebhookID != webhook.ID {
+		return nil, ErrMessageNotFound
+	}
+	return &msg, nil
+}
 
-	return &webhook, &msg, nil
+// isHTTPURL reports whether s is an absolute http(s) URL of reasonable length.
+func isHTTPURL(s string) bool {
+	if len(s) > 2048 {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 // GetWebhookForExecution returns a webhook by ID (used for public execute endpoint).
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/models"
+	"github.com/M-McCallum/thicket/internal/testutil"
+)
+
+func TestExecuteWebhook_UsesWebhookIdentity(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	exec, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Content: "build passed"})
+	require.NoError(t, err)
+	msg := exec.Message
+	assert.Equal(t, &webhook.ID, msg.WebhookID)
+	require.NotNil(t, msg.WebhookUsername)
+	assert.Equal(t, "CI", *msg.WebhookUsername)
+
+	exec, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{
+		Content:   "deploying",
+		Username:  "Deploy Bot",
+		AvatarURL: "https://cdn.example.com/deploy.png",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy Bot", *exec.Message.WebhookUsername)
+	assert.Equal(t, "https://cdn.example.com/deploy.png", *exec.Message.WebhookAvatarURL)
+
+	// The overrides are stored with the message, not the webhook
+	stored, err := queries().GetMessageByID(ctx, exec.Message.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deploy Bot", *stored.WebhookUsername)
+	hook, err := queries().GetWebhookByID(ctx, webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "CI", hook.Name)
+
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Content: "x", AvatarURL: "javascript:alert(1)"})
+	assert.ErrorIs(t, err, ErrInvalidWebhookAvatar)
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, "wrong", ExecuteWebhookParams{Content: "x"})
+	assert.ErrorIs(t, err, ErrWebhookTokenInvalid)
+}
+
+func TestExecuteWebhook_Embeds(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	embeds := `[{"title":"Build #42","url":"https://ci.example.com/42","color":3066993,
+		"fields":[{"name":"Branch","value":"main","inline":true}],"footer":{"text":"CI"}}]`
+	exec, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Embeds: json.RawMessage(embeds)})
+	require.NoError(t, err)
+
+	var got []Embed
+	require.NoError(t, json.Unmarshal(exec.Message.Embeds, &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "Build #42", got[0].Title)
+	require.Len(t, got[0].Fields, 1)
+	assert.True(t, got[0].Fields[0].Inline)
+
+	for name, raw := range map[string]string{
+		"empty embed":    `[{}]`,
+		"bad url":        `[{"title":"x","url":"ftp://example.com"}]`,
+		"bad color":      `[{"title":"x","color":16777216}]`,
+		"field no value": `[{"fields":[{"name":"a","value":""}]}]`,
+		"not an array":   `{"title":"x"}`,
+	} {
+		_, err := ParseEmbeds(json.RawMessage(raw))
+		assert.ErrorIs(t, err, ErrInvalidEmbeds, name)
+	}
+
+	// Files alone are enough; nothing at all is not
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{HasFiles: true})
+	require.NoError(t, err)
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{})
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+}
+
+func TestExecuteWebhook_ThreadTarget(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	parent, err := queries().CreateMessage(ctx, models.CreateMessageParams{
+		ChannelID: channel.ID, AuthorID: owner.User.ID, Content: "release thread",
+	})
+	require.NoError(t, err)
+	thread, err := NewThreadService(queries()).CreateThread(ctx, channel.ID, parent.ID, "Releases", owner.User.ID)
+	require.NoError(t, err)
+
+	exec, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{
+		Content: "v1.2.0 is out", Username: "Releases", ThreadID: &thread.ID,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, exec.Message)
+	require.NotNil(t, exec.ThreadMessage)
+	assert.Equal(t, thread.ID, exec.ThreadMessage.ThreadID)
+	assert.Equal(t, &webhook.ID, exec.ThreadMessage.WebhookID)
+	assert.Equal(t, "Releases", *exec.ThreadMessage.WebhookUsername)
+	assert.Equal(t, 1, exec.Thread.MessageCount)
+
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{
+		Content: "with files", ThreadID: &thread.ID, HasFiles: true,
+	})
+	assert.ErrorIs(t, err, ErrThreadAttachments)
+
+	// Threads of other channels are out of reach
+	_, elsewhere, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Elsewhere", owner.User.ID)
+	require.NoError(t, err)
+	other, otherToken, err := svc.CreateWebhook(ctx, elsewhere.ID, owner.User.ID, "Other")
+	require.NoError(t, err)
+	_, err = svc.ExecuteWebhook(ctx, other.ID, otherToken, ExecuteWebhookParams{
+		Content: "hi", ThreadID: &thread.ID,
+	})
+	assert.ErrorIs(t, err, ErrThreadNotFound)
+}
+
+func TestWebhookMessage_EditAndDelete(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	exec, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{
+		Content: "build running", Embeds: json.RawMessage(`[{"title":"Build #7"}]`),
+	})
+	require.NoError(t, err)
+	msgID := exec.Message.ID
+
+	content := "build passed"
+	updated, err := svc.EditWebhookMessage(ctx, webhook.ID, token, msgID, EditWebhookMessageParams{Content: &content})
+	require.NoError(t, err)
+	assert.Equal(t, "build passed", updated.Content)
+	assert.JSONEq(t, `[{"title":"Build #7"}]`, string(updated.Embeds), "embeds are kept unless sent")
+
+	edits, err := queries().GetMessageEdits(ctx, msgID)
+	require.NoError(t, err)
+	require.Len(t, edits, 1)
+	assert.Equal(t, "build running", edits[0].Content)
+
+	updated, err = svc.EditWebhookMessage(ctx, webhook.ID, token, msgID, EditWebhookMessageParams{Embeds: json.RawMessage(`[]`)})
+	require.NoError(t, err)
+	assert.Nil(t, updated.Embeds)
+
+	empty := ""
+	_, err = svc.EditWebhookMessage(ctx, webhook.ID, token, msgID, EditWebhookMessageParams{Content: &empty})
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+
+	_, err = svc.EditWebhookMessage(ctx, webhook.ID, "wrong", msgID, EditWebhookMessageParams{Content: &content})
+	assert.ErrorIs(t, err, ErrWebhookTokenInvalid)
+	_, err = svc.DeleteWebhookMessage(ctx, webhook.ID, "wrong", msgID)
+	assert.ErrorIs(t, err, ErrWebhookTokenInvalid)
+
+	deleted, err := svc.DeleteWebhookMessage(ctx, webhook.ID, token, msgID)
+	require.NoError(t, err)
+	assert.Equal(t, channel.ID, deleted.ChannelID)
+	_, err = queries().GetMessageByID(ctx, msgID)
+	assert.Error(t, err)
+}
+
+func TestWebhookMessage_OnlyOwnMessages(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	// A message by the webhook's creator, not the webhook
+	human, err := queries().CreateMessage(ctx, models.CreateMessageParams{
+		ChannelID: channel.ID, AuthorID: owner.User.ID, Content: "hello",
+	})
+	require.NoError(t, err)
+
+	// And one by another webhook in the same channel
+	other, otherToken, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "Other")
+	require.NoError(t, err)
+	exec, err := svc.ExecuteWebhook(ctx, other.ID, otherToken, ExecuteWebhookParams{Content: "mine"})
+	require.NoError(t, err)
+
+	content := "hijacked"
+	for _, id := range []uuid.UUID{human.ID, exec.Message.ID} {
+		_, err = svc.EditWebhookMessage(ctx, webhook.ID, token, id, EditWebhookMessageParams{Content: &content})
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+		_, err = svc.DeleteWebhookMessage(ctx, webhook.ID, token, id)
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+	channels := NewChannelService(queries(), permSvc)
+
+	name, avatar := "Deploys", "https://cdn.example.com/deploy.png"
+	updated, err := svc.UpdateWebhook(ctx, webhook.ID, owner.User.ID, UpdateWebhookParams{Name: &name, AvatarURL: &avatar})
+	require.NoError(t, err)
+	assert.Equal(t, "Deploys", updated.Name)
+	assert.Equal(t, avatar, updated.AvatarURL)
+	assert.Equal(t, channel.ID, updated.ChannelID)
+
+	// Executions now default to the new identity
+	exec, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Content: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "Deploys", *exec.Message.WebhookUsername)
+	assert.Equal(t, avatar, *exec.Message.WebhookAvatarURL)
+
+	target, err := channels.CreateChannel(ctx, channel.ServerID, owner.User.ID, "deploys", "text")
+	require.NoError(t, err)
+	updated, err = svc.UpdateWebhook(ctx, webhook.ID, owner.User.ID, UpdateWebhookParams{ChannelID: &target.ID})
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, updated.ChannelID)
+	assert.Equal(t, "Deploys", updated.Name)
+
+	voice, err := channels.CreateChannel(ctx, channel.ServerID, owner.User.ID, "Lounge", "voice")
+	require.NoError(t, err)
+	_, err = svc.UpdateWebhook(ctx, webhook.ID, owner.User.ID, UpdateWebhookParams{ChannelID: &voice.ID})
+	assert.ErrorIs(t, err, ErrInvalidWebhookTarget)
+
+	// Channels of other servers are out of reach
+	outsider := createUser(t)
+	_, elsewhere, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Elsewhere", outsider.User.ID)
+	require.NoError(t, err)
+	_, err = svc.UpdateWebhook(ctx, webhook.ID, owner.User.ID, UpdateWebhookParams{ChannelID: &elsewhere.ID})
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+
+	bad := "ftp://example.com/a.png"
+	_, err = svc.UpdateWebhook(ctx, webhook.ID, owner.User.ID, UpdateWebhookParams{AvatarURL: &bad})
+	assert.ErrorIs(t, err, ErrInvalidWebhookAvatar)
+	_, err = svc.UpdateWebhook(ctx, webhook.ID, outsider.User.ID, UpdateWebhookParams{Name: &name})
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestRotateWebhookToken(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	rotated, newToken, err := svc.RotateWebhookToken(ctx, webhook.ID, owner.User.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.ID, rotated.ID)
+	assert.NotEqual(t, token, newToken)
+
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Content: "old"})
+	assert.ErrorIs(t, err, ErrWebhookTokenInvalid)
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, newToken, ExecuteWebhookParams{Content: "new"})
+	require.NoError(t, err)
+
+	member := createUser(t)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), channel.ServerID, member.User.ID, "member"))
+	_, _, err = svc.RotateWebhookToken(ctx, webhook.ID, member.User.ID)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+}
+
+func TestWebhookUsageAndServerListing(t *testing.T) {
+	ctx := context.Background()
+	permSvc := NewPermissionService(queries())
+	svc := NewWebhookService(queries(), permSvc)
+	owner := createUser(t)
+
+	_, channel, err := NewServerService(queries(), permSvc).CreateServer(ctx, "Hooks", owner.User.ID)
+	require.NoError(t, err)
+	webhook, token, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "CI")
+	require.NoError(t, err)
+
+	assert.Nil(t, webhook.LastUsedAt)
+	for i := 0; i < 3; i++ {
+		_, err := svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{Content: "tick"})
+		require.NoError(t, err)
+	}
+	// Rejected executions are not counted
+	_, err = svc.ExecuteWebhook(ctx, webhook.ID, token, ExecuteWebhookParams{})
+	require.ErrorIs(t, err, ErrEmptyMessage)
+
+	second, _, err := svc.CreateWebhook(ctx, channel.ID, owner.User.ID, "Alerts")
+	require.NoError(t, err)
+
+	webhooks, err := svc.ListServerWebhooks(ctx, channel.ServerID, owner.User.ID)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	byID := map[uuid.UUID]models.Webhook{}
+	for _, w := range webhooks {
+		byID[w.ID] = w
+	}
+	assert.Equal(t, int64(3), byID[webhook.ID].MessageCount)
+	assert.NotNil(t, byID[webhook.ID].LastUsedAt)
+	assert.Equal(t, int64(0), byID[second.ID].MessageCount)
+	assert.Nil(t, byID[second.ID].LastUsedAt)
+
+	// Server admins only
+	member := createUser(t)
+	require.NoError(t, testutil.AddTestMember(ctx, queries(), channel.ServerID, member.User.ID, "member"))
+	_, err = svc.ListServerWebhooks(ctx, channel.ServerID, member.User.ID)
+	assert.ErrorIs(t, err, ErrInsufficientRole)
+}
@@ -111,6 +111,20 @@ func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
 		"000036_e2ee_identity_keys.up.sql",
 		"000037_large_file_uploads.up.sql",
 		"000038_rename_webhook_token_column.up.sql",
+		"000039_server_invitations.up.sql",
+		"000040_ws_bus_payloads.up.sql",
+		"000041_bot_accounts.up.sql",
+		"000042_interactions.up.sql",
+		"000043_message_components.up.sql",
+		"000044_event_subscriptions.up.sql",
+		"000045_webhook_message_overrides.up.sql",
+		"000046_webhook_adapter_secret.up.sql",
+		"000047_webhook_usage.up.sql",
+		"000048_bot_managed_roles.up.sql",
+		"000049_mention_notifications_message.up.sql",
+		"000050_message_history_cursor.up.sql",
+		"000051_message_nonces.up.sql",
+		"000052_message_soft_delete.up.sql",
 	}
 
 	for _, name := range migrations {
@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const accessCheckTimeout = 5 * time.Second
+
+// ChannelAccessFn reports whether a user may receive a channel's events.
+type ChannelAccessFn func(ctx context.Context, userID, channelID uuid.UUID) (bool, error)
+
+// SetChannelAccessFn installs the check used to authorize SUBSCRIBE and to
+// revalidate live subscriptions. Without one every subscription is allowed.
+func (h *Hub) SetChannelAccessFn(fn ChannelAccessFn) {
+	h.channelAccess = fn
+}
+
+// canAccessChannel runs the access check. Errors fail closed.
+func (h *Hub) canAccessChannel(userID uuid.UUID, channelID string) bool {
+	if h.channelAccess == nil {
+		return true
+	}
+	cid, err := uuid.Parse(channelID)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), accessCheckTimeout)
+	defer cancel()
+	ok, err := h.channelAccess(ctx, userID, cid)
+	if err != nil {
+		log.Printf("WS access check failed for user %s channel %s: %v", userID, channelID, err)
+		return false
+	}
+	return ok
+}
+
+// RevalidateUser re-checks every channel the user is subscribed to, on every
+// node, and drops the ones they can no longer see. Call it after a kick, ban
+// or role removal.
+func (h *Hub) RevalidateUser(userID uuid.UUID) {
+	go h.revalidateUser(userID)
+	h.publish(&Envelope{Kind: EnvelopeRevalidate, UserID: userID})
+}
+
+// RevalidateChannel re-checks every subscriber of a channel, on every node.
+// Call it after a permission override or role permission change.
+func (h *Hub) RevalidateChannel(channelID string) {
+	go h.revalidateChannel(channelID)
+	h.publish(&Envelope{Kind: EnvelopeRevalidate, ChannelID: channelID})
+}
+
+func (h *Hub) revalidateUser(userID uuid.UUID) {
+	if h.channelAccess == nil {
+		return
+	}
+
+	h.mu.RLock()
+	var channelIDs []string
+	for chID, members := range h.channels {
+		if members[userID] {
+			channelIDs = append(channelIDs, chID)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, chID := range channelIDs {
+		if !h.canAccessChannel(userID, chID) {
+			h.Unsubscribe(userID, chID)
+			log.Printf("Revoked subscription of user %s to channel %s", userID, chID)
+		}
+	}
+}
+
+func (h *Hub) revalidateChannel(channelID string) {
+	if h.channelAccess == nil {
+		return
+	}
+
+	h.mu.RLock()
+	userIDs := make([]uuid.UUID, 0, len(h.channels[channelID]))
+	for id := range h.channels[channelID] {
+		userIDs = append(userIDs, id)
+	}
+	h.mu.RUnlock()
+
+	for _, id := range userIDs {
+		if !h.canAccessChannel(id, channelID) {
+			h.Unsubscribe(id, channelID)
+			log.Printf("Revoked subscription of user %s to channel %s", id, channelID)
+		}
+	}
+}
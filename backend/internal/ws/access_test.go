@@ -0,0 +1,151 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// accessTable is a mutable ChannelAccessFn for tests.
+type accessTable struct {
+	mu      sync.Mutex
+	allowed map[uuid.UUID]map[uuid.UUID]bool // channelID -> userID
+}
+
+func newAccessTable() *accessTable {
+	return &accessTable{allowed: make(map[uuid.UUID]map[uuid.UUID]bool)}
+}
+
+func (a *accessTable) set(channelID, userID uuid.UUID, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.allowed[channelID] == nil {
+		a.allowed[channelID] = make(map[uuid.UUID]bool)
+	}
+	a.allowed[channelID][userID] = ok
+}
+
+func (a *accessTable) check(_ context.Context, userID, channelID uuid.UUID) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allowed[channelID][userID], nil
+}
+
+func subscribeEvent(t *testing.T, channelID uuid.UUID) *Event {
+	t.Helper()
+	data, err := json.Marshal(SubscribeData{ChannelID: channelID.String()})
+	require.NoError(t, err)
+	return &Event{Type: EventSubscribe, Data: data}
+}
+
+func TestAccess_SubscribeRequiresAccess(t *testing.T) {
+	hub := NewHub()
+	access := newAccessTable()
+	hub.SetChannelAccessFn(access.check)
+	go hub.Run()
+
+	channelID := uuid.New()
+	member := newTestClient(hub, uuid.New(), "member")
+	outsider := newTestClient(hub, uuid.New(), "outsider")
+	access.set(channelID, member.UserID, true)
+
+	member.handleEvent(subscribeEvent(t, channelID))
+	outsider.handleEvent(subscribeEvent(t, channelID))
+
+	assert.True(t, hub.IsSubscribed(member.UserID, channelID.String()))
+	assert.False(t, hub.IsSubscribed(outsider.UserID, channelID.String()))
+}
+
+func TestAccess_SubscribeRejectsInvalidChannelID(t *testing.T) {
+	hub := NewHub()
+	hub.SetChannelAccessFn(newAccessTable().check)
+	go hub.Run()
+
+	client := newTestClient(hub, uuid.New(), "user")
+	data, _ := json.Marshal(SubscribeData{ChannelID: "not-a-uuid"})
+	client.handleEvent(&Event{Type: EventSubscribe, Data: data})
+
+	assert.False(t, hub.IsSubscribed(client.UserID, "not-a-uuid"))
+}
+
+func TestAccess_RevalidateUserDropsLostChannels(t *testing.T) {
+	hub := NewHub()
+	access := newAccessTable()
+	hub.SetChannelAccessFn(access.check)
+	go hub.Run()
+
+	userID := uuid.New()
+	kept, lost := uuid.New(), uuid.New()
+	access.set(kept, userID, true)
+	access.set(lost, userID, true)
+
+	client := newTestClient(hub, userID, "user")
+	client.handleEvent(subscribeEvent(t, kept))
+	client.handleEvent(subscribeEvent(t, lost))
+
+	// User is kicked from the server owning one channel
+	access.set(lost, userID, false)
+	hub.RevalidateUser(userID)
+
+	assert.Eventually(t, func() bool {
+		return !hub.IsSubscribed(userID, lost.String())
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, hub.IsSubscribed(userID, kept.String()))
+}
+
+func TestAccess_RevalidateChannelDropsDeniedSubscribers(t *testing.T) {
+	hub := NewHub()
+	access := newAccessTable()
+	hub.SetChannelAccessFn(access.check)
+	go hub.Run()
+
+	channelID := uuid.New()
+	staff := newTestClient(hub, uuid.New(), "staff")
+	member := newTestClient(hub, uuid.New(), "member")
+	access.set(channelID, staff.UserID, true)
+	access.set(channelID, member.UserID, true)
+	staff.handleEvent(subscribeEvent(t, channelID))
+	member.handleEvent(subscribeEvent(t, channelID))
+
+	// An override now denies view to the member's roles
+	access.set(channelID, member.UserID, false)
+	hub.RevalidateChannel(channelID.String())
+
+	assert.Eventually(t, func() bool {
+		return !hub.IsSubscribed(member.UserID, channelID.String())
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, hub.IsSubscribed(staff.UserID, channelID.String()))
+}
+
+func TestAccess_RevalidateReachesOtherNode(t *testing.T) {
+	bus := &memBus{}
+	hubA, hubB := NewHub(), NewHub()
+	access := newAccessTable()
+	for _, h := range []*Hub{hubA, hubB} {
+		h.SetBus(bus)
+		h.SetChannelAccessFn(access.check)
+		go h.Run()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	userID := uuid.New()
+	channelID := uuid.New()
+	access.set(channelID, userID, true)
+
+	client := newTestClient(hubB, userID, "remote")
+	client.handleEvent(subscribeEvent(t, channelID))
+	require.True(t, hubB.IsSubscribed(userID, channelID.String()))
+
+	access.set(channelID, userID, false)
+	hubA.RevalidateUser(userID)
+
+	assert.Eventually(t, func() bool {
+		return !hubB.IsSubscribed(userID, channelID.String())
+	}, time.Second, 10*time.Millisecond)
+}
@@ -5,10 +5,33 @@ import "github.com/google/uuid"
 // BroadcastToServerMembers sends an event to each member by user ID,
 // optionally excluding one user (e.g. the sender).
 func BroadcastToServerMembers(hub *Hub, memberIDs []uuid.UUID, event *Event, excludeID *uuid.UUID) {
-	for _, id := range memberIDs {
-		if excludeID != nil && id == *excludeID {
-			continue
+	hub.observe("", event)
+	recipients := memberIDs
+	if excludeID != nil {
+		recipients = make([]uuid.UUID, 0, len(memberIDs))
+		for _, id := range memberIDs {
+			if id != *excludeID {
+				recipients = append(recipients, id)
+			}
 		}
-		hub.SendToUser(id, event)
+	}
+	hub.SendToUsers(recipients, event)
+}
+
+// EventObserver is told about every event broadcast to a channel (with its
+// channel ID) or to a server's members (with an empty channel ID). It runs
+// on the broadcasting goroutine, so it must not block.
+type EventObserver func(channelID string, event *Event)
+
+// SetEventObserver installs the observer, e.g. to forward server events to
+// outgoing webhooks. Events relayed from other replicas are not observed,
+// so each event is seen once across the cluster.
+func (h *Hub) SetEventObserver(fn EventObserver) {
+	h.observer = fn
+}
+
+func (h *Hub) observe(channelID string, event *Event) {
+	if h.observer != nil {
+		h.observer(channelID, event)
 	}
 }
@@ -0,0 +1,302 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bus relays hub traffic between API nodes so that channel broadcasts,
+// per-user sends and presence changes reach sockets held by any replica.
+type Bus interface {
+	// Publish delivers an envelope to every node listening on the bus.
+	Publish(ctx context.Context, env *Envelope) error
+	// Listen blocks, passing envelopes from the bus to deliver until ctx is done.
+	Listen(ctx context.Context, deliver func(*Envelope)) error
+}
+
+// Envelope kinds
+const (
+	EnvelopeChannel    = "channel"
+	EnvelopeUser       = "user"
+	EnvelopeUsers      = "users"
+	EnvelopePresence   = "presence"
+	EnvelopeHeartbeat  = "heartbeat"
+	EnvelopeRevalidate = "revalidate"
+	EnvelopeVoice      = "voice"
+)
+
+const (
+	busHeartbeatInterval = 10 * time.Second
+	busNodeTimeout       = 3 * busHeartbeatInterval
+	busOutboundSize      = 1024
+)
+
+// Envelope is the unit of traffic on the bus.
+type Envelope struct {
+	NodeID    string      `json:"node_id"`
+	Kind      string      `json:"kind"`
+	ChannelID string      `json:"channel_id,omitempty"`
+	UserID    uuid.UUID   `json:"user_id,omitempty"`
+	ExcludeID *uuid.UUID  `json:"exclude_id,omitempty"`
+	Online    bool        `json:"online,omitempty"`
+	Resumable bool        `json:"resumable,omitempty"` // offline but holds a resumable session
+	UserIDs   []uuid.UUID `json:"user_ids,omitempty"`
+	Detached  []uuid.UUID `json:"detached,omitempty"` // users with only resumable sessions
+	Event     *Event      `json:"event,omitempty"`
+	Voice     *VoiceState `json:"voice,omitempty"` // nil with ChannelID set clears the channel
+}
+
+// remoteNode tracks the users connected to another replica, as last reported.
+type remoteNode struct {
+	users    map[uuid.UUID]bool
+	detached map[uuid.UUID]bool // users whose sessions there can still RESUME
+	lastSeen time.Time
+}
+
+// SetBus attaches a cross-node bus. Must be called before Run.
+func (h *Hub) SetBus(bus Bus) {
+	h.bus = bus
+}
+
+// NodeID returns the identifier this hub uses on the bus.
+func (h *Hub) NodeID() string {
+	return h.nodeID
+}
+
+func (h *Hub) runBus() {
+	ctx := context.Background()
+
+	go func() {
+		if err := h.bus.Listen(ctx, h.handleEnvelope); err != nil {
+			log.Printf("WS bus listener stopped: %v", err)
+		}
+	}()
+
+	heartbeat := time.NewTicker(busHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	h.publish(h.heartbeatEnvelope())
+	for {
+		select {
+		case env := <-h.outbound:
+			if err := h.bus.Publish(ctx, env); err != nil {
+				log.Printf("WS bus publish failed (%s): %v", env.Kind, err)
+			}
+		case <-heartbeat.C:
+			h.pruneRemoteNodes()
+			h.publish(h.heartbeatEnvelope())
+		}
+	}
+}
+
+// PublishVoiceState shares a voice state change applied on this hub with
+// the other replicas; joined false means the user left state.ChannelID.
+// Used when LiveKit webhooks, which reach a single replica, own voice state.
+func (h *Hub) PublishVoiceState(state VoiceState, joined bool) {
+	h.publish(&Envelope{Kind: EnvelopeVoice, Voice: &state, Online: joined})
+}
+
+// PublishVoiceClear shares a ClearVoiceChannel with the other replicas.
+func (h *Hub) PublishVoiceClear(channelID string) {
+	h.publish(&Envelope{Kind: EnvelopeVoice, ChannelID: channelID})
+}
+
+// publish queues an envelope for the bus without blocking the caller.
+func (h *Hub) publish(env *Envelope) {
+	if h.bus == nil {
+		return
+	}
+	env.NodeID = h.nodeID
+	select {
+	case h.outbound <- env:
+	default:
+		log.Printf("WS bus outbound queue full, dropping %s envelope", env.Kind)
+	}
+}
+
+func (h *Hub) handleEnvelope(env *Envelope) {
+	if env.NodeID == h.nodeID {
+		return
+	}
+
+	switch env.Kind {
+	case EnvelopeChannel:
+		if env.Event == nil {
+			return
+		}
+		// Never stall the bus listener behind a busy hub loop
+		select {
+		case h.broadcast <- &ChannelMessage{
+			ChannelID: env.ChannelID,
+			Event:     env.Event,
+			ExcludeID: env.ExcludeID,
+		}:
+		default:
+			h.busDropped.Add(1)
+			log.Printf("WS broadcast queue full, dropping %s from node %s", env.Event.Type, env.NodeID)
+		}
+
+	case EnvelopeUser:
+		if env.Event == nil {
+			return
+		}
+		h.sendLocal(env.UserID, env.Event)
+
+	case EnvelopeUsers:
+		if env.Event == nil {
+			return
+		}
+		h.sendLocalMany(env.UserIDs, env.Event)
+
+	case EnvelopePresence:
+		h.mu.Lock()
+		node := h.remoteNodeLocked(env.NodeID)
+		if env.Online {
+			node.users[env.UserID] = true
+		} else {
+			delete(node.users, env.UserID)
+		}
+		if env.Resumable {
+			node.detached[env.UserID] = true
+		} else {
+			delete(node.detached, env.UserID)
+		}
+		h.mu.Unlock()
+
+	case EnvelopeHeartbeat:
+		h.mu.Lock()
+		node := h.remoteNodeLocked(env.NodeID)
+		node.users = make(map[uuid.UUID]bool, len(env.UserIDs))
+		for _, id := range env.UserIDs {
+			node.users[id] = true
+		}
+		node.detached = make(map[uuid.UUID]bool, len(env.Detached))
+		for _, id := range env.Detached {
+			node.detached[id] = true
+		}
+		h.mu.Unlock()
+
+	case EnvelopeRevalidate:
+		if env.ChannelID != "" {
+			go h.revalidateChannel(env.ChannelID)
+		} else {
+			go h.revalidateUser(env.UserID)
+		}
+
+	case EnvelopeVoice:
+		switch {
+		case env.Voice == nil:
+			h.ClearVoiceChannel(env.ChannelID)
+		case env.Online:
+			h.JoinVoiceChannel(*env.Voice)
+		default:
+			h.LeaveVoiceChannel(env.Voice.UserID, env.Voice.ChannelID)
+		}
+	}
+}
+
+// remoteNodeLocked returns the entry for a remote node, creating it if needed.
+// Caller must hold h.mu.
+func (h *Hub) remoteNodeLocked(nodeID string) *remoteNode {
+	node, ok := h.remote[nodeID]
+	if !ok {
+		node = &remoteNode{
+			users:    make(map[uuid.UUID]bool),
+			detached: make(map[uuid.UUID]bool),
+		}
+		h.remote[nodeID] = node
+	}
+	node.lastSeen = time.Now()
+	return node
+}
+
+// pruneRemoteNodes forgets replicas that have stopped sending heartbeats.
+// Users who were connected only there are now offline, and the replica that
+// would have reported it is gone, so the surviving node with the lowest ID
+// runs the disconnect callback for them.
+func (h *Hub) pruneRemoteNodes() {
+	h.mu.Lock()
+	cutoff := time.Now().Add(-busNodeTimeout)
+	lost := make(map[uuid.UUID]bool)
+	for id, node := range h.remote {
+		if node.lastSeen.Before(cutoff) {
+			delete(h.remote, id)
+			log.Printf("WS bus node %s timed out", id)
+			for userID := range node.users {
+				lost[userID] = true
+			}
+		}
+	}
+	var offline []uuid.UUID
+	if h.lowestNodeLocked() {
+		for userID := range lost {
+			if len(h.clients[userID]) == 0 && !h.onlineElsewhereLocked(userID) {
+				offline = append(offline, userID)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if h.onDisconnect == nil {
+		return
+	}
+	for _, userID := range offline {
+		// The username went with the node; presence is keyed by ID
+		go h.onDisconnect(userID, "")
+	}
+}
+
+// lowestNodeLocked reports whether this hub has the lowest ID among the
+// replicas it knows of. Caller must hold h.mu.
+func (h *Hub) lowestNodeLocked() bool {
+	for id := range h.remote {
+		if id < h.nodeID {
+			return false
+		}
+	}
+	return true
+}
+
+// onlineElsewhereLocked reports whether a user has a socket on another replica.
+// Caller must hold h.mu.
+func (h *Hub) onlineElsewhereLocked(userID uuid.UUID) bool {
+	for _, node := range h.remote {
+		if node.users[userID] {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableElsewhereLocked reports whether another replica would deliver or
+// buffer an event for the user. Caller must hold h.mu.
+func (h *Hub) reachableElsewhereLocked(userID uuid.UUID) bool {
+	for _, node := range h.remote {
+		if node.users[userID] || node.detached[userID] {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) heartbeatEnvelope() *Envelope {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	env := &Envelope{
+		Kind:    EnvelopeHeartbeat,
+		UserIDs: make([]uuid.UUID, 0, len(h.clients)),
+	}
+	for id := range h.clients {
+		env.UserIDs = append(env.UserIDs, id)
+	}
+	for id := range h.detached {
+		if len(h.clients[id]) == 0 {
+			env.Detached = append(env.Detached, id)
+		}
+	}
+	return env
+}
@@ -0,0 +1,228 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memBus is an in-process Bus shared by several hubs.
+type memBus struct {
+	mu        sync.Mutex
+	listeners []func(*Envelope)
+}
+
+func (b *memBus) Publish(_ context.Context, env *Envelope) error {
+	// Round-trip through JSON like a real transport would.
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	listeners := append([]func(*Envelope){}, b.listeners...)
+	b.mu.Unlock()
+	for _, deliver := range listeners {
+		var copied Envelope
+		if err := json.Unmarshal(data, &copied); err != nil {
+			return err
+		}
+		deliver(&copied)
+	}
+	return nil
+}
+
+func (b *memBus) Listen(ctx context.Context, deliver func(*Envelope)) error {
+	b.mu.Lock()
+	b.listeners = append(b.listeners, deliver)
+	b.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newClusterHubs(t *testing.T) (*Hub, *Hub) {
+	t.Helper()
+	bus := &memBus{}
+	a, b := NewHub(), NewHub()
+	a.SetBus(bus)
+	b.SetBus(bus)
+	go a.Run()
+	go b.Run()
+	time.Sleep(20 * time.Millisecond)
+	return a, b
+}
+
+func expectEvent(t *testing.T, c *Client, eventType string) {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		var e Event
+		require.NoError(t, json.Unmarshal(msg, &e))
+		assert.Equal(t, eventType, e.Type)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatalf("%s did not receive %s", c.Username, eventType)
+	}
+}
+
+func TestHubBus_BroadcastReachesOtherNode(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	userID := uuid.New()
+	client := newTestClient(hubB, userID, "remote")
+	hubB.Register(client)
+	time.Sleep(20 * time.Millisecond)
+
+	channelID := uuid.New().String()
+	hubB.Subscribe(userID, channelID)
+
+	event, err := NewEvent(EventMessageCreate, map[string]string{"content": "hi"})
+	require.NoError(t, err)
+	hubA.BroadcastToChannel(channelID, event, nil)
+
+	expectEvent(t, client, EventMessageCreate)
+}
+
+func TestHubBus_SendToUserOnOtherNode(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	userID := uuid.New()
+	client := newTestClient(hubB, userID, "remote")
+	hubB.Register(client)
+	time.Sleep(20 * time.Millisecond)
+
+	assert.True(t, hubA.IsOnline(userID))
+	assert.Contains(t, hubA.GetOnlineUsers(), userID)
+
+	event, err := NewEvent(EventNotification, map[string]string{"type": "message"})
+	require.NoError(t, err)
+	hubA.SendToUser(userID, event)
+
+	expectEvent(t, client, EventNotification)
+}
+
+func TestHubBus_PresenceCallbacksAreClusterWide(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	var mu sync.Mutex
+	var connects, disconnects int
+	for _, h := range []*Hub{hubA, hubB} {
+		h.SetOnConnect(func(uuid.UUID, string) { mu.Lock(); connects++; mu.Unlock() })
+		h.SetOnDisconnect(func(uuid.UUID, string) { mu.Lock(); disconnects++; mu.Unlock() })
+	}
+
+	userID := uuid.New()
+	onA := newTestClient(hubA, userID, "user")
+	onB := newTestClient(hubB, userID, "user")
+
+	hubA.Register(onA)
+	time.Sleep(20 * time.Millisecond)
+	hubB.Register(onB)
+	time.Sleep(20 * time.Millisecond)
+
+	hubA.Unregister(onA)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, hubA.IsOnline(userID), "user still connected via the other node")
+
+	hubB.Unregister(onB)
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, hubA.IsOnline(userID))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, connects)
+	assert.Equal(t, 1, disconnects)
+}
+
+func TestHubBus_VoiceStateIsClusterWide(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	channelID := uuid.New().String()
+	state := VoiceState{UserID: uuid.New(), ChannelID: channelID, Username: "speaker"}
+	hubA.JoinVoiceChannel(state)
+	hubA.PublishVoiceState(state, true)
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, hubB.GetVoiceParticipants(channelID), 1)
+
+	state.Muted = true
+	hubA.PublishVoiceState(state, true)
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, hubB.GetVoiceParticipants(channelID)[0].Muted)
+
+	hubA.PublishVoiceState(state, false)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, hubB.GetVoiceParticipants(channelID))
+
+	hubB.JoinVoiceChannel(state)
+	hubA.PublishVoiceClear(channelID)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, hubB.GetVoiceParticipants(channelID))
+}
+
+func TestHubBus_PrunedNodeUsersGoOffline(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	disconnected := make(chan uuid.UUID, 1)
+	hubA.SetOnDisconnect(func(id uuid.UUID, _ string) { disconnected <- id })
+
+	userID := uuid.New()
+	hubB.Register(newTestClient(hubB, userID, "stranded"))
+	time.Sleep(20 * time.Millisecond)
+	require.True(t, hubA.IsOnline(userID))
+
+	// hubB stops heartbeating
+	hubA.mu.Lock()
+	hubA.remote[hubB.NodeID()].lastSeen = time.Now().Add(-2 * busNodeTimeout)
+	hubA.mu.Unlock()
+	hubA.pruneRemoteNodes()
+
+	assert.False(t, hubA.IsOnline(userID))
+	select {
+	case id := <-disconnected:
+		assert.Equal(t, userID, id)
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("onDisconnect not called for the stranded user")
+	}
+}
+
+func TestHubBus_FullBroadcastQueueDropsInsteadOfBlocking(t *testing.T) {
+	hub := NewHub() // not running, so nothing drains h.broadcast
+	event, err := NewEvent(EventNotification, nil)
+	require.NoError(t, err)
+	env := &Envelope{NodeID: "other", Kind: EnvelopeChannel, ChannelID: uuid.New().String(), Event: event}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < cap(hub.broadcast)+3; i++ {
+			hub.handleEnvelope(env)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handleEnvelope blocked on a full broadcast queue")
+	}
+	assert.EqualValues(t, 3, hub.Stats().BusDroppedEvents)
+}
+
+func TestHubBus_SendToUsersReachesBothNodes(t *testing.T) {
+	hubA, hubB := newClusterHubs(t)
+
+	local := newTestClient(hubA, uuid.New(), "local")
+	remote := newTestClient(hubB, uuid.New(), "remote")
+	hubA.Register(local)
+	hubB.Register(remote)
+	time.Sleep(20 * time.Millisecond)
+
+	event, err := NewEvent(EventPresenceUpdBcast, map[string]string{"status": "idle"})
+	require.NoError(t, err)
+	hubA.SendToUsers([]uuid.UUID{local.UserID, remote.UserID}, event)
+
+	expectEvent(t, local, EventPresenceUpdBcast)
+	expectEvent(t, remote, EventPresenceUpdBcast)
+}
@@ -3,6 +3,8 @@ package ws
 import (
 	"encoding/json"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fasthttp/websocket"
@@ -19,7 +21,15 @@ const (
 	sendBufferSize = 256
 )
 
-const CloseSessionExpired = 4001
+const (
+	CloseSessionExpired = 4001
+	// CloseSlowConsumer tells a client it fell too far behind; it should
+	// RESUME, or IDENTIFY again if the resume fails.
+	CloseSlowConsumer = 4002
+	// CloseRateLimited is sent to connections that keep exceeding the
+	// client event rate limits.
+	CloseRateLimited = 4003
+)
 
 // GetMemberIDsFn fetches member user IDs for a given server ID.
 type GetMemberIDsFn func(serverID string) ([]uuid.UUID, error)
@@ -33,9 +43,18 @@ type Client struct {
 	UserID               uuid.UUID
 	Username             string
 	send                 chan []byte
+	encoding             string // EncodingJSON or EncodingMsgpack
 	jwksManager          *auth.JWKSManager
+	isBot                bool // authenticated with a bot token, which does not expire
 	GetMemberIDsFn       GetMemberIDsFn
 	GetDMParticipantsFn  GetDMParticipantsFn
+	session              *Session
+	resume               *resumeRequest
+	closed               bool // send channel closed; guarded by Hub.mu
+	dropped              atomic.Int64
+	slowOnce             sync.Once
+	limiter              *rateLimiter // used only by the read loop
+	rateOnce             sync.Once
 }
 
 func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, username string) *Client {
@@ -45,6 +64,22 @@ func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, username string
 		UserID:   userID,
 		Username: username,
 		send:     make(chan []byte, sendBufferSize),
+		encoding: EncodingJSON,
+	}
+}
+
+// queue hands a frame, encoded for this connection, to the write pump
+// without blocking. Callers must hold Hub.mu and have checked c.closed.
+func (c *Client) queue(f *frame) {
+	data, err := f.encode(c.encoding)
+	if err != nil {
+		log.Printf("Failed to encode %s for %s: %v", f.event.Type, c.Username, err)
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		c.Hub.recordDrop(c)
 	}
 }
 
@@ -67,12 +102,12 @@ func (c *Client) ReadPump() {
 			break
 		}
 
-		var event Event
-		if err := json.Unmarshal(message, &event); err != nil {
+		event, err := decodeEvent(c.encoding, message)
+		if err != nil {
 			continue
 		}
 
-		c.handleEvent(&event)
+		c.handleEvent(event)
 	}
 }
 
@@ -92,9 +127,10 @@ func (c *Client) WritePump() {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if err := c.conn.WriteMessage(messageType(c.encoding), message); err != nil {
 				return
 			}
+			c.drained()
 
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
@@ -106,15 +142,16 @@ func (c *Client) WritePump() {
 }
 
 func (c *Client) handleTokenRefresh(token string) {
+	if c.isBot {
+		return
+	}
 	claims, err := c.jwksManager.ValidateToken(token)
 	if err != nil || claims.Ext.UserID != c.UserID {
 		expiredEvent, _ := NewEvent(EventSessionExpired, map[string]string{
 			"reason": "invalid_token",
 		})
 		if expiredEvent != nil {
-			if data, err := json.Marshal(expiredEvent); err == nil {
-				c.send <- data
-			}
+			c.Hub.sendToClient(c, expiredEvent)
 		}
 		c.conn.WriteMessage(websocket.CloseMessage,
 			websocket.FormatCloseMessage(CloseSessionExpired, "session expired"))
@@ -126,19 +163,27 @@ func (c *Client) handleTokenRefresh(token string) {
 }
 
 func (c *Client) handleEvent(event *Event) {
+	if !c.checkRate(event.Type) {
+		return
+	}
+
 	switch event.Type {
 	case EventHeartbeat:
 		ack, err := NewEvent(EventHeartbeatAck, nil)
 		if err != nil {
 			return
 		}
-		c.Hub.SendToUser(c.UserID, ack)
+		c.Hub.sendUnsequenced(c, ack)
 
 	case EventSubscribe:
 		var data SubscribeData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			return
 		}
+		if !c.Hub.canAccessChannel(c.UserID, data.ChannelID) {
+			log.Printf("User %s denied subscription to channel %s", c.Username, data.ChannelID)
+			return
+		}
 		c.Hub.Subscribe(c.UserID, data.ChannelID)
 		log.Printf("User %s subscribed to channel %s", c.Username, data.ChannelID)
 
@@ -164,6 +209,9 @@ func (c *Client) handleEvent(event *Event) {
 		c.Hub.BroadcastToChannel(data.ChannelID, bcastEvent, &c.UserID)
 
 	case EventVoiceJoin:
+		if c.Hub.externalVoice {
+			return
+		}
 		var data VoiceJoinData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			return
@@ -194,6 +242,9 @@ func (c *Client) handleEvent(event *Event) {
 		}
 
 	case EventVoiceLeave:
+		if c.Hub.externalVoice {
+			return
+		}
 		var data VoiceLeaveData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			return
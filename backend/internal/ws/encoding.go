@@ -0,0 +1,110 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/fasthttp/websocket"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Wire encodings a client may request with the ?encoding= query parameter.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// ValidEncoding reports whether the hub can speak the given encoding.
+func ValidEncoding(encoding string) bool {
+	return encoding == EncodingJSON || encoding == EncodingMsgpack
+}
+
+// messageType returns the WebSocket frame type used for an encoding.
+func messageType(encoding string) int {
+	if encoding == EncodingMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// frame is an outgoing event stamped with its sequence number. It is encoded
+// at most once per wire encoding, however many connections receive it.
+type frame struct {
+	seq   int64
+	event *Event
+
+	jsonOnce    sync.Once
+	jsonData    []byte
+	jsonErr     error
+	msgpackOnce sync.Once
+	msgpackData []byte
+	msgpackErr  error
+}
+
+// newFrame wraps an event for delivery. The shared event is copied so
+// concurrent senders never race on Seq.
+func newFrame(event *Event, seq int64) *frame {
+	stamped := *event
+	stamped.Seq = seq
+	return &frame{seq: seq, event: &stamped}
+}
+
+// encode returns the frame serialized for the given encoding.
+func (f *frame) encode(encoding string) ([]byte, error) {
+	if encoding == EncodingMsgpack {
+		f.msgpackOnce.Do(func() {
+			f.msgpackData, f.msgpackErr = marshalMsgpack(f.event)
+		})
+		return f.msgpackData, f.msgpackErr
+	}
+	f.jsonOnce.Do(func() {
+		f.jsonData, f.jsonErr = json.Marshal(f.event)
+	})
+	return f.jsonData, f.jsonErr
+}
+
+// marshalMsgpack transcodes an event to MessagePack. Event payloads are
+// built as JSON, so the data is decoded generically (keeping numbers exact)
+// and re-encoded.
+func marshalMsgpack(event *Event) ([]byte, error) {
+	var data any
+	if len(event.Data) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(event.Data))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := 2
+	if event.Seq != 0 {
+		fields++
+	}
+	b := msgp.AppendMapHeader(nil, uint32(fields))
+	b = msgp.AppendString(b, "type")
+	b = msgp.AppendString(b, event.Type)
+	if event.Seq != 0 {
+		b = msgp.AppendString(b, "seq")
+		b = msgp.AppendInt64(b, event.Seq)
+	}
+	b = msgp.AppendString(b, "data")
+	return msgp.AppendIntf(b, data)
+}
+
+// decodeEvent parses a client frame in the connection's encoding.
+func decodeEvent(encoding string, msg []byte) (*Event, error) {
+	if encoding == EncodingMsgpack {
+		var buf bytes.Buffer
+		if _, err := msgp.UnmarshalAsJSON(&buf, msg); err != nil {
+			return nil, err
+		}
+		msg = buf.Bytes()
+	}
+
+	var event Event
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,122 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestFrame_MsgpackEncoding(t *testing.T) {
+	event, err := NewEvent(EventMessageCreate, map[string]any{
+		"content": "hello",
+		"count":   42,
+		"ratio":   1.5,
+		"tags":    []string{"a", "b"},
+	})
+	require.NoError(t, err)
+
+	data, err := newFrame(event, 7).encode(EncodingMsgpack)
+	require.NoError(t, err)
+
+	decoded, _, err := msgp.ReadIntfBytes(data)
+	require.NoError(t, err)
+	m := decoded.(map[string]any)
+	assert.Equal(t, EventMessageCreate, m["type"])
+	assert.EqualValues(t, 7, m["seq"])
+
+	payload := m["data"].(map[string]any)
+	assert.Equal(t, "hello", payload["content"])
+	assert.EqualValues(t, 42, payload["count"])
+	assert.EqualValues(t, 1.5, payload["ratio"])
+	assert.Equal(t, []any{"a", "b"}, payload["tags"])
+}
+
+func TestFrame_EncodedOncePerEncoding(t *testing.T) {
+	event, _ := NewEvent(EventMessageCreate, map[string]string{"content": "hi"})
+	f := newFrame(event, 1)
+
+	first, err := f.encode(EncodingJSON)
+	require.NoError(t, err)
+	second, _ := f.encode(EncodingJSON)
+	assert.Same(t, &first[0], &second[0])
+
+	packed, err := f.encode(EncodingMsgpack)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, packed)
+}
+
+func TestDecodeEvent_Msgpack(t *testing.T) {
+	b := msgp.AppendMapHeader(nil, 2)
+	b = msgp.AppendString(b, "type")
+	b = msgp.AppendString(b, EventSubscribe)
+	b = msgp.AppendString(b, "data")
+	b, err := msgp.AppendIntf(b, map[string]any{"channel_id": "abc"})
+	require.NoError(t, err)
+
+	event, err := decodeEvent(EncodingMsgpack, b)
+	require.NoError(t, err)
+	assert.Equal(t, EventSubscribe, event.Type)
+	assert.JSONEq(t, `{"channel_id":"abc"}`, string(event.Data))
+}
+
+func TestHub_MixedEncodingsShareBroadcast(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	jsonClient := newTestClient(hub, uuid.New(), "json")
+	packClient := newTestClient(hub, uuid.New(), "msgpack")
+	packClient.encoding = EncodingMsgpack
+	hub.Register(jsonClient)
+	hub.Register(packClient)
+	time.Sleep(10 * time.Millisecond)
+
+	channelID := uuid.New().String()
+	hub.Subscribe(jsonClient.UserID, channelID)
+	hub.Subscribe(packClient.UserID, channelID)
+
+	event, _ := NewEvent(EventMessageCreate, map[string]string{"content": "hi"})
+	hub.BroadcastToChannel(channelID, event, nil)
+
+	assert.Equal(t, EventMessageCreate, readFrame(t, jsonClient).Type)
+
+	select {
+	case data := <-packClient.send:
+		decoded, err := decodeEvent(EncodingMsgpack, data)
+		require.NoError(t, err)
+		assert.Equal(t, EventMessageCreate, decoded.Type)
+		assert.JSONEq(t, `{"content":"hi"}`, string(decoded.Data))
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("msgpack client did not receive the broadcast")
+	}
+}
+
+func TestHub_ServerMembersShareOneFrame(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	a := newTestClient(hub, uuid.New(), "a")
+	b := newTestClient(hub, uuid.New(), "b")
+	sender := newTestClient(hub, uuid.New(), "sender")
+	for _, c := range []*Client{a, b, sender} {
+		hub.Register(c)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	event, _ := NewEvent(EventPresenceUpdBcast, map[string]string{"status": "online"})
+	BroadcastToServerMembers(hub, []uuid.UUID{a.UserID, b.UserID, sender.UserID}, event, &sender.UserID)
+
+	dataA, dataB := <-a.send, <-b.send
+	// Same backing array: serialized once, not once per member
+	assert.Same(t, &dataA[0], &dataB[0])
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(dataA, &decoded))
+	assert.Equal(t, EventPresenceUpdBcast, decoded.Type)
+	assert.NotZero(t, decoded.Seq)
+	assert.Empty(t, sender.send, "excluded member receives nothing")
+}
@@ -5,6 +5,7 @@ import "encoding/json"
 // Client → Server event types
 const (
 	EventIdentify       = "IDENTIFY"
+	EventResume         = "RESUME"
 	EventHeartbeat      = "HEARTBEAT"
 	EventSubscribe      = "SUBSCRIBE"
 	EventUnsubscribe    = "UNSUBSCRIBE"
@@ -21,10 +22,14 @@ const (
 // Server → Client event types
 const (
 	EventReady              = "READY"
+	EventResumed            = "RESUMED"
+	EventInvalidSession     = "INVALID_SESSION"
 	EventHeartbeatAck       = "HEARTBEAT_ACK"
 	EventMessageCreate      = "MESSAGE_CREATE"
 	EventMessageUpdate      = "MESSAGE_UPDATE"
 	EventMessageDelete      = "MESSAGE_DELETE"
+	EventMessageDeleteBulk  = "MESSAGE_DELETE_BULK"
+	EventMessageRestore     = "MESSAGE_RESTORE"
 	EventTypingStartBcast   = "TYPING_START"
 	EventPresenceUpdBcast   = "PRESENCE_UPDATE"
 	EventChannelCreate      = "CHANNEL_CREATE"
@@ -75,26 +80,58 @@ const (
 	EventDMMessagePin         = "DM_MESSAGE_PIN"
 	EventDMMessageUnpin       = "DM_MESSAGE_UNPIN"
 	EventNotification         = "NOTIFICATION"
+	EventError                = "ERROR"
 	EventStageStart          = "STAGE_START"
 	EventStageEnd            = "STAGE_END"
 	EventStageSpeakerAdd     = "STAGE_SPEAKER_ADD"
 	EventStageSpeakerRemove  = "STAGE_SPEAKER_REMOVE"
 	EventStageHandRaise      = "STAGE_HAND_RAISE"
 	EventStageHandLower      = "STAGE_HAND_LOWER"
+	EventForumPostCreate            = "FORUM_POST_CREATE"
 	EventForumPostDelete            = "FORUM_POST_DELETE"
+	EventForumPostPin               = "FORUM_POST_PIN"
 	EventForumPostMessageCreate     = "FORUM_POST_MESSAGE_CREATE"
 	EventServerInvitationReceived   = "SERVER_INVITATION_RECEIVED"
 	EventServerInvitationAccepted   = "SERVER_INVITATION_ACCEPTED"
 	EventServerInvitationDeclined   = "SERVER_INVITATION_DECLINED"
+	EventInteractionCreate          = "INTERACTION_CREATE"
 )
 
 type Event struct {
 	Type string          `json:"type"`
+	Seq  int64           `json:"seq,omitempty"` // set on server → client frames
 	Data json.RawMessage `json:"data,omitempty"`
 }
 
 type IdentifyData struct {
-	Token string `json:"token"`
+	Token        string   `json:"token"`
+	Capabilities []string `json:"capabilities,omitempty"` // see Cap* constants
+	Intents      *int64   `json:"intents,omitempty"`      // see Intent* constants; nil means all
+}
+
+// ResumeData is sent instead of IDENTIFY to continue a dropped session.
+// Seq is the last sequence number the client received.
+type ResumeData struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Seq       int64  `json:"seq"`
+}
+
+type InvalidSessionData struct {
+	Reason string `json:"reason"`
+}
+
+// Error codes sent in ERROR events.
+const (
+	ErrorCodeRateLimited = "rate_limited"
+)
+
+// ErrorData reports a client event the server refused.
+type ErrorData struct {
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	Event        string `json:"event,omitempty"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
 }
 
 type TokenRefreshData struct {
@@ -118,11 +155,16 @@ type PresenceData struct {
 }
 
 type ReadyData struct {
+	SessionID      string              `json:"session_id"`
 	UserID         string              `json:"user_id"`
 	Username       string              `json:"username"`
+	Bot            bool                `json:"bot,omitempty"`
 	OnlineUserIDs  []string            `json:"online_user_ids"`
 	UnreadCounts   []UnreadCountData   `json:"unread_counts"`
 	DMUnreadCounts []DMUnreadCountData `json:"dm_unread_counts"`
+
+	// Initial state, present only when IDENTIFY listed capabilities
+	*ReadyState
 }
 
 type UnreadCountData struct {
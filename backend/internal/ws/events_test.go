@@ -52,3 +52,34 @@ func TestEvent_NilData(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, string(data), `"type":"HEARTBEAT_ACK"`)
 }
+
+func TestReadyData_StateOmittedWithoutCapabilities(t *testing.T) {
+	data, err := json.Marshal(ReadyData{UserID: "u1"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"servers"`)
+	assert.NotContains(t, string(data), `"voice_states"`)
+}
+
+func TestReadyData_StateInlined(t *testing.T) {
+	ready := ReadyData{
+		UserID:     "u1",
+		ReadyState: &ReadyState{VoiceStates: []VoiceState{{ChannelID: "c1", ServerID: "s1"}}},
+	}
+	data, err := json.Marshal(ready)
+	require.NoError(t, err)
+
+	var decoded map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Contains(t, decoded, "voice_states")
+	assert.Equal(t, "null", string(decoded["servers"]), "parts not requested are null")
+}
+
+func TestIdentifyData_Capabilities(t *testing.T) {
+	raw := `{"token":"abc","capabilities":["servers","voice_states"]}`
+
+	var data IdentifyData
+	require.NoError(t, json.Unmarshal([]byte(raw), &data))
+	assert.True(t, HasCapability(data.Capabilities, CapServers))
+	assert.True(t, HasCapability(data.Capabilities, CapVoiceStates))
+	assert.False(t, HasCapability(data.Capabilities, CapFolders))
+}
@@ -3,6 +3,7 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 
@@ -18,6 +19,8 @@ import (
 var AllowedOrigins []string
 
 var upgrader = websocket.FastHTTPUpgrader{
+	// Negotiate permessage-deflate when the client offers it
+	EnableCompression: true,
 	CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
 		origin := string(ctx.Request.Header.Peek("Origin"))
 		if origin == "" {
@@ -50,44 +53,88 @@ type DMParticipantIDsFn func(ctx context.Context, conversationID uuid.UUID) ([]u
 // HandlerOpts are optional dependencies for the WS handler.
 var HandlerDMParticipantsFn DMParticipantIDsFn
 
+// HandlerBotValidatorFn validates "Bot <token>" credentials sent in IDENTIFY
+// or RESUME. Without it, bot tokens are rejected.
+var HandlerBotValidatorFn auth.BotValidator
+
+// authenticate resolves the IDENTIFY/RESUME token. Bot tokens carry a "Bot "
+// prefix, as in the REST Authorization header; anything else is a JWT.
+func authenticate(jwksManager *auth.JWKSManager, token string) (userID uuid.UUID, username string, isBot bool, err error) {
+	if botToken, ok := strings.CutPrefix(token, "Bot "); ok {
+		if HandlerBotValidatorFn == nil {
+			return uuid.Nil, "", false, errors.New("bot tokens are not accepted")
+		}
+		userID, username, err = HandlerBotValidatorFn(context.Background(), botToken)
+		return userID, username, true, err
+	}
+
+	claims, err := jwksManager.ValidateToken(token)
+	if err != nil {
+		return uuid.Nil, "", false, err
+	}
+	return claims.Ext.UserID, claims.Ext.Username, false, nil
+}
+
 func Handler(hub *Hub, jwksManager *auth.JWKSManager, coMemberIDsFn CoMemberIDsFn, serverMemberIDsFn ...ServerMemberIDsFn) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		fctx, ok := c.(interface{ RequestCtx() *fasthttp.RequestCtx })
 		if !ok {
 			return fiber.ErrInternalServerError
 		}
+		encoding := c.Query("encoding", EncodingJSON)
+		if !ValidEncoding(encoding) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unsupported encoding"})
+		}
 		return upgrader.Upgrade(fctx.RequestCtx(), func(conn *websocket.Conn) {
 			defer conn.Close()
 
-			// Client must send IDENTIFY with JWT first
+			// Client must send IDENTIFY (or RESUME) with JWT first
 			_, msg, err := conn.ReadMessage()
 			if err != nil {
 				return
 			}
 
-			var event Event
-			if err := json.Unmarshal(msg, &event); err != nil {
-				return
-			}
-
-			if event.Type != EventIdentify {
+			event, err := decodeEvent(encoding, msg)
+			if err != nil {
 				return
 			}
 
-			var identify IdentifyData
-			if err := json.Unmarshal(event.Data, &identify); err != nil {
+			var token string
+			var capabilities []string
+			intents := IntentAll
+			var resume *ResumeData
+			switch event.Type {
+			case EventIdentify:
+				var identify IdentifyData
+				if err := json.Unmarshal(event.Data, &identify); err != nil {
+					return
+				}
+				token = identify.Token
+				capabilities = identify.Capabilities
+				if identify.Intents != nil {
+					intents = *identify.Intents & IntentAll
+				}
+			case EventResume:
+				resume = &ResumeData{}
+				if err := json.Unmarshal(event.Data, resume); err != nil {
+					return
+				}
+				token = resume.Token
+			default:
 				return
 			}
 
-			claims, err := jwksManager.ValidateToken(identify.Token)
+			userID, username, isBot, err := authenticate(jwksManager, token)
 			if err != nil {
 				return
 			}
 
-			log.Printf("WebSocket authenticated: %s (%s)", claims.Ext.Username, claims.Ext.UserID)
+			log.Printf("WebSocket authenticated: %s (%s)", username, userID)
 
-			client := NewClient(hub, conn, claims.Ext.UserID, claims.Ext.Username)
+			client := NewClient(hub, conn, userID, username)
 			client.jwksManager = jwksManager
+			client.isBot = isBot
+			client.encoding = encoding
 			if len(serverMemberIDsFn) > 0 && serverMemberIDsFn[0] != nil {
 				fn := serverMemberIDsFn[0]
 				client.GetMemberIDsFn = func(serverID string) ([]uuid.UUID, error) {
@@ -108,6 +155,32 @@ func Handler(hub *Hub, jwksManager *auth.JWKSManager, coMemberIDsFn CoMemberIDsF
 					return dmFn(context.Background(), cid)
 				}
 			}
+
+			if resume != nil {
+				if err := hub.Resume(client, resume.SessionID, resume.Seq); err != nil {
+					// Client must start over with IDENTIFY
+					invalidEvent, _ := NewEvent(EventInvalidSession, InvalidSessionData{Reason: err.Error()})
+					if invalidEvent != nil {
+						if data, err := newFrame(invalidEvent, 0).encode(encoding); err == nil {
+							conn.WriteMessage(messageType(encoding), data)
+						}
+					}
+					return
+				}
+				log.Printf("WebSocket session resumed: %s (%s) from seq %d", client.Username, client.UserID, resume.Seq)
+
+				resumedEvent, _ := NewEvent(EventResumed, nil)
+				if resumedEvent != nil {
+					hub.sendToClient(client, resumedEvent)
+				}
+
+				go client.WritePump()
+				client.ReadPump()
+				return
+			}
+
+			client.session = newSession(client.UserID)
+			client.session.Intents = intents
 			hub.Register(client)
 
 			// Build READY payload with online users
@@ -117,17 +190,27 @@ func Handler(hub *Hub, jwksManager *auth.JWKSManager, coMemberIDsFn CoMemberIDsF
 				onlineIDs[i] = id.String()
 			}
 
-			readyEvent, _ := NewEvent(EventReady, ReadyData{
-				UserID:        claims.Ext.UserID.String(),
-				Username:      claims.Ext.Username,
+			ready := ReadyData{
+				SessionID:     client.session.ID,
+				UserID:        userID.String(),
+				Username:      username,
+				Bot:           isBot,
 				OnlineUserIDs: onlineIDs,
-			})
-			if readyEvent != nil {
-				if data, err := json.Marshal(readyEvent); err == nil {
-					client.send <- data
+			}
+			if len(capabilities) > 0 && HandlerReadyStateFn != nil {
+				state, err := HandlerReadyStateFn(context.Background(), client.UserID, capabilities)
+				if err != nil {
+					log.Printf("Failed to build READY state for %s: %v", client.UserID, err)
+				} else {
+					ready.ReadyState = state
 				}
 			}
 
+			readyEvent, _ := NewEvent(EventReady, ready)
+			if readyEvent != nil {
+				hub.sendToClient(client, readyEvent)
+			}
+
 			// Presence broadcast moved to onConnect callback in main.go
 			// so it can read the user's actual preferred status from the DB.
 
@@ -1,9 +1,10 @@
 package ws
 
 import (
-	"encoding/json"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -21,6 +22,39 @@ type Hub struct {
 	mu           sync.RWMutex
 	onConnect    func(userID uuid.UUID, username string)
 	onDisconnect func(userID uuid.UUID, username string)
+
+	// Authorizes SUBSCRIBE; nil allows everything
+	channelAccess ChannelAccessFn
+
+	// Sees each server event once, where it originated
+	observer EventObserver
+
+	// Voice state is driven by LiveKit webhooks instead of client events
+	externalVoice bool
+
+	// Slow-consumer detection
+	slowConsumerLimit int64
+	droppedFrames     atomic.Int64
+	slowDisconnects   atomic.Int64
+
+	// Client event rate limiting
+	eventLimits       map[string]EventLimit
+	maxRateViolations int
+	rateLimited       atomic.Int64
+	rateDisconnects   atomic.Int64
+
+	// Resumable sessions
+	seq      int64                       // last sequence number stamped on a frame; guarded by mu
+	sessions map[string]*Session         // sessionID -> session
+	detached map[uuid.UUID][]*Session    // userID -> sessions awaiting RESUME
+
+	// Cross-node fan-out (nil when running a single replica)
+	nodeID   string
+	bus      Bus
+	outbound chan *Envelope
+	remote   map[string]*remoteNode // nodeID -> users connected there
+	// Channel events from the bus dropped because h.broadcast was full
+	busDropped atomic.Int64
 }
 
 type VoiceState struct {
@@ -46,6 +80,15 @@ func NewHub() *Hub {
 		register:    make(chan *Client, 256),
 		unregister:  make(chan *Client, 256),
 		broadcast:   make(chan *ChannelMessage, 256),
+		sessions:    make(map[string]*Session),
+		detached:    make(map[uuid.UUID][]*Session),
+		nodeID:      uuid.New().String(),
+		outbound:    make(chan *Envelope, busOutboundSize),
+		remote:      make(map[string]*remoteNode),
+
+		slowConsumerLimit: DefaultSlowConsumerLimit,
+		eventLimits:       DefaultEventLimits,
+		maxRateViolations: DefaultMaxRateViolations,
 	}
 }
 
@@ -58,92 +101,133 @@ func (h *Hub) SetOnDisconnect(fn func(userID uuid.UUID, username string)) {
 }
 
 func (h *Hub) Run() {
+	if h.bus != nil {
+		go h.runBus()
+	}
+
+	sweep := time.NewTicker(sessionSweepInterval)
+	defer sweep.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if err := h.attachSessionLocked(client); err != nil {
+				h.mu.Unlock()
+				client.resume.result <- err
+				continue
+			}
 			conns := h.clients[client.UserID]
 
 			// Enforce per-user connection limit: close oldest if at max
 			if len(conns) >= MaxConnectionsPerUser {
 				oldest := conns[0]
+				h.detachSessionLocked(oldest)
+				oldest.closed = true
 				close(oldest.send)
 				conns = conns[1:]
 				log.Printf("Evicted oldest connection for user %s (limit %d)", client.UserID, MaxConnectionsPerUser)
 			}
 
 			wasOffline := len(conns) == 0
+			elsewhere := h.onlineElsewhereLocked(client.UserID)
 			h.clients[client.UserID] = append(conns, client)
 			h.mu.Unlock()
+			if client.resume != nil {
+				client.resume.result <- nil
+			}
 			log.Printf("Client registered: %s (%s)", client.Username, client.UserID)
-			if wasOffline && h.onConnect != nil {
+			if wasOffline {
+				h.publish(&Envelope{Kind: EnvelopePresence, UserID: client.UserID, Online: true})
+			}
+			if wasOffline && !elsewhere && h.onConnect != nil {
 				go h.onConnect(client.UserID, client.Username)
 			}
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			conns := h.clients[client.UserID]
-			// Remove this specific client from the slice
-			for i, c := range conns {
-				if c == client {
-					close(c.send)
-					conns = append(conns[:i], conns[i+1:]...)
-					break
-				}
+			// Already evicted or taken over by a resumed connection
+			if !h.removeClientLocked(client) {
+				h.mu.Unlock()
+				continue
 			}
+			h.detachSessionLocked(client)
 
-			nowOffline := len(conns) == 0
+			nowOffline := len(h.clients[client.UserID]) == 0
 			if nowOffline {
-				delete(h.clients, client.UserID)
-
-				// Remove from all channels
-				for chID, members := range h.channels {
-					delete(members, client.UserID)
-					if len(members) == 0 {
-						delete(h.channels, chID)
-					}
+				// Channel subscriptions stay while a session is resumable
+				if len(h.detached[client.UserID]) == 0 {
+					h.removeSubscriptionsLocked(client.UserID)
 				}
 
-				// Remove from voice channels
-				for chID, users := range h.voiceStates {
-					if _, inVoice := users[client.UserID]; inVoice {
-						delete(users, client.UserID)
-						if len(users) == 0 {
-							delete(h.voiceStates, chID)
+				// Remove from voice channels, unless LiveKit reports leaves
+				if !h.externalVoice {
+					for chID, users := range h.voiceStates {
+						if _, inVoice := users[client.UserID]; inVoice {
+							delete(users, client.UserID)
+							if len(users) == 0 {
+								delete(h.voiceStates, chID)
+							}
 						}
 					}
 				}
-			} else {
-				h.clients[client.UserID] = conns
 			}
+			elsewhere := h.onlineElsewhereLocked(client.UserID)
+			resumable := len(h.detached[client.UserID]) > 0
 			h.mu.Unlock()
 			log.Printf("Client unregistered: %s (%s)", client.Username, client.UserID)
-			if nowOffline && h.onDisconnect != nil {
+			if nowOffline {
+				h.publish(&Envelope{Kind: EnvelopePresence, UserID: client.UserID, Online: false, Resumable: resumable})
+			}
+			if nowOffline && !elsewhere && h.onDisconnect != nil {
 				go h.onDisconnect(client.UserID, client.Username)
 			}
 
 		case msg := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			if subscribers, ok := h.channels[msg.ChannelID]; ok {
-				data, err := json.Marshal(msg.Event)
-				if err != nil {
-					h.mu.RUnlock()
-					continue
-				}
+				f := newFrame(msg.Event, h.nextSeqLocked())
 				for userID := range subscribers {
 					if msg.ExcludeID != nil && userID == *msg.ExcludeID {
 						continue
 					}
-					for _, client := range h.clients[userID] {
-						select {
-						case client.send <- data:
-						default:
-							// Client buffer full, skip
-						}
-					}
+					h.deliverLocked(userID, f)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+
+		case <-sweep.C:
+			h.expireSessions()
+		}
+	}
+}
+
+// removeClientLocked drops a connection from the user's list and closes its
+// send channel. Returns false if it was not registered. Caller must hold h.mu.
+func (h *Hub) removeClientLocked(client *Client) bool {
+	conns := h.clients[client.UserID]
+	for i, c := range conns {
+		if c == client {
+			c.closed = true
+			close(c.send)
+			conns = append(conns[:i], conns[i+1:]...)
+			if len(conns) == 0 {
+				delete(h.clients, client.UserID)
+			} else {
+				h.clients[client.UserID] = conns
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// removeSubscriptionsLocked removes the user from every channel. Caller must hold h.mu.
+func (h *Hub) removeSubscriptionsLocked(userID uuid.UUID) {
+	for chID, members := range h.channels {
+		delete(members, userID)
+		if len(members) == 0 {
+			delete(h.channels, chID)
 		}
 	}
 }
@@ -171,32 +255,82 @@ func (h *Hub) Unsubscribe(userID uuid.UUID, channelID string) {
 }
 
 func (h *Hub) BroadcastToChannel(channelID string, event *Event, excludeID *uuid.UUID) {
+	h.observe(channelID, event)
 	h.broadcast <- &ChannelMessage{
 		ChannelID: channelID,
 		Event:     event,
 		ExcludeID: excludeID,
 	}
+	h.publish(&Envelope{
+		Kind:      EnvelopeChannel,
+		ChannelID: channelID,
+		Event:     event,
+		ExcludeID: excludeID,
+	})
 }
 
+// SendToUser delivers an event to every connection the user holds, on this
+// node and on any other replica reachable through the bus.
 func (h *Hub) SendToUser(userID uuid.UUID, event *Event) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	if h.bus != nil {
+		h.mu.RLock()
+		elsewhere := h.reachableElsewhereLocked(userID)
+		h.mu.RUnlock()
+		if elsewhere {
+			h.publish(&Envelope{Kind: EnvelopeUser, UserID: userID, Event: event})
+		}
+	}
+	h.sendLocal(userID, event)
+}
 
-	conns, ok := h.clients[userID]
-	if !ok {
+// sendLocal delivers an event to the user's connections on this node only.
+func (h *Hub) sendLocal(userID uuid.UUID, event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Skip encoding when no connection opted into this event
+	if !h.wantsLocked(userID, event.Type) {
 		return
 	}
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		return
+	h.deliverLocked(userID, newFrame(event, h.nextSeqLocked()))
+}
+
+// SendToUsers delivers one event to many users, on this node and on any
+// other replica. The event is framed once, with a single sequence number,
+// so each encoding is serialized once however many users receive it.
+func (h *Hub) SendToUsers(userIDs []uuid.UUID, event *Event) {
+	if h.bus != nil {
+		h.mu.RLock()
+		var elsewhere []uuid.UUID
+		for _, id := range userIDs {
+			if h.reachableElsewhereLocked(id) {
+				elsewhere = append(elsewhere, id)
+			}
+		}
+		h.mu.RUnlock()
+		if len(elsewhere) > 0 {
+			h.publish(&Envelope{Kind: EnvelopeUsers, UserIDs: elsewhere, Event: event})
+		}
 	}
+	h.sendLocalMany(userIDs, event)
+}
 
-	for _, client := range conns {
-		select {
-		case client.send <- data:
-		default:
+// sendLocalMany delivers a shared frame to the users' connections on this
+// node only.
+func (h *Hub) sendLocalMany(userIDs []uuid.UUID, event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var f *frame
+	for _, id := range userIDs {
+		if !h.wantsLocked(id, event.Type) {
+			continue
+		}
+		if f == nil {
+			f = newFrame(event, h.nextSeqLocked())
 		}
+		h.deliverLocked(id, f)
 	}
 }
 
@@ -204,17 +338,29 @@ func (h *Hub) IsOnline(userID uuid.UUID) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	conns := h.clients[userID]
-	return len(conns) > 0
+	return len(conns) > 0 || h.onlineElsewhereLocked(userID)
 }
 
+// GetOnlineUsers returns every user connected to this node or, when a bus
+// is attached, to any other replica.
 func (h *Hub) GetOnlineUsers() []uuid.UUID {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	seen := make(map[uuid.UUID]bool, len(h.clients))
 	users := make([]uuid.UUID, 0, len(h.clients))
 	for id := range h.clients {
+		seen[id] = true
 		users = append(users, id)
 	}
+	for _, node := range h.remote {
+		for id := range node.users {
+			if !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		}
+	}
 	return users
 }
 
@@ -258,6 +404,43 @@ func (h *Hub) LeaveVoiceChannel(userID uuid.UUID, channelID string) {
 	}
 }
 
+// SetExternalVoiceState makes LiveKit webhooks the only source of voice
+// state: client VOICE_JOIN/VOICE_LEAVE events and disconnects no longer
+// change it. Call before Run.
+func (h *Hub) SetExternalVoiceState(enabled bool) {
+	h.externalVoice = enabled
+}
+
+// SetVoiceMuted updates a participant's mute flag and returns the new state.
+// It reports false if the user is not in the channel.
+func (h *Hub) SetVoiceMuted(userID uuid.UUID, channelID string, muted bool) (VoiceState, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.voiceStates[channelID][userID]
+	if !ok {
+		return VoiceState{}, false
+	}
+	state.Muted = muted
+	h.voiceStates[channelID][userID] = state
+	return state, true
+}
+
+// ClearVoiceChannel removes every participant from a voice channel and
+// returns the states that were removed.
+func (h *Hub) ClearVoiceChannel(channelID string) []VoiceState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := h.voiceStates[channelID]
+	states := make([]VoiceState, 0, len(users))
+	for _, s := range users {
+		states = append(states, s)
+	}
+	delete(h.voiceStates, channelID)
+	return states
+}
+
 func (h *Hub) IsSubscribed(userID uuid.UUID, channelID string) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -283,3 +466,19 @@ func (h *Hub) GetVoiceParticipants(channelID string) []VoiceState {
 	}
 	return states
 }
+
+// GetVoiceStatesForServers returns the voice states in any of the given servers.
+func (h *Hub) GetVoiceStatesForServers(serverIDs map[string]bool) []VoiceState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	states := []VoiceState{}
+	for _, users := range h.voiceStates {
+		for _, s := range users {
+			if serverIDs[s.ServerID] {
+				states = append(states, s)
+			}
+		}
+	}
+	return states
+}
@@ -174,6 +174,34 @@ func TestHub_SendToUser(t *testing.T) {
 	}
 }
 
+func TestHub_EventObserverSeesServerEventsOnce(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	type observed struct {
+		channelID string
+		eventType string
+	}
+	var seen []observed
+	hub.SetEventObserver(func(channelID string, event *Event) {
+		seen = append(seen, observed{channelID, event.Type})
+	})
+
+	channelID := uuid.New().String()
+	msg, err := NewEvent(EventMessageCreate, map[string]string{"content": "hi"})
+	require.NoError(t, err)
+	join, err := NewEvent(EventMemberJoin, map[string]string{"server_id": uuid.NewString()})
+	require.NoError(t, err)
+	ready, err := NewEvent(EventReady, map[string]string{})
+	require.NoError(t, err)
+
+	hub.BroadcastToChannel(channelID, msg, nil)
+	BroadcastToServerMembers(hub, []uuid.UUID{uuid.New(), uuid.New()}, join, nil)
+	hub.SendToUser(uuid.New(), ready)
+
+	assert.Equal(t, []observed{{channelID, EventMessageCreate}, {"", EventMemberJoin}}, seen)
+}
+
 func TestHub_GetOnlineUsers(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
@@ -188,3 +216,47 @@ func TestHub_GetOnlineUsers(t *testing.T) {
 	online := hub.GetOnlineUsers()
 	assert.Len(t, online, 2)
 }
+
+func TestHub_SetVoiceMuted(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	hub.JoinVoiceChannel(VoiceState{UserID: userID, ChannelID: "ch", ServerID: "srv", Username: "user"})
+
+	state, ok := hub.SetVoiceMuted(userID, "ch", true)
+	require.True(t, ok)
+	assert.True(t, state.Muted)
+	assert.True(t, hub.GetVoiceParticipants("ch")[0].Muted)
+
+	_, ok = hub.SetVoiceMuted(uuid.New(), "ch", true)
+	assert.False(t, ok)
+}
+
+func TestHub_ClearVoiceChannel(t *testing.T) {
+	hub := NewHub()
+	hub.JoinVoiceChannel(VoiceState{UserID: uuid.New(), ChannelID: "ch", ServerID: "srv"})
+	hub.JoinVoiceChannel(VoiceState{UserID: uuid.New(), ChannelID: "ch", ServerID: "srv"})
+
+	removed := hub.ClearVoiceChannel("ch")
+	assert.Len(t, removed, 2)
+	assert.Empty(t, hub.GetVoiceParticipants("ch"))
+}
+
+func TestHub_ExternalVoiceStateIgnoresClients(t *testing.T) {
+	hub := NewHub()
+	hub.SetExternalVoiceState(true)
+	go hub.Run()
+
+	client := newTestClient(hub, uuid.New(), "user")
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	data, _ := json.Marshal(VoiceJoinData{ChannelID: "ch", ServerID: "srv"})
+	client.handleEvent(&Event{Type: EventVoiceJoin, Data: data})
+	assert.Empty(t, hub.GetVoiceParticipants("ch"))
+
+	// State reported by LiveKit survives the WebSocket disconnecting
+	hub.JoinVoiceChannel(VoiceState{UserID: client.UserID, ChannelID: "ch", ServerID: "srv"})
+	hub.Unregister(client)
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, hub.GetVoiceParticipants("ch"), 1)
+}
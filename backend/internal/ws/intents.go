@@ -0,0 +1,88 @@
+package ws
+
+// Gateway intents select the event categories a connection receives. A
+// client sends the bitmask in IDENTIFY; omitting it subscribes to all.
+// Events outside these categories (READY, membership, channel and role
+// changes, notifications, ...) are always delivered.
+const (
+	IntentServerMessages int64 = 1 << 0
+	IntentDirectMessages int64 = 1 << 1
+	IntentPresence       int64 = 1 << 2
+	IntentTyping         int64 = 1 << 3
+	IntentVoice          int64 = 1 << 4
+	IntentReactions      int64 = 1 << 5
+	IntentModeration     int64 = 1 << 6
+	IntentStage          int64 = 1 << 7
+	IntentForum          int64 = 1 << 8
+
+	IntentAll = IntentServerMessages | IntentDirectMessages | IntentPresence |
+		IntentTyping | IntentVoice | IntentReactions | IntentModeration |
+		IntentStage | IntentForum
+)
+
+var eventIntents = map[string]int64{
+	EventMessageCreate:       IntentServerMessages,
+	EventMessageUpdate:       IntentServerMessages,
+	EventMessageDelete:       IntentServerMessages,
+	EventMessageDeleteBulk:   IntentServerMessages,
+	EventMessageRestore:      IntentServerMessages,
+	EventMessagePin:          IntentServerMessages,
+	EventMessageUnpin:        IntentServerMessages,
+	EventThreadCreate:        IntentServerMessages,
+	EventThreadUpdate:        IntentServerMessages,
+	EventThreadMessageCreate: IntentServerMessages,
+	EventThreadMessageDelete: IntentServerMessages,
+	EventPollCreate:          IntentServerMessages,
+	EventPollVote:            IntentServerMessages,
+
+	EventDMMessageCreate:      IntentDirectMessages,
+	EventDMMessageUpdate:      IntentDirectMessages,
+	EventDMMessageDelete:      IntentDirectMessages,
+	EventDMMessagePin:         IntentDirectMessages,
+	EventDMMessageUnpin:       IntentDirectMessages,
+	EventDMParticipantAdd:     IntentDirectMessages,
+	EventDMParticipantRemove:  IntentDirectMessages,
+	EventDMConversationUpdate: IntentDirectMessages,
+
+	EventPresenceUpdBcast: IntentPresence,
+
+	EventTypingStartBcast: IntentTyping,
+
+	EventVoiceStateUpdate:  IntentVoice,
+	EventDMCallRing:        IntentVoice,
+	EventDMCallAcceptBcast: IntentVoice,
+	EventDMCallEndBcast:    IntentVoice,
+
+	EventReactionAdd:      IntentReactions,
+	EventReactionRemove:   IntentReactions,
+	EventDMReactionAdd:    IntentReactions,
+	EventDMReactionRemove: IntentReactions,
+
+	EventMemberBan:     IntentModeration,
+	EventMemberTimeout: IntentModeration,
+
+	EventStageStart:         IntentStage,
+	EventStageEnd:           IntentStage,
+	EventStageSpeakerAdd:    IntentStage,
+	EventStageSpeakerRemove: IntentStage,
+	EventStageHandRaise:     IntentStage,
+	EventStageHandLower:     IntentStage,
+
+	EventForumPostCreate:        IntentForum,
+	EventForumPostDelete:        IntentForum,
+	EventForumPostPin:           IntentForum,
+	EventForumPostMessageCreate: IntentForum,
+}
+
+// EventIntent returns the intent gating an event type, or 0 if the event is
+// always delivered.
+func EventIntent(eventType string) int64 {
+	return eventIntents[eventType]
+}
+
+// wantsEvent reports whether a connection with the given intents receives
+// events of this type.
+func wantsEvent(intents int64, eventType string) bool {
+	intent := eventIntents[eventType]
+	return intent == 0 || intents&intent != 0
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIntentClient(hub *Hub, userID uuid.UUID, intents int64) *Client {
+	c := newTestClient(hub, userID, "user")
+	c.session = newSession(userID)
+	c.session.Intents = intents
+	return c
+}
+
+func TestIntents_SendToUserFiltersPerConnection(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	full := newIntentClient(hub, userID, IntentAll)
+	bot := newIntentClient(hub, userID, IntentServerMessages)
+	hub.Register(full)
+	hub.Register(bot)
+	time.Sleep(10 * time.Millisecond)
+
+	presence, _ := NewEvent(EventPresenceUpdBcast, PresenceData{UserID: uuid.New().String(), Status: "online"})
+	hub.SendToUser(userID, presence)
+
+	assert.Equal(t, EventPresenceUpdBcast, readFrame(t, full).Type)
+	select {
+	case <-bot.send:
+		t.Fatal("connection without the presence intent received PRESENCE_UPDATE")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestIntents_ChannelBroadcastFiltersTyping(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newIntentClient(hub, userID, IntentServerMessages)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	channelID := uuid.New().String()
+	hub.Subscribe(userID, channelID)
+
+	typing, _ := NewEvent(EventTypingStartBcast, TypingData{ChannelID: channelID})
+	hub.BroadcastToChannel(channelID, typing, nil)
+	message, _ := NewEvent(EventMessageCreate, map[string]string{"content": "hi"})
+	hub.BroadcastToChannel(channelID, message, nil)
+
+	// Only the message arrives
+	assert.Equal(t, EventMessageCreate, readFrame(t, client).Type)
+}
+
+func TestIntents_UngatedEventsAlwaysDelivered(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newIntentClient(hub, userID, 0)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	event, _ := NewEvent(EventChannelCreate, map[string]string{"id": "c1"})
+	hub.SendToUser(userID, event)
+
+	assert.Equal(t, EventChannelCreate, readFrame(t, client).Type)
+}
+
+func TestIntents_EventIntentMapping(t *testing.T) {
+	assert.Equal(t, IntentTyping, EventIntent(EventTypingStartBcast))
+	assert.Equal(t, IntentForum, EventIntent(EventForumPostCreate))
+	assert.Equal(t, IntentReactions, EventIntent(EventDMReactionAdd))
+	assert.Zero(t, EventIntent(EventReady))
+}
@@ -0,0 +1,150 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+const (
+	// PGBusChannel is the LISTEN/NOTIFY channel shared by all API nodes.
+	PGBusChannel = "thicket_ws"
+
+	// pg_notify rejects payloads of 8000 bytes or more; larger envelopes
+	// are spilled to ws_bus_payloads and sent by reference.
+	pgNotifyMaxPayload = 7900
+	pgPayloadTTL       = 5 * time.Minute
+	pgReconnectDelay   = 2 * time.Second
+)
+
+// PGBus is a Bus backed by Postgres LISTEN/NOTIFY.
+type PGBus struct {
+	pool    *pgxpool.Pool
+	queries *models.Queries
+}
+
+// pgNotice is the wire format of a notification payload.
+type pgNotice struct {
+	Ref int64     `json:"ref,omitempty"`
+	Env *Envelope `json:"env,omitempty"`
+}
+
+func NewPGBus(pool *pgxpool.Pool) *PGBus {
+	return &PGBus{
+		pool:    pool,
+		queries: models.New(pool),
+	}
+}
+
+func (b *PGBus) Publish(ctx context.Context, env *Envelope) error {
+	payload, err := json.Marshal(pgNotice{Env: env})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > pgNotifyMaxPayload {
+		envData, err := json.Marshal(env)
+		if err != nil {
+			return err
+		}
+		id, err := b.queries.InsertWSBusPayload(ctx, envData)
+		if err != nil {
+			return fmt.Errorf("store oversized payload: %w", err)
+		}
+		payload, err = json.Marshal(pgNotice{Ref: id})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = b.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, PGBusChannel, string(payload))
+	return err
+}
+
+// Listen holds a dedicated connection open for LISTEN, reconnecting after
+// errors until ctx is cancelled.
+func (b *PGBus) Listen(ctx context.Context, deliver func(*Envelope)) error {
+	go b.pruneLoop(ctx)
+
+	for {
+		err := b.listenOnce(ctx, deliver)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("WS bus: listen connection lost, retrying: %v", err)
+
+		select {
+		case <-time.After(pgReconnectDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *PGBus) listenOnce(ctx context.Context, deliver func(*Envelope)) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listen conn: %w", err)
+	}
+	// Take the connection out of the pool so a LISTEN session is never
+	// handed to another caller; it is closed when listening stops.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background()) //nolint:errcheck
+
+	if _, err := conn.Exec(ctx, "LISTEN "+PGBusChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var notice pgNotice
+		if err := json.Unmarshal([]byte(n.Payload), &notice); err != nil {
+			log.Printf("WS bus: malformed notification: %v", err)
+			continue
+		}
+
+		env := notice.Env
+		if notice.Ref != 0 {
+			data, err := b.queries.GetWSBusPayload(ctx, notice.Ref)
+			if err != nil {
+				log.Printf("WS bus: failed to load payload %d: %v", notice.Ref, err)
+				continue
+			}
+			env = &Envelope{}
+			if err := json.Unmarshal(data, env); err != nil {
+				log.Printf("WS bus: malformed payload %d: %v", notice.Ref, err)
+				continue
+			}
+		}
+		if env != nil {
+			deliver(env)
+		}
+	}
+}
+
+// pruneLoop removes spilled payloads once every node has had time to read them.
+func (b *PGBus) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(pgPayloadTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.queries.DeleteWSBusPayloadsBefore(ctx, time.Now().Add(-pgPayloadTTL)); err != nil {
+				log.Printf("WS bus: failed to prune payloads: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
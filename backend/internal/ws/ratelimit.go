@@ -0,0 +1,197 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// EventLimit is a token bucket: a connection may send Burst events of one
+// type at once, and the bucket refills completely over Per.
+type EventLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// DefaultEventLimits are the per-connection limits for client events. Event
+// types not listed here are not limited.
+var DefaultEventLimits = map[string]EventLimit{
+	EventHeartbeat:      {Burst: 5, Per: 10 * time.Second},
+	EventSubscribe:      {Burst: 100, Per: 10 * time.Second},
+	EventUnsubscribe:    {Burst: 100, Per: 10 * time.Second},
+	EventTypingStart:    {Burst: 5, Per: 10 * time.Second},
+	EventPresenceUpdate: {Burst: 5, Per: time.Minute},
+	EventTokenRefresh:   {Burst: 3, Per: time.Minute},
+	EventVoiceJoin:      {Burst: 5, Per: 30 * time.Second},
+	EventVoiceLeave:     {Burst: 5, Per: 30 * time.Second},
+	EventDMCallStart:    {Burst: 3, Per: time.Minute},
+	EventDMCallAccept:   {Burst: 5, Per: time.Minute},
+	EventDMCallEnd:      {Burst: 5, Per: time.Minute},
+}
+
+// DefaultMaxRateViolations is how many rate-limited events a connection may
+// send within rateViolationWindow before it is disconnected.
+const DefaultMaxRateViolations = 20
+
+const rateViolationWindow = time.Minute
+
+// ParseEventLimits parses overrides of the form
+// "TYPING_START=5/10s,DM_CALL_START=3/1m": a burst size and the duration
+// over which it refills.
+func ParseEventLimits(spec string) (map[string]EventLimit, error) {
+	limits := make(map[string]EventLimit)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		eventType, rule, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid event limit %q: want EVENT=burst/duration", part)
+		}
+		burstStr, perStr, ok := strings.Cut(rule, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid event limit %q: want EVENT=burst/duration", part)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid burst in event limit %q", part)
+		}
+		per, err := time.ParseDuration(perStr)
+		if err != nil || per <= 0 {
+			return nil, fmt.Errorf("invalid duration in event limit %q", part)
+		}
+		limits[strings.TrimSpace(eventType)] = EventLimit{Burst: burst, Per: per}
+	}
+	return limits, nil
+}
+
+// SetEventLimits overrides the default limits for the given event types.
+// Must be called before Run.
+func (h *Hub) SetEventLimits(overrides map[string]EventLimit) {
+	limits := make(map[string]EventLimit, len(h.eventLimits)+len(overrides))
+	for eventType, l := range h.eventLimits {
+		limits[eventType] = l
+	}
+	for eventType, l := range overrides {
+		limits[eventType] = l
+	}
+	h.eventLimits = limits
+}
+
+// SetMaxRateViolations sets how many rate-limited events a connection may
+// send per minute before it is closed with CloseRateLimited. Must be called
+// before Run.
+func (h *Hub) SetMaxRateViolations(n int) {
+	if n > 0 {
+		h.maxRateViolations = n
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter holds one connection's buckets. It is only used from the
+// connection's read loop.
+type rateLimiter struct {
+	limits          map[string]EventLimit
+	buckets         map[string]*bucket
+	violations      int
+	violationsSince time.Time
+}
+
+func newRateLimiter(limits map[string]EventLimit) *rateLimiter {
+	return &rateLimiter{limits: limits, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token for the event type. When the bucket is empty it
+// returns false and how long until the next token.
+func (r *rateLimiter) allow(eventType string, now time.Time) (bool, time.Duration) {
+	limit, ok := r.limits[eventType]
+	if !ok || limit.Burst <= 0 || limit.Per <= 0 {
+		return true, 0
+	}
+
+	rate := float64(limit.Burst) / limit.Per.Seconds() // tokens per second
+	b, ok := r.buckets[eventType]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		r.buckets[eventType] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+// violate records a limited event and reports how many fell in the current
+// window.
+func (r *rateLimiter) violate(now time.Time) int {
+	if now.Sub(r.violationsSince) > rateViolationWindow {
+		r.violations = 0
+		r.violationsSince = now
+	}
+	r.violations++
+	return r.violations
+}
+
+// checkRate applies the connection's limit for an event. A limited event is
+// answered with an ERROR event; repeat offenders are disconnected.
+func (c *Client) checkRate(eventType string) bool {
+	if c.limiter == nil {
+		c.limiter = newRateLimiter(c.Hub.eventLimits)
+	}
+
+	now := time.Now()
+	ok, retryAfter := c.limiter.allow(eventType, now)
+	if ok {
+		return true
+	}
+
+	c.Hub.rateLimited.Add(1)
+	if c.limiter.violate(now) > c.Hub.maxRateViolations {
+		c.rateOnce.Do(func() {
+			c.Hub.rateDisconnects.Add(1)
+			log.Printf("Disconnecting %s (%s) for exceeding WebSocket rate limits", c.Username, c.UserID)
+			go c.closeRateLimited()
+		})
+		return false
+	}
+
+	errEvent, err := NewEvent(EventError, ErrorData{
+		Code:         ErrorCodeRateLimited,
+		Message:      "rate limit exceeded",
+		Event:        eventType,
+		RetryAfterMs: retryAfter.Milliseconds() + 1,
+	})
+	if err == nil {
+		c.Hub.sendUnsequenced(c, errEvent)
+	}
+	return false
+}
+
+// closeRateLimited sends the rate-limit close frame and tears the socket
+// down; ReadPump then unregisters the client.
+func (c *Client) closeRateLimited() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(CloseRateLimited, "rate limited"),
+		time.Now().Add(writeWait))
+	c.conn.Close()
+}
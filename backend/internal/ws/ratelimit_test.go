@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_BucketRefills(t *testing.T) {
+	r := newRateLimiter(map[string]EventLimit{
+		EventTypingStart: {Burst: 2, Per: 2 * time.Second},
+	})
+	now := time.Now()
+
+	ok, _ := r.allow(EventTypingStart, now)
+	assert.True(t, ok)
+	ok, _ = r.allow(EventTypingStart, now)
+	assert.True(t, ok)
+	ok, wait := r.allow(EventTypingStart, now)
+	assert.False(t, ok)
+	assert.InDelta(t, time.Second, wait, float64(10*time.Millisecond))
+
+	// One token per second
+	ok, _ = r.allow(EventTypingStart, now.Add(time.Second))
+	assert.True(t, ok)
+
+	// Unlisted events are unlimited
+	for i := 0; i < 100; i++ {
+		ok, _ = r.allow(EventUnsubscribe, now)
+		require.True(t, ok)
+	}
+}
+
+func TestParseEventLimits(t *testing.T) {
+	limits, err := ParseEventLimits("TYPING_START=5/10s, DM_CALL_START=3/1m")
+	require.NoError(t, err)
+	assert.Equal(t, EventLimit{Burst: 5, Per: 10 * time.Second}, limits[EventTypingStart])
+	assert.Equal(t, EventLimit{Burst: 3, Per: time.Minute}, limits[EventDMCallStart])
+
+	limits, err = ParseEventLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, bad := range []string{"TYPING_START", "TYPING_START=5", "TYPING_START=x/1s", "TYPING_START=5/soon", "TYPING_START=0/1s"} {
+		_, err := ParseEventLimits(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestHub_SetEventLimitsKeepsDefaults(t *testing.T) {
+	hub := NewHub()
+	hub.SetEventLimits(map[string]EventLimit{EventTypingStart: {Burst: 1, Per: time.Minute}})
+
+	assert.Equal(t, EventLimit{Burst: 1, Per: time.Minute}, hub.eventLimits[EventTypingStart])
+	assert.Equal(t, DefaultEventLimits[EventDMCallStart], hub.eventLimits[EventDMCallStart])
+	assert.Equal(t, 5, DefaultEventLimits[EventTypingStart].Burst, "defaults are not mutated")
+}
+
+func TestRateLimit_LimitedEventGetsError(t *testing.T) {
+	hub := NewHub()
+	hub.SetEventLimits(map[string]EventLimit{EventSubscribe: {Burst: 1, Per: time.Minute}})
+	go hub.Run()
+
+	client := newTestClient(hub, uuid.New(), "user")
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	client.handleEvent(subscribeEvent(t, uuid.New()))
+	second := uuid.New()
+	client.handleEvent(subscribeEvent(t, second))
+
+	assert.False(t, hub.IsSubscribed(client.UserID, second.String()))
+	event := readFrame(t, client)
+	require.Equal(t, EventError, event.Type)
+	var data ErrorData
+	require.NoError(t, json.Unmarshal(event.Data, &data))
+	assert.Equal(t, ErrorCodeRateLimited, data.Code)
+	assert.Equal(t, EventSubscribe, data.Event)
+	assert.Positive(t, data.RetryAfterMs)
+	assert.EqualValues(t, 1, hub.Stats().RateLimitedEvents)
+}
+
+func TestRateLimit_RepeatOffenderDisconnected(t *testing.T) {
+	hub := NewHub()
+	hub.SetEventLimits(map[string]EventLimit{EventTypingStart: {Burst: 1, Per: time.Minute}})
+	hub.SetMaxRateViolations(3)
+	go hub.Run()
+
+	client := newTestClient(hub, uuid.New(), "spammer")
+	data, _ := json.Marshal(TypingData{ChannelID: uuid.New().String()})
+	for i := 0; i < 10; i++ {
+		client.handleEvent(&Event{Type: EventTypingStart, Data: data})
+	}
+
+	stats := hub.Stats()
+	assert.EqualValues(t, 9, stats.RateLimitedEvents)
+	assert.EqualValues(t, 1, stats.RateLimitDisconnects, "disconnect fires once")
+}
@@ -0,0 +1,54 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/M-McCallum/thicket/internal/models"
+)
+
+// Capabilities a client may list in IDENTIFY to receive parts of its
+// initial state inside READY instead of fetching them over REST.
+const (
+	CapServers           = "servers"            // servers with channels, categories, roles and own role IDs
+	CapFolders           = "folders"            // server folders
+	CapPreferences       = "preferences"        // user preferences
+	CapNotificationPrefs = "notification_prefs" // notification preferences
+	CapVoiceStates       = "voice_states"       // voice states in the user's servers
+)
+
+// ReadyStateFn builds the initial state snapshot for the requested capabilities.
+type ReadyStateFn func(ctx context.Context, userID uuid.UUID, caps []string) (*ReadyState, error)
+
+// HandlerReadyStateFn is set during setup; without it READY carries no state.
+var HandlerReadyStateFn ReadyStateFn
+
+// ReadyState is the optional snapshot embedded in READY. Parts the client
+// did not ask for are null.
+type ReadyState struct {
+	Servers           []ReadyServer             `json:"servers"`
+	Folders           []models.ServerFolder     `json:"folders"`
+	Preferences       *models.UserPreferences   `json:"preferences"`
+	NotificationPrefs []models.NotificationPref `json:"notification_prefs"`
+	VoiceStates       []VoiceState              `json:"voice_states"`
+}
+
+// ReadyServer is a server together with everything needed to render it.
+type ReadyServer struct {
+	models.Server
+	Channels      []models.Channel         `json:"channels"`
+	Categories    []models.ChannelCategory `json:"categories"`
+	Roles         []models.Role            `json:"roles"`
+	MemberRoleIDs []uuid.UUID              `json:"member_role_ids"`
+}
+
+// HasCapability reports whether cap is in the list sent with IDENTIFY.
+func HasCapability(caps []string, cap string) bool {
+	for _, c := range caps {
+		if c == cap {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ResumeBufferSize is the number of recent frames kept per session for
+	// replay. It stays below sendBufferSize so a full replay fits in the
+	// new connection's send channel.
+	ResumeBufferSize = 200
+
+	// SessionResumeWindow is how long a disconnected session stays resumable.
+	SessionResumeWindow = 2 * time.Minute
+
+	sessionSweepInterval = 30 * time.Second
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionGap      = errors.New("missed events no longer buffered")
+)
+
+// Session outlives a single socket so a reconnecting client can RESUME and
+// receive the events it missed instead of starting over with IDENTIFY.
+type Session struct {
+	ID      string
+	UserID  uuid.UUID
+	Intents int64 // event categories the client opted into
+
+	mu         sync.Mutex
+	buf        []*frame // ring buffer, oldest at head
+	head       int
+	evictedSeq int64 // seq of the newest frame dropped from the buffer
+
+	// Guarded by Hub.mu
+	client     *Client
+	detachedAt time.Time
+}
+
+func newSession(userID uuid.UUID) *Session {
+	return &Session{
+		ID:      uuid.New().String(),
+		UserID:  userID,
+		Intents: IntentAll,
+		buf:     make([]*frame, 0, ResumeBufferSize),
+	}
+}
+
+// record appends a frame to the replay buffer, evicting the oldest when full.
+func (s *Session) record(f *frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buf) < ResumeBufferSize {
+		s.buf = append(s.buf, f)
+		return
+	}
+	s.evictedSeq = s.buf[s.head].seq
+	s.buf[s.head] = f
+	s.head = (s.head + 1) % ResumeBufferSize
+}
+
+// framesAfter returns the buffered frames with a sequence number greater
+// than seq, or ErrSessionGap if some of them have already been evicted.
+func (s *Session) framesAfter(seq int64) ([]*frame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.evictedSeq > seq {
+		return nil, ErrSessionGap
+	}
+
+	var frames []*frame
+	for i := 0; i < len(s.buf); i++ {
+		f := s.buf[(s.head+i)%len(s.buf)]
+		if f.seq > seq {
+			frames = append(frames, f)
+		}
+	}
+	return frames, nil
+}
+
+// resumeRequest is attached to a client that connected with RESUME.
+type resumeRequest struct {
+	sessionID string
+	seq       int64
+	result    chan error
+}
+
+// nextSeqLocked allocates the next sequence number. Allocating and recording
+// under the write lock keeps every replay buffer in sequence order. Caller
+// must hold h.mu for writing.
+func (h *Hub) nextSeqLocked() int64 {
+	h.seq++
+	return h.seq
+}
+
+// deliverLocked hands a frame to every live connection of the user that
+// opted into the event, and records it in any matching detached session.
+// Caller must hold h.mu for writing, since the frame's sequence number was
+// allocated under the same lock.
+func (h *Hub) deliverLocked(userID uuid.UUID, f *frame) {
+	for _, client := range h.clients[userID] {
+		if client.session != nil {
+			if !wantsEvent(client.session.Intents, f.event.Type) {
+				continue
+			}
+			client.session.record(f)
+		}
+		client.queue(f)
+	}
+	for _, s := range h.detached[userID] {
+		if wantsEvent(s.Intents, f.event.Type) {
+			s.record(f)
+		}
+	}
+}
+
+// wantsLocked reports whether any of the user's connections or detached
+// sessions would receive the event. Caller must hold h.mu.
+func (h *Hub) wantsLocked(userID uuid.UUID, eventType string) bool {
+	for _, client := range h.clients[userID] {
+		if client.session == nil || wantsEvent(client.session.Intents, eventType) {
+			return true
+		}
+	}
+	for _, s := range h.detached[userID] {
+		if wantsEvent(s.Intents, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendToClient delivers a sequenced event to a single connection.
+func (h *Hub) sendToClient(client *Client, event *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if client.closed {
+		return
+	}
+	f := newFrame(event, h.nextSeqLocked())
+	if client.session != nil {
+		client.session.record(f)
+	}
+	client.queue(f)
+}
+
+// sendUnsequenced delivers a connection-level event (such as HEARTBEAT_ACK)
+// that is neither stamped with a sequence number nor kept for replay.
+func (h *Hub) sendUnsequenced(client *Client, event *Event) {
+	f := newFrame(event, 0)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if client.closed {
+		return
+	}
+	client.queue(f)
+}
+
+// Resume attaches a freshly authenticated client to an existing session and
+// queues the frames it missed. It blocks until the hub has processed it.
+func (h *Hub) Resume(client *Client, sessionID string, seq int64) error {
+	req := &resumeRequest{sessionID: sessionID, seq: seq, result: make(chan error, 1)}
+	client.resume = req
+	h.register <- client
+	return <-req.result
+}
+
+// attachSessionLocked binds a registering client to its session: a new one
+// for IDENTIFY, or the requested existing one for RESUME, replaying missed
+// frames into the client's send buffer. Caller must hold h.mu.
+func (h *Hub) attachSessionLocked(client *Client) error {
+	if client.resume == nil {
+		if client.session == nil {
+			client.session = newSession(client.UserID)
+		}
+		client.session.client = client
+		h.sessions[client.session.ID] = client.session
+		return nil
+	}
+
+	s, ok := h.sessions[client.resume.sessionID]
+	if !ok || s.UserID != client.UserID {
+		return ErrSessionNotFound
+	}
+	frames, err := s.framesAfter(client.resume.seq)
+	if err != nil {
+		h.dropSessionLocked(s)
+		return err
+	}
+
+	// A stale socket may still hold the session; the new one takes over.
+	if old := s.client; old != nil {
+		h.removeClientLocked(old)
+	}
+	h.removeDetachedLocked(s)
+
+	s.client = client
+	client.session = s
+	for _, f := range frames {
+		client.queue(f)
+	}
+	return nil
+}
+
+// detachSessionLocked keeps a disconnected client's session around for
+// SessionResumeWindow. Caller must hold h.mu.
+func (h *Hub) detachSessionLocked(client *Client) {
+	s := client.session
+	if s == nil || s.client != client {
+		return
+	}
+	s.client = nil
+	s.detachedAt = time.Now()
+	h.detached[s.UserID] = append(h.detached[s.UserID], s)
+}
+
+func (h *Hub) removeDetachedLocked(s *Session) {
+	list := h.detached[s.UserID]
+	for i, d := range list {
+		if d == s {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(h.detached, s.UserID)
+	} else {
+		h.detached[s.UserID] = list
+	}
+}
+
+func (h *Hub) dropSessionLocked(s *Session) {
+	delete(h.sessions, s.ID)
+	h.removeDetachedLocked(s)
+}
+
+// expireSessions drops detached sessions past the resume window, and clears
+// channel subscriptions for users left with neither sockets nor sessions.
+func (h *Hub) expireSessions() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-SessionResumeWindow)
+	var expired []*Session
+	for _, list := range h.detached {
+		for _, s := range list {
+			if s.detachedAt.Before(cutoff) {
+				expired = append(expired, s)
+			}
+		}
+	}
+	for _, s := range expired {
+		h.dropSessionLocked(s)
+		if len(h.clients[s.UserID]) == 0 && len(h.detached[s.UserID]) == 0 {
+			h.removeSubscriptionsLocked(s.UserID)
+			h.publish(&Envelope{Kind: EnvelopePresence, UserID: s.UserID})
+		}
+	}
+}
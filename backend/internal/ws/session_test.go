@@ -0,0 +1,120 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFrame(t *testing.T, c *Client) Event {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		var e Event
+		require.NoError(t, json.Unmarshal(msg, &e))
+		return e
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("client did not receive a frame")
+	}
+	return Event{}
+}
+
+func TestSession_ResumeReplaysMissedEvents(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newTestClient(hub, userID, "user")
+	client.session = newSession(userID)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	channelID := uuid.New().String()
+	hub.Subscribe(userID, channelID)
+
+	first, _ := NewEvent(EventMessageCreate, map[string]string{"content": "one"})
+	hub.BroadcastToChannel(channelID, first, nil)
+	got := readFrame(t, client)
+	require.NotZero(t, got.Seq)
+	lastSeq := got.Seq
+
+	// Connection drops; events keep arriving while the client is away
+	hub.Unregister(client)
+	time.Sleep(10 * time.Millisecond)
+
+	second, _ := NewEvent(EventMessageCreate, map[string]string{"content": "two"})
+	hub.BroadcastToChannel(channelID, second, nil)
+	time.Sleep(10 * time.Millisecond)
+	third, _ := NewEvent(EventNotification, map[string]string{"type": "message"})
+	hub.SendToUser(userID, third)
+	time.Sleep(10 * time.Millisecond)
+
+	resumed := newTestClient(hub, userID, "user")
+	require.NoError(t, hub.Resume(resumed, client.session.ID, lastSeq))
+
+	replay1 := readFrame(t, resumed)
+	replay2 := readFrame(t, resumed)
+	assert.Equal(t, EventMessageCreate, replay1.Type)
+	assert.Equal(t, EventNotification, replay2.Type)
+	assert.Greater(t, replay1.Seq, lastSeq)
+	assert.Greater(t, replay2.Seq, replay1.Seq)
+	assert.True(t, hub.IsOnline(userID))
+}
+
+func TestSession_ResumeGapTooLarge(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newTestClient(hub, userID, "user")
+	client.session = newSession(userID)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	hub.Unregister(client)
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < ResumeBufferSize+10; i++ {
+		event, _ := NewEvent(EventNotification, map[string]int{"n": i})
+		hub.SendToUser(userID, event)
+	}
+
+	resumed := newTestClient(hub, userID, "user")
+	assert.ErrorIs(t, hub.Resume(resumed, client.session.ID, 0), ErrSessionGap)
+
+	// The session is discarded; a second attempt cannot find it
+	again := newTestClient(hub, userID, "user")
+	assert.ErrorIs(t, hub.Resume(again, client.session.ID, 0), ErrSessionNotFound)
+}
+
+func TestSession_ResumeRejectsOtherUser(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	owner := newTestClient(hub, uuid.New(), "owner")
+	owner.session = newSession(owner.UserID)
+	hub.Register(owner)
+	time.Sleep(10 * time.Millisecond)
+
+	intruder := newTestClient(hub, uuid.New(), "intruder")
+	assert.ErrorIs(t, hub.Resume(intruder, owner.session.ID, 0), ErrSessionNotFound)
+	assert.False(t, hub.IsOnline(intruder.UserID))
+}
+
+func TestSession_HeartbeatAckIsUnsequenced(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := newTestClient(hub, uuid.New(), "user")
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	client.handleEvent(&Event{Type: EventHeartbeat})
+	ack := readFrame(t, client)
+	assert.Equal(t, EventHeartbeatAck, ack.Type)
+	assert.Zero(t, ack.Seq)
+}
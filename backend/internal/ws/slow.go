@@ -0,0 +1,90 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+// DefaultSlowConsumerLimit is the number of frames a connection may drop
+// before it is disconnected. The count starts over whenever its send buffer
+// drains, so only a connection that stays backed up reaches the limit.
+const DefaultSlowConsumerLimit = 16
+
+// HubStats are counters for operators.
+type HubStats struct {
+	Connections             int   `json:"connections"`
+	OnlineUsers             int   `json:"online_users"`
+	DroppedFrames           int64 `json:"dropped_frames"`
+	SlowConsumerDisconnects int64 `json:"slow_consumer_disconnects"`
+	RateLimitedEvents       int64 `json:"rate_limited_events"`
+	RateLimitDisconnects    int64 `json:"rate_limit_disconnects"`
+	BusDroppedEvents        int64 `json:"bus_dropped_events"`
+}
+
+// SetSlowConsumerLimit sets how many frames a connection may drop before it
+// is closed with CloseSlowConsumer. Must be called before Run.
+func (h *Hub) SetSlowConsumerLimit(limit int) {
+	if limit > 0 {
+		h.slowConsumerLimit = int64(limit)
+	}
+}
+
+// Stats returns a snapshot of the hub's connection and drop counters.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	conns := 0
+	for _, list := range h.clients {
+		conns += len(list)
+	}
+	users := len(h.clients)
+	h.mu.RUnlock()
+
+	return HubStats{
+		Connections:             conns,
+		OnlineUsers:             users,
+		DroppedFrames:           h.droppedFrames.Load(),
+		SlowConsumerDisconnects: h.slowDisconnects.Load(),
+		RateLimitedEvents:       h.rateLimited.Load(),
+		RateLimitDisconnects:    h.rateDisconnects.Load(),
+		BusDroppedEvents:        h.busDropped.Load(),
+	}
+}
+
+// recordDrop counts a frame that did not fit in a connection's send buffer
+// and disconnects the connection once it crosses the limit without the
+// buffer draining in between. The frame is still in the session's replay
+// buffer, so a prompt RESUME recovers it.
+func (h *Hub) recordDrop(c *Client) {
+	h.droppedFrames.Add(1)
+	if c.dropped.Add(1) < h.slowConsumerLimit {
+		return
+	}
+	c.slowOnce.Do(func() {
+		h.slowDisconnects.Add(1)
+		log.Printf("Disconnecting slow consumer %s (%s) after %d dropped frames", c.Username, c.UserID, c.dropped.Load())
+		go c.closeSlow()
+	})
+}
+
+// drained is called by the write pump after each write and forgets earlier
+// drops once the send buffer is empty, so occasional bursts over a long
+// connection don't add up to a disconnect.
+func (c *Client) drained() {
+	if len(c.send) == 0 {
+		c.dropped.Store(0)
+	}
+}
+
+// closeSlow sends the slow-consumer close frame and tears the socket down;
+// ReadPump then unregisters the client.
+func (c *Client) closeSlow() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(CloseSlowConsumer, "slow consumer"),
+		time.Now().Add(writeWait))
+	c.conn.Close()
+}
@@ -0,0 +1,89 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowConsumer_DropsAreCountedAndLimited(t *testing.T) {
+	hub := NewHub()
+	hub.SetSlowConsumerLimit(3)
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newTestClient(hub, userID, "slow")
+	client.session = newSession(userID)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	// Nobody drains the send buffer
+	event, _ := NewEvent(EventNotification, map[string]string{"type": "message"})
+	for i := 0; i < sendBufferSize+5; i++ {
+		hub.SendToUser(userID, event)
+	}
+
+	assert.EqualValues(t, 5, client.dropped.Load())
+	stats := hub.Stats()
+	assert.EqualValues(t, 5, stats.DroppedFrames)
+	assert.EqualValues(t, 1, stats.SlowConsumerDisconnects, "disconnect fires once")
+	assert.Equal(t, 1, stats.Connections)
+}
+
+func TestSlowConsumer_DrainResetsDropCount(t *testing.T) {
+	hub := NewHub()
+	hub.SetSlowConsumerLimit(3)
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newTestClient(hub, userID, "bursty")
+	client.send = make(chan []byte, 1)
+	client.session = newSession(userID)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	event, _ := NewEvent(EventNotification, map[string]string{"type": "message"})
+	burst := func() {
+		// One frame fits, the next two are dropped
+		for i := 0; i < 3; i++ {
+			hub.SendToUser(userID, event)
+		}
+		<-client.send
+		client.drained()
+	}
+	burst()
+	burst()
+
+	assert.EqualValues(t, 0, client.dropped.Load())
+	stats := hub.Stats()
+	assert.EqualValues(t, 4, stats.DroppedFrames)
+	assert.EqualValues(t, 0, stats.SlowConsumerDisconnects, "drops spread across drained bursts are forgiven")
+}
+
+func TestSlowConsumer_DroppedFramesStayResumable(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	userID := uuid.New()
+	client := newTestClient(hub, userID, "slow")
+	client.send = make(chan []byte, 1)
+	client.session = newSession(userID)
+	hub.Register(client)
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		event, _ := NewEvent(EventNotification, map[string]int{"n": i})
+		hub.SendToUser(userID, event)
+	}
+	first := readFrame(t, client)
+
+	hub.Unregister(client)
+	time.Sleep(10 * time.Millisecond)
+
+	resumed := newTestClient(hub, userID, "slow")
+	assert.NoError(t, hub.Resume(resumed, client.session.ID, first.Seq))
+	assert.Equal(t, EventNotification, readFrame(t, resumed).Type)
+	assert.Equal(t, EventNotification, readFrame(t, resumed).Type)
+}
@@ -0,0 +1,318 @@
+package thicket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// CreateBot creates a bot owned by the caller. The bot token is only
+// returned here and by RegenerateBotToken.
+func (c *Client) CreateBot(ctx context.Context, username string) (*Bot, string, error) {
+	var resp struct {
+		Bot   Bot    `json:"bot"`
+		Token string `json:"token"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("bots"), map[string]string{"username": username}, &resp); err != nil {
+		return nil, "", err
+	}
+	return &resp.Bot, resp.Token, nil
+}
+
+func (c *Client) ListBots(ctx context.Context) ([]Bot, error) {
+	return callList[Bot](ctx, c, http.MethodGet, apiPath("bots"), nil)
+}
+
+func (c *Client) DeleteBot(ctx context.Context, botID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("bots", botID.String()), nil, nil)
+}
+
+// RegenerateBotToken replaces a bot's token, invalidating the old one.
+func (c *Client) RegenerateBotToken(ctx context.Context, botID uuid.UUID) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("bots", botID.String(), "regenerate-token"), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// SetBotInteractionsURL sets the URL a bot receives interactions at and
+// returns the secret they are signed with. An empty URL switches the bot
+// back to gateway delivery.
+func (c *Client) SetBotInteractionsURL(ctx context.Context, botID uuid.UUID, interactionsURL string) (*Bot, string, error) {
+	var resp struct {
+		Bot    Bot    `json:"bot"`
+		Secret string `json:"interactions_secret"`
+	}
+	if err := c.Do(ctx, http.MethodPut, apiPath("bots", botID.String(), "interactions-endpoint"),
+		map[string]string{"interactions_url": interactionsURL}, &resp); err != nil {
+		return nil, "", err
+	}
+	return &resp.Bot, resp.Secret, nil
+}
+
+// SetBotPermissions sets the permissions a bot requests by default when it
+// is authorized into a server.
+func (c *Client) SetBotPermissions(ctx context.Context, botID uuid.UUID, permissions int64) (*Bot, error) {
+	return call[Bot](ctx, c, http.MethodPut, apiPath("bots", botID.String(), "permissions"),
+		map[string]string{"permissions": strconv.FormatInt(permissions, 10)})
+}
+
+// RemoveBotFromServer removes a bot from a server along with its managed
+// role.
+func (c *Client) RemoveBotFromServer(ctx context.Context, serverID, botID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "bots", botID.String()), nil, nil)
+}
+
+// CommandParams describes a slash command to register.
+type CommandParams struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// RegisterCommand creates or replaces one of the calling bot's global
+// commands. Bot clients only.
+func (c *Client) RegisterCommand(ctx context.Context, params CommandParams) (*SlashCommand, error) {
+	return call[SlashCommand](ctx, c, http.MethodPost, apiPath("commands"), params)
+}
+
+// RegisterServerCommand creates or replaces a command available only in
+// one server. Bot clients only.
+func (c *Client) RegisterServerCommand(ctx context.Context, serverID uuid.UUID, params CommandParams) (*SlashCommand, error) {
+	return call[SlashCommand](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "commands"), params)
+}
+
+// ListCommands returns the calling bot's global commands. Bot clients only.
+func (c *Client) ListCommands(ctx context.Context) ([]SlashCommand, error) {
+	return callList[SlashCommand](ctx, c, http.MethodGet, apiPath("commands"), nil)
+}
+
+// ListServerCommands returns the calling bot's commands for one server. Bot
+// clients only.
+func (c *Client) ListServerCommands(ctx context.Context, serverID uuid.UUID) ([]SlashCommand, error) {
+	return callList[SlashCommand](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "commands"), nil)
+}
+
+func (c *Client) DeleteCommand(ctx context.Context, commandID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("commands", commandID.String()), nil, nil)
+}
+
+// GetChannelCommands returns every command that can be invoked in a channel.
+func (c *Client) GetChannelCommands(ctx context.Context, channelID uuid.UUID) ([]SlashCommand, error) {
+	return callList[SlashCommand](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "commands"), nil)
+}
+
+// InvokeCommand invokes a slash command in a channel.
+func (c *Client) InvokeCommand(ctx context.Context, channelID, commandID uuid.UUID, options []InteractionOption) (*InteractionAck, error) {
+	return call[InteractionAck](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "interactions"), map[string]any{
+		"command_id": commandID.String(),
+		"options":    options,
+	})
+}
+
+// ClickComponent activates a button, or submits select menu values, on a
+// message.
+func (c *Client) ClickComponent(ctx context.Context, messageID uuid.UUID, customID string, values []string) (*InteractionAck, error) {
+	return call[InteractionAck](ctx, c, http.MethodPost, apiPath("messages", messageID.String(), "components"), map[string]any{
+		"custom_id": customID,
+		"values":    values,
+	})
+}
+
+// InteractionResponse answers an interaction. Type is ResponseMessage,
+// ResponseUpdate (edit the message a component is on) or ResponseDeferred.
+type InteractionResponse struct {
+	Type       string      `json:"type"`
+	Content    string      `json:"content"`
+	Components []ActionRow `json:"components,omitempty"`
+	Ephemeral  bool        `json:"ephemeral,omitempty"`
+}
+
+// RespondToInteraction sends the initial response to an interaction,
+// authenticated by the interaction token. It returns the created or updated
+// message, or nil for a deferred response.
+func (c *Client) RespondToInteraction(ctx context.Context, interactionID uuid.UUID, token string, resp InteractionResponse) (*Message, error) {
+	var raw []byte
+	if err := c.Do(ctx, http.MethodPost, apiPath("interactions", interactionID.String(), token, "callback"), resp, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// InteractionFollowUp is a further message sent for an acknowledged
+// interaction.
+type InteractionFollowUp struct {
+	Content    string      `json:"content"`
+	Components []ActionRow `json:"components,omitempty"`
+	Ephemeral  bool        `json:"ephemeral,omitempty"`
+}
+
+func (c *Client) FollowUpInteraction(ctx context.Context, interactionID uuid.UUID, token string, msg InteractionFollowUp) (*Message, error) {
+	return call[Message](ctx, c, http.MethodPost, apiPath("interactions", interactionID.String(), token, "followup"), msg)
+}
+
+// CreateWebhook creates a webhook in a channel. The returned webhook carries
+// its token and URL, which are not shown again.
+func (c *Client) CreateWebhook(ctx context.Context, channelID uuid.UUID, name, avatarURL string) (*Webhook, error) {
+	return call[Webhook](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "webhooks"), map[string]string{
+		"name":       name,
+		"avatar_url": avatarURL,
+	})
+}
+
+func (c *Client) ListWebhooks(ctx context.Context, channelID uuid.UUID) ([]Webhook, error) {
+	return callList[Webhook](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "webhooks"), nil)
+}
+
+func (c *Client) ListServerWebhooks(ctx context.Context, serverID uuid.UUID) ([]Webhook, error) {
+	return callList[Webhook](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "webhooks"), nil)
+}
+
+// UpdateWebhookParams changes webhook fields; nil fields are left as they
+// are. Setting ChannelID moves the webhook to another channel of the same
+// server.
+type UpdateWebhookParams struct {
+	Name      *string
+	AvatarURL *string
+	ChannelID *uuid.UUID
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, webhookID uuid.UUID, params UpdateWebhookParams) (*Webhook, error) {
+	body := map[string]any{}
+	if params.Name != nil {
+		body["name"] = *params.Name
+	}
+	if params.AvatarURL != nil {
+		body["avatar_url"] = *params.AvatarURL
+	}
+	if params.ChannelID != nil {
+		body["channel_id"] = params.ChannelID.String()
+	}
+	return call[Webhook](ctx, c, http.MethodPatch, apiPath("webhooks", webhookID.String()), body)
+}
+
+// RotateWebhookToken replaces a webhook's token. The returned webhook
+// carries the new token and URL.
+func (c *Client) RotateWebhookToken(ctx context.Context, webhookID uuid.UUID) (*Webhook, error) {
+	return call[Webhook](ctx, c, http.MethodPost, apiPath("webhooks", webhookID.String(), "token"), nil)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("webhooks", webhookID.String()), nil, nil)
+}
+
+// SetWebhookInteractionsURL sets the URL that receives component
+// interactions on the webhook's messages and returns the signing secret.
+func (c *Client) SetWebhookInteractionsURL(ctx context.Context, webhookID uuid.UUID, interactionsURL string) (*Webhook, string, error) {
+	var resp struct {
+		Webhook Webhook `json:"webhook"`
+		Secret  string  `json:"interactions_secret"`
+	}
+	if err := c.Do(ctx, http.MethodPut, apiPath("webhooks", webhookID.String(), "interactions-endpoint"),
+		map[string]string{"interactions_url": interactionsURL}, &resp); err != nil {
+		return nil, "", err
+	}
+	return &resp.Webhook, resp.Secret, nil
+}
+
+// SetWebhookAdapterSecret sets the secret GitHub, GitLab and Slack payloads
+// are verified against. An empty secret removes it.
+func (c *Client) SetWebhookAdapterSecret(ctx context.Context, webhookID uuid.UUID, secret string) error {
+	return c.Do(ctx, http.MethodPut, apiPath("webhooks", webhookID.String(), "adapter-secret"), map[string]string{"secret": secret}, nil)
+}
+
+// ExecuteWebhookParams is a message posted through a webhook. Username and
+// AvatarURL override the webhook's own for this message; ThreadID posts into
+// a thread of the webhook's channel.
+type ExecuteWebhookParams struct {
+	Content    string      `json:"content"`
+	Username   string      `json:"username,omitempty"`
+	AvatarURL  string      `json:"avatar_url,omitempty"`
+	Embeds     []Embed     `json:"embeds,omitempty"`
+	Components []ActionRow `json:"components,omitempty"`
+	ThreadID   *uuid.UUID  `json:"thread_id,omitempty"`
+}
+
+// ExecuteWebhook posts a message through a webhook, authenticated by the
+// webhook token.
+func (c *Client) ExecuteWebhook(ctx context.Context, webhookID uuid.UUID, token string, params ExecuteWebhookParams) error {
+	return c.Do(ctx, http.MethodPost, apiPath("webhooks", webhookID.String(), token), params, nil)
+}
+
+// ExecuteWebhookWait is ExecuteWebhook, but waits for and returns the posted
+// message. For a thread target, ChannelID is the thread's parent channel.
+func (c *Client) ExecuteWebhookWait(ctx context.Context, webhookID uuid.UUID, token string, params ExecuteWebhookParams) (*Message, error) {
+	return call[Message](ctx, c, http.MethodPost,
+		withQuery(apiPath("webhooks", webhookID.String(), token), url.Values{"wait": {"true"}}), params)
+}
+
+// EditWebhookMessageParams changes a webhook message; nil fields are left as
+// they are.
+type EditWebhookMessageParams struct {
+	Content    *string     `json:"content,omitempty"`
+	Embeds     []Embed     `json:"embeds,omitempty"`
+	Components []ActionRow `json:"components,omitempty"`
+}
+
+// EditWebhookMessage edits a message the webhook posted.
+func (c *Client) EditWebhookMessage(ctx context.Context, webhookID uuid.UUID, token string, messageID uuid.UUID, params EditWebhookMessageParams) (*Message, error) {
+	return call[Message](ctx, c, http.MethodPatch, apiPath("webhooks", webhookID.String(), token, "messages", messageID.String()), params)
+}
+
+// DeleteWebhookMessage deletes a message the webhook posted.
+func (c *Client) DeleteWebhookMessage(ctx context.Context, webhookID uuid.UUID, token string, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("webhooks", webhookID.String(), token, "messages", messageID.String()), nil, nil)
+}
+
+// EventSubscriptionParams configures an outgoing event subscription.
+// EventTypes are gateway event names such as EventMessageCreate.
+type EventSubscriptionParams struct {
+	URL        string   `json:"url,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+	Enabled    *bool    `json:"enabled,omitempty"`
+}
+
+// CreateEventSubscription subscribes a URL to a server's events. The secret
+// deliveries are signed with is only returned here.
+func (c *Client) CreateEventSubscription(ctx context.Context, serverID uuid.UUID, params EventSubscriptionParams) (*EventSubscription, string, error) {
+	var resp struct {
+		Subscription EventSubscription `json:"subscription"`
+		Secret       string            `json:"secret"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("servers", serverID.String(), "event-subscriptions"), params, &resp); err != nil {
+		return nil, "", err
+	}
+	return &resp.Subscription, resp.Secret, nil
+}
+
+func (c *Client) ListEventSubscriptions(ctx context.Context, serverID uuid.UUID) ([]EventSubscription, error) {
+	return callList[EventSubscription](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "event-subscriptions"), nil)
+}
+
+func (c *Client) UpdateEventSubscription(ctx context.Context, subscriptionID uuid.UUID, params EventSubscriptionParams) (*EventSubscription, error) {
+	return call[EventSubscription](ctx, c, http.MethodPatch, apiPath("event-subscriptions", subscriptionID.String()), params)
+}
+
+func (c *Client) DeleteEventSubscription(ctx context.Context, subscriptionID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("event-subscriptions", subscriptionID.String()), nil, nil)
+}
+
+// ListEventDeliveries returns a subscription's most recent deliveries.
+func (c *Client) ListEventDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]EventDelivery, error) {
+	return callList[EventDelivery](ctx, c, http.MethodGet,
+		withQuery(apiPath("event-subscriptions", subscriptionID.String(), "deliveries"), url.Values{"limit": {itoa(limit)}}), nil)
+}
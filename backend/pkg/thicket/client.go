@@ -0,0 +1,265 @@
+// Package thicket is a Go client for the Thicket REST API and gateway.
+//
+// A Client calls the REST API as a user (with an OAuth2 access token) or as
+// a bot (with a bot token):
+//
+//	client := thicket.NewBotClient("https://chat.example.com", token)
+//	msg, err := client.SendMessage(ctx, channelID, thicket.SendMessageParams{Content: "hello"})
+//
+// A Gateway receives real-time events over the WebSocket gateway. It sends
+// IDENTIFY and heartbeats, and reconnects and resumes when the connection
+// drops:
+//
+//	gw := client.Gateway()
+//	gw.OnMessageCreate(func(e *thicket.MessageCreateEvent) { ... })
+//	err := gw.Run(ctx)
+package thicket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Client calls the Thicket REST API. It is safe for concurrent use.
+type Client struct {
+	// HTTPClient sends requests; it defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	baseURL string
+
+	mu            sync.RWMutex
+	authorization string
+	bot           bool
+}
+
+// NewClient returns a client that authenticates as a user with an OAuth2
+// access token. Replace an expired token with SetToken.
+func NewClient(baseURL, accessToken string) *Client {
+	return &Client{
+		HTTPClient:    http.DefaultClient,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		authorization: "Bearer " + accessToken,
+	}
+}
+
+// NewBotClient returns a client that authenticates with a bot token.
+func NewBotClient(baseURL, botToken string) *Client {
+	return &Client{
+		HTTPClient:    http.DefaultClient,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		authorization: "Bot " + botToken,
+		bot:           true,
+	}
+}
+
+// BaseURL returns the URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// SetToken replaces the credentials of a user client, for example after an
+// access token refresh. Gateways created from the client pick up the new
+// token on their next connect.
+func (c *Client) SetToken(accessToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bot {
+		c.authorization = "Bot " + accessToken
+	} else {
+		c.authorization = "Bearer " + accessToken
+	}
+}
+
+// gatewayToken is the token sent in IDENTIFY and RESUME: a bare access
+// token, or "Bot <token>".
+func (c *Client) gatewayToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.bot {
+		return c.authorization
+	}
+	return strings.TrimPrefix(c.authorization, "Bearer ")
+}
+
+func (c *Client) authHeader() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authorization
+}
+
+// APIError is an error response from the API.
+type APIError struct {
+	StatusCode int
+	// Message is the "error" field of the response body.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("thicket: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("thicket: %d %s", e.StatusCode, e.Message)
+}
+
+// File is a file to upload.
+type File struct {
+	Name        string
+	ContentType string
+	Reader      io.Reader
+}
+
+// Do sends a request to an API path, such as "/api/me", encoding body (if
+// not nil) as JSON and decoding the response into out (if not nil). Errors
+// from the API are returned as *APIError. If out is a *[]byte it receives the
+// raw response body. Do is the escape hatch for routes without a typed
+// method.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+		contentType = "application/json"
+	}
+	return c.send(ctx, method, path, contentType, reader, out)
+}
+
+// doMultipart sends form fields and files as multipart/form-data.
+func (c *Client) doMultipart(ctx context.Context, method, path string, fields map[string]string, fileField string, files []File, out any) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, fileField, escapeQuotes(f.Name)))
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Reader); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.send(ctx, method, path, w.FormDataContentType(), &buf, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if auth := c.authHeader(); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: raw}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(raw, &payload) == nil {
+			apiErr.Message = payload.Error
+		}
+		return apiErr
+	}
+	if b, ok := out.(*[]byte); ok {
+		*b = raw
+		return nil
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// call sends a request and decodes a single value from the response.
+func call[T any](ctx context.Context, c *Client, method, path string, body any) (*T, error) {
+	var out T
+	if err := c.Do(ctx, method, path, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// callList sends a request and decodes a list from the response.
+func callList[T any](ctx context.Context, c *Client, method, path string, body any) ([]T, error) {
+	var out []T
+	if err := c.Do(ctx, method, path, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// apiPath joins escaped path segments onto /api.
+func apiPath(segments ...string) string {
+	var b strings.Builder
+	b.WriteString("/api")
+	for _, s := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(s))
+	}
+	return b.String()
+}
+
+// withQuery appends non-empty query parameters to a path.
+func withQuery(p string, query url.Values) string {
+	for k, v := range query {
+		if len(v) == 0 || v[0] == "" {
+			delete(query, k)
+		}
+	}
+	if len(query) == 0 {
+		return p
+	}
+	return p + "?" + query.Encode()
+}
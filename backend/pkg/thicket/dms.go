@@ -0,0 +1,136 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateDM opens (or returns the existing) direct conversation with a user.
+func (c *Client) CreateDM(ctx context.Context, userID uuid.UUID) (*DMConversation, error) {
+	return call[DMConversation](ctx, c, http.MethodPost, apiPath("dm", "conversations"),
+		map[string]string{"participant_id": userID.String()})
+}
+
+func (c *Client) CreateGroupDM(ctx context.Context, userIDs []uuid.UUID) (*DMConversation, error) {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+	return call[DMConversation](ctx, c, http.MethodPost, apiPath("dm", "conversations", "group"),
+		map[string][]string{"participant_ids": ids})
+}
+
+func (c *Client) ListDMs(ctx context.Context) ([]DMConversation, error) {
+	return callList[DMConversation](ctx, c, http.MethodGet, apiPath("dm", "conversations"), nil)
+}
+
+// RenameDM names a group conversation.
+func (c *Client) RenameDM(ctx context.Context, conversationID uuid.UUID, name string) error {
+	return c.Do(ctx, http.MethodPatch, apiPath("dm", "conversations", conversationID.String()), map[string]string{"name": name}, nil)
+}
+
+// AcceptDM accepts a message request from a user the caller shares no
+// server or friendship with.
+func (c *Client) AcceptDM(ctx context.Context, conversationID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("dm", "conversations", conversationID.String(), "accept"), nil, nil)
+}
+
+func (c *Client) DeclineDM(ctx context.Context, conversationID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("dm", "conversations", conversationID.String(), "decline"), nil, nil)
+}
+
+func (c *Client) AddDMParticipant(ctx context.Context, conversationID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("dm", "conversations", conversationID.String(), "participants"),
+		map[string]string{"user_id": userID.String()}, nil)
+}
+
+func (c *Client) RemoveDMParticipant(ctx context.Context, conversationID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("dm", "conversations", conversationID.String(), "participants", userID.String()), nil, nil)
+}
+
+// SendDMParams is a direct message to send. Messages with files are sent as
+// multipart/form-data. Nonce works as in SendMessageParams.
+type SendDMParams struct {
+	Content   string
+	Type      string
+	ReplyToID *uuid.UUID
+	Files     []File
+	Nonce     string
+}
+
+func (c *Client) SendDM(ctx context.Context, conversationID uuid.UUID, params SendDMParams) (*DMMessage, error) {
+	path := apiPath("dm", "conversations", conversationID.String(), "messages")
+	if len(params.Files) > 0 {
+		fields := map[string]string{"content": params.Content}
+		if params.Type != "" {
+			fields["type"] = params.Type
+		}
+		if params.ReplyToID != nil {
+			fields["reply_to_id"] = params.ReplyToID.String()
+		}
+		if params.Nonce != "" {
+			fields["nonce"] = params.Nonce
+		}
+		var m DMMessage
+		if err := c.doMultipart(ctx, http.MethodPost, path, fields, "files[]", params.Files, &m); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+
+	body := struct {
+		Content   string     `json:"content"`
+		Type      string     `json:"type,omitempty"`
+		ReplyToID *uuid.UUID `json:"reply_to_id,omitempty"`
+		Nonce     string     `json:"nonce,omitempty"`
+	}{params.Content, params.Type, params.ReplyToID, params.Nonce}
+	return call[DMMessage](ctx, c, http.MethodPost, path, body)
+}
+
+// GetDMMessages returns a page of conversation history.
+func (c *Client) GetDMMessages(ctx context.Context, conversationID uuid.UUID, params HistoryParams) ([]DMMessage, error) {
+	return callList[DMMessage](ctx, c, http.MethodGet, withQuery(apiPath("dm", "conversations", conversationID.String(), "messages"), params.query()), nil)
+}
+
+func (c *Client) GetDMMessagesAround(ctx context.Context, conversationID uuid.UUID, at time.Time, limit int) ([]DMMessage, error) {
+	return callList[DMMessage](ctx, c, http.MethodGet, withQuery(apiPath("dm", "conversations", conversationID.String(), "messages", "around"), url.Values{
+		"timestamp": {at.UTC().Format(time.RFC3339Nano)},
+		"limit":     {itoa(limit)},
+	}), nil)
+}
+
+func (c *Client) EditDM(ctx context.Context, messageID uuid.UUID, content string) (*DMMessage, error) {
+	return call[DMMessage](ctx, c, http.MethodPut, apiPath("dm", "messages", messageID.String()), map[string]string{"content": content})
+}
+
+func (c *Client) DeleteDM(ctx context.Context, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("dm", "messages", messageID.String()), nil, nil)
+}
+
+func (c *Client) GetDMEdits(ctx context.Context, messageID uuid.UUID) ([]DMMessageEdit, error) {
+	return callList[DMMessageEdit](ctx, c, http.MethodGet, apiPath("dm", "messages", messageID.String(), "edits"), nil)
+}
+
+func (c *Client) AddDMReaction(ctx context.Context, messageID uuid.UUID, emoji string) error {
+	return c.Do(ctx, http.MethodPut, withQuery(apiPath("dm", "messages", messageID.String(), "reactions"), url.Values{"emoji": {emoji}}), nil, nil)
+}
+
+func (c *Client) RemoveDMReaction(ctx context.Context, messageID uuid.UUID, emoji string) error {
+	return c.Do(ctx, http.MethodDelete, withQuery(apiPath("dm", "messages", messageID.String(), "reactions"), url.Values{"emoji": {emoji}}), nil, nil)
+}
+
+func (c *Client) PinDM(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPut, apiPath("dm", "conversations", conversationID.String(), "pins", messageID.String()), nil, nil)
+}
+
+func (c *Client) UnpinDM(ctx context.Context, conversationID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("dm", "conversations", conversationID.String(), "pins", messageID.String()), nil, nil)
+}
+
+func (c *Client) GetPinnedDMs(ctx context.Context, conversationID uuid.UUID) ([]DMMessage, error) {
+	return callList[DMMessage](ctx, c, http.MethodGet, apiPath("dm", "conversations", conversationID.String(), "pins"), nil)
+}
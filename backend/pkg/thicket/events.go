@@ -0,0 +1,773 @@
+package thicket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Gateway event types sent by the client.
+const (
+	EventIdentify     = "IDENTIFY"
+	EventResume       = "RESUME"
+	EventHeartbeat    = "HEARTBEAT"
+	EventSubscribe    = "SUBSCRIBE"
+	EventUnsubscribe  = "UNSUBSCRIBE"
+	EventTokenRefresh = "TOKEN_REFRESH"
+	EventVoiceJoin    = "VOICE_JOIN"
+	EventVoiceLeave   = "VOICE_LEAVE"
+	EventDMCallStart  = "DM_CALL_START"
+)
+
+// Gateway event types sent by the server. TYPING_START, PRESENCE_UPDATE,
+// DM_CALL_ACCEPT and DM_CALL_END are also sent by the client, with a
+// smaller payload.
+const (
+	EventReady                    = "READY"
+	EventResumed                  = "RESUMED"
+	EventInvalidSession           = "INVALID_SESSION"
+	EventHeartbeatAck             = "HEARTBEAT_ACK"
+	EventError                    = "ERROR"
+	EventSessionExpired           = "SESSION_EXPIRED"
+	EventMessageCreate            = "MESSAGE_CREATE"
+	EventMessageUpdate            = "MESSAGE_UPDATE"
+	EventMessageDelete            = "MESSAGE_DELETE"
+	EventMessageDeleteBulk        = "MESSAGE_DELETE_BULK"
+	EventMessageRestore           = "MESSAGE_RESTORE"
+	EventMessagePin               = "MESSAGE_PIN"
+	EventMessageUnpin             = "MESSAGE_UNPIN"
+	EventReactionAdd              = "REACTION_ADD"
+	EventReactionRemove           = "REACTION_REMOVE"
+	EventMentionCreate            = "MENTION_CREATE"
+	EventNotification             = "NOTIFICATION"
+	EventUnreadUpdate             = "UNREAD_UPDATE"
+	EventTypingStart              = "TYPING_START"
+	EventPresenceUpdate           = "PRESENCE_UPDATE"
+	EventVoiceStateUpdate         = "VOICE_STATE_UPDATE"
+	EventUserProfileUpdate        = "USER_PROFILE_UPDATE"
+	EventChannelCreate            = "CHANNEL_CREATE"
+	EventChannelUpdate            = "CHANNEL_UPDATE"
+	EventChannelDelete            = "CHANNEL_DELETE"
+	EventCategoryCreate           = "CATEGORY_CREATE"
+	EventCategoryUpdate           = "CATEGORY_UPDATE"
+	EventCategoryDelete           = "CATEGORY_DELETE"
+	EventServerUpdate             = "SERVER_UPDATE"
+	EventMemberJoin               = "MEMBER_JOIN"
+	EventMemberLeave              = "MEMBER_LEAVE"
+	EventMemberUpdate             = "MEMBER_UPDATE"
+	EventMemberBan                = "MEMBER_BAN"
+	EventMemberTimeout            = "MEMBER_TIMEOUT"
+	EventRoleCreate               = "ROLE_CREATE"
+	EventRoleUpdate               = "ROLE_UPDATE"
+	EventRoleDelete               = "ROLE_DELETE"
+	EventMemberRoleUpdate         = "MEMBER_ROLE_UPDATE"
+	EventDMMessageCreate          = "DM_MESSAGE_CREATE"
+	EventDMMessageUpdate          = "DM_MESSAGE_UPDATE"
+	EventDMMessageDelete          = "DM_MESSAGE_DELETE"
+	EventDMMessagePin             = "DM_MESSAGE_PIN"
+	EventDMMessageUnpin           = "DM_MESSAGE_UNPIN"
+	EventDMReactionAdd            = "DM_REACTION_ADD"
+	EventDMReactionRemove         = "DM_REACTION_REMOVE"
+	EventDMParticipantAdd         = "DM_PARTICIPANT_ADD"
+	EventDMParticipantRemove      = "DM_PARTICIPANT_REMOVE"
+	EventDMConversationUpdate     = "DM_CONVERSATION_UPDATE"
+	EventDMCallRing               = "DM_CALL_RING"
+	EventDMCallAccept             = "DM_CALL_ACCEPT"
+	EventDMCallEnd                = "DM_CALL_END"
+	EventFriendRequestCreate      = "FRIEND_REQUEST_CREATE"
+	EventFriendRequestAccept      = "FRIEND_REQUEST_ACCEPT"
+	EventFriendRemove             = "FRIEND_REMOVE"
+	EventThreadCreate             = "THREAD_CREATE"
+	EventThreadUpdate             = "THREAD_UPDATE"
+	EventThreadMessageCreate      = "THREAD_MESSAGE_CREATE"
+	EventThreadMessageDelete      = "THREAD_MESSAGE_DELETE"
+	EventPollCreate               = "POLL_CREATE"
+	EventPollVote                 = "POLL_VOTE"
+	EventStageStart               = "STAGE_START"
+	EventStageEnd                 = "STAGE_END"
+	EventStageSpeakerAdd          = "STAGE_SPEAKER_ADD"
+	EventStageSpeakerRemove       = "STAGE_SPEAKER_REMOVE"
+	EventStageHandRaise           = "STAGE_HAND_RAISE"
+	EventStageHandLower           = "STAGE_HAND_LOWER"
+	EventForumPostCreate          = "FORUM_POST_CREATE"
+	EventForumPostDelete          = "FORUM_POST_DELETE"
+	EventForumPostPin             = "FORUM_POST_PIN"
+	EventForumPostMessageCreate   = "FORUM_POST_MESSAGE_CREATE"
+	EventServerInvitationReceived = "SERVER_INVITATION_RECEIVED"
+	EventServerInvitationAccepted = "SERVER_INVITATION_ACCEPTED"
+	EventServerInvitationDeclined = "SERVER_INVITATION_DECLINED"
+	EventInteractionCreate        = "INTERACTION_CREATE"
+)
+
+// ErrUnknownEvent is returned by Event.Decode for event types this package
+// does not know.
+var ErrUnknownEvent = errors.New("thicket: unknown event type")
+
+// Event is a gateway frame. Seq is set on sequenced server frames.
+type Event struct {
+	Type string          `json:"type"`
+	Seq  int64           `json:"seq,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode decodes Data into the typed payload for the event type, such as
+// *MessageCreateEvent for MESSAGE_CREATE. Server payloads are returned for
+// types the client also sends.
+func (e *Event) Decode() (any, error) {
+	newPayload, ok := eventPayloads[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, e.Type)
+	}
+	v := newPayload()
+	if len(e.Data) == 0 || string(e.Data) == "null" {
+		return v, nil
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return nil, fmt.Errorf("thicket: decode %s: %w", e.Type, err)
+	}
+	return v, nil
+}
+
+// eventPayloads maps each event type to a constructor for its payload.
+var eventPayloads = map[string]func() any{
+	EventIdentify:     func() any { return new(IdentifyEvent) },
+	EventResume:       func() any { return new(ResumeEvent) },
+	EventHeartbeat:    func() any { return new(HeartbeatEvent) },
+	EventSubscribe:    func() any { return new(SubscribeEvent) },
+	EventUnsubscribe:  func() any { return new(SubscribeEvent) },
+	EventTokenRefresh: func() any { return new(TokenRefreshEvent) },
+	EventVoiceJoin:    func() any { return new(VoiceJoinEvent) },
+	EventVoiceLeave:   func() any { return new(VoiceJoinEvent) },
+	EventDMCallStart:  func() any { return new(DMCallStartEvent) },
+
+	EventReady:                    func() any { return new(ReadyEvent) },
+	EventResumed:                  func() any { return new(ResumedEvent) },
+	EventInvalidSession:           func() any { return new(InvalidSessionEvent) },
+	EventHeartbeatAck:             func() any { return new(HeartbeatAckEvent) },
+	EventError:                    func() any { return new(ErrorEvent) },
+	EventSessionExpired:           func() any { return new(SessionExpiredEvent) },
+	EventMessageCreate:            func() any { return new(MessageCreateEvent) },
+	EventMessageUpdate:            func() any { return new(MessageUpdateEvent) },
+	EventMessageDelete:            func() any { return new(MessageDeleteEvent) },
+	EventMessageDeleteBulk:        func() any { return new(MessageDeleteBulkEvent) },
+	EventMessageRestore:           func() any { return new(MessageRestoreEvent) },
+	EventMessagePin:               func() any { return new(MessagePinEvent) },
+	EventMessageUnpin:             func() any { return new(MessageUnpinEvent) },
+	EventReactionAdd:              func() any { return new(ReactionEvent) },
+	EventReactionRemove:           func() any { return new(ReactionEvent) },
+	EventMentionCreate:            func() any { return new(MentionCreateEvent) },
+	EventNotification:             func() any { return new(NotificationEvent) },
+	EventUnreadUpdate:             func() any { return new(UnreadUpdateEvent) },
+	EventTypingStart:              func() any { return new(TypingStartEvent) },
+	EventPresenceUpdate:           func() any { return new(PresenceUpdateEvent) },
+	EventVoiceStateUpdate:         func() any { return new(VoiceStateUpdateEvent) },
+	EventUserProfileUpdate:        func() any { return new(UserProfileUpdateEvent) },
+	EventChannelCreate:            func() any { return new(ChannelCreateEvent) },
+	EventChannelUpdate:            func() any { return new(ChannelUpdateEvent) },
+	EventChannelDelete:            func() any { return new(ChannelDeleteEvent) },
+	EventCategoryCreate:           func() any { return new(CategoryCreateEvent) },
+	EventCategoryUpdate:           func() any { return new(CategoryUpdateEvent) },
+	EventCategoryDelete:           func() any { return new(CategoryDeleteEvent) },
+	EventServerUpdate:             func() any { return new(ServerUpdateEvent) },
+	EventMemberJoin:               func() any { return new(MemberJoinEvent) },
+	EventMemberLeave:              func() any { return new(MemberLeaveEvent) },
+	EventMemberUpdate:             func() any { return new(MemberUpdateEvent) },
+	EventMemberBan:                func() any { return new(MemberBanEvent) },
+	EventMemberTimeout:            func() any { return new(MemberTimeoutEvent) },
+	EventRoleCreate:               func() any { return new(RoleCreateEvent) },
+	EventRoleUpdate:               func() any { return new(RoleUpdateEvent) },
+	EventRoleDelete:               func() any { return new(RoleDeleteEvent) },
+	EventMemberRoleUpdate:         func() any { return new(MemberRoleUpdateEvent) },
+	EventDMMessageCreate:          func() any { return new(DMMessageCreateEvent) },
+	EventDMMessageUpdate:          func() any { return new(DMMessageUpdateEvent) },
+	EventDMMessageDelete:          func() any { return new(DMMessageDeleteEvent) },
+	EventDMMessagePin:             func() any { return new(DMMessagePinEvent) },
+	EventDMMessageUnpin:           func() any { return new(DMMessageUnpinEvent) },
+	EventDMReactionAdd:            func() any { return new(DMReactionEvent) },
+	EventDMReactionRemove:         func() any { return new(DMReactionEvent) },
+	EventDMParticipantAdd:         func() any { return new(DMParticipantAddEvent) },
+	EventDMParticipantRemove:      func() any { return new(DMParticipantRemoveEvent) },
+	EventDMConversationUpdate:     func() any { return new(DMConversationUpdateEvent) },
+	EventDMCallRing:               func() any { return new(DMCallRingEvent) },
+	EventDMCallAccept:             func() any { return new(DMCallAcceptEvent) },
+	EventDMCallEnd:                func() any { return new(DMCallEndEvent) },
+	EventFriendRequestCreate:      func() any { return new(FriendRequestCreateEvent) },
+	EventFriendRequestAccept:      func() any { return new(FriendRequestAcceptEvent) },
+	EventFriendRemove:             func() any { return new(FriendRemoveEvent) },
+	EventThreadCreate:             func() any { return new(ThreadCreateEvent) },
+	EventThreadUpdate:             func() any { return new(ThreadUpdateEvent) },
+	EventThreadMessageCreate:      func() any { return new(ThreadMessageCreateEvent) },
+	EventThreadMessageDelete:      func() any { return new(ThreadMessageDeleteEvent) },
+	EventPollCreate:               func() any { return new(PollCreateEvent) },
+	EventPollVote:                 func() any { return new(PollVoteEvent) },
+	EventStageStart:               func() any { return new(StageStartEvent) },
+	EventStageEnd:                 func() any { return new(StageEndEvent) },
+	EventStageSpeakerAdd:          func() any { return new(StageSpeakerAddEvent) },
+	EventStageSpeakerRemove:       func() any { return new(StageSpeakerRemoveEvent) },
+	EventStageHandRaise:           func() any { return new(StageHandRaiseEvent) },
+	EventStageHandLower:           func() any { return new(StageHandLowerEvent) },
+	EventForumPostCreate:          func() any { return new(ForumPostCreateEvent) },
+	EventForumPostDelete:          func() any { return new(ForumPostDeleteEvent) },
+	EventForumPostPin:             func() any { return new(ForumPostPinEvent) },
+	EventForumPostMessageCreate:   func() any { return new(ForumPostMessageCreateEvent) },
+	EventServerInvitationReceived: func() any { return new(ServerInvitationReceivedEvent) },
+	EventServerInvitationAccepted: func() any { return new(ServerInvitationAcceptedEvent) },
+	EventServerInvitationDeclined: func() any { return new(ServerInvitationDeclinedEvent) },
+	EventInteractionCreate:        func() any { return new(InteractionCreateEvent) },
+}
+
+// Client payloads.
+
+// IdentifyEvent opens a session. A nil Intents receives every event.
+type IdentifyEvent struct {
+	Token        string   `json:"token"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Intents      *int64   `json:"intents,omitempty"`
+}
+
+// ResumeEvent continues a dropped session from the last sequence number
+// received.
+type ResumeEvent struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Seq       int64  `json:"seq"`
+}
+
+type HeartbeatEvent struct{}
+
+// SubscribeEvent is the payload of SUBSCRIBE and UNSUBSCRIBE.
+type SubscribeEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+}
+
+type TokenRefreshEvent struct {
+	Token string `json:"token"`
+}
+
+// VoiceJoinEvent is the payload of VOICE_JOIN and VOICE_LEAVE.
+type VoiceJoinEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	ServerID  uuid.UUID `json:"server_id"`
+}
+
+type DMCallStartEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+// Session payloads.
+
+// ReadyEvent opens a session. The fields of ReadyState are only set for the
+// capabilities listed in IDENTIFY.
+type ReadyEvent struct {
+	SessionID      string          `json:"session_id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Username       string          `json:"username"`
+	Bot            bool            `json:"bot"`
+	OnlineUserIDs  []uuid.UUID     `json:"online_user_ids"`
+	UnreadCounts   []UnreadCount   `json:"unread_counts"`
+	DMUnreadCounts []DMUnreadCount `json:"dm_unread_counts"`
+	ReadyState
+}
+
+// ReadyState is the initial state snapshot carried by READY.
+type ReadyState struct {
+	Servers           []ReadyServer      `json:"servers"`
+	Folders           []ServerFolder     `json:"folders"`
+	Preferences       *UserPreferences   `json:"preferences"`
+	NotificationPrefs []NotificationPref `json:"notification_prefs"`
+	VoiceStates       []VoiceState       `json:"voice_states"`
+}
+
+// ReadyServer is a server with everything needed to render it.
+type ReadyServer struct {
+	Server
+	Channels      []Channel         `json:"channels"`
+	Categories    []ChannelCategory `json:"categories"`
+	Roles         []Role            `json:"roles"`
+	MemberRoleIDs []uuid.UUID       `json:"member_role_ids"`
+}
+
+type ResumedEvent struct{}
+
+type InvalidSessionEvent struct {
+	Reason string `json:"reason"`
+}
+
+type HeartbeatAckEvent struct{}
+
+// ErrorEvent reports a client event the server refused.
+type ErrorEvent struct {
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	Event        string `json:"event"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+// SessionExpiredEvent is sent before the server closes a session whose
+// token could not be refreshed.
+type SessionExpiredEvent struct {
+	Reason string `json:"reason"`
+}
+
+// Message payloads.
+
+// MessageCreateEvent is a new channel message.
+type MessageCreateEvent struct {
+	Message
+}
+
+func (e *MessageCreateEvent) UnmarshalJSON(b []byte) error {
+	type plain Message
+	aux := struct {
+		*plain
+		Username string `json:"username"`
+	}{plain: (*plain)(&e.Message)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if e.AuthorUsername == "" {
+		e.AuthorUsername = aux.Username
+	}
+	return nil
+}
+
+type MessageUpdateEvent struct {
+	Message
+}
+
+type MessageDeleteEvent struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+}
+
+// MessageDeleteBulkEvent is a moderator's bulk delete or purge of channel
+// messages.
+type MessageDeleteBulkEvent struct {
+	IDs       []uuid.UUID `json:"ids"`
+	ChannelID uuid.UUID   `json:"channel_id"`
+}
+
+// MessageRestoreEvent is a moderator undoing a message's deletion.
+type MessageRestoreEvent struct {
+	Message
+}
+
+type MessagePinEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	PinnedBy  uuid.UUID `json:"pinned_by"`
+}
+
+type MessageUnpinEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// ReactionEvent is the payload of REACTION_ADD and REACTION_REMOVE.
+type ReactionEvent struct {
+	MessageID uuid.UUID `json:"message_id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+}
+
+// MentionCreateEvent is sent to a user mentioned in a channel message.
+type MentionCreateEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	AuthorID  uuid.UUID `json:"author_id"`
+	Content   string    `json:"content"`
+	Username  string    `json:"username"`
+}
+
+// NotificationEvent asks the client to show a notification.
+type NotificationEvent struct {
+	Type      string    `json:"type"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	ServerID  uuid.UUID `json:"server_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	AuthorID  uuid.UUID `json:"author_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UnreadUpdateEvent struct {
+	UnreadCount
+}
+
+// TypingStartEvent is the broadcast form of TYPING_START.
+type TypingStartEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+}
+
+type PresenceUpdateEvent struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Status   string    `json:"status"`
+}
+
+// VoiceStateUpdateEvent reports a user joining (Joined) or leaving a voice
+// channel.
+type VoiceStateUpdateEvent struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	ServerID  uuid.UUID `json:"server_id"`
+	Joined    bool      `json:"joined"`
+	Muted     bool      `json:"muted"`
+	Deafened  bool      `json:"deafened"`
+}
+
+// UserProfileUpdateEvent is a co-member's updated public profile.
+type UserProfileUpdateEvent struct {
+	User
+}
+
+// Server payloads.
+
+type ChannelCreateEvent struct {
+	Channel
+}
+
+type ChannelUpdateEvent struct {
+	Channel
+}
+
+type ChannelDeleteEvent struct {
+	ID       uuid.UUID `json:"id"`
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+type CategoryCreateEvent struct {
+	ChannelCategory
+}
+
+type CategoryUpdateEvent struct {
+	ChannelCategory
+}
+
+type CategoryDeleteEvent struct {
+	ID       uuid.UUID `json:"id"`
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+type ServerUpdateEvent struct {
+	Server
+}
+
+type MemberJoinEvent struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+type MemberLeaveEvent struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+type MemberUpdateEvent struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Nickname *string   `json:"nickname"`
+}
+
+type MemberBanEvent struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+type MemberTimeoutEvent struct {
+	ServerID  uuid.UUID `json:"server_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Reason    string    `json:"reason"`
+}
+
+type RoleCreateEvent struct {
+	Role
+}
+
+// RoleUpdateEvent is a single updated role or, after a reorder, every role
+// of the server in Roles with only ServerID set on the embedded Role.
+type RoleUpdateEvent struct {
+	Role
+	Roles []Role `json:"roles,omitempty"`
+}
+
+type RoleDeleteEvent struct {
+	ID       uuid.UUID `json:"id"`
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+// MemberRoleUpdateEvent reports a role being assigned to ("assign") or
+// removed from ("remove") a member.
+type MemberRoleUpdateEvent struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	RoleID   uuid.UUID `json:"role_id"`
+	Action   string    `json:"action"`
+}
+
+// Direct message payloads.
+
+// DMMessageCreateEvent is a new DM. Content is ciphertext when Encrypted is
+// set.
+type DMMessageCreateEvent struct {
+	DMMessage
+	Encrypted bool `json:"encrypted"`
+}
+
+func (e *DMMessageCreateEvent) UnmarshalJSON(b []byte) error {
+	type plain DMMessage
+	aux := struct {
+		*plain
+		Username  string `json:"username"`
+		Encrypted bool   `json:"encrypted"`
+	}{plain: (*plain)(&e.DMMessage)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if e.AuthorUsername == "" {
+		e.AuthorUsername = aux.Username
+	}
+	e.Encrypted = aux.Encrypted
+	return nil
+}
+
+type DMMessageUpdateEvent struct {
+	DMMessage
+	Encrypted bool `json:"encrypted"`
+}
+
+type DMMessageDeleteEvent struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+type DMMessagePinEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	PinnedBy       uuid.UUID `json:"pinned_by"`
+}
+
+type DMMessageUnpinEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+}
+
+// DMReactionEvent is the payload of DM_REACTION_ADD and DM_REACTION_REMOVE.
+type DMReactionEvent struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Emoji          string    `json:"emoji"`
+}
+
+type DMParticipantAddEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	AddedBy        uuid.UUID `json:"added_by"`
+}
+
+type DMParticipantRemoveEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	RemovedBy      uuid.UUID `json:"removed_by"`
+}
+
+type DMConversationUpdateEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Name           *string   `json:"name"`
+}
+
+type DMCallRingEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CallerID       uuid.UUID `json:"caller_id"`
+	CallerUsername string    `json:"caller_username"`
+}
+
+type DMCallAcceptEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Username       string    `json:"username"`
+}
+
+type DMCallEndEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// Friend payloads.
+
+type FriendRequestCreateEvent struct {
+	ID          uuid.UUID `json:"id"`
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+	Status      string    `json:"status"`
+	Username    string    `json:"username"`
+}
+
+type FriendRequestAcceptEvent struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+type FriendRemoveEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Thread and poll payloads.
+
+type ThreadCreateEvent struct {
+	Thread
+}
+
+type ThreadUpdateEvent struct {
+	Thread
+}
+
+// ThreadMessageCreateEvent is a new thread message with the thread's parent
+// channel and updated message count.
+type ThreadMessageCreateEvent struct {
+	ThreadMessage
+	ChannelID    uuid.UUID `json:"channel_id"`
+	MessageCount int       `json:"message_count"`
+}
+
+type ThreadMessageDeleteEvent struct {
+	ID        uuid.UUID `json:"id"`
+	ThreadID  uuid.UUID `json:"thread_id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+}
+
+type PollCreateEvent struct {
+	Poll
+}
+
+type PollVoteEvent struct {
+	Poll
+}
+
+// Stage payloads.
+
+type StageStartEvent struct {
+	ChannelID uuid.UUID     `json:"channel_id"`
+	Instance  StageInstance `json:"instance"`
+	StartedBy uuid.UUID     `json:"started_by"`
+}
+
+type StageEndEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+}
+
+// StageSpeakerAddEvent reports a new speaker; Invited is set when they were
+// invited rather than joining the stage themselves.
+type StageSpeakerAddEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Invited   bool      `json:"invited"`
+}
+
+type StageSpeakerRemoveEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+type StageHandRaiseEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+}
+
+type StageHandLowerEvent struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// Forum payloads.
+
+type ForumPostCreateEvent struct {
+	ForumPost
+}
+
+type ForumPostDeleteEvent struct {
+	PostID    uuid.UUID `json:"post_id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+}
+
+type ForumPostPinEvent struct {
+	PostID    uuid.UUID `json:"post_id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	Pinned    bool      `json:"pinned"`
+}
+
+type ForumPostMessageCreateEvent struct {
+	PostID    uuid.UUID        `json:"post_id"`
+	ChannelID uuid.UUID        `json:"channel_id"`
+	Message   ForumPostMessage `json:"message"`
+}
+
+// Invitation payloads.
+
+type ServerInvitationReceivedEvent struct {
+	ServerInvitation
+}
+
+type ServerInvitationAcceptedEvent struct {
+	InvitationID uuid.UUID `json:"invitation_id"`
+	ServerID     uuid.UUID `json:"server_id"`
+	RecipientID  uuid.UUID `json:"recipient_id"`
+	Username     string    `json:"username"`
+}
+
+type ServerInvitationDeclinedEvent struct {
+	InvitationID uuid.UUID `json:"invitation_id"`
+	ServerID     uuid.UUID `json:"server_id"`
+	RecipientID  uuid.UUID `json:"recipient_id"`
+}
+
+// InteractionCreateEvent delivers a command or component interaction to a
+// bot connected to the gateway. Answer it with RespondToInteraction using
+// ID and Token.
+type InteractionCreateEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	Token     string          `json:"token"`
+	BotID     *uuid.UUID      `json:"bot_id"`
+	WebhookID *uuid.UUID      `json:"webhook_id"`
+	MessageID *uuid.UUID      `json:"message_id"`
+	ServerID  uuid.UUID       `json:"server_id"`
+	ChannelID uuid.UUID       `json:"channel_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Username  string          `json:"username"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// CommandData decodes Data of an InteractionCommand interaction.
+func (e *InteractionCreateEvent) CommandData() (*CommandInteractionData, error) {
+	var d CommandInteractionData
+	if err := json.Unmarshal(e.Data, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ComponentData decodes Data of an InteractionComponent interaction.
+func (e *InteractionCreateEvent) ComponentData() (*ComponentInteractionData, error) {
+	var d ComponentInteractionData
+	if err := json.Unmarshal(e.Data, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,47 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ListFriends returns accepted friendships with the other user's profile.
+func (c *Client) ListFriends(ctx context.Context) ([]Friendship, error) {
+	return callList[Friendship](ctx, c, http.MethodGet, apiPath("friends"), nil)
+}
+
+// ListFriendRequests returns pending requests sent and received.
+func (c *Client) ListFriendRequests(ctx context.Context) ([]Friendship, error) {
+	return callList[Friendship](ctx, c, http.MethodGet, apiPath("friends", "requests"), nil)
+}
+
+func (c *Client) SendFriendRequest(ctx context.Context, username string) (*Friendship, error) {
+	return call[Friendship](ctx, c, http.MethodPost, apiPath("friends", "request"), map[string]string{"username": username})
+}
+
+func (c *Client) AcceptFriendRequest(ctx context.Context, friendshipID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("friends", friendshipID.String(), "accept"), nil, nil)
+}
+
+func (c *Client) DeclineFriendRequest(ctx context.Context, friendshipID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("friends", friendshipID.String(), "decline"), nil, nil)
+}
+
+func (c *Client) RemoveFriend(ctx context.Context, friendshipID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("friends", friendshipID.String()), nil, nil)
+}
+
+// ListBlockedUsers returns the IDs of users the caller has blocked.
+func (c *Client) ListBlockedUsers(ctx context.Context) ([]uuid.UUID, error) {
+	return callList[uuid.UUID](ctx, c, http.MethodGet, apiPath("users", "blocked"), nil)
+}
+
+func (c *Client) BlockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("users", userID.String(), "block"), nil, nil)
+}
+
+func (c *Client) UnblockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("users", userID.String(), "block"), nil, nil)
+}
@@ -0,0 +1,467 @@
+package thicket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/google/uuid"
+)
+
+// Gateway intents select the event categories a connection receives.
+// Events outside these categories (READY, membership, channel and role
+// changes, notifications, ...) are always delivered.
+const (
+	IntentServerMessages int64 = 1 << 0
+	IntentDirectMessages int64 = 1 << 1
+	IntentPresence       int64 = 1 << 2
+	IntentTyping         int64 = 1 << 3
+	IntentVoice          int64 = 1 << 4
+	IntentReactions      int64 = 1 << 5
+	IntentModeration     int64 = 1 << 6
+	IntentStage          int64 = 1 << 7
+	IntentForum          int64 = 1 << 8
+)
+
+// Capabilities request initial state in READY.
+const (
+	CapServers           = "servers"            // servers with channels, categories, roles and own role IDs
+	CapFolders           = "folders"            // server folders
+	CapPreferences       = "preferences"        // user preferences
+	CapNotificationPrefs = "notification_prefs" // notification preferences
+	CapVoiceStates       = "voice_states"       // voice states in the user's servers
+)
+
+// ErrNotConnected is returned when sending on a gateway without an open
+// connection.
+var ErrNotConnected = errors.New("thicket: gateway not connected")
+
+// errInvalidSession ends a connection whose RESUME was refused.
+var errInvalidSession = errors.New("thicket: invalid session")
+
+// Gateway is a connection to the real-time gateway. Configure it and
+// register handlers before calling Run.
+type Gateway struct {
+	// URL is the gateway endpoint; it defaults to /ws on the API host.
+	URL string
+	// Origin is sent in the WebSocket handshake and must be one of the
+	// server's allowed origins. It defaults to the API base URL.
+	Origin string
+	// Intents selects the event categories to receive; zero receives all.
+	Intents int64
+	// Capabilities request initial state in READY, see the Cap* constants.
+	Capabilities []string
+	// HeartbeatInterval is the time between heartbeats; it defaults to 30s.
+	// A heartbeat left unacknowledged for a full interval drops the
+	// connection.
+	HeartbeatInterval time.Duration
+	// ReconnectDelay is the first delay before reconnecting, doubled on each
+	// failed attempt up to MaxReconnectDelay. They default to 1s and 30s.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// Dialer opens connections; it defaults to websocket.DefaultDialer.
+	Dialer *websocket.Dialer
+
+	client *Client
+
+	handlersMu sync.RWMutex
+	handlers   []func(*Event)
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+
+	mu            sync.Mutex
+	sessionID     string
+	seq           int64
+	subscriptions map[uuid.UUID]struct{}
+}
+
+// Gateway returns a gateway that authenticates with the client's
+// credentials.
+func (c *Client) Gateway() *Gateway {
+	return &Gateway{client: c, subscriptions: make(map[uuid.UUID]struct{})}
+}
+
+// OnEvent registers a handler for every event, including HEARTBEAT_ACK.
+// Handlers run one at a time, in order, on the goroutine calling Run.
+func (g *Gateway) OnEvent(fn func(*Event)) {
+	g.handlersMu.Lock()
+	defer g.handlersMu.Unlock()
+	g.handlers = append(g.handlers, fn)
+}
+
+// On registers a typed handler for one event type. T must be the payload
+// type Event.Decode returns for it, such as MessageCreateEvent for
+// EventMessageCreate; On panics otherwise. Events that fail to decode are
+// skipped.
+func On[T any](g *Gateway, eventType string, fn func(*T)) {
+	newPayload, ok := eventPayloads[eventType]
+	if !ok {
+		panic("thicket: unknown event type " + eventType)
+	}
+	if _, ok := newPayload().(*T); !ok {
+		panic(fmt.Sprintf("thicket: %s does not decode to %T", eventType, new(T)))
+	}
+	g.OnEvent(func(e *Event) {
+		if e.Type != eventType {
+			return
+		}
+		v, err := e.Decode()
+		if err != nil {
+			return
+		}
+		fn(v.(*T))
+	})
+}
+
+func (g *Gateway) OnReady(fn func(*ReadyEvent)) { On(g, EventReady, fn) }
+
+func (g *Gateway) OnMessageCreate(fn func(*MessageCreateEvent)) { On(g, EventMessageCreate, fn) }
+
+func (g *Gateway) OnMessageUpdate(fn func(*MessageUpdateEvent)) { On(g, EventMessageUpdate, fn) }
+
+func (g *Gateway) OnMessageDelete(fn func(*MessageDeleteEvent)) { On(g, EventMessageDelete, fn) }
+
+func (g *Gateway) OnMessageDeleteBulk(fn func(*MessageDeleteBulkEvent)) {
+	On(g, EventMessageDeleteBulk, fn)
+}
+
+func (g *Gateway) OnMessageRestore(fn func(*MessageRestoreEvent)) { On(g, EventMessageRestore, fn) }
+
+func (g *Gateway) OnDMMessageCreate(fn func(*DMMessageCreateEvent)) { On(g, EventDMMessageCreate, fn) }
+
+func (g *Gateway) OnThreadMessageCreate(fn func(*ThreadMessageCreateEvent)) {
+	On(g, EventThreadMessageCreate, fn)
+}
+
+func (g *Gateway) OnInteractionCreate(fn func(*InteractionCreateEvent)) {
+	On(g, EventInteractionCreate, fn)
+}
+
+// OnError registers a handler for ERROR events, sent when the server
+// refuses a client event.
+func (g *Gateway) OnError(fn func(*ErrorEvent)) { On(g, EventError, fn) }
+
+// SessionID returns the current session ID, empty before READY.
+func (g *Gateway) SessionID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sessionID
+}
+
+// Seq returns the sequence number of the last event received.
+func (g *Gateway) Seq() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.seq
+}
+
+// Run connects to the gateway and dispatches events until ctx is done. A
+// dropped connection is resumed, replaying missed events, or re-identified
+// when the session is gone. Run returns ctx's error, or the *APIError when
+// the client's credentials are rejected.
+func (g *Gateway) Run(ctx context.Context) error {
+	initial := g.ReconnectDelay
+	if initial <= 0 {
+		initial = time.Second
+	}
+	maxDelay := g.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+
+	delay := initial
+	for {
+		established, err := g.connect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, errInvalidSession) {
+			continue
+		}
+		if established {
+			delay = initial
+		} else if _, err := g.client.GetMe(ctx); err != nil {
+			// The server closes unauthenticated connections without a
+			// reply; ask the API whether the credentials are to blame
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+				return err
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// connect runs a single connection until it closes. established reports
+// whether the session was opened or resumed.
+func (g *Gateway) connect(ctx context.Context) (established bool, err error) {
+	dialer := g.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	header := http.Header{}
+	header.Set("Origin", g.origin())
+	conn, _, err := dialer.DialContext(ctx, g.url(), header)
+	if err != nil {
+		return false, err
+	}
+
+	g.writeMu.Lock()
+	g.conn = conn
+	g.writeMu.Unlock()
+	defer func() {
+		g.writeMu.Lock()
+		g.conn = nil
+		g.writeMu.Unlock()
+		conn.Close()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	g.mu.Lock()
+	sessionID, seq := g.sessionID, g.seq
+	g.mu.Unlock()
+	if sessionID != "" {
+		err = g.Send(EventResume, ResumeEvent{Token: g.client.gatewayToken(), SessionID: sessionID, Seq: seq})
+	} else {
+		identify := IdentifyEvent{Token: g.client.gatewayToken(), Capabilities: g.Capabilities}
+		if g.Intents != 0 {
+			intents := g.Intents
+			identify.Intents = &intents
+		}
+		err = g.Send(EventIdentify, identify)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var acked atomic.Bool
+	acked.Store(true)
+	heartbeating := false
+	startHeartbeat := func() {
+		if !heartbeating {
+			heartbeating = true
+			go g.heartbeat(conn, &acked, done)
+		}
+	}
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return established, err
+		}
+		var event Event
+		if err := json.Unmarshal(raw, &event); err != nil {
+			continue
+		}
+		if event.Seq > 0 {
+			g.mu.Lock()
+			g.seq = event.Seq
+			g.mu.Unlock()
+		}
+
+		switch event.Type {
+		case EventReady:
+			var ready struct {
+				SessionID string `json:"session_id"`
+			}
+			if err := json.Unmarshal(event.Data, &ready); err == nil {
+				g.mu.Lock()
+				g.sessionID = ready.SessionID
+				g.mu.Unlock()
+			}
+			established = true
+			startHeartbeat()
+			// A new session starts without channel subscriptions
+			g.resubscribe()
+		case EventResumed:
+			established = true
+			startHeartbeat()
+		case EventInvalidSession:
+			g.mu.Lock()
+			g.sessionID = ""
+			g.seq = 0
+			g.mu.Unlock()
+			g.dispatch(&event)
+			return false, errInvalidSession
+		case EventHeartbeatAck:
+			acked.Store(true)
+		}
+		g.dispatch(&event)
+	}
+}
+
+// heartbeat sends HEARTBEAT until done, closing conn when the previous one
+// went unacknowledged.
+func (g *Gateway) heartbeat(conn *websocket.Conn, acked *atomic.Bool, done <-chan struct{}) {
+	interval := g.HeartbeatInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !acked.Swap(false) {
+				conn.Close()
+				return
+			}
+			if err := g.Send(EventHeartbeat, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (g *Gateway) dispatch(event *Event) {
+	g.handlersMu.RLock()
+	handlers := g.handlers
+	g.handlersMu.RUnlock()
+	for _, fn := range handlers {
+		fn(event)
+	}
+}
+
+func (g *Gateway) resubscribe() {
+	g.mu.Lock()
+	ids := make([]uuid.UUID, 0, len(g.subscriptions))
+	for id := range g.subscriptions {
+		ids = append(ids, id)
+	}
+	g.mu.Unlock()
+	for _, id := range ids {
+		g.Send(EventSubscribe, SubscribeEvent{ChannelID: id})
+	}
+}
+
+// Send sends a client event. It returns ErrNotConnected when no connection
+// is open.
+func (g *Gateway) Send(eventType string, data any) error {
+	event := Event{Type: eventType}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		event.Data = raw
+	}
+	frame, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	if g.conn == nil {
+		return ErrNotConnected
+	}
+	return g.conn.WriteMessage(websocket.TextMessage, frame)
+}
+
+// Subscribe starts receiving a channel's messages and typing events. The
+// subscription is restored when the gateway reconnects, so it may be called
+// before Run.
+func (g *Gateway) Subscribe(channelID uuid.UUID) error {
+	g.mu.Lock()
+	g.subscriptions[channelID] = struct{}{}
+	g.mu.Unlock()
+	if err := g.Send(EventSubscribe, SubscribeEvent{ChannelID: channelID}); err != nil && !errors.Is(err, ErrNotConnected) {
+		return err
+	}
+	return nil
+}
+
+func (g *Gateway) Unsubscribe(channelID uuid.UUID) error {
+	g.mu.Lock()
+	delete(g.subscriptions, channelID)
+	g.mu.Unlock()
+	if err := g.Send(EventUnsubscribe, SubscribeEvent{ChannelID: channelID}); err != nil && !errors.Is(err, ErrNotConnected) {
+		return err
+	}
+	return nil
+}
+
+// StartTyping shows the caller as typing in a subscribed channel.
+func (g *Gateway) StartTyping(channelID uuid.UUID) error {
+	return g.Send(EventTypingStart, SubscribeEvent{ChannelID: channelID})
+}
+
+func (g *Gateway) JoinVoice(serverID, channelID uuid.UUID) error {
+	return g.Send(EventVoiceJoin, VoiceJoinEvent{ChannelID: channelID, ServerID: serverID})
+}
+
+func (g *Gateway) LeaveVoice(serverID, channelID uuid.UUID) error {
+	return g.Send(EventVoiceLeave, VoiceJoinEvent{ChannelID: channelID, ServerID: serverID})
+}
+
+func (g *Gateway) StartDMCall(conversationID uuid.UUID) error {
+	return g.Send(EventDMCallStart, DMCallStartEvent{ConversationID: conversationID})
+}
+
+func (g *Gateway) AcceptDMCall(conversationID uuid.UUID) error {
+	return g.Send(EventDMCallAccept, DMCallStartEvent{ConversationID: conversationID})
+}
+
+func (g *Gateway) EndDMCall(conversationID uuid.UUID) error {
+	return g.Send(EventDMCallEnd, DMCallStartEvent{ConversationID: conversationID})
+}
+
+// RefreshToken replaces the access token of the open session and of the
+// client. The server closes the session if the token is invalid.
+func (g *Gateway) RefreshToken(accessToken string) error {
+	g.client.SetToken(accessToken)
+	return g.Send(EventTokenRefresh, TokenRefreshEvent{Token: accessToken})
+}
+
+func (g *Gateway) url() string {
+	if g.URL != "" {
+		return g.URL
+	}
+	base := g.client.BaseURL()
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		base = "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base + "/ws?" + url.Values{"encoding": {"json"}}.Encode()
+}
+
+func (g *Gateway) origin() string {
+	if g.Origin != "" {
+		return g.Origin
+	}
+	u, err := url.Parse(g.client.BaseURL())
+	if err != nil {
+		return g.client.BaseURL()
+	}
+	return u.Scheme + "://" + u.Host
+}
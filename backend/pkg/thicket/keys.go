@@ -0,0 +1,92 @@
+package thicket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RegisterIdentityKey publishes the public identity key of one of the
+// caller's devices.
+func (c *Client) RegisterIdentityKey(ctx context.Context, deviceID string, publicKeyJWK json.RawMessage) (*IdentityKey, error) {
+	return call[IdentityKey](ctx, c, http.MethodPost, apiPath("keys", "identity"), map[string]any{
+		"device_id":      deviceID,
+		"public_key_jwk": publicKeyJWK,
+	})
+}
+
+func (c *Client) GetMyIdentityKeys(ctx context.Context) ([]IdentityKey, error) {
+	return callList[IdentityKey](ctx, c, http.MethodGet, apiPath("keys", "identity"), nil)
+}
+
+func (c *Client) GetIdentityKeys(ctx context.Context, userID uuid.UUID) ([]IdentityKey, error) {
+	return callList[IdentityKey](ctx, c, http.MethodGet, apiPath("keys", "identity", userID.String()), nil)
+}
+
+func (c *Client) RemoveDeviceKey(ctx context.Context, deviceID string) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("keys", "identity", "devices", deviceID), nil, nil)
+}
+
+// StoreKeyEnvelope stores the caller's encrypted private key backup,
+// replacing any previous one.
+func (c *Client) StoreKeyEnvelope(ctx context.Context, envelope []byte) error {
+	return c.Do(ctx, http.MethodPut, apiPath("keys", "envelope"), map[string][]byte{"envelope": envelope}, nil)
+}
+
+func (c *Client) GetKeyEnvelope(ctx context.Context) (*KeyEnvelope, error) {
+	return call[KeyEnvelope](ctx, c, http.MethodGet, apiPath("keys", "envelope"), nil)
+}
+
+func (c *Client) DeleteKeyEnvelope(ctx context.Context) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("keys", "envelope"), nil, nil)
+}
+
+// StoreGroupKey stores a group DM key for an epoch, encrypted for one
+// participant.
+func (c *Client) StoreGroupKey(ctx context.Context, conversationID uuid.UUID, epoch int, userID uuid.UUID, encryptedKey []byte) error {
+	return c.Do(ctx, http.MethodPost, apiPath("keys", "group", conversationID.String()), map[string]any{
+		"epoch":         epoch,
+		"user_id":       userID.String(),
+		"encrypted_key": encryptedKey,
+	}, nil)
+}
+
+// GetGroupKeys returns the caller's keys for a group DM, one per epoch.
+func (c *Client) GetGroupKeys(ctx context.Context, conversationID uuid.UUID) ([]GroupKey, error) {
+	return callList[GroupKey](ctx, c, http.MethodGet, apiPath("keys", "group", conversationID.String()), nil)
+}
+
+// InitiateUpload starts a direct-to-storage multipart upload for a file too
+// large to send with a message.
+func (c *Client) InitiateUpload(ctx context.Context, filename, contentType string, fileSize int64) (*MultipartUpload, error) {
+	return call[MultipartUpload](ctx, c, http.MethodPost, apiPath("uploads", "initiate"), map[string]any{
+		"filename":     filename,
+		"content_type": contentType,
+		"file_size":    fileSize,
+	})
+}
+
+// ReportUploadPart records a part that was PUT to its presigned URL, with
+// the ETag storage returned for it.
+func (c *Client) ReportUploadPart(ctx context.Context, uploadID uuid.UUID, partNumber int, etag string) error {
+	return c.Do(ctx, http.MethodPost, apiPath("uploads", uploadID.String(), "part-complete"), map[string]any{
+		"part_number": partNumber,
+		"etag":        etag,
+	}, nil)
+}
+
+// CompleteUpload finishes an upload and attaches the file to a channel
+// message or, with dm set, a DM message.
+func (c *Client) CompleteUpload(ctx context.Context, uploadID, messageID uuid.UUID, dm bool) (*Attachment, error) {
+	body := map[string]string{"message_id": messageID.String()}
+	if dm {
+		body = map[string]string{"dm_message_id": messageID.String()}
+	}
+	return call[Attachment](ctx, c, http.MethodPost, apiPath("uploads", uploadID.String(), "complete"), body)
+}
+
+func (c *Client) AbortUpload(ctx context.Context, uploadID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("uploads", uploadID.String()), nil, nil)
+}
@@ -0,0 +1,103 @@
+package thicket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) ListEmojis(ctx context.Context, serverID uuid.UUID) ([]CustomEmoji, error) {
+	return callList[CustomEmoji](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "emojis"), nil)
+}
+
+func (c *Client) CreateEmoji(ctx context.Context, serverID uuid.UUID, name string, image File) (*CustomEmoji, error) {
+	var e CustomEmoji
+	if err := c.doMultipart(ctx, http.MethodPost, apiPath("servers", serverID.String(), "emojis"),
+		map[string]string{"name": name}, "image", []File{image}, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (c *Client) DeleteEmoji(ctx context.Context, serverID, emojiID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "emojis", emojiID.String()), nil, nil)
+}
+
+func (c *Client) ListSounds(ctx context.Context, serverID uuid.UUID) ([]SoundboardSound, error) {
+	return callList[SoundboardSound](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "soundboard"), nil)
+}
+
+func (c *Client) CreateSound(ctx context.Context, serverID uuid.UUID, name string, durationMs int, sound File) (*SoundboardSound, error) {
+	fields := map[string]string{"name": name}
+	if durationMs > 0 {
+		fields["duration_ms"] = strconv.Itoa(durationMs)
+	}
+	var s SoundboardSound
+	if err := c.doMultipart(ctx, http.MethodPost, apiPath("servers", serverID.String(), "soundboard"), fields, "sound", []File{sound}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (c *Client) DeleteSound(ctx context.Context, serverID, soundID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "soundboard", soundID.String()), nil, nil)
+}
+
+// SearchGifs proxies a GIPHY search and returns GIPHY's response unchanged.
+func (c *Client) SearchGifs(ctx context.Context, query string, limit, offset int) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := c.Do(ctx, http.MethodGet, withQuery(apiPath("gifs", "search"), url.Values{
+		"q":      {query},
+		"limit":  {itoa(limit)},
+		"offset": {itoa(offset)},
+	}), nil, &raw)
+	return raw, err
+}
+
+// TrendingGifs proxies GIPHY's trending GIFs and returns GIPHY's response
+// unchanged.
+func (c *Client) TrendingGifs(ctx context.Context, limit, offset int) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := c.Do(ctx, http.MethodGet, withQuery(apiPath("gifs", "trending"), url.Values{
+		"limit":  {itoa(limit)},
+		"offset": {itoa(offset)},
+	}), nil, &raw)
+	return raw, err
+}
+
+func (c *Client) StartStage(ctx context.Context, channelID uuid.UUID, topic string) (*StageInstance, error) {
+	return call[StageInstance](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "stage"), map[string]string{"topic": topic})
+}
+
+func (c *Client) EndStage(ctx context.Context, channelID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "stage"), nil, nil)
+}
+
+func (c *Client) GetStage(ctx context.Context, channelID uuid.UUID) (*StageInfo, error) {
+	return call[StageInfo](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "stage"), nil)
+}
+
+// BecomeSpeaker moves the caller onto the stage.
+func (c *Client) BecomeSpeaker(ctx context.Context, channelID uuid.UUID) (*StageSpeaker, error) {
+	return call[StageSpeaker](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "stage", "speakers"), nil)
+}
+
+func (c *Client) RemoveSpeaker(ctx context.Context, channelID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "stage", "speakers", userID.String()), nil, nil)
+}
+
+func (c *Client) RaiseHand(ctx context.Context, channelID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("channels", channelID.String(), "stage", "hand-raise"), nil, nil)
+}
+
+func (c *Client) LowerHand(ctx context.Context, channelID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "stage", "hand-raise"), nil, nil)
+}
+
+func (c *Client) InviteToSpeak(ctx context.Context, channelID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("channels", channelID.String(), "stage", "invite", userID.String()), nil, nil)
+}
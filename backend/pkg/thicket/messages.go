@@ -0,0 +1,287 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SendMessageParams is a message to send to a channel. Components may only
+// be sent by bots. Messages with files are sent as multipart/form-data and
+// cannot carry components. Resending with the same Nonce within a few
+// minutes returns the original message instead of sending another, and the
+// nonce is echoed in MESSAGE_CREATE.
+type SendMessageParams struct {
+	Content    string
+	Type       string
+	ReplyToID  *uuid.UUID
+	Components []ActionRow
+	Files      []File
+	Nonce      string
+}
+
+func (c *Client) SendMessage(ctx context.Context, channelID uuid.UUID, params SendMessageParams) (*Message, error) {
+	path := apiPath("channels", channelID.String(), "messages")
+	if len(params.Files) > 0 {
+		fields := map[string]string{"content": params.Content}
+		if params.Type != "" {
+			fields["type"] = params.Type
+		}
+		if params.ReplyToID != nil {
+			fields["reply_to_id"] = params.ReplyToID.String()
+		}
+		if params.Nonce != "" {
+			fields["nonce"] = params.Nonce
+		}
+		var m Message
+		if err := c.doMultipart(ctx, http.MethodPost, path, fields, "files[]", params.Files, &m); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+
+	body := struct {
+		Content    string      `json:"content"`
+		Type       string      `json:"type,omitempty"`
+		ReplyToID  *uuid.UUID  `json:"reply_to_id,omitempty"`
+		Components []ActionRow `json:"components,omitempty"`
+		Nonce      string      `json:"nonce,omitempty"`
+	}{params.Content, params.Type, params.ReplyToID, params.Components, params.Nonce}
+	return call[Message](ctx, c, http.MethodPost, path, body)
+}
+
+// HistoryParams pages through message history, newest first. Set at most
+// one of BeforeID, AfterID and AroundID to page from a message, or Before to
+// page from a point in time; with none set, paging starts from the latest
+// message. A zero Limit uses the server default.
+type HistoryParams struct {
+	BeforeID *uuid.UUID
+	AfterID  *uuid.UUID
+	AroundID *uuid.UUID
+	Before   time.Time
+	Limit    int
+}
+
+func (p HistoryParams) query() url.Values {
+	q := url.Values{"limit": {itoa(p.Limit)}}
+	switch {
+	case p.BeforeID != nil:
+		q.Set("before", p.BeforeID.String())
+	case p.AfterID != nil:
+		q.Set("after", p.AfterID.String())
+	case p.AroundID != nil:
+		q.Set("around", p.AroundID.String())
+	case !p.Before.IsZero():
+		q.Set("before", p.Before.UTC().Format(time.RFC3339Nano))
+	}
+	return q
+}
+
+// GetMessages returns a page of channel history.
+func (c *Client) GetMessages(ctx context.Context, channelID uuid.UUID, params HistoryParams) ([]Message, error) {
+	return callList[Message](ctx, c, http.MethodGet, withQuery(apiPath("channels", channelID.String(), "messages"), params.query()), nil)
+}
+
+// GetMessagesAround returns up to limit messages on each side of a point in
+// time, for jumping to a message.
+func (c *Client) GetMessagesAround(ctx context.Context, channelID uuid.UUID, at time.Time, limit int) ([]Message, error) {
+	return callList[Message](ctx, c, http.MethodGet, withQuery(apiPath("channels", channelID.String(), "messages", "around"), url.Values{
+		"timestamp": {at.UTC().Format(time.RFC3339Nano)},
+		"limit":     {itoa(limit)},
+	}), nil)
+}
+
+func (c *Client) EditMessage(ctx context.Context, messageID uuid.UUID, content string) (*Message, error) {
+	return call[Message](ctx, c, http.MethodPut, apiPath("messages", messageID.String()), map[string]string{"content": content})
+}
+
+func (c *Client) DeleteMessage(ctx context.Context, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("messages", messageID.String()), nil, nil)
+}
+
+// BulkDeleteMessages deletes up to 250 messages from a channel and returns
+// the IDs deleted. Requires PermManageMessages.
+func (c *Client) BulkDeleteMessages(ctx context.Context, channelID uuid.UUID, messageIDs []uuid.UUID, reason string) ([]uuid.UUID, error) {
+	body := struct {
+		MessageIDs []uuid.UUID `json:"message_ids"`
+		Reason     string      `json:"reason,omitempty"`
+	}{messageIDs, reason}
+	var out struct {
+		Deleted []uuid.UUID `json:"deleted"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("channels", channelID.String(), "messages", "bulk-delete"), body, &out); err != nil {
+		return nil, err
+	}
+	return out.Deleted, nil
+}
+
+// PurgeParams selects the channel messages a purge deletes. At least one of
+// AuthorID, After, Before and Contains must be set. Up to Limit (at most 250)
+// of the newest matching messages are deleted.
+type PurgeParams struct {
+	AuthorID *uuid.UUID `json:"author_id,omitempty"`
+	After    *time.Time `json:"after,omitempty"`
+	Before   *time.Time `json:"before,omitempty"`
+	Contains string     `json:"contains,omitempty"`
+	Limit    int        `json:"limit,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// PurgeMessages deletes matching channel messages and returns the IDs
+// deleted. Requires PermManageMessages.
+func (c *Client) PurgeMessages(ctx context.Context, channelID uuid.UUID, params PurgeParams) ([]uuid.UUID, error) {
+	var out struct {
+		Deleted []uuid.UUID `json:"deleted"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("channels", channelID.String(), "messages", "purge"), params, &out); err != nil {
+		return nil, err
+	}
+	return out.Deleted, nil
+}
+
+// GetDeletedMessages returns a channel's deleted messages, most recently
+// deleted first, with their content. before, if not zero, pages back by
+// deletion time. Requires PermManageMessages.
+func (c *Client) GetDeletedMessages(ctx context.Context, channelID uuid.UUID, before time.Time, limit int) ([]Message, error) {
+	q := url.Values{}
+	if !before.IsZero() {
+		q.Set("before", before.UTC().Format(time.RFC3339Nano))
+	}
+	if limit > 0 {
+		q.Set("limit", itoa(limit))
+	}
+	return callList[Message](ctx, c, http.MethodGet, withQuery(apiPath("channels", channelID.String(), "messages", "deleted"), q), nil)
+}
+
+// RestoreMessage undoes a message's deletion. Messages can be restored for
+// 30 days after they are deleted. Requires PermManageMessages.
+func (c *Client) RestoreMessage(ctx context.Context, messageID uuid.UUID) (*Message, error) {
+	return call[Message](ctx, c, http.MethodPost, apiPath("messages", messageID.String(), "restore"), nil)
+}
+
+// GetMessageEdits returns the previous versions of an edited message.
+func (c *Client) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]MessageEdit, error) {
+	return callList[MessageEdit](ctx, c, http.MethodGet, apiPath("messages", messageID.String(), "edits"), nil)
+}
+
+func (c *Client) PinMessage(ctx context.Context, channelID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPut, apiPath("channels", channelID.String(), "pins", messageID.String()), nil, nil)
+}
+
+func (c *Client) UnpinMessage(ctx context.Context, channelID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "pins", messageID.String()), nil, nil)
+}
+
+func (c *Client) GetPinnedMessages(ctx context.Context, channelID uuid.UUID) ([]Message, error) {
+	return callList[Message](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "pins"), nil)
+}
+
+func (c *Client) AddReaction(ctx context.Context, messageID uuid.UUID, emoji string) error {
+	return c.Do(ctx, http.MethodPut, withQuery(apiPath("messages", messageID.String(), "reactions"), url.Values{"emoji": {emoji}}), nil, nil)
+}
+
+func (c *Client) RemoveReaction(ctx context.Context, messageID uuid.UUID, emoji string) error {
+	return c.Do(ctx, http.MethodDelete, withQuery(apiPath("messages", messageID.String(), "reactions"), url.Values{"emoji": {emoji}}), nil, nil)
+}
+
+// PollOptionParams is one answer of a new poll.
+type PollOptionParams struct {
+	Text  string `json:"text"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+type CreatePollParams struct {
+	Question    string
+	Options     []PollOptionParams
+	MultiSelect bool
+	Anonymous   bool
+	ExpiresAt   *time.Time
+}
+
+func (c *Client) CreatePoll(ctx context.Context, channelID uuid.UUID, params CreatePollParams) (*Poll, error) {
+	body := map[string]any{
+		"question":     params.Question,
+		"options":      params.Options,
+		"multi_select": params.MultiSelect,
+		"anonymous":    params.Anonymous,
+	}
+	if params.ExpiresAt != nil {
+		body["expires_at"] = params.ExpiresAt.Format(time.RFC3339)
+	}
+	return call[Poll](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "polls"), body)
+}
+
+func (c *Client) GetPoll(ctx context.Context, pollID uuid.UUID) (*Poll, error) {
+	return call[Poll](ctx, c, http.MethodGet, apiPath("polls", pollID.String()), nil)
+}
+
+func (c *Client) Vote(ctx context.Context, pollID, optionID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("polls", pollID.String(), "vote"), map[string]string{"option_id": optionID.String()}, nil)
+}
+
+func (c *Client) RemoveVote(ctx context.Context, pollID, optionID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("polls", pollID.String(), "vote", optionID.String()), nil, nil)
+}
+
+// SearchParams filters a message search. Zero values are not applied.
+type SearchParams struct {
+	Query         string
+	ChannelID     *uuid.UUID
+	ServerID      *uuid.UUID
+	AuthorID      *uuid.UUID
+	Before        time.Time
+	Limit         int
+	HasAttachment bool
+	HasLink       bool
+	DateFrom      time.Time
+	DateTo        time.Time
+}
+
+func (c *Client) SearchMessages(ctx context.Context, params SearchParams) ([]Message, error) {
+	q := url.Values{"q": {params.Query}, "limit": {itoa(params.Limit)}}
+	if params.ChannelID != nil {
+		q.Set("channel_id", params.ChannelID.String())
+	}
+	if params.ServerID != nil {
+		q.Set("server_id", params.ServerID.String())
+	}
+	if params.AuthorID != nil {
+		q.Set("author_id", params.AuthorID.String())
+	}
+	if !params.Before.IsZero() {
+		q.Set("before", params.Before.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.DateFrom.IsZero() {
+		q.Set("date_from", params.DateFrom.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.DateTo.IsZero() {
+		q.Set("date_to", params.DateTo.UTC().Format(time.RFC3339Nano))
+	}
+	if params.HasAttachment {
+		q.Set("has_attachment", "true")
+	}
+	if params.HasLink {
+		q.Set("has_link", "true")
+	}
+	return callList[Message](ctx, c, http.MethodGet, withQuery(apiPath("search", "messages"), q), nil)
+}
+
+// SearchDMs searches the caller's direct messages, optionally in one
+// conversation.
+func (c *Client) SearchDMs(ctx context.Context, query string, conversationID *uuid.UUID, before time.Time, limit int) ([]DMMessage, error) {
+	q := url.Values{"q": {query}, "limit": {itoa(limit)}}
+	if conversationID != nil {
+		q.Set("conversation_id", conversationID.String())
+	}
+	if !before.IsZero() {
+		q.Set("before", before.UTC().Format(time.RFC3339Nano))
+	}
+	return callList[DMMessage](ctx, c, http.MethodGet, withQuery(apiPath("search", "dm"), q), nil)
+}
+
+func (c *Client) GetLinkPreview(ctx context.Context, rawURL string) (*LinkPreview, error) {
+	return call[LinkPreview](ctx, c, http.MethodGet, withQuery(apiPath("link-preview"), url.Values{"url": {rawURL}}), nil)
+}
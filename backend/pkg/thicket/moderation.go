@@ -0,0 +1,97 @@
+package thicket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) BanUser(ctx context.Context, serverID, userID uuid.UUID, reason string) (*Ban, error) {
+	return call[Ban](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "bans"), map[string]string{
+		"user_id": userID.String(),
+		"reason":  reason,
+	})
+}
+
+func (c *Client) UnbanUser(ctx context.Context, serverID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "bans", userID.String()), nil, nil)
+}
+
+func (c *Client) ListBans(ctx context.Context, serverID uuid.UUID) ([]Ban, error) {
+	return callList[Ban](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "bans"), nil)
+}
+
+func (c *Client) KickUser(ctx context.Context, serverID, userID uuid.UUID, reason string) error {
+	return c.Do(ctx, http.MethodPost, apiPath("servers", serverID.String(), "kick", userID.String()),
+		map[string]string{"reason": reason}, nil)
+}
+
+// TimeoutUser stops a member from sending messages for the given duration,
+// rounded down to whole seconds.
+func (c *Client) TimeoutUser(ctx context.Context, serverID, userID uuid.UUID, duration time.Duration, reason string) (*Timeout, error) {
+	return call[Timeout](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "timeout", userID.String()), map[string]any{
+		"reason":   reason,
+		"duration": int(duration / time.Second),
+	})
+}
+
+func (c *Client) RemoveTimeout(ctx context.Context, serverID, userID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "timeout", userID.String()), nil, nil)
+}
+
+func (c *Client) ListTimeouts(ctx context.Context, serverID uuid.UUID) ([]Timeout, error) {
+	return callList[Timeout](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "timeouts"), nil)
+}
+
+// GetAuditLog returns audit log entries older than before (or the newest
+// when zero), up to limit.
+func (c *Client) GetAuditLog(ctx context.Context, serverID uuid.UUID, before time.Time, limit int) ([]AuditLogEntry, error) {
+	q := url.Values{"limit": {itoa(limit)}}
+	if !before.IsZero() {
+		q.Set("before", before.UTC().Format(time.RFC3339Nano))
+	}
+	return callList[AuditLogEntry](ctx, c, http.MethodGet, withQuery(apiPath("servers", serverID.String(), "audit-log"), q), nil)
+}
+
+func (c *Client) ListAutoModRules(ctx context.Context, serverID uuid.UUID) ([]AutoModRule, error) {
+	return callList[AutoModRule](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "automod", "rules"), nil)
+}
+
+type CreateAutoModRuleParams struct {
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	TriggerData    json.RawMessage `json:"trigger_data,omitempty"`
+	Action         string          `json:"action"`
+	ActionMetadata json.RawMessage `json:"action_metadata,omitempty"`
+	Enabled        *bool           `json:"enabled,omitempty"`
+	ExemptRoles    []uuid.UUID     `json:"exempt_roles,omitempty"`
+	ExemptChannels []uuid.UUID     `json:"exempt_channels,omitempty"`
+}
+
+func (c *Client) CreateAutoModRule(ctx context.Context, serverID uuid.UUID, params CreateAutoModRuleParams) (*AutoModRule, error) {
+	return call[AutoModRule](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "automod", "rules"), params)
+}
+
+// UpdateAutoModRuleParams changes rule fields; nil fields are left as they
+// are.
+type UpdateAutoModRuleParams struct {
+	Name           *string         `json:"name,omitempty"`
+	TriggerData    json.RawMessage `json:"trigger_data,omitempty"`
+	Action         *string         `json:"action,omitempty"`
+	ActionMetadata json.RawMessage `json:"action_metadata,omitempty"`
+	Enabled        *bool           `json:"enabled,omitempty"`
+	ExemptRoles    *[]uuid.UUID    `json:"exempt_roles,omitempty"`
+	ExemptChannels *[]uuid.UUID    `json:"exempt_channels,omitempty"`
+}
+
+func (c *Client) UpdateAutoModRule(ctx context.Context, serverID, ruleID uuid.UUID, params UpdateAutoModRuleParams) (*AutoModRule, error) {
+	return call[AutoModRule](ctx, c, http.MethodPatch, apiPath("servers", serverID.String(), "automod", "rules", ruleID.String()), params)
+}
+
+func (c *Client) DeleteAutoModRule(ctx context.Context, serverID, ruleID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "automod", "rules", ruleID.String()), nil, nil)
+}
@@ -0,0 +1,92 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) ListRoles(ctx context.Context, serverID uuid.UUID) ([]Role, error) {
+	return callList[Role](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "roles"), nil)
+}
+
+type CreateRoleParams struct {
+	Name        string
+	Color       *string
+	Permissions int64
+	Hoist       bool
+}
+
+func (c *Client) CreateRole(ctx context.Context, serverID uuid.UUID, params CreateRoleParams) (*Role, error) {
+	return call[Role](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "roles"), map[string]any{
+		"name":        params.Name,
+		"color":       params.Color,
+		"permissions": strconv.FormatInt(params.Permissions, 10),
+		"hoist":       params.Hoist,
+	})
+}
+
+// UpdateRoleParams changes role fields; nil fields are left as they are.
+type UpdateRoleParams struct {
+	Name        *string
+	Color       *string
+	Permissions *int64
+	Hoist       *bool
+}
+
+func (c *Client) UpdateRole(ctx context.Context, serverID, roleID uuid.UUID, params UpdateRoleParams) (*Role, error) {
+	body := map[string]any{}
+	if params.Name != nil {
+		body["name"] = *params.Name
+	}
+	if params.Color != nil {
+		body["color"] = *params.Color
+	}
+	if params.Permissions != nil {
+		body["permissions"] = strconv.FormatInt(*params.Permissions, 10)
+	}
+	if params.Hoist != nil {
+		body["hoist"] = *params.Hoist
+	}
+	return call[Role](ctx, c, http.MethodPatch, apiPath("servers", serverID.String(), "roles", roleID.String()), body)
+}
+
+func (c *Client) DeleteRole(ctx context.Context, serverID, roleID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "roles", roleID.String()), nil, nil)
+}
+
+func (c *Client) ReorderRoles(ctx context.Context, serverID uuid.UUID, positions []RolePosition) error {
+	return c.Do(ctx, http.MethodPut, apiPath("servers", serverID.String(), "roles", "reorder"), positions, nil)
+}
+
+func (c *Client) AssignRole(ctx context.Context, serverID, userID, roleID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPut, apiPath("servers", serverID.String(), "members", userID.String(), "roles", roleID.String()), nil, nil)
+}
+
+func (c *Client) RemoveRole(ctx context.Context, serverID, userID, roleID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "members", userID.String(), "roles", roleID.String()), nil, nil)
+}
+
+// GetMembersWithRoles returns every member of a server with their roles.
+func (c *Client) GetMembersWithRoles(ctx context.Context, serverID uuid.UUID) ([]MemberWithRoles, error) {
+	return callList[MemberWithRoles](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "members-with-roles"), nil)
+}
+
+func (c *Client) GetChannelOverrides(ctx context.Context, serverID, channelID uuid.UUID) ([]ChannelPermissionOverride, error) {
+	return callList[ChannelPermissionOverride](ctx, c, http.MethodGet,
+		apiPath("servers", serverID.String(), "channels", channelID.String(), "permissions"), nil)
+}
+
+// SetChannelOverride allows and denies permission bits for a role in a
+// channel, replacing any existing override.
+func (c *Client) SetChannelOverride(ctx context.Context, serverID, channelID, roleID uuid.UUID, allow, deny int64) (*ChannelPermissionOverride, error) {
+	return call[ChannelPermissionOverride](ctx, c, http.MethodPut,
+		apiPath("servers", serverID.String(), "channels", channelID.String(), "permissions", roleID.String()),
+		map[string]string{"allow": strconv.FormatInt(allow, 10), "deny": strconv.FormatInt(deny, 10)})
+}
+
+func (c *Client) DeleteChannelOverride(ctx context.Context, serverID, channelID, roleID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "channels", channelID.String(), "permissions", roleID.String()), nil, nil)
+}
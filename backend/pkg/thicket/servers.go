@@ -0,0 +1,268 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateServer creates a server and returns it with its default channel.
+func (c *Client) CreateServer(ctx context.Context, name string) (*Server, *Channel, error) {
+	var out struct {
+		Server  Server  `json:"server"`
+		Channel Channel `json:"channel"`
+	}
+	if err := c.Do(ctx, http.MethodPost, apiPath("servers"), map[string]string{"name": name}, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out.Server, &out.Channel, nil
+}
+
+// ListServers returns the servers the caller is a member of.
+func (c *Client) ListServers(ctx context.Context) ([]Server, error) {
+	return callList[Server](ctx, c, http.MethodGet, apiPath("servers"), nil)
+}
+
+func (c *Client) GetServer(ctx context.Context, serverID uuid.UUID) (*Server, error) {
+	return call[Server](ctx, c, http.MethodGet, apiPath("servers", serverID.String()), nil)
+}
+
+type UpdateServerParams struct {
+	Name                        *string `json:"name,omitempty"`
+	IconURL                     *string `json:"icon_url,omitempty"`
+	IsPublic                    *bool   `json:"is_public,omitempty"`
+	Description                 *string `json:"description,omitempty"`
+	GifsEnabled                 *bool   `json:"gifs_enabled,omitempty"`
+	DefaultMessageRetentionDays *int    `json:"default_message_retention_days,omitempty"`
+	// DeletedMessageDisplay is "tombstone" to leave a placeholder for
+	// deleted messages in history or "hidden" to leave nothing
+	DeletedMessageDisplay *string `json:"deleted_message_display,omitempty"`
+}
+
+func (c *Client) UpdateServer(ctx context.Context, serverID uuid.UUID, params UpdateServerParams) (*Server, error) {
+	return call[Server](ctx, c, http.MethodPatch, apiPath("servers", serverID.String()), params)
+}
+
+func (c *Client) DeleteServer(ctx context.Context, serverID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String()), nil, nil)
+}
+
+// JoinServer joins a server by its permanent invite code.
+func (c *Client) JoinServer(ctx context.Context, inviteCode string) (*Server, error) {
+	return call[Server](ctx, c, http.MethodPost, apiPath("servers", "join"), map[string]string{"invite_code": inviteCode})
+}
+
+func (c *Client) LeaveServer(ctx context.Context, serverID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("servers", serverID.String(), "leave"), nil, nil)
+}
+
+// GetServerPreview describes the server behind an invite code. It does not
+// require authentication.
+func (c *Client) GetServerPreview(ctx context.Context, code string) (*ServerPreview, error) {
+	return call[ServerPreview](ctx, c, http.MethodGet, apiPath("servers", "invite", code, "preview"), nil)
+}
+
+// DiscoverServers searches public servers.
+func (c *Client) DiscoverServers(ctx context.Context, query string, limit, offset int) ([]PublicServer, error) {
+	return callList[PublicServer](ctx, c, http.MethodGet, withQuery(apiPath("servers", "discover"), url.Values{
+		"q":      {query},
+		"limit":  {itoa(limit)},
+		"offset": {itoa(offset)},
+	}), nil)
+}
+
+func (c *Client) GetMembers(ctx context.Context, serverID uuid.UUID) ([]Member, error) {
+	return callList[Member](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "members"), nil)
+}
+
+// SetNickname sets the caller's nickname in a server; nil clears it.
+func (c *Client) SetNickname(ctx context.Context, serverID uuid.UUID, nickname *string) error {
+	return c.Do(ctx, http.MethodPatch, apiPath("servers", serverID.String(), "members", "me", "nickname"),
+		map[string]*string{"nickname": nickname}, nil)
+}
+
+// Channel types.
+const (
+	ChannelText  = "text"
+	ChannelVoice = "voice"
+	ChannelForum = "forum"
+)
+
+type CreateChannelParams struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	IsAnnouncement bool   `json:"is_announcement,omitempty"`
+}
+
+func (c *Client) CreateChannel(ctx context.Context, serverID uuid.UUID, params CreateChannelParams) (*Channel, error) {
+	return call[Channel](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "channels"), params)
+}
+
+func (c *Client) ListChannels(ctx context.Context, serverID uuid.UUID) ([]Channel, error) {
+	return callList[Channel](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "channels"), nil)
+}
+
+type UpdateChannelParams struct {
+	Name             *string    `json:"name,omitempty"`
+	Topic            *string    `json:"topic,omitempty"`
+	CategoryID       *uuid.UUID `json:"category_id,omitempty"`
+	SlowModeInterval *int       `json:"slow_mode_interval,omitempty"`
+}
+
+func (c *Client) UpdateChannel(ctx context.Context, serverID, channelID uuid.UUID, params UpdateChannelParams) (*Channel, error) {
+	return call[Channel](ctx, c, http.MethodPatch, apiPath("servers", serverID.String(), "channels", channelID.String()), params)
+}
+
+func (c *Client) DeleteChannel(ctx context.Context, serverID, channelID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "channels", channelID.String()), nil, nil)
+}
+
+func (c *Client) CreateCategory(ctx context.Context, serverID uuid.UUID, name string, position int32) (*ChannelCategory, error) {
+	return call[ChannelCategory](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "categories"), map[string]any{
+		"name":     name,
+		"position": position,
+	})
+}
+
+func (c *Client) ListCategories(ctx context.Context, serverID uuid.UUID) ([]ChannelCategory, error) {
+	return callList[ChannelCategory](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "categories"), nil)
+}
+
+type UpdateCategoryParams struct {
+	Name     *string `json:"name,omitempty"`
+	Position *int32  `json:"position,omitempty"`
+}
+
+func (c *Client) UpdateCategory(ctx context.Context, serverID, categoryID uuid.UUID, params UpdateCategoryParams) (*ChannelCategory, error) {
+	return call[ChannelCategory](ctx, c, http.MethodPatch, apiPath("servers", serverID.String(), "categories", categoryID.String()), params)
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, serverID, categoryID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "categories", categoryID.String()), nil, nil)
+}
+
+// CreateInviteParams limits an invite; zero values mean unlimited uses and no
+// expiry.
+type CreateInviteParams struct {
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (c *Client) CreateInvite(ctx context.Context, serverID uuid.UUID, params CreateInviteParams) (*Invite, error) {
+	return call[Invite](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "invites"), params)
+}
+
+func (c *Client) ListInvites(ctx context.Context, serverID uuid.UUID) ([]Invite, error) {
+	return callList[Invite](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "invites"), nil)
+}
+
+func (c *Client) DeleteInvite(ctx context.Context, serverID, inviteID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("servers", serverID.String(), "invites", inviteID.String()), nil, nil)
+}
+
+// UseInvite joins a server with an invite created by CreateInvite.
+func (c *Client) UseInvite(ctx context.Context, code string) (*Server, error) {
+	return call[Server](ctx, c, http.MethodPost, apiPath("servers", "join", "invite"), map[string]string{"code": code})
+}
+
+// InviteUser sends a direct server invitation to a user.
+func (c *Client) InviteUser(ctx context.Context, serverID uuid.UUID, username string) (*ServerInvitation, error) {
+	return call[ServerInvitation](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "invites", "username"),
+		map[string]string{"username": username})
+}
+
+func (c *Client) ListSentInvitations(ctx context.Context, serverID uuid.UUID) ([]ServerInvitation, error) {
+	return callList[ServerInvitation](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "invitations", "sent"), nil)
+}
+
+func (c *Client) ListReceivedInvitations(ctx context.Context) ([]ServerInvitation, error) {
+	return callList[ServerInvitation](ctx, c, http.MethodGet, apiPath("invitations", "received"), nil)
+}
+
+// AcceptInvitation accepts a server invitation and returns the joined server.
+func (c *Client) AcceptInvitation(ctx context.Context, invitationID uuid.UUID) (*Server, error) {
+	return call[Server](ctx, c, http.MethodPost, apiPath("invitations", invitationID.String(), "accept"), nil)
+}
+
+func (c *Client) DeclineInvitation(ctx context.Context, invitationID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("invitations", invitationID.String(), "decline"), nil, nil)
+}
+
+func (c *Client) GetWelcome(ctx context.Context, serverID uuid.UUID) (*WelcomeConfig, error) {
+	return call[WelcomeConfig](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "welcome"), nil)
+}
+
+func (c *Client) UpdateWelcome(ctx context.Context, serverID uuid.UUID, config WelcomeConfig) (*WelcomeConfig, error) {
+	return call[WelcomeConfig](ctx, c, http.MethodPut, apiPath("servers", serverID.String(), "welcome"), config)
+}
+
+func (c *Client) GetOnboarding(ctx context.Context, serverID uuid.UUID) ([]OnboardingPrompt, error) {
+	return callList[OnboardingPrompt](ctx, c, http.MethodGet, apiPath("servers", serverID.String(), "onboarding"), nil)
+}
+
+// UpdateOnboarding replaces the server's onboarding prompts.
+func (c *Client) UpdateOnboarding(ctx context.Context, serverID uuid.UUID, prompts []OnboardingPrompt) ([]OnboardingPrompt, error) {
+	return callList[OnboardingPrompt](ctx, c, http.MethodPut, apiPath("servers", serverID.String(), "onboarding"),
+		map[string]any{"prompts": prompts})
+}
+
+// CompleteOnboarding applies the roles and channels of the selected options.
+func (c *Client) CompleteOnboarding(ctx context.Context, serverID uuid.UUID, selectedOptionIDs []uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("servers", serverID.String(), "onboarding", "complete"),
+		map[string]any{"selected_option_ids": selectedOptionIDs}, nil)
+}
+
+func (c *Client) GetOnboardingStatus(ctx context.Context, serverID uuid.UUID) (bool, error) {
+	var out struct {
+		Completed bool `json:"completed"`
+	}
+	if err := c.Do(ctx, http.MethodGet, apiPath("servers", serverID.String(), "onboarding", "status"), nil, &out); err != nil {
+		return false, err
+	}
+	return out.Completed, nil
+}
+
+// FollowChannel cross-posts an announcement channel into a target channel.
+func (c *Client) FollowChannel(ctx context.Context, channelID, targetChannelID uuid.UUID) (*ChannelFollow, error) {
+	return call[ChannelFollow](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "followers"),
+		map[string]string{"target_channel_id": targetChannelID.String()})
+}
+
+func (c *Client) UnfollowChannel(ctx context.Context, channelID, followID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "followers", followID.String()), nil, nil)
+}
+
+func (c *Client) ListChannelFollowers(ctx context.Context, channelID uuid.UUID) ([]ChannelFollow, error) {
+	return callList[ChannelFollow](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "followers"), nil)
+}
+
+// GetVoiceToken returns a LiveKit token for a voice or stage channel.
+func (c *Client) GetVoiceToken(ctx context.Context, serverID, channelID uuid.UUID) (*VoiceToken, error) {
+	return call[VoiceToken](ctx, c, http.MethodPost, apiPath("servers", serverID.String(), "channels", channelID.String(), "voice-token"), nil)
+}
+
+// GetDMVoiceToken returns a LiveKit token for a DM call.
+func (c *Client) GetDMVoiceToken(ctx context.Context, conversationID uuid.UUID) (*VoiceToken, error) {
+	return call[VoiceToken](ctx, c, http.MethodPost, apiPath("dm", "conversations", conversationID.String(), "voice-token"), nil)
+}
+
+// ExportChannel returns a channel's history as "json" or "html".
+func (c *Client) ExportChannel(ctx context.Context, channelID uuid.UUID, format string) ([]byte, error) {
+	var raw []byte
+	if err := c.Do(ctx, http.MethodPost, withQuery(apiPath("channels", channelID.String(), "export"), url.Values{"format": {format}}), nil, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// itoa formats a positive limit or offset; zero is left out of the query.
+func itoa(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,472 @@
+package thicket
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-McCallum/thicket/internal/auth"
+	"github.com/M-McCallum/thicket/internal/handler"
+	"github.com/M-McCallum/thicket/internal/router"
+	"github.com/M-McCallum/thicket/internal/service"
+	"github.com/M-McCallum/thicket/internal/testutil"
+	"github.com/M-McCallum/thicket/internal/ws"
+)
+
+var (
+	testDB     *testutil.TestDB
+	jwksServer *testutil.TestJWKSServer
+	jwksMgr    *auth.JWKSManager
+)
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	var err error
+	testDB, err = testutil.SetupTestDB(ctx)
+	if err != nil {
+		log.Fatalf("setup test db: %v", err)
+	}
+
+	jwksServer = testutil.NewTestJWKSServer()
+	jwksMgr = auth.NewJWKSManager(jwksServer.JWKSURL())
+
+	code := m.Run()
+
+	jwksServer.Close()
+	testDB.Cleanup(ctx)
+	os.Exit(code)
+}
+
+// startServer serves the real router, wired as in cmd/server, and returns
+// its base URL.
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	q := testDB.Queries
+	permSvc := service.NewPermissionService(q)
+	serverSvc := service.NewServerService(q, permSvc)
+	channelSvc := service.NewChannelService(q, permSvc)
+	messageSvc := service.NewMessageService(q, permSvc)
+	roleSvc := service.NewRoleService(q, permSvc)
+	dmSvc := service.NewDMService(q)
+	userSvc := service.NewUserService(q)
+	botSvc := service.NewBotService(q, permSvc)
+	threadSvc := service.NewThreadService(q)
+	webhookSvc := service.NewWebhookService(q, permSvc)
+
+	hub := ws.NewHub()
+	ws.HandlerDMParticipantsFn = dmSvc.GetParticipantIDs
+	ws.HandlerBotValidatorFn = botSvc.AuthenticateBot
+	ws.HandlerReadyStateFn = service.NewReadyService(q, hub).BuildReadyState
+	hub.SetChannelAccessFn(func(ctx context.Context, userID, channelID uuid.UUID) (bool, error) {
+		return permSvc.CanViewChannel(ctx, channelID, userID)
+	})
+	go hub.Run()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	baseURL := "http://" + ln.Addr().String()
+
+	app := fiber.New()
+	router.Setup(app, router.Config{
+		ServerHandler:     handler.NewServerHandler(serverSvc, channelSvc, hub),
+		MessageHandler:    handler.NewMessageHandler(messageSvc, hub, nil),
+		DMHandler:         handler.NewDMHandler(dmSvc, hub, nil),
+		UserHandler:       handler.NewUserHandler(userSvc, hub, serverSvc.GetUserCoMemberIDs, nil),
+		RoleHandler:       handler.NewRoleHandler(roleSvc, serverSvc, hub),
+		BotHandler:        handler.NewBotHandler(botSvc),
+		ThreadHandler:     handler.NewThreadHandler(threadSvc, hub),
+		WebhookHandler:    handler.NewWebhookHandler(webhookSvc, nil, hub),
+		JWKSManager:       jwksMgr,
+		BotValidator:      botSvc.AuthenticateBot,
+		Hub:               hub,
+		CoMemberIDsFn:     serverSvc.GetUserCoMemberIDs,
+		ServerMemberIDsFn: serverSvc.GetServerMemberUserIDs,
+		// The gateway's default Origin is the API base URL
+		CORSOrigin: baseURL,
+	})
+
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	t.Cleanup(func() {
+		_ = app.Shutdown()
+	})
+
+	return baseURL
+}
+
+func newUserClient(t *testing.T, baseURL string) (*testutil.TestUser, *Client) {
+	t.Helper()
+	u, err := testutil.CreateTestUser(context.Background(), testDB.Queries, jwksServer)
+	require.NoError(t, err)
+	return u, NewClient(baseURL, u.AccessToken)
+}
+
+// runGateway runs gw until the test ends and returns Run's result.
+func runGateway(t *testing.T, gw *Gateway) <-chan error {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- gw.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return done
+}
+
+// collect forwards every event to a buffered channel.
+func collect(gw *Gateway) <-chan *Event {
+	events := make(chan *Event, 256)
+	gw.OnEvent(func(e *Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	return events
+}
+
+// waitFor returns the first event of the given type that matches.
+func waitFor(t *testing.T, events <-chan *Event, eventType string, match func(any) bool) any {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != eventType {
+				continue
+			}
+			v, err := e.Decode()
+			require.NoError(t, err)
+			if match == nil || match(v) {
+				return v
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+			return nil
+		}
+	}
+}
+
+func TestREST_ServerMessagesAndRoles(t *testing.T) {
+	ctx := context.Background()
+	baseURL := startServer(t)
+	owner, ownerClient := newUserClient(t, baseURL)
+	member, memberClient := newUserClient(t, baseURL)
+
+	me, err := ownerClient.GetMe(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, owner.User.ID, me.UserID)
+
+	server, general, err := ownerClient.CreateServer(ctx, "SDK Server")
+	require.NoError(t, err)
+	assert.Equal(t, "SDK Server", server.Name)
+	assert.Equal(t, server.ID, general.ServerID)
+
+	_, err = memberClient.JoinServer(ctx, server.InviteCode)
+	require.NoError(t, err)
+	members, err := ownerClient.GetMembers(ctx, server.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	msg, err := memberClient.SendMessage(ctx, general.ID, SendMessageParams{Content: "hello from the sdk"})
+	require.NoError(t, err)
+	assert.Equal(t, member.User.ID, msg.AuthorID)
+
+	edited, err := memberClient.EditMessage(ctx, msg.ID, "edited")
+	require.NoError(t, err)
+	assert.Equal(t, "edited", edited.Content)
+
+	require.NoError(t, ownerClient.AddReaction(ctx, msg.ID, "👍"))
+	require.NoError(t, ownerClient.PinMessage(ctx, general.ID, msg.ID))
+	pinned, err := ownerClient.GetPinnedMessages(ctx, general.ID)
+	require.NoError(t, err)
+	require.Len(t, pinned, 1)
+	assert.Equal(t, msg.ID, pinned[0].ID)
+
+	history, err := ownerClient.GetMessages(ctx, general.ID, HistoryParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "edited", history[0].Content)
+	assert.Equal(t, member.User.Username, history[0].AuthorUsername)
+
+	thread, err := ownerClient.CreateThread(ctx, general.ID, msg.ID, "Discussion")
+	require.NoError(t, err)
+	reply, err := memberClient.SendThreadMessage(ctx, thread.ID, "in a thread", nil)
+	require.NoError(t, err)
+	assert.Equal(t, thread.ID, reply.ThreadID)
+
+	role, err := ownerClient.CreateRole(ctx, server.ID, CreateRoleParams{
+		Name:        "Moderator",
+		Permissions: PermManageMessages | PermKickMembers,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PermManageMessages|PermKickMembers, role.Permissions)
+	require.NoError(t, ownerClient.AssignRole(ctx, server.ID, member.User.ID, role.ID))
+
+	// Members without ManageChannels may not create channels
+	_, err = memberClient.CreateChannel(ctx, server.ID, CreateChannelParams{Name: "nope", Type: ChannelText})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.NotEmpty(t, apiErr.Message)
+
+	_, err = ownerClient.GetServer(ctx, uuid.New())
+	require.ErrorAs(t, err, &apiErr)
+	assert.Contains(t, []int{http.StatusForbidden, http.StatusNotFound}, apiErr.StatusCode)
+}
+
+func TestREST_WebhookTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	baseURL := startServer(t)
+	_, ownerClient := newUserClient(t, baseURL)
+
+	_, general, err := ownerClient.CreateServer(ctx, "Webhook Server")
+	require.NoError(t, err)
+	webhook, err := ownerClient.CreateWebhook(ctx, general.ID, "Deploys", "")
+	require.NoError(t, err)
+
+	rotated, err := ownerClient.RotateWebhookToken(ctx, webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.ID, rotated.ID)
+	assert.NotEmpty(t, rotated.Token)
+	assert.NotEqual(t, webhook.Token, rotated.Token)
+
+	err = ownerClient.ExecuteWebhook(ctx, webhook.ID, webhook.Token, ExecuteWebhookParams{Content: "old token"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	require.NoError(t, ownerClient.ExecuteWebhook(ctx, webhook.ID, rotated.Token, ExecuteWebhookParams{Content: "new token"}))
+}
+
+func TestGateway_ReadyAndMessageCreate(t *testing.T) {
+	ctx := context.Background()
+	baseURL := startServer(t)
+	user, client := newUserClient(t, baseURL)
+	server, general, err := client.CreateServer(ctx, "Gateway Server")
+	require.NoError(t, err)
+
+	gw := client.Gateway()
+	gw.Capabilities = []string{CapServers}
+	// The server allows five heartbeats per ten seconds
+	gw.HeartbeatInterval = 2500 * time.Millisecond
+	require.NoError(t, gw.Subscribe(general.ID))
+
+	ready := make(chan *ReadyEvent, 1)
+	gw.OnReady(func(e *ReadyEvent) {
+		select {
+		case ready <- e:
+		default:
+		}
+	})
+	created := make(chan *MessageCreateEvent, 16)
+	gw.OnMessageCreate(func(e *MessageCreateEvent) {
+		select {
+		case created <- e:
+		default:
+		}
+	})
+	events := collect(gw)
+	runGateway(t, gw)
+
+	select {
+	case r := <-ready:
+		assert.Equal(t, user.User.ID, r.UserID)
+		assert.NotEmpty(t, r.SessionID)
+		assert.False(t, r.Bot)
+		require.Len(t, r.Servers, 1)
+		assert.Equal(t, server.ID, r.Servers[0].ID)
+		assert.NotEmpty(t, r.Servers[0].Channels)
+		assert.Equal(t, r.SessionID, gw.SessionID())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for READY")
+	}
+
+	// Heartbeats are acknowledged and keep the connection alive
+	waitFor(t, events, EventHeartbeatAck, nil)
+
+	msg := sendUntilDelivered(t, client, general.ID, events)
+	var e *MessageCreateEvent
+	for e == nil || e.ID != msg.ID {
+		e = <-created
+	}
+	assert.Equal(t, general.ID, e.ChannelID)
+	assert.Equal(t, "typed", e.Content)
+	assert.Equal(t, user.User.Username, e.AuthorUsername)
+	assert.Greater(t, gw.Seq(), int64(0))
+}
+
+// sendUntilDelivered sends messages until one arrives over the gateway,
+// since SUBSCRIBE is not acknowledged. Sends are spaced to stay within the
+// message rate limit.
+func sendUntilDelivered(t *testing.T, client *Client, channelID uuid.UUID, events <-chan *Event) *Message {
+	t.Helper()
+	ctx := context.Background()
+	for range 8 {
+		msg, err := client.SendMessage(ctx, channelID, SendMessageParams{Content: "typed"})
+		require.NoError(t, err)
+		timeout := time.After(500 * time.Millisecond)
+	wait:
+		for {
+			select {
+			case e := <-events:
+				if e.Type != EventMessageCreate {
+					continue
+				}
+				v, err := e.Decode()
+				require.NoError(t, err)
+				if v.(*MessageCreateEvent).ID == msg.ID {
+					return msg
+				}
+			case <-timeout:
+				break wait
+			}
+		}
+	}
+	t.Fatal("timed out waiting for MESSAGE_CREATE")
+	return nil
+}
+
+func TestGateway_ResumesAfterDisconnect(t *testing.T) {
+	ctx := context.Background()
+	baseURL := startServer(t)
+	_, client := newUserClient(t, baseURL)
+	_, general, err := client.CreateServer(ctx, "Resume Server")
+	require.NoError(t, err)
+
+	gw := client.Gateway()
+	gw.ReconnectDelay = 100 * time.Millisecond
+	events := collect(gw)
+	require.NoError(t, gw.Subscribe(general.ID))
+	runGateway(t, gw)
+	ready := waitFor(t, events, EventReady, nil).(*ReadyEvent)
+
+	sendUntilDelivered(t, client, general.ID, events)
+
+	// Drop the connection and send while the client is away
+	gw.writeMu.Lock()
+	gw.conn.Close()
+	gw.writeMu.Unlock()
+	missed, err := client.SendMessage(ctx, general.ID, SendMessageParams{Content: "while away"})
+	require.NoError(t, err)
+
+	waitFor(t, events, EventMessageCreate, func(v any) bool {
+		return v.(*MessageCreateEvent).ID == missed.ID
+	})
+	waitFor(t, events, EventResumed, nil)
+	assert.Equal(t, ready.SessionID, gw.SessionID())
+}
+
+func TestGateway_InvalidSessionReidentifies(t *testing.T) {
+	baseURL := startServer(t)
+	_, client := newUserClient(t, baseURL)
+
+	gw := client.Gateway()
+	gw.sessionID = "not-a-session"
+	gw.seq = 42
+	events := collect(gw)
+	runGateway(t, gw)
+
+	invalid := waitFor(t, events, EventInvalidSession, nil).(*InvalidSessionEvent)
+	assert.NotEmpty(t, invalid.Reason)
+	ready := waitFor(t, events, EventReady, nil).(*ReadyEvent)
+	assert.NotEqual(t, "not-a-session", ready.SessionID)
+}
+
+func TestBotClient(t *testing.T) {
+	ctx := context.Background()
+	baseURL := startServer(t)
+	owner, client := newUserClient(t, baseURL)
+
+	bot, token, err := client.CreateBot(ctx, "sdkbot_"+strconv.FormatInt(time.Now().UnixNano()%100000, 10))
+	require.NoError(t, err)
+	assert.Equal(t, owner.User.ID, bot.OwnerID)
+	require.NotEmpty(t, token)
+
+	botClient := NewBotClient(baseURL, token)
+	me, err := botClient.GetMe(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, bot.Username, me.Username)
+
+	gw := botClient.Gateway()
+	gw.Intents = IntentServerMessages
+	events := collect(gw)
+	runGateway(t, gw)
+	ready := waitFor(t, events, EventReady, nil).(*ReadyEvent)
+	assert.True(t, ready.Bot)
+
+	// Bots cannot manage bots
+	_, _, err = botClient.CreateBot(ctx, "nested")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+}
+
+func TestGateway_RejectedTokenStopsRun(t *testing.T) {
+	baseURL := startServer(t)
+	client := NewClient(baseURL, "not-a-jwt")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := client.Gateway().Run(ctx)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr), "got %v", err)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+// TestDecode_CoversEveryGatewayEvent checks that every event type declared
+// by the server decodes to a typed payload.
+func TestDecode_CoversEveryGatewayEvent(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../../internal/ws/events.go", nil, 0)
+	require.NoError(t, err)
+
+	var types []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for i, name := range spec.(*ast.ValueSpec).Names {
+				if len(name.Name) <= len("Event") || name.Name[:len("Event")] != "Event" {
+					continue
+				}
+				lit, ok := spec.(*ast.ValueSpec).Values[i].(*ast.BasicLit)
+				require.True(t, ok, name.Name)
+				value, err := strconv.Unquote(lit.Value)
+				require.NoError(t, err)
+				types = append(types, value)
+			}
+		}
+	}
+	require.NotEmpty(t, types)
+
+	for _, eventType := range types {
+		e := &Event{Type: eventType, Data: []byte(`{}`)}
+		v, err := e.Decode()
+		require.NoError(t, err, eventType)
+		assert.NotNil(t, v, eventType)
+	}
+
+	_, err = (&Event{Type: "NOT_AN_EVENT"}).Decode()
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
@@ -0,0 +1,174 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CreateThread starts a thread from a channel message.
+func (c *Client) CreateThread(ctx context.Context, channelID, parentMessageID uuid.UUID, name string) (*Thread, error) {
+	return call[Thread](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "threads"), map[string]string{
+		"parent_message_id": parentMessageID.String(),
+		"name":              name,
+	})
+}
+
+func (c *Client) ListThreads(ctx context.Context, channelID uuid.UUID) ([]Thread, error) {
+	return callList[Thread](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "threads"), nil)
+}
+
+func (c *Client) GetThread(ctx context.Context, threadID uuid.UUID) (*Thread, error) {
+	return call[Thread](ctx, c, http.MethodGet, apiPath("threads", threadID.String()), nil)
+}
+
+type UpdateThreadParams struct {
+	Name     *string `json:"name,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	Locked   *bool   `json:"locked,omitempty"`
+}
+
+func (c *Client) UpdateThread(ctx context.Context, threadID uuid.UUID, params UpdateThreadParams) (*Thread, error) {
+	return call[Thread](ctx, c, http.MethodPatch, apiPath("threads", threadID.String()), params)
+}
+
+func (c *Client) SendThreadMessage(ctx context.Context, threadID uuid.UUID, content string, replyToID *uuid.UUID) (*ThreadMessage, error) {
+	body := struct {
+		Content   string     `json:"content"`
+		ReplyToID *uuid.UUID `json:"reply_to_id,omitempty"`
+	}{content, replyToID}
+	return call[ThreadMessage](ctx, c, http.MethodPost, apiPath("threads", threadID.String(), "messages"), body)
+}
+
+func (c *Client) GetThreadMessages(ctx context.Context, threadID uuid.UUID, params HistoryParams) ([]ThreadMessage, error) {
+	return callList[ThreadMessage](ctx, c, http.MethodGet, withQuery(apiPath("threads", threadID.String(), "messages"), params.query()), nil)
+}
+
+func (c *Client) DeleteThreadMessage(ctx context.Context, threadID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("threads", threadID.String(), "messages", messageID.String()), nil, nil)
+}
+
+// SetThreadSubscription sets how the caller is notified about a thread:
+// "all", "mentions" or "none".
+func (c *Client) SetThreadSubscription(ctx context.Context, threadID uuid.UUID, level string) (*ThreadSubscription, error) {
+	return call[ThreadSubscription](ctx, c, http.MethodPut, apiPath("threads", threadID.String(), "subscription"),
+		map[string]string{"notification_level": level})
+}
+
+func (c *Client) ListForumTags(ctx context.Context, channelID uuid.UUID) ([]ForumTag, error) {
+	return callList[ForumTag](ctx, c, http.MethodGet, apiPath("channels", channelID.String(), "forum", "tags"), nil)
+}
+
+type CreateForumTagParams struct {
+	Name      string `json:"name"`
+	Color     string `json:"color,omitempty"`
+	Emoji     string `json:"emoji,omitempty"`
+	Position  int    `json:"position"`
+	Moderated bool   `json:"moderated,omitempty"`
+}
+
+func (c *Client) CreateForumTag(ctx context.Context, channelID uuid.UUID, params CreateForumTagParams) (*ForumTag, error) {
+	return call[ForumTag](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "forum", "tags"), params)
+}
+
+type UpdateForumTagParams struct {
+	Name      *string `json:"name,omitempty"`
+	Color     *string `json:"color,omitempty"`
+	Emoji     *string `json:"emoji,omitempty"`
+	Position  *int    `json:"position,omitempty"`
+	Moderated *bool   `json:"moderated,omitempty"`
+}
+
+func (c *Client) UpdateForumTag(ctx context.Context, channelID, tagID uuid.UUID, params UpdateForumTagParams) (*ForumTag, error) {
+	return call[ForumTag](ctx, c, http.MethodPatch, apiPath("channels", channelID.String(), "forum", "tags", tagID.String()), params)
+}
+
+func (c *Client) DeleteForumTag(ctx context.Context, channelID, tagID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("channels", channelID.String(), "forum", "tags", tagID.String()), nil, nil)
+}
+
+// Forum post orderings.
+const (
+	ForumSortLatest = "latest" // last activity
+	ForumSortNewest = "newest" // creation time
+	ForumSortTop    = "top"    // most replies
+)
+
+// ForumPostsParams filters and pages forum posts. With TagIDs, only posts
+// carrying at least one of the tags are returned.
+type ForumPostsParams struct {
+	Sort   string
+	TagIDs []uuid.UUID
+	Limit  int
+	Offset int
+}
+
+func (c *Client) ListForumPosts(ctx context.Context, channelID uuid.UUID, params ForumPostsParams) ([]ForumPost, error) {
+	tags := make([]string, len(params.TagIDs))
+	for i, id := range params.TagIDs {
+		tags[i] = id.String()
+	}
+	return callList[ForumPost](ctx, c, http.MethodGet, withQuery(apiPath("channels", channelID.String(), "forum", "posts"), url.Values{
+		"sort":   {params.Sort},
+		"tags":   {strings.Join(tags, ",")},
+		"limit":  {itoa(params.Limit)},
+		"offset": {itoa(params.Offset)},
+	}), nil)
+}
+
+// CreateForumPost opens a post; content becomes its first message.
+func (c *Client) CreateForumPost(ctx context.Context, channelID uuid.UUID, title, content string, tagIDs []uuid.UUID) (*ForumPost, error) {
+	tags := make([]string, len(tagIDs))
+	for i, id := range tagIDs {
+		tags[i] = id.String()
+	}
+	return call[ForumPost](ctx, c, http.MethodPost, apiPath("channels", channelID.String(), "forum", "posts"), map[string]any{
+		"title":   title,
+		"content": content,
+		"tag_ids": tags,
+	})
+}
+
+func (c *Client) GetForumPost(ctx context.Context, postID uuid.UUID) (*ForumPost, error) {
+	return call[ForumPost](ctx, c, http.MethodGet, apiPath("forum", "posts", postID.String()), nil)
+}
+
+func (c *Client) DeleteForumPost(ctx context.Context, postID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("forum", "posts", postID.String()), nil, nil)
+}
+
+// SetForumPostTags replaces a post's tags.
+func (c *Client) SetForumPostTags(ctx context.Context, postID uuid.UUID, tagIDs []uuid.UUID) error {
+	tags := make([]string, len(tagIDs))
+	for i, id := range tagIDs {
+		tags[i] = id.String()
+	}
+	return c.Do(ctx, http.MethodPut, apiPath("forum", "posts", postID.String(), "tags"), map[string]any{"tag_ids": tags}, nil)
+}
+
+func (c *Client) PinForumPost(ctx context.Context, postID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPut, apiPath("forum", "posts", postID.String(), "pin"), nil, nil)
+}
+
+func (c *Client) UnpinForumPost(ctx context.Context, postID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("forum", "posts", postID.String(), "pin"), nil, nil)
+}
+
+func (c *Client) GetForumPostMessages(ctx context.Context, postID uuid.UUID, limit, offset int) ([]ForumPostMessage, error) {
+	return callList[ForumPostMessage](ctx, c, http.MethodGet, withQuery(apiPath("forum", "posts", postID.String(), "messages"), url.Values{
+		"limit":  {itoa(limit)},
+		"offset": {itoa(offset)},
+	}), nil)
+}
+
+func (c *Client) SendForumPostMessage(ctx context.Context, postID uuid.UUID, content string) (*ForumPostMessage, error) {
+	return call[ForumPostMessage](ctx, c, http.MethodPost, apiPath("forum", "posts", postID.String(), "messages"),
+		map[string]string{"content": content})
+}
+
+func (c *Client) DeleteForumPostMessage(ctx context.Context, postID, messageID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("forum", "posts", postID.String(), "messages", messageID.String()), nil, nil)
+}
@@ -0,0 +1,891 @@
+package thicket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission bits for roles and channel overrides.
+const (
+	PermViewChannels    int64 = 1 << 0
+	PermSendMessages    int64 = 1 << 1
+	PermManageMessages  int64 = 1 << 2
+	PermManageChannels  int64 = 1 << 3
+	PermManageRoles     int64 = 1 << 4
+	PermKickMembers     int64 = 1 << 5
+	PermBanMembers      int64 = 1 << 6
+	PermManageServer    int64 = 1 << 7
+	PermAddReactions    int64 = 1 << 8
+	PermAttachFiles     int64 = 1 << 9
+	PermCreateInvite    int64 = 1 << 10
+	PermMentionEveryone int64 = 1 << 11
+	PermPinMessages     int64 = 1 << 12
+	PermVoiceConnect    int64 = 1 << 13
+	PermVoiceSpeak      int64 = 1 << 14
+	PermAdministrator   int64 = 1 << 30
+)
+
+// User is a user account. Public profiles leave Email, KratosID and the
+// timestamps empty.
+type User struct {
+	ID                    uuid.UUID  `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email,omitempty"`
+	AvatarURL             *string    `json:"avatar_url"`
+	DisplayName           *string    `json:"display_name"`
+	Status                string     `json:"status"`
+	KratosID              uuid.UUID  `json:"kratos_id"`
+	Bio                   string     `json:"bio"`
+	Pronouns              string     `json:"pronouns"`
+	CustomStatusText      string     `json:"custom_status_text"`
+	CustomStatusEmoji     string     `json:"custom_status_emoji"`
+	CustomStatusExpiresAt *time.Time `json:"custom_status_expires_at"`
+	IsBot                 bool       `json:"is_bot"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// Identity is the caller as seen by the API.
+type Identity struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+type Server struct {
+	ID                          uuid.UUID   `json:"id"`
+	Name                        string      `json:"name"`
+	IconURL                     *string     `json:"icon_url"`
+	OwnerID                     uuid.UUID   `json:"owner_id"`
+	InviteCode                  string      `json:"invite_code"`
+	IsPublic                    bool        `json:"is_public"`
+	Description                 string      `json:"description"`
+	GifsEnabled                 bool        `json:"gifs_enabled"`
+	WelcomeMessage              string      `json:"welcome_message"`
+	WelcomeChannels             []uuid.UUID `json:"welcome_channels"`
+	DefaultMessageRetentionDays *int        `json:"default_message_retention_days"`
+	// DeletedMessageDisplay is "tombstone" or "hidden"
+	DeletedMessageDisplay string    `json:"deleted_message_display"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// ServerPreview is what an invite code reveals before joining.
+type ServerPreview struct {
+	Name        string  `json:"name"`
+	MemberCount int64   `json:"member_count"`
+	IconURL     *string `json:"icon_url"`
+	Description string  `json:"description"`
+}
+
+// PublicServer is a server listed in discovery.
+type PublicServer struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	IconURL     *string   `json:"icon_url"`
+	Description string    `json:"description"`
+	MemberCount int64     `json:"member_count"`
+	IsPublic    bool      `json:"is_public"`
+}
+
+// Member is a server member together with their user profile.
+type Member struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName *string   `json:"display_name"`
+	AvatarURL   *string   `json:"avatar_url"`
+	Status      string    `json:"status"`
+	Role        string    `json:"role"`
+	Nickname    *string   `json:"nickname"`
+	IsBot       bool      `json:"is_bot"`
+}
+
+// MemberWithRoles is a member together with their assigned roles.
+type MemberWithRoles struct {
+	Member
+	Roles []Role `json:"roles"`
+}
+
+type Channel struct {
+	ID               uuid.UUID  `json:"id"`
+	ServerID         uuid.UUID  `json:"server_id"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Position         int32      `json:"position"`
+	Topic            string     `json:"topic"`
+	CategoryID       *uuid.UUID `json:"category_id"`
+	SlowModeInterval int        `json:"slow_mode_interval"`
+	VoiceStatus      string     `json:"voice_status"`
+	IsAnnouncement   bool       `json:"is_announcement"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type ChannelCategory struct {
+	ID        uuid.UUID `json:"id"`
+	ServerID  uuid.UUID `json:"server_id"`
+	Name      string    `json:"name"`
+	Position  int32     `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChannelFollow cross-posts an announcement channel into another channel.
+type ChannelFollow struct {
+	ID              uuid.UUID `json:"id"`
+	SourceChannelID uuid.UUID `json:"source_channel_id"`
+	TargetChannelID uuid.UUID `json:"target_channel_id"`
+	CreatedBy       uuid.UUID `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Message is a channel message. Author details, attachments, the replied-to
+// message and reactions are filled in by history and search endpoints and
+// by MESSAGE_CREATE events.
+type Message struct {
+	ID         uuid.UUID   `json:"id"`
+	ChannelID  uuid.UUID   `json:"channel_id"`
+	AuthorID   uuid.UUID   `json:"author_id"`
+	Content    string      `json:"content"`
+	Type       string      `json:"type"`
+	ReplyToID  *uuid.UUID  `json:"reply_to_id"`
+	Components []ActionRow `json:"components,omitempty"`
+	Embeds     []Embed     `json:"embeds,omitempty"`
+	WebhookID  *uuid.UUID  `json:"webhook_id,omitempty"`
+	// Webhook messages show these instead of the creator's name and avatar
+	WebhookUsername  *string `json:"webhook_username,omitempty"`
+	WebhookAvatarURL *string `json:"webhook_avatar_url,omitempty"`
+	// Nonce is the sender's nonce, set on send responses and create events
+	Nonce string `json:"nonce,omitempty"`
+	// DeletedAt is set on tombstones in history and on deleted messages
+	// listed for moderators; DeletedBy only on the latter
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *uuid.UUID `json:"deleted_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	AuthorUsername    string        `json:"author_username,omitempty"`
+	AuthorDisplayName *string       `json:"author_display_name,omitempty"`
+	AuthorAvatarURL   *string       `json:"author_avatar_url,omitempty"`
+	Attachments       []Attachment  `json:"attachments,omitempty"`
+	ReplyTo           *ReplySnippet `json:"reply_to,omitempty"`
+	Reactions         []Reaction    `json:"reactions,omitempty"`
+}
+
+// Component types. Rows hold either up to five buttons or a single select.
+const (
+	ComponentRow           = "row"
+	ComponentButton        = "button"
+	ComponentStringSelect  = "string_select"
+	ComponentUserSelect    = "user_select"
+	ComponentRoleSelect    = "role_select"
+	ComponentChannelSelect = "channel_select"
+)
+
+// Button styles. Link buttons open their URL and never create interactions.
+const (
+	ButtonPrimary   = "primary"
+	ButtonSecondary = "secondary"
+	ButtonSuccess   = "success"
+	ButtonDanger    = "danger"
+	ButtonLink      = "link"
+)
+
+// ActionRow is one row of message components.
+type ActionRow struct {
+	Type       string      `json:"type"`
+	Components []Component `json:"components"`
+}
+
+// Component is a button or select menu on a message.
+type Component struct {
+	Type        string         `json:"type"`
+	CustomID    string         `json:"custom_id,omitempty"`
+	Label       string         `json:"label,omitempty"`
+	Style       string         `json:"style,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Disabled    bool           `json:"disabled,omitempty"`
+	Placeholder string         `json:"placeholder,omitempty"`
+	Options     []SelectOption `json:"options,omitempty"`
+	MinValues   *int           `json:"min_values,omitempty"`
+	MaxValues   *int           `json:"max_values,omitempty"`
+}
+
+// SelectOption is one choice in a string select.
+type SelectOption struct {
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Default     bool   `json:"default,omitempty"`
+}
+
+// Embed is a rich content block on a webhook message.
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       *int         `json:"color,omitempty"`
+	Timestamp   *time.Time   `json:"timestamp,omitempty"`
+	Author      *EmbedAuthor `json:"author,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+	Image       *EmbedMedia  `json:"image,omitempty"`
+	Thumbnail   *EmbedMedia  `json:"thumbnail,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+}
+
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type EmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type EmbedMedia struct {
+	URL string `json:"url"`
+}
+
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// ReplySnippet is a short view of the message being replied to.
+type ReplySnippet struct {
+	ID             uuid.UUID `json:"id"`
+	AuthorID       uuid.UUID `json:"author_id"`
+	AuthorUsername string    `json:"author_username"`
+	Content        string    `json:"content"`
+}
+
+// Reaction counts one emoji on a message; Me is set if the caller reacted.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	Me    bool   `json:"me"`
+}
+
+// MessageEdit is a previous version of an edited message.
+type MessageEdit struct {
+	ID        uuid.UUID `json:"id"`
+	MessageID uuid.UUID `json:"message_id"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+type Attachment struct {
+	ID               uuid.UUID  `json:"id"`
+	MessageID        *uuid.UUID `json:"message_id,omitempty"`
+	DMMessageID      *uuid.UUID `json:"dm_message_id,omitempty"`
+	Filename         string     `json:"filename"`
+	OriginalFilename string     `json:"original_filename"`
+	ContentType      string     `json:"content_type"`
+	Size             int64      `json:"size"`
+	Width            *int       `json:"width,omitempty"`
+	Height           *int       `json:"height,omitempty"`
+	URL              string     `json:"url"`
+	IsExternal       bool       `json:"is_external"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// DMConversation is a direct or group conversation and its participants.
+type DMConversation struct {
+	ID           uuid.UUID       `json:"id"`
+	IsGroup      bool            `json:"is_group"`
+	Name         *string         `json:"name"`
+	Accepted     bool            `json:"accepted"`
+	Encrypted    bool            `json:"encrypted"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Participants []DMParticipant `json:"participants,omitempty"`
+}
+
+type DMParticipant struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName *string   `json:"display_name"`
+	AvatarURL   *string   `json:"avatar_url"`
+	Status      string    `json:"status"`
+}
+
+// DMMessage is a direct message. As with Message, author details are filled
+// in by history endpoints and DM_MESSAGE_CREATE events.
+type DMMessage struct {
+	ID             uuid.UUID  `json:"id"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	AuthorID       uuid.UUID  `json:"author_id"`
+	Content        string     `json:"content"`
+	Type           string     `json:"type"`
+	ReplyToID      *uuid.UUID `json:"reply_to_id"`
+	Nonce          string     `json:"nonce,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	AuthorUsername    string        `json:"author_username,omitempty"`
+	AuthorDisplayName *string       `json:"author_display_name,omitempty"`
+	AuthorAvatarURL   *string       `json:"author_avatar_url,omitempty"`
+	Attachments       []Attachment  `json:"attachments,omitempty"`
+	ReplyTo           *ReplySnippet `json:"reply_to,omitempty"`
+	Reactions         []Reaction    `json:"reactions,omitempty"`
+}
+
+type DMMessageEdit struct {
+	ID          uuid.UUID `json:"id"`
+	DMMessageID uuid.UUID `json:"dm_message_id"`
+	Content     string    `json:"content"`
+	EditedAt    time.Time `json:"edited_at"`
+}
+
+type Role struct {
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	Name        string    `json:"name"`
+	Color       *string   `json:"color"`
+	Position    int       `json:"position"`
+	Permissions int64     `json:"permissions,string"`
+	Hoist       bool      `json:"hoist"`
+	// ManagedBotID is set on the role a bot receives when it is authorized
+	// into a server.
+	ManagedBotID *uuid.UUID `json:"managed_bot_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RolePosition moves a role when reordering.
+type RolePosition struct {
+	RoleID   uuid.UUID `json:"role_id"`
+	Position int       `json:"position"`
+}
+
+// ChannelPermissionOverride allows or denies permissions for a role in one
+// channel.
+type ChannelPermissionOverride struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	RoleID    uuid.UUID `json:"role_id"`
+	Allow     int64     `json:"allow,string"`
+	Deny      int64     `json:"deny,string"`
+}
+
+type CustomEmoji struct {
+	ID        uuid.UUID `json:"id"`
+	ServerID  uuid.UUID `json:"server_id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	CreatorID uuid.UUID `json:"creator_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SoundboardSound struct {
+	ID         uuid.UUID `json:"id"`
+	ServerID   uuid.UUID `json:"server_id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	DurationMs int       `json:"duration_ms"`
+	CreatorID  uuid.UUID `json:"creator_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type LinkPreview struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	ImageURL    *string   `json:"image_url"`
+	SiteName    *string   `json:"site_name"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Friendship is a friend request or friendship. Listings add the other
+// user's profile.
+type Friendship struct {
+	ID          uuid.UUID `json:"id"`
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Username    string  `json:"username,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
+	AvatarURL   *string `json:"avatar_url,omitempty"`
+	UserStatus  string  `json:"user_status,omitempty"`
+}
+
+type Ban struct {
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	BannedBy    uuid.UUID `json:"banned_by"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    string    `json:"username,omitempty"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	AvatarURL   *string   `json:"avatar_url,omitempty"`
+}
+
+type Timeout struct {
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	TimedOutBy  uuid.UUID `json:"timed_out_by"`
+	Reason      string    `json:"reason"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    string    `json:"username,omitempty"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	AvatarURL   *string   `json:"avatar_url,omitempty"`
+}
+
+type AuditLogEntry struct {
+	ID            uuid.UUID       `json:"id"`
+	ServerID      uuid.UUID       `json:"server_id"`
+	ActorID       uuid.UUID       `json:"actor_id"`
+	Action        string          `json:"action"`
+	TargetID      *uuid.UUID      `json:"target_id"`
+	TargetType    *string         `json:"target_type"`
+	Changes       json.RawMessage `json:"changes"`
+	Reason        string          `json:"reason"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ActorUsername string          `json:"actor_username,omitempty"`
+}
+
+type Invite struct {
+	ID        uuid.UUID  `json:"id"`
+	ServerID  uuid.UUID  `json:"server_id"`
+	CreatorID uuid.UUID  `json:"creator_id"`
+	Code      string     `json:"code"`
+	MaxUses   *int       `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ServerInvitation is a direct invitation from one user to another.
+type ServerInvitation struct {
+	ID                uuid.UUID `json:"id"`
+	ServerID          uuid.UUID `json:"server_id"`
+	SenderID          uuid.UUID `json:"sender_id"`
+	RecipientID       uuid.UUID `json:"recipient_id"`
+	Status            string    `json:"status"`
+	CreatedAt         time.Time `json:"created_at"`
+	ServerName        string    `json:"server_name"`
+	ServerIconURL     *string   `json:"server_icon_url"`
+	SenderUsername    string    `json:"sender_username"`
+	RecipientUsername string    `json:"recipient_username"`
+}
+
+type Thread struct {
+	ID                 uuid.UUID  `json:"id"`
+	ChannelID          uuid.UUID  `json:"channel_id"`
+	ParentMessageID    uuid.UUID  `json:"parent_message_id"`
+	Name               string     `json:"name"`
+	CreatorID          uuid.UUID  `json:"creator_id"`
+	Archived           bool       `json:"archived"`
+	Locked             bool       `json:"locked"`
+	AutoArchiveMinutes int        `json:"auto_archive_minutes"`
+	MessageCount       int        `json:"message_count"`
+	LastMessageAt      *time.Time `json:"last_message_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+type ThreadMessage struct {
+	ID                uuid.UUID  `json:"id"`
+	ThreadID          uuid.UUID  `json:"thread_id"`
+	AuthorID          uuid.UUID  `json:"author_id"`
+	Content           string     `json:"content"`
+	ReplyToID         *uuid.UUID `json:"reply_to_id"`
+	Embeds            []Embed    `json:"embeds,omitempty"`
+	WebhookID         *uuid.UUID `json:"webhook_id,omitempty"`
+	WebhookUsername   *string    `json:"webhook_username,omitempty"`
+	WebhookAvatarURL  *string    `json:"webhook_avatar_url,omitempty"`
+	Nonce             string     `json:"nonce,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	AuthorUsername    string     `json:"author_username"`
+	AuthorDisplayName *string    `json:"author_display_name"`
+	AuthorAvatarURL   *string    `json:"author_avatar_url"`
+}
+
+type ThreadSubscription struct {
+	ThreadID          uuid.UUID `json:"thread_id"`
+	UserID            uuid.UUID `json:"user_id"`
+	NotificationLevel string    `json:"notification_level"`
+}
+
+type Poll struct {
+	ID          uuid.UUID    `json:"id"`
+	MessageID   *uuid.UUID   `json:"message_id"`
+	Question    string       `json:"question"`
+	MultiSelect bool         `json:"multi_select"`
+	Anonymous   bool         `json:"anonymous"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Options     []PollOption `json:"options"`
+	TotalVotes  int          `json:"total_votes"`
+}
+
+type PollOption struct {
+	ID        uuid.UUID `json:"id"`
+	PollID    uuid.UUID `json:"poll_id"`
+	Text      string    `json:"text"`
+	Emoji     string    `json:"emoji"`
+	Position  int       `json:"position"`
+	VoteCount int       `json:"vote_count"`
+	Voted     bool      `json:"voted"`
+}
+
+type ForumTag struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Emoji     string    `json:"emoji"`
+	Position  int       `json:"position"`
+	Moderated bool      `json:"moderated"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ForumPost struct {
+	ID                uuid.UUID  `json:"id"`
+	ChannelID         uuid.UUID  `json:"channel_id"`
+	AuthorID          uuid.UUID  `json:"author_id"`
+	Title             string     `json:"title"`
+	Pinned            bool       `json:"pinned"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	AuthorUsername    string     `json:"author_username"`
+	AuthorDisplayName *string    `json:"author_display_name"`
+	AuthorAvatarURL   *string    `json:"author_avatar_url"`
+	Tags              []ForumTag `json:"tags"`
+	ReplyCount        int        `json:"reply_count"`
+	LastActivityAt    time.Time  `json:"last_activity_at"`
+	ContentPreview    string     `json:"content_preview"`
+}
+
+type ForumPostMessage struct {
+	ID                uuid.UUID `json:"id"`
+	PostID            uuid.UUID `json:"post_id"`
+	AuthorID          uuid.UUID `json:"author_id"`
+	Content           string    `json:"content"`
+	Nonce             string    `json:"nonce,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	AuthorUsername    string    `json:"author_username"`
+	AuthorDisplayName *string   `json:"author_display_name"`
+	AuthorAvatarURL   *string   `json:"author_avatar_url"`
+}
+
+type WelcomeConfig struct {
+	WelcomeMessage  string      `json:"welcome_message"`
+	WelcomeChannels []uuid.UUID `json:"welcome_channels"`
+}
+
+type OnboardingPrompt struct {
+	ID          uuid.UUID          `json:"id"`
+	ServerID    uuid.UUID          `json:"server_id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Required    bool               `json:"required"`
+	Position    int                `json:"position"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Options     []OnboardingOption `json:"options"`
+}
+
+type OnboardingOption struct {
+	ID          uuid.UUID   `json:"id"`
+	PromptID    uuid.UUID   `json:"prompt_id"`
+	Label       string      `json:"label"`
+	Description string      `json:"description"`
+	Emoji       string      `json:"emoji"`
+	RoleIDs     []uuid.UUID `json:"role_ids"`
+	ChannelIDs  []uuid.UUID `json:"channel_ids"`
+	Position    int         `json:"position"`
+}
+
+type AutoModRule struct {
+	ID             uuid.UUID       `json:"id"`
+	ServerID       uuid.UUID       `json:"server_id"`
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	TriggerData    json.RawMessage `json:"trigger_data"`
+	Action         string          `json:"action"`
+	ActionMetadata json.RawMessage `json:"action_metadata"`
+	Enabled        bool            `json:"enabled"`
+	ExemptRoles    []uuid.UUID     `json:"exempt_roles"`
+	ExemptChannels []uuid.UUID     `json:"exempt_channels"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type StageInstance struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	Topic     string    `json:"topic"`
+	StartedBy uuid.UUID `json:"started_by"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type StageSpeaker struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Invited   bool      `json:"invited"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+type StageHandRaise struct {
+	ChannelID uuid.UUID `json:"channel_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	RaisedAt  time.Time `json:"raised_at"`
+}
+
+// StageInfo is a stage channel's live state; Instance is nil when no stage
+// is running.
+type StageInfo struct {
+	Instance   *StageInstance   `json:"instance"`
+	Speakers   []StageSpeaker   `json:"speakers"`
+	HandRaises []StageHandRaise `json:"hand_raises"`
+}
+
+// VoiceToken grants access to a LiveKit room.
+type VoiceToken struct {
+	Token string `json:"token"`
+	Room  string `json:"room"`
+}
+
+type Bot struct {
+	ID              uuid.UUID `json:"id"`
+	OwnerID         uuid.UUID `json:"owner_id"`
+	Username        string    `json:"username"`
+	AvatarURL       string    `json:"avatar_url"`
+	Permissions     int64     `json:"permissions"`
+	InteractionsURL string    `json:"interactions_url"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Command option types.
+const (
+	OptionString  = "string"
+	OptionInteger = "integer"
+	OptionNumber  = "number"
+	OptionBoolean = "boolean"
+	OptionUser    = "user"
+	OptionChannel = "channel"
+	OptionRole    = "role"
+)
+
+// SlashCommand is a command registered by a bot, global when ServerID is
+// nil.
+type SlashCommand struct {
+	ID          uuid.UUID       `json:"id"`
+	BotID       uuid.UUID       `json:"bot_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options"`
+	ServerID    *uuid.UUID      `json:"server_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type CommandOption struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Type        string         `json:"type"`
+	Required    bool           `json:"required,omitempty"`
+	Choices     []OptionChoice `json:"choices,omitempty"`
+}
+
+// OptionChoice restricts a string, integer or number option to fixed values.
+type OptionChoice struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Interaction types and response types.
+const (
+	InteractionCommand   = "command"
+	InteractionComponent = "component"
+
+	ResponseMessage  = "message"
+	ResponseUpdate   = "update"
+	ResponseDeferred = "deferred"
+)
+
+// InteractionOption is an option value supplied when invoking a command.
+type InteractionOption struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// InteractionAck is returned when an interaction has been dispatched.
+type InteractionAck struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	ChannelID uuid.UUID  `json:"channel_id"`
+	MessageID *uuid.UUID `json:"message_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CommandInteractionData is the Data of a command interaction.
+type CommandInteractionData struct {
+	CommandID uuid.UUID        `json:"command_id"`
+	Name      string           `json:"name"`
+	Options   []ResolvedOption `json:"options"`
+}
+
+// ResolvedOption is a validated command option value. Value is a string,
+// float64 or bool as decoded from JSON.
+type ResolvedOption struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// ComponentInteractionData is the Data of a component interaction.
+type ComponentInteractionData struct {
+	CustomID      string   `json:"custom_id"`
+	ComponentType string   `json:"component_type"`
+	Values        []string `json:"values"`
+}
+
+type Webhook struct {
+	ID               uuid.UUID  `json:"id"`
+	ChannelID        uuid.UUID  `json:"channel_id"`
+	Name             string     `json:"name"`
+	AvatarURL        string     `json:"avatar_url"`
+	CreatorID        uuid.UUID  `json:"creator_id"`
+	InteractionsURL  string     `json:"interactions_url"`
+	HasAdapterSecret bool       `json:"has_adapter_secret"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	MessageCount     int64      `json:"message_count"`
+	CreatedAt        time.Time  `json:"created_at"`
+	// Token and URL are only returned when a webhook is created or its
+	// token is rotated.
+	Token string `json:"token,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+type EventSubscription struct {
+	ID                  uuid.UUID `json:"id"`
+	ServerID            uuid.UUID `json:"server_id"`
+	CreatorID           uuid.UUID `json:"creator_id"`
+	URL                 string    `json:"url"`
+	EventTypes          []string  `json:"event_types"`
+	Enabled             bool      `json:"enabled"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledReason      string    `json:"disabled_reason"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type EventDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      string          `json:"last_error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+type IdentityKey struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       uuid.UUID       `json:"user_id"`
+	DeviceID     string          `json:"device_id"`
+	PublicKeyJWK json.RawMessage `json:"public_key_jwk"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+type KeyEnvelope struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Envelope  []byte    `json:"envelope"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GroupKey is a group DM key for one epoch, encrypted for one participant.
+type GroupKey struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Epoch          int       `json:"epoch"`
+	UserID         uuid.UUID `json:"user_id"`
+	EncryptedKey   []byte    `json:"encrypted_key"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ScheduledMessage is sent at ScheduledAt to a channel or DM conversation.
+type ScheduledMessage struct {
+	ID               uuid.UUID  `json:"id"`
+	ChannelID        *uuid.UUID `json:"channel_id"`
+	DMConversationID *uuid.UUID `json:"dm_conversation_id"`
+	AuthorID         uuid.UUID  `json:"author_id"`
+	Content          string     `json:"content"`
+	Type             string     `json:"type"`
+	ScheduledAt      time.Time  `json:"scheduled_at"`
+	Sent             bool       `json:"sent"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// NotificationPref is a notification setting ("all", "mentions" or "none")
+// for a server, channel or DM conversation.
+type NotificationPref struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ScopeType string    `json:"scope_type"`
+	ScopeID   uuid.UUID `json:"scope_id"`
+	Setting   string    `json:"setting"`
+}
+
+type UserPreferences struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Theme       string    `json:"theme"`
+	CompactMode bool      `json:"compact_mode"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ServerFolder struct {
+	ID        uuid.UUID   `json:"id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	Name      string      `json:"name"`
+	Color     string      `json:"color"`
+	Position  int         `json:"position"`
+	CreatedAt time.Time   `json:"created_at"`
+	ServerIDs []uuid.UUID `json:"server_ids"`
+}
+
+type UnreadCount struct {
+	ChannelID    uuid.UUID `json:"channel_id"`
+	UnreadCount  int       `json:"unread_count"`
+	MentionCount int       `json:"mention_count"`
+}
+
+type DMUnreadCount struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UnreadCount    int       `json:"unread_count"`
+}
+
+// Unread is the caller's unread state across channels and DMs.
+type Unread struct {
+	Channels []UnreadCount   `json:"channels"`
+	DMs      []DMUnreadCount `json:"dms"`
+}
+
+// MultipartUpload is a started direct-to-storage upload. Each part is PUT to
+// its URL, reported with ReportUploadPart, and the upload is then completed.
+type MultipartUpload struct {
+	PendingUploadID uuid.UUID `json:"pending_upload_id"`
+	PartURLs        []string  `json:"part_urls"`
+	PartSize        int64     `json:"part_size"`
+}
+
+type VoiceState struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	ServerID  uuid.UUID `json:"server_id"`
+	Username  string    `json:"username"`
+	Muted     bool      `json:"muted"`
+	Deafened  bool      `json:"deafened"`
+}
@@ -0,0 +1,192 @@
+package thicket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetMe returns the authenticated user's ID and username.
+func (c *Client) GetMe(ctx context.Context) (*Identity, error) {
+	return call[Identity](ctx, c, http.MethodGet, apiPath("me"), nil)
+}
+
+// GetMyProfile returns the authenticated user's full profile.
+func (c *Client) GetMyProfile(ctx context.Context) (*User, error) {
+	return call[User](ctx, c, http.MethodGet, apiPath("me", "profile"), nil)
+}
+
+// UpdateProfileParams changes profile fields; nil fields are left as they
+// are.
+type UpdateProfileParams struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	Bio         *string `json:"bio,omitempty"`
+	Pronouns    *string `json:"pronouns,omitempty"`
+}
+
+func (c *Client) UpdateProfile(ctx context.Context, params UpdateProfileParams) (*User, error) {
+	return call[User](ctx, c, http.MethodPatch, apiPath("me", "profile"), params)
+}
+
+// SetStatus sets the presence status ("online", "idle", "dnd" or
+// "invisible") and returns the status as stored.
+func (c *Client) SetStatus(ctx context.Context, status string) (string, error) {
+	var out struct {
+		Status string `json:"status"`
+	}
+	if err := c.Do(ctx, http.MethodPut, apiPath("me", "status"), map[string]string{"status": status}, &out); err != nil {
+		return "", err
+	}
+	return out.Status, nil
+}
+
+// CustomStatusParams sets a custom status. ExpiresIn is "30m", "1h", "4h",
+// "today" or empty for no expiry.
+type CustomStatusParams struct {
+	Text      string  `json:"text"`
+	Emoji     string  `json:"emoji"`
+	ExpiresIn *string `json:"expires_in,omitempty"`
+}
+
+func (c *Client) SetCustomStatus(ctx context.Context, params CustomStatusParams) (*User, error) {
+	return call[User](ctx, c, http.MethodPut, apiPath("me", "custom-status"), params)
+}
+
+func (c *Client) UploadAvatar(ctx context.Context, avatar File) (*User, error) {
+	var u User
+	if err := c.doMultipart(ctx, http.MethodPost, apiPath("me", "avatar"), nil, "avatar", []File{avatar}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (c *Client) DeleteAvatar(ctx context.Context) (*User, error) {
+	return call[User](ctx, c, http.MethodDelete, apiPath("me", "avatar"), nil)
+}
+
+// GetUserProfile returns another user's public profile.
+func (c *Client) GetUserProfile(ctx context.Context, userID uuid.UUID) (*User, error) {
+	return call[User](ctx, c, http.MethodGet, apiPath("users", userID.String(), "profile"), nil)
+}
+
+func (c *Client) GetPreferences(ctx context.Context) (*UserPreferences, error) {
+	return call[UserPreferences](ctx, c, http.MethodGet, apiPath("me", "preferences"), nil)
+}
+
+type UpdatePreferencesParams struct {
+	Theme       *string `json:"theme,omitempty"`
+	CompactMode *bool   `json:"compact_mode,omitempty"`
+}
+
+func (c *Client) UpdatePreferences(ctx context.Context, params UpdatePreferencesParams) (*UserPreferences, error) {
+	return call[UserPreferences](ctx, c, http.MethodPatch, apiPath("me", "preferences"), params)
+}
+
+func (c *Client) GetNotificationPrefs(ctx context.Context) ([]NotificationPref, error) {
+	return callList[NotificationPref](ctx, c, http.MethodGet, apiPath("me", "notification-prefs"), nil)
+}
+
+// SetNotificationPref sets the notification setting ("all", "mentions" or
+// "none") for a scope ("server", "channel" or "dm").
+func (c *Client) SetNotificationPref(ctx context.Context, scopeType string, scopeID uuid.UUID, setting string) error {
+	return c.Do(ctx, http.MethodPut, apiPath("me", "notification-prefs", scopeType, scopeID.String()),
+		map[string]string{"setting": setting}, nil)
+}
+
+// GetUnread returns unread and mention counts for every channel and DM
+// conversation.
+func (c *Client) GetUnread(ctx context.Context) (*Unread, error) {
+	return call[Unread](ctx, c, http.MethodGet, apiPath("me", "unread"), nil)
+}
+
+// AckChannel marks a channel as read.
+func (c *Client) AckChannel(ctx context.Context, channelID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("channels", channelID.String(), "ack"), nil, nil)
+}
+
+// AckDM marks a DM conversation as read.
+func (c *Client) AckDM(ctx context.Context, conversationID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPost, apiPath("dm", "conversations", conversationID.String(), "ack"), nil, nil)
+}
+
+func (c *Client) ListServerFolders(ctx context.Context) ([]ServerFolder, error) {
+	return callList[ServerFolder](ctx, c, http.MethodGet, apiPath("me", "server-folders"), nil)
+}
+
+func (c *Client) CreateServerFolder(ctx context.Context, name, color string) (*ServerFolder, error) {
+	return call[ServerFolder](ctx, c, http.MethodPost, apiPath("me", "server-folders"),
+		map[string]string{"name": name, "color": color})
+}
+
+type UpdateServerFolderParams struct {
+	Name     *string `json:"name,omitempty"`
+	Color    *string `json:"color,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+func (c *Client) UpdateServerFolder(ctx context.Context, folderID uuid.UUID, params UpdateServerFolderParams) (*ServerFolder, error) {
+	return call[ServerFolder](ctx, c, http.MethodPatch, apiPath("me", "server-folders", folderID.String()), params)
+}
+
+func (c *Client) DeleteServerFolder(ctx context.Context, folderID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("me", "server-folders", folderID.String()), nil, nil)
+}
+
+func (c *Client) AddServerToFolder(ctx context.Context, folderID, serverID uuid.UUID) error {
+	return c.Do(ctx, http.MethodPut, apiPath("me", "server-folders", folderID.String(), "servers", serverID.String()), nil, nil)
+}
+
+func (c *Client) RemoveServerFromFolder(ctx context.Context, folderID, serverID uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("me", "server-folders", folderID.String(), "servers", serverID.String()), nil, nil)
+}
+
+func (c *Client) ListScheduledMessages(ctx context.Context) ([]ScheduledMessage, error) {
+	return callList[ScheduledMessage](ctx, c, http.MethodGet, apiPath("me", "scheduled-messages"), nil)
+}
+
+// ScheduleMessageParams schedules a message to exactly one of a channel or a
+// DM conversation.
+type ScheduleMessageParams struct {
+	ChannelID        *uuid.UUID
+	DMConversationID *uuid.UUID
+	Content          string
+	Type             string
+	ScheduledAt      time.Time
+}
+
+func (c *Client) ScheduleMessage(ctx context.Context, params ScheduleMessageParams) (*ScheduledMessage, error) {
+	body := map[string]any{
+		"content":      params.Content,
+		"type":         params.Type,
+		"scheduled_at": params.ScheduledAt.Format(time.RFC3339),
+	}
+	if params.ChannelID != nil {
+		body["channel_id"] = params.ChannelID.String()
+	}
+	if params.DMConversationID != nil {
+		body["dm_conversation_id"] = params.DMConversationID.String()
+	}
+	return call[ScheduledMessage](ctx, c, http.MethodPost, apiPath("me", "scheduled-messages"), body)
+}
+
+func (c *Client) UpdateScheduledMessage(ctx context.Context, id uuid.UUID, content string, scheduledAt time.Time) (*ScheduledMessage, error) {
+	return call[ScheduledMessage](ctx, c, http.MethodPatch, apiPath("me", "scheduled-messages", id.String()), map[string]string{
+		"content":      content,
+		"scheduled_at": scheduledAt.Format(time.RFC3339),
+	})
+}
+
+func (c *Client) DeleteScheduledMessage(ctx context.Context, id uuid.UUID) error {
+	return c.Do(ctx, http.MethodDelete, apiPath("me", "scheduled-messages", id.String()), nil, nil)
+}
+
+// ExportAccountData returns a JSON export of the authenticated user's data.
+func (c *Client) ExportAccountData(ctx context.Context) ([]byte, error) {
+	var raw []byte
+	if err := c.Do(ctx, http.MethodPost, apiPath("me", "data-export"), nil, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
@@ -18,6 +18,8 @@ import type {
   PresenceData,
   MessageCreateData,
   MessageDeleteData,
+  MessageDeleteBulkData,
+  MessageRestoreData,
   MessageUpdateData,
   ChannelCreateData,
   ChannelDeleteData,
@@ -194,9 +196,40 @@ export function useWebSocketEvents() {
     unsubs.push(
       wsService.on('MESSAGE_DELETE', (data) => {
         const msg = data as MessageDeleteData
+        const { activeChannelId, activeServerId, servers } = useServerStore.getState()
+        if (msg.channel_id === activeChannelId) {
+          const server = servers.find((s) => s.id === activeServerId)
+          const { messages, updateMessage, removeMessage } = useMessageStore.getState()
+          const existing = messages.find((m) => m.id === msg.id)
+          if (existing && server?.deleted_message_display !== 'hidden') {
+            // Leave a tombstone, as history will show after a reload
+            updateMessage({ ...existing, content: '', attachments: [], reactions: [], deleted_at: new Date().toISOString() })
+          } else {
+            removeMessage(msg.id)
+          }
+        }
+      })
+    )
+
+    // MESSAGE_DELETE_BULK
+    unsubs.push(
+      wsService.on('MESSAGE_DELETE_BULK', (data) => {
+        const bulk = data as MessageDeleteBulkData
+        const { activeChannelId } = useServerStore.getState()
+        if (bulk.channel_id === activeChannelId) {
+          const { removeMessage } = useMessageStore.getState()
+          bulk.ids.forEach((id) => removeMessage(id))
+        }
+      })
+    )
+
+    // MESSAGE_RESTORE: put restored content back into its tombstone
+    unsubs.push(
+      wsService.on('MESSAGE_RESTORE', (data) => {
+        const msg = data as MessageRestoreData
         const { activeChannelId } = useServerStore.getState()
         if (msg.channel_id === activeChannelId) {
-          useMessageStore.getState().removeMessage(msg.id)
+          useMessageStore.getState().updateMessage({ ...msg, deleted_at: null })
         }
       })
     )
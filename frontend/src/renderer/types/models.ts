@@ -25,6 +25,7 @@ export interface Server {
   welcome_message: string
   welcome_channels: string[]
   default_message_retention_days?: number | null
+  deleted_message_display?: 'tombstone' | 'hidden'
   created_at: string
 }
 
@@ -119,6 +120,7 @@ export interface Message {
   author_avatar_url?: string | null
   attachments?: Attachment[]
   poll?: PollWithOptions | null
+  deleted_at?: string | null
 }
 
 export interface ServerMember {
@@ -12,6 +12,7 @@ export const PermManageServer   = 1n << 7n
 export const PermAddReactions   = 1n << 8n
 export const PermAttachFiles    = 1n << 9n
 export const PermCreateInvite   = 1n << 10n
+export const PermMentionEveryone = 1n << 11n
 export const PermPinMessages    = 1n << 12n
 export const PermVoiceConnect   = 1n << 13n
 export const PermVoiceSpeak     = 1n << 14n
@@ -38,6 +39,7 @@ export const PERMISSION_LABELS: { perm: bigint; name: string; description: strin
   { perm: PermAddReactions, name: 'Add Reactions', description: 'Allows adding reactions to messages', category: 'Text' },
   { perm: PermAttachFiles, name: 'Attach Files', description: 'Allows uploading files and images', category: 'Text' },
   { perm: PermCreateInvite, name: 'Create Invite', description: 'Allows creating invite links and inviting users', category: 'General' },
+  { perm: PermMentionEveryone, name: 'Mention @everyone and @here', description: 'Allows notifying every member with @everyone, or every online member with @here', category: 'Text' },
   { perm: PermPinMessages, name: 'Pin Messages', description: 'Allows pinning messages in a channel', category: 'Text' },
   { perm: PermManageChannels, name: 'Manage Channels', description: 'Allows creating, editing, and deleting channels', category: 'Management' },
   { perm: PermManageRoles, name: 'Manage Roles', description: 'Allows creating and editing roles below their highest role', category: 'Management' },
@@ -183,6 +183,22 @@ export default function MessageItem({ message, isOwn }: MessageItemProps) {
     }
   }, [isEditing])
 
+  // Render deleted message tombstone
+  if (message.deleted_at) {
+    return (
+      <div className="flex gap-3 py-1.5 px-2 -mx-2 rounded-lg">
+        <div className="flex-shrink-0 w-10 h-10" />
+        <div className="flex items-center gap-2 text-sm text-sol-text-muted italic">
+          <svg width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+            <polyline points="3 6 5 6 21 6" />
+            <path d="M19 6l-1 14a2 2 0 0 1-2 2H8a2 2 0 0 1-2-2L5 6" />
+          </svg>
+          <span>This message was deleted</span>
+        </div>
+      </div>
+    )
+  }
+
   // Render blocked message placeholder
   if (isAuthorBlocked && !showBlockedContent) {
     return (
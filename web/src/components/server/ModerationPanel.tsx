@@ -526,6 +526,9 @@ const ACTION_LABELS: Record<string, { label: string; color: string }> = {
   MEMBER_KICK: { label: 'Kicked', color: 'text-sol-amber' },
   MEMBER_TIMEOUT: { label: 'Timed out', color: 'text-sol-amber' },
   MEMBER_TIMEOUT_REMOVE: { label: 'Removed timeout', color: 'text-sol-sage' },
+  MESSAGE_BULK_DELETE: { label: 'Bulk deleted messages', color: 'text-sol-coral' },
+  MESSAGE_PURGE: { label: 'Purged messages', color: 'text-sol-coral' },
+  MESSAGE_RESTORE: { label: 'Restored message', color: 'text-sol-sage' },
 }
 
 function AuditLogTab() {
@@ -1,4 +1,4 @@
-import type { Attachment } from './models'
+import type { Attachment, Message } from './models'
 
 export type WSEventType =
   // Client -> Server
@@ -20,6 +20,8 @@ export type WSEventType =
   | 'MESSAGE_CREATE'
   | 'MESSAGE_UPDATE'
   | 'MESSAGE_DELETE'
+  | 'MESSAGE_DELETE_BULK'
+  | 'MESSAGE_RESTORE'
   | 'CHANNEL_CREATE'
   | 'CHANNEL_UPDATE'
   | 'CHANNEL_DELETE'
@@ -123,6 +125,14 @@ export interface MessageDeleteData {
   channel_id: string
 }
 
+// MESSAGE_RESTORE carries the message as history shows it
+export type MessageRestoreData = Message
+
+export interface MessageDeleteBulkData {
+  ids: string[]
+  channel_id: string
+}
+
 export interface MessageUpdateData {
   id: string
   channel_id: string